@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"cryptowatch/internal/app/admin"
+	"cryptowatch/internal/app/portfolio"
+	"cryptowatch/internal/app/token"
+	"cryptowatch/internal/app/trigger"
+	"cryptowatch/internal/app/user"
+	pb "cryptowatch/pkg/api/cryptowatchv1"
+	pbv2 "cryptowatch/pkg/api/cryptowatchv2"
+	"cryptowatch/pkg/ratelimit"
+	"cryptowatch/pkg/util/authtoken"
+	"errors"
+	"flag"
+	"fmt"
+	"google.golang.org/grpc"
+	"log"
+	"net"
+	"time"
+)
+
+func runServeAPI(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("serve-api", flag.ExitOnError)
+	autoMigrate := fs.Bool("auto-migrate", false, "apply pending database migrations on start")
+	fs.Parse(args)
+
+	if *autoMigrate {
+		err := migrateUp(ctx, a.db)
+		if err != nil {
+			return err
+		}
+	}
+
+	return serveAPI(ctx, a)
+}
+
+func serveAPI(ctx context.Context, a *app) error {
+	userRepo := user.NewPostgresRepo(a.db)
+	paseto, err := newTokenMaker(ctx, a)
+	if err != nil {
+		return fmt.Errorf("failed to craete paseto token maker: %w", err)
+	}
+	otpManager := user.NewOTPManager(userRepo, user.OTPConfig{
+		TTL:              a.cfg.OTPTTL,
+		MaxAttempts:      a.cfg.OTPMaxAttempts,
+		GenerateInterval: a.cfg.OTPGenerateInterval,
+	})
+	denylist := user.NewDenylist(userRepo, a.cfg.AccessTokenDuration)
+	err = denylist.Refresh(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load session denylist: %w", err)
+	}
+	go denylist.Run(ctx, a.cfg.SessionDenylistInterval)
+	idTokenVerifier, err := newIDTokenVerifier(ctx, a)
+	if err != nil {
+		return err
+	}
+	userSvc := user.NewService(
+		userRepo,
+		paseto,
+		otpManager,
+		user.WithTokenDurations(a.cfg.AccessTokenDuration, a.cfg.RefreshTokenDuration),
+		user.WithDenylist(denylist),
+		user.WithTOTPIssuer(a.cfg.TOTPIssuer),
+		user.WithTokenClaims(a.cfg.TokenIssuer, a.cfg.TokenAudience),
+		user.WithOIDC(idTokenVerifier, a.cfg.OIDCProvision),
+		user.WithPasswordPolicy(user.PasswordPolicy{
+			MinLength:      a.cfg.PasswordMinLength,
+			MinCharClasses: a.cfg.PasswordMinCharClasses,
+			AllowUsername:  a.cfg.PasswordAllowUsername,
+		}),
+		user.WithLoginThrottle(user.LoginThrottle{
+			DelayAfter:      a.cfg.LoginDelayAfter,
+			LockoutAfter:    a.cfg.LoginLockoutAfter,
+			LockoutDuration: a.cfg.LoginLockoutDuration,
+			Window:          a.cfg.LoginFailureWindow,
+		}),
+	)
+	proxies, err := user.ParseNetworks(a.cfg.RateLimitTrustedProxies)
+	if err != nil {
+		return fmt.Errorf("failed to parse trusted proxies: %w", err)
+	}
+	userSrv := user.NewGRPCHandler(userSvc, proxies)
+
+	tokenRepo := token.NewPostgresRepo(a.db)
+	tokenSvc := token.NewService(tokenRepo, nil)
+	err = tokenSvc.Watch(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to watch token prices: %w", err)
+	}
+
+	portfolioRepo := portfolio.NewPostgresRepo(a.db)
+	portfolioSvc := portfolio.NewService(portfolioRepo, tokenSvc)
+	portfolioSrv := portfolio.NewGRPCHandler(portfolioSvc)
+
+	migrator, err := newMigrator(a.db)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+	adminSvc := admin.NewService(admin.NewPostgresRepo(a.db), migrator)
+	adminSrv := admin.NewGRPCHandler(adminSvc, userSvc, portfolioSvc)
+
+	triggerRepo := trigger.NewPostgresRepo(a.db)
+	triggerSvc := trigger.NewService(triggerRepo, tokenSvc)
+	triggerSrv := trigger.NewGRPCHandler(triggerSvc)
+
+	lis, err := net.Listen("tcp", a.cfg.BindAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	var opts []grpc.ServerOption
+
+	creds, err := grpcServerCredentials(a.cfg)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS credentials: %w", err)
+	}
+	if creds != nil {
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	rateLimiter, err := newRateLimiter(ctx, a, proxies)
+	if err != nil {
+		return err
+	}
+
+	authorizer := user.NewAuthorizer(
+		paseto,
+		denylist,
+		user.WithAPIKeys(userSvc),
+		user.WithTokenExpectations(authtoken.ExpectIssuer(a.cfg.TokenIssuer), authtoken.ExpectAudience(a.cfg.TokenAudience)),
+	)
+	opts = append(
+		opts,
+		grpc.ChainUnaryInterceptor(user.RateLimitUnaryInterceptor(rateLimiter), user.AuthUnaryInterceptor(authorizer)),
+		grpc.ChainStreamInterceptor(user.AuthStreamInterceptor(authorizer, a.cfg.StreamRevalidateInterval)),
+	)
+
+	grpcServer := grpc.NewServer(opts...)
+
+	pb.RegisterUsersServer(grpcServer, userSrv)
+	pb.RegisterPortfoliosServer(grpcServer, portfolioSrv)
+	pb.RegisterTriggersServer(grpcServer, triggerSrv)
+	pbv2.RegisterUsersServer(grpcServer, user.NewGRPCHandlerV2(userSvc, proxies))
+	pbv2.RegisterPortfoliosServer(grpcServer, portfolio.NewGRPCHandlerV2(portfolioSvc))
+	pbv2.RegisterTriggersServer(grpcServer, trigger.NewGRPCHandlerV2(triggerSvc))
+	pbv2.RegisterPricesServer(grpcServer, token.NewGRPCHandlerV2(tokenSvc))
+	pbv2.RegisterAdminServer(grpcServer, adminSrv)
+
+	err = authorizer.Load(grpcServer.GetServiceInfo())
+	if err != nil {
+		return fmt.Errorf("failed to load authorization policies: %w", err)
+	}
+	err = rateLimiter.Load(grpcServer.GetServiceInfo())
+	if err != nil {
+		return fmt.Errorf("failed to load rate limits: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		grpcServer.GracefulStop()
+	}()
+
+	log.Println("Listening on " + a.cfg.BindAddr)
+	return grpcServer.Serve(lis)
+}
+
+// newTokenMaker creates the maker of the configured token format. Signing keys
+// of the public format are rotated in the background until ctx is done.
+func newTokenMaker(ctx context.Context, a *app) (authtoken.Maker, error) {
+	if a.cfg.TokenFormat != "public" {
+		if a.cfg.SymmetricKey == "" {
+			return nil, errors.New("SYMMETRIC_KEY is required with the local token format")
+		}
+		return authtoken.NewPasetoMaker(a.cfg.SymmetricKey)
+	}
+
+	if a.cfg.TokenKeyRefreshInterval <= 0 {
+		return nil, errors.New("TOKEN_KEY_REFRESH_INTERVAL must be positive")
+	}
+	if a.cfg.TokenKeyRotationInterval <= 2*a.cfg.TokenKeyRefreshInterval {
+		return nil, errors.New("TOKEN_KEY_ROTATION_INTERVAL must be more than twice TOKEN_KEY_REFRESH_INTERVAL")
+	}
+
+	keys := authtoken.NewKeyRing(authtoken.NewPostgresKeyStore(a.db), authtoken.KeyRingConfig{
+		RotationInterval: a.cfg.TokenKeyRotationInterval,
+		TokenDuration:    a.cfg.AccessTokenDuration,
+		RefreshInterval:  a.cfg.TokenKeyRefreshInterval,
+	})
+	err := keys.Refresh(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load signing keys: %w", err)
+	}
+	go keys.Run(ctx)
+
+	return authtoken.NewPasetoPublicMaker(keys), nil
+}
+
+// rateLimitDropInterval is how often full rate limit buckets are dropped.
+const rateLimitDropInterval = time.Minute
+
+func newRateLimiter(ctx context.Context, a *app, proxies []*net.IPNet) (*user.RateLimiter, error) {
+	var store ratelimit.Store
+	switch a.cfg.RateLimitStore {
+	case "postgres":
+		s := ratelimit.NewPostgresStore(a.db)
+		go s.Run(ctx, rateLimitDropInterval)
+		store = s
+	default:
+		s := ratelimit.NewMemoryStore()
+		go s.Run(ctx, rateLimitDropInterval)
+		store = s
+	}
+
+	return user.NewRateLimiter(store, user.RateLimits{
+		IP:             ratelimit.Limit{Burst: a.cfg.RateLimitIPBurst, Interval: a.cfg.RateLimitIPInterval},
+		Key:            ratelimit.Limit{Burst: a.cfg.RateLimitKeyBurst, Interval: a.cfg.RateLimitKeyInterval},
+		TrustedProxies: proxies,
+	}), nil
+}
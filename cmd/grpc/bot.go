@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"cryptowatch/internal/app/telegram"
+	"cryptowatch/pkg/botapi"
+	"cryptowatch/pkg/config"
+	"errors"
+	"flag"
+	"fmt"
+	runtime2 "github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"net/http"
+	"regexp"
+)
+
+func runBot(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("run-bot", flag.ExitOnError)
+	fs.Parse(args)
+
+	return serveBot(ctx, a)
+}
+
+func serveBot(ctx context.Context, a *app) error {
+	if a.cfg.TelegramMode == "webhook" {
+		return errors.New("the gateway receives updates in the webhook mode, run-bot isn't needed")
+	}
+
+	tgSvc, err := newBot(a)
+	if err != nil {
+		return err
+	}
+
+	return tgSvc.Serve(ctx)
+}
+
+// webhookSecretRe matches the secret tokens Telegram accepts.
+var webhookSecretRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// handleTelegramWebhook runs the bot on the gateway in the webhook mode, receiving
+// updates at the path of the webhook URL.
+func handleTelegramWebhook(ctx context.Context, a *app, mux *runtime2.ServeMux) error {
+	if a.cfg.TelegramMode != "webhook" {
+		return nil
+	}
+	if a.cfg.TelegramWebhookURL == nil || a.cfg.TelegramWebhookURL.Scheme != "https" || len(a.cfg.TelegramWebhookURL.Path) < 2 {
+		return errors.New("TELEGRAM_WEBHOOK_URL must be an HTTPS URL with a path in the webhook mode")
+	}
+	if !webhookSecretRe.MatchString(a.cfg.TelegramWebhookSecret) {
+		return errors.New("TELEGRAM_WEBHOOK_SECRET must be 1 to 256 letters, digits, _ or - in the webhook mode")
+	}
+
+	tgSvc, err := newBot(a)
+	if err != nil {
+		return err
+	}
+
+	err = tgSvc.SetWebhook(ctx, telegram.WebhookConfig{
+		URL:         a.cfg.TelegramWebhookURL.String(),
+		SecretToken: a.cfg.TelegramWebhookSecret,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set telegram webhook: %w", err)
+	}
+	go tgSvc.PruneUpdates(ctx)
+	go tgSvc.ExpireConversations(ctx)
+	go tgSvc.RunSubscriptions(ctx)
+
+	handler := tgSvc.WebhookHandler(ctx, a.cfg.TelegramWebhookSecret)
+	return mux.HandlePath(http.MethodPost, a.cfg.TelegramWebhookURL.Path, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func newBot(a *app) (telegram.Telegram, error) {
+	creds, err := grpcClientCredentials(a.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS credentials: %w", err)
+	}
+
+	userClient := telegram.NewUserClient(a.cfg.GRPCEndpoint, creds)
+	tgRepo := telegram.NewPostgresRepo(a.db)
+	api := botapi.NewClient(a.cfg.TelegramToken, botapi.WithBaseURL(a.cfg.TelegramAPIURL.String()))
+	tgSvc := telegram.New(api, userClient, tgRepo)
+	tgSvc.SetPollInterval(a.cfg.TelegramPollInterval)
+	tgSvc.SetAlertInterval(a.cfg.AlertInterval)
+	tgSvc.SetConversationTimeout(a.cfg.TelegramConversationTimeout)
+	a.watcher.Subscribe(config.SubscriberFunc(func(cfg *config.Config) {
+		tgSvc.SetPollInterval(cfg.TelegramPollInterval)
+		tgSvc.SetAlertInterval(cfg.AlertInterval)
+		tgSvc.SetConversationTimeout(cfg.TelegramConversationTimeout)
+	}))
+
+	return tgSvc, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	pb "cryptowatch/pkg/api/cryptowatchv1"
+	pbv2 "cryptowatch/pkg/api/cryptowatchv2"
+	"errors"
+	"flag"
+	"fmt"
+	runtime2 "github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func runServeGateway(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("serve-gateway", flag.ExitOnError)
+	fs.Parse(args)
+
+	return serveGateway(ctx, a)
+}
+
+func serveGateway(ctx context.Context, a *app) error {
+	// Register gRPC server endpoint
+	// Note: Make sure the gRPC server is running properly and accessible
+	mux := runtime2.NewServeMux(
+		runtime2.WithIncomingHeaderMatcher(incomingHeaderMatcher),
+		runtime2.WithOutgoingHeaderMatcher(outgoingHeaderMatcher),
+	)
+	creds, err := grpcClientCredentials(a.cfg)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS credentials: %w", err)
+	}
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	err = pb.RegisterUsersHandlerFromEndpoint(ctx, mux, a.cfg.GRPCEndpoint, opts)
+	if err != nil {
+		return err
+	}
+	err = pb.RegisterPortfoliosHandlerFromEndpoint(ctx, mux, a.cfg.GRPCEndpoint, opts)
+	if err != nil {
+		return err
+	}
+	err = pb.RegisterTriggersHandlerFromEndpoint(ctx, mux, a.cfg.GRPCEndpoint, opts)
+	if err != nil {
+		return err
+	}
+	err = pbv2.RegisterUsersHandlerFromEndpoint(ctx, mux, a.cfg.GRPCEndpoint, opts)
+	if err != nil {
+		return err
+	}
+	err = pbv2.RegisterPortfoliosHandlerFromEndpoint(ctx, mux, a.cfg.GRPCEndpoint, opts)
+	if err != nil {
+		return err
+	}
+	err = pbv2.RegisterTriggersHandlerFromEndpoint(ctx, mux, a.cfg.GRPCEndpoint, opts)
+	if err != nil {
+		return err
+	}
+	err = pbv2.RegisterPricesHandlerFromEndpoint(ctx, mux, a.cfg.GRPCEndpoint, opts)
+	if err != nil {
+		return err
+	}
+	err = pbv2.RegisterAdminHandlerFromEndpoint(ctx, mux, a.cfg.GRPCEndpoint, opts)
+	if err != nil {
+		return err
+	}
+
+	conn, err := grpc.DialContext(ctx, a.cfg.GRPCEndpoint, opts...)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	usersClient := pbv2.NewUsersClient(conn)
+	err = mux.HandlePath(http.MethodGet, "/v2/token-keys", tokenKeysHandler(mux, usersClient))
+	if err != nil {
+		return err
+	}
+	err = handleOIDC(ctx, a, mux, usersClient)
+	if err != nil {
+		return err
+	}
+	err = handleTelegramWebhook(ctx, a, mux)
+	if err != nil {
+		return err
+	}
+
+	tlsCfg, err := gatewayTLSConfig(a.cfg)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS config: %w", err)
+	}
+
+	srv := &http.Server{
+		Addr:      a.cfg.GatewayAddr,
+		Handler:   mux,
+		TLSConfig: tlsCfg,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), a.cfg.ShutdownTimeout)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	// Serve HTTP server (and proxy calls to gRPC server endpoint)
+	log.Println("Gateway listening on " + a.cfg.GatewayAddr)
+	if tlsCfg != nil {
+		// The certificate is provided by TLSConfig.
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// tokenKeysMaxAge is how long clients may cache the token keys. New keys are
+// published a key refresh interval before they sign, so it should be shorter than that.
+const tokenKeysMaxAge = 30 * time.Second
+
+// tokenKeysHandler serves the keys verifying access tokens at a plain GET path,
+// so other services can fetch them like a JSON Web Key Set.
+func tokenKeysHandler(mux *runtime2.ServeMux, client pbv2.UsersClient) runtime2.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		_, marshaler := runtime2.MarshalerForRequest(mux, r)
+
+		res, err := client.GetTokenKeys(r.Context(), &emptypb.Empty{})
+		if err != nil {
+			runtime2.HTTPError(r.Context(), mux, marshaler, w, r, err)
+			return
+		}
+
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(tokenKeysMaxAge.Seconds())))
+		runtime2.ForwardResponseMessage(r.Context(), mux, marshaler, w, r, res)
+	}
+}
+
+// incomingHeaderMatcher passes the X-API-Key header as the x-api-key metadata of API keys
+// and other headers like the default matcher.
+func incomingHeaderMatcher(key string) (string, bool) {
+	if strings.EqualFold(key, "X-API-Key") {
+		return "x-api-key", true
+	}
+
+	return runtime2.DefaultHeaderMatcher(key)
+}
+
+// outgoingHeaderMatcher passes retry-after of rate limited calls as the standard HTTP header
+// and prefixes other gRPC headers like the default matcher.
+func outgoingHeaderMatcher(key string) (string, bool) {
+	if strings.EqualFold(key, "retry-after") {
+		return "Retry-After", true
+	}
+
+	return fmt.Sprintf("%s%s", runtime2.MetadataHeaderPrefix, key), true
+}
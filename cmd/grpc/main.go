@@ -2,144 +2,223 @@ package main
 
 import (
 	"context"
-	"cryptowatch/internal/app/portfolio"
-	"cryptowatch/internal/app/telegram"
-	"cryptowatch/internal/app/token"
-	"cryptowatch/internal/app/trigger"
-	"cryptowatch/internal/app/user"
-	pb "cryptowatch/pkg/api/cryptowatchv1"
 	"cryptowatch/pkg/config"
 	"cryptowatch/pkg/util"
-	"cryptowatch/pkg/util/authtoken"
 	"flag"
 	"fmt"
-	runtime2 "github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"github.com/jackc/pgx/v4/pgxpool"
 	"log"
-	"net"
-	"net/http"
-	"path"
-	"runtime"
-	"time"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
-var (
-	grpcServerEndpoint = flag.String("grpc-server-endpoint", "localhost:9090", "gRPC server endpoint")
-)
+const usage = `usage: cryptowatch [-config file] <command> [flags] [args]
+
+commands:
+  all             run every component in one process (default)
+  serve-api       run the gRPC API server
+  serve-gateway   run the HTTP gateway to the gRPC API
+  run-bot         run the Telegram bot
+  run-pricefeed   stream exchange prices into the database
+  migrate         manage database migrations, see "cryptowatch migrate"
+  reset-totp      turn two-factor authentication off for a user who lost it
+  set-role        set the role of a user, e.g. to make the first administrator
+
+Without -config the configuration is read from the environment only.`
+
+var configFile = flag.String("config", os.Getenv("CONFIG_FILE"), "path to the config file")
+
+type command struct {
+	// keys are the config keys the command requires.
+	keys []string
+	// db is true when the command needs a database connection.
+	db bool
+	// webhook is true when the command runs the bot in the Telegram webhook mode, which
+	// requires webhookKeys and a database connection as well.
+	webhook bool
+	run     func(ctx context.Context, app *app, args []string) error
+}
 
-func run() error {
-	ctx := context.Background()
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
+// webhookKeys are the config keys the gateway requires to run the bot in the Telegram webhook mode.
+var webhookKeys = append([]string{"TELEGRAM_TOKEN", "TELEGRAM_WEBHOOK_URL", "TELEGRAM_WEBHOOK_SECRET"}, config.DBKeys...)
 
-	// Register gRPC server endpoint
-	// Note: Make sure the gRPC server is running properly and accessible
-	mux := runtime2.NewServeMux()
-	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
-	err := pb.RegisterUsersHandlerFromEndpoint(ctx, mux, *grpcServerEndpoint, opts)
-	if err != nil {
-		return err
-	}
-	err = pb.RegisterPortfoliosHandlerFromEndpoint(ctx, mux, *grpcServerEndpoint, opts)
-	if err != nil {
-		return err
-	}
-	err = pb.RegisterTriggersHandlerFromEndpoint(ctx, mux, *grpcServerEndpoint, opts)
-	if err != nil {
-		return err
-	}
+// app holds what was loaded for the command being run.
+type app struct {
+	cfg     *config.Config
+	watcher *config.Watcher
+	db      *pgxpool.Pool
+}
 
-	// Serve HTTP server (and proxy calls to gRPC server endpoint)
-	return http.ListenAndServe(":8081", mux)
+var commands = map[string]command{
+	"all": {
+		keys:    append([]string{"BIND_ADDR", "GATEWAY_ADDR", "GRPC_ENDPOINT", "TELEGRAM_TOKEN", "CRYPTOCOMPARE_TOKEN"}, config.DBKeys...),
+		db:      true,
+		webhook: true,
+		run:     runAll,
+	},
+	"serve-api": {
+		keys: append([]string{"BIND_ADDR"}, config.DBKeys...),
+		db:   true,
+		run:  runServeAPI,
+	},
+	"serve-gateway": {
+		keys: []string{"GATEWAY_ADDR", "GRPC_ENDPOINT"},
+		// The gateway runs the bot in the Telegram webhook mode.
+		webhook: true,
+		run:     runServeGateway,
+	},
+	"run-bot": {
+		keys: append([]string{"GRPC_ENDPOINT", "TELEGRAM_TOKEN"}, config.DBKeys...),
+		db:   true,
+		run:  runBot,
+	},
+	"run-pricefeed": {
+		keys: append([]string{"CRYPTOCOMPARE_TOKEN"}, config.DBKeys...),
+		db:   true,
+		run:  runPricefeed,
+	},
+	"migrate": {
+		keys: config.DBKeys,
+		db:   true,
+		run: func(ctx context.Context, app *app, args []string) error {
+			return runMigrate(ctx, app.db, args)
+		},
+	},
+	"reset-totp": {
+		keys: config.DBKeys,
+		db:   true,
+		run:  runResetTOTP,
+	},
+	"set-role": {
+		keys: config.DBKeys,
+		db:   true,
+		run:  runSetRole,
+	},
 }
 
 func main() {
-	_, filename, _, _ := runtime.Caller(0)
-	rootDir := path.Join(path.Dir(filename), "../..")
-
-	cfg, err := config.LoadConfig(
-		path.Join(rootDir, "configs"),
-		"local",
-	)
-	if err != nil {
-		log.Fatalf("failed to load config: %v", err)
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
 	}
+	flag.Parse()
 
-	dbSource := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBSSLMode,
-	)
+	name, args := "all", flag.Args()
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
 
-	db, err := util.OpenDB(dbSource)
-	if err != nil {
-		log.Fatalf("failed to open db: %v", err)
+	cmd, ok := commands[name]
+	if !ok {
+		flag.Usage()
+		os.Exit(2)
 	}
 
-	userRepo := user.NewPostgresRepo(db)
-	paseto, err := authtoken.NewPasetoMaker(cfg.SymmetricKey)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	a, err := loadApp(cmd)
 	if err != nil {
-		log.Fatalf("failed to craete paseto token maker: %v", err)
+		log.Fatalf("%s: %v", name, err)
+	}
+	if a.db != nil {
+		defer a.db.Close()
 	}
-	otpManager := user.NewInMemOTPManager()
-	userSvc := user.NewService(userRepo, paseto, otpManager)
-	userSrv := user.NewGRPCHandler(userSvc)
 
-	exchange := token.NewCryptoCompareProvider(
-		cfg.CryptoCompareToken,
-		&http.Client{Timeout: 10 * time.Second},
-	)
-	tokenRepo := token.NewPostgresRepo(db)
-	tokenSvc := token.NewService(tokenRepo, exchange)
+	go func() {
+		err := a.watcher.Run(ctx)
+		if err != nil {
+			log.Printf("config watcher: %v", err)
+		}
+	}()
 
-	portfolioRepo := portfolio.NewPostgresRepo(db)
-	portfolioSvc := portfolio.NewService(portfolioRepo, tokenSvc)
-	portfolioSrv := portfolio.NewGRPCHandler(portfolioSvc)
+	err = cmd.run(ctx, a, args)
+	if err != nil {
+		log.Fatalf("%s: %v", name, err)
+	}
+}
 
-	triggerRepo := trigger.NewPostgresRepo(db)
-	triggerSvc := trigger.NewService(triggerRepo, tokenSvc)
-	triggerSrv := trigger.NewGRPCHandler(triggerSvc)
+func loadApp(cmd command) (*app, error) {
+	keys := cmd.keys
+	webhook := false
+	if cmd.webhook {
+		// The mode decides the keys required, it is read first for the validation errors to name them all.
+		cfg, err := config.Load(*configFile)
+		webhook = err == nil && cfg.TelegramMode == "webhook"
+	}
+	if webhook {
+		keys = append(append([]string{}, keys...), webhookKeys...)
+	}
 
-	lis, err := net.Listen("tcp", cfg.BindAddr)
+	cfg, err := config.Load(*configFile, keys...)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
-	var opts []grpc.ServerOption
+	a := &app{
+		cfg:     cfg,
+		watcher: config.NewWatcher(cfg, *configFile, keys...),
+	}
+	if !cmd.db && !webhook {
+		return a, nil
+	}
 
-	opts = append(opts, grpc.ChainUnaryInterceptor(user.AuthUnaryInterceptor(paseto)))
+	a.db, err = util.OpenDB(cfg.DBSource())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db: %w", err)
+	}
 
-	grpcServer := grpc.NewServer(opts...)
+	return a, nil
+}
 
-	pb.RegisterUsersServer(grpcServer, userSrv)
-	pb.RegisterPortfoliosServer(grpcServer, portfolioSrv)
-	pb.RegisterTriggersServer(grpcServer, triggerSrv)
+func runAll(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("all", flag.ExitOnError)
+	autoMigrate := fs.Bool("auto-migrate", false, "apply pending database migrations on start")
+	fs.Parse(args)
 
-	userClient := telegram.NewUserClient(cfg.BindAddr)
-	tgRepo := telegram.NewPostgresRepo(db)
-	tgSvc := telegram.New(cfg.TelegramToken, userClient, tgRepo)
-	go func() {
-		err := tgSvc.Serve(context.Background())
+	if *autoMigrate {
+		err := migrateUp(ctx, a.db)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
-	}()
+	}
 
-	err = tokenSvc.Start(context.Background())
-	if err != nil {
-		log.Fatal(err)
+	fns := []func(ctx context.Context) error{
+		func(ctx context.Context) error { return serveAPI(ctx, a) },
+		func(ctx context.Context) error { return serveGateway(ctx, a) },
+		func(ctx context.Context) error { return servePricefeed(ctx, a) },
+	}
+	// In the webhook mode the gateway receives the bot updates.
+	if a.cfg.TelegramMode != "webhook" {
+		fns = append(fns, func(ctx context.Context) error { return serveBot(ctx, a) })
 	}
 
-	go func() {
-		err := run()
-		if err != nil {
-			log.Fatal(err)
-		}
-	}()
+	return runGroup(ctx, fns...)
+}
 
-	log.Println("Listening on " + cfg.BindAddr)
-	err = grpcServer.Serve(lis)
-	if err != nil {
-		log.Fatal(err)
+// runGroup runs fns concurrently until one of them returns,
+// then cancels the rest and returns the first error.
+func runGroup(ctx context.Context, fns ...func(ctx context.Context) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, len(fns))
+	for _, fn := range fns {
+		fn := fn
+		go func() {
+			errs <- fn(ctx)
+		}()
+	}
+
+	var first error
+	for range fns {
+		err := <-errs
+		if err != nil && first == nil {
+			first = err
+		}
+		cancel()
 	}
+
+	return first
 }
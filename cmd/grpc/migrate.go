@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	dbschema "cryptowatch/db"
+	"cryptowatch/pkg/migrate"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+const migrateUsage = `usage: migrate <command>
+
+commands:
+  up          apply all pending migrations
+  down N      revert the N most recently applied migrations
+  status      show the applied version and known migrations
+  force V     set the version to V without running migrations (0 means none)`
+
+var errMigrateUsage = errors.New(migrateUsage)
+
+func newMigrator(db *pgxpool.Pool) (*migrate.Migrator, error) {
+	migrations, err := migrate.Load(dbschema.Migrations, dbschema.MigrationsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.New(db, migrations), nil
+}
+
+func migrateUp(ctx context.Context, db *pgxpool.Pool) error {
+	m, err := newMigrator(db)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	err = m.Up(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to migrate db: %w", err)
+	}
+
+	return nil
+}
+
+func runMigrate(ctx context.Context, db *pgxpool.Pool, args []string) error {
+	if len(args) == 0 {
+		return errMigrateUsage
+	}
+
+	m, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		return m.Up(ctx)
+	case "down":
+		if len(args) != 2 {
+			return errMigrateUsage
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid number of migrations %q", args[1])
+		}
+		return m.Down(ctx, n)
+	case "force":
+		if len(args) != 2 {
+			return errMigrateUsage
+		}
+		version, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		return m.Force(ctx, version)
+	case "status":
+		st, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		printMigrateStatus(st)
+		return nil
+	default:
+		return errMigrateUsage
+	}
+}
+
+func printMigrateStatus(st *migrate.Status) {
+	fmt.Printf("version: %d, dirty: %v\n", st.Version, st.Dirty)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+	for _, mg := range st.Migrations {
+		fmt.Fprintf(w, "%d\t%s\t%v\n", mg.Version, mg.Name, mg.Applied)
+	}
+	w.Flush()
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"cryptowatch/internal/app/user"
+	pbv2 "cryptowatch/pkg/api/cryptowatchv2"
+	"cryptowatch/pkg/util/oidc"
+	"errors"
+	"fmt"
+	runtime2 "github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// oidcCookie keeps state, nonce and code verifier of a login between the redirects.
+	oidcCookie = "cryptowatch_oidc"
+	// oidcLoginTTL limits how long a user may take to log in at the provider.
+	oidcLoginTTL = 10 * time.Minute
+)
+
+// oidcHTTPClient talks to the OpenID provider.
+var oidcHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// discoverOIDC loads the configuration of the OpenID provider, nil if logins with one are disabled.
+func discoverOIDC(ctx context.Context, a *app) (*oidc.Provider, error) {
+	if a.cfg.OIDCIssuerURL == nil {
+		return nil, nil
+	}
+	if a.cfg.OIDCClientID == "" {
+		return nil, errors.New("OIDC_CLIENT_ID is required with OIDC_ISSUER_URL")
+	}
+
+	provider, err := oidc.Discover(ctx, oidcHTTPClient, a.cfg.OIDCIssuerURL.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover OpenID provider: %w", err)
+	}
+
+	return provider, nil
+}
+
+// newIDTokenVerifier returns the verifier of ID tokens the API accepts, nil if logins with a provider are disabled.
+func newIDTokenVerifier(ctx context.Context, a *app) (user.IDTokenVerifier, error) {
+	provider, err := discoverOIDC(ctx, a)
+	if err != nil || provider == nil {
+		return nil, err
+	}
+
+	return oidc.NewIDTokenVerifier(provider, a.cfg.OIDCClientID, oidcHTTPClient), nil
+}
+
+// handleOIDC serves the authorization code flow of the OpenID provider if logins with one are enabled.
+func handleOIDC(ctx context.Context, a *app, mux *runtime2.ServeMux, client pbv2.UsersClient) error {
+	provider, err := discoverOIDC(ctx, a)
+	if err != nil || provider == nil {
+		return err
+	}
+	if a.cfg.OIDCRedirectURL == nil {
+		return errors.New("OIDC_REDIRECT_URL is required with OIDC_ISSUER_URL")
+	}
+
+	oidcClient := oidc.NewClient(provider, oidc.Config{
+		ClientID:     a.cfg.OIDCClientID,
+		ClientSecret: a.cfg.OIDCClientSecret,
+		RedirectURL:  a.cfg.OIDCRedirectURL.String(),
+		Scopes:       strings.Fields(a.cfg.OIDCScopes),
+	}, oidcHTTPClient)
+	secure := a.cfg.OIDCRedirectURL.Scheme == "https"
+
+	err = mux.HandlePath(http.MethodGet, "/v2/oidc/login", oidcLoginHandler(mux, oidcClient, secure))
+	if err != nil {
+		return err
+	}
+
+	return mux.HandlePath(http.MethodGet, "/v2/oidc/callback", oidcCallbackHandler(mux, oidcClient, client))
+}
+
+// oidcLoginHandler sends the browser to log in at the provider.
+func oidcLoginHandler(mux *runtime2.ServeMux, oidcClient *oidc.Client, secure bool) runtime2.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		var values [3]string
+		for i := range values {
+			v, err := oidc.RandomString()
+			if err != nil {
+				_, marshaler := runtime2.MarshalerForRequest(mux, r)
+				runtime2.HTTPError(r.Context(), mux, marshaler, w, r, status.Error(codes.Internal, "internal error"))
+				return
+			}
+			values[i] = v
+		}
+		state, nonce, verifier := values[0], values[1], values[2]
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     oidcCookie,
+			Value:    strings.Join(values[:], "."),
+			Path:     "/v2/oidc",
+			MaxAge:   int(oidcLoginTTL.Seconds()),
+			Secure:   secure,
+			HttpOnly: true,
+			// Lax, so the cookie is sent with the redirect back from the provider.
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, oidcClient.AuthCodeURL(state, nonce, verifier), http.StatusFound)
+	}
+}
+
+// oidcCallbackHandler redeems the code the provider redirected back with
+// and exchanges the ID token for tokens of the API.
+func oidcCallbackHandler(mux *runtime2.ServeMux, oidcClient *oidc.Client, client pbv2.UsersClient) runtime2.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		_, marshaler := runtime2.MarshalerForRequest(mux, r)
+		fail := func(err error) {
+			runtime2.HTTPError(r.Context(), mux, marshaler, w, r, err)
+		}
+
+		cookie, err := r.Cookie(oidcCookie)
+		if err != nil {
+			fail(status.Error(codes.FailedPrecondition, "login was not started"))
+			return
+		}
+		// A login can be finished once only.
+		http.SetCookie(w, &http.Cookie{Name: oidcCookie, Path: "/v2/oidc", MaxAge: -1})
+
+		values := strings.Split(cookie.Value, ".")
+		if len(values) != 3 {
+			fail(status.Error(codes.FailedPrecondition, "login was not started"))
+			return
+		}
+		state, nonce, verifier := values[0], values[1], values[2]
+
+		q := r.URL.Query()
+		if subtle.ConstantTimeCompare([]byte(q.Get("state")), []byte(state)) != 1 {
+			fail(status.Error(codes.InvalidArgument, "state mismatch"))
+			return
+		}
+		if e := q.Get("error"); e != "" {
+			fail(status.Errorf(codes.Unauthenticated, "login failed at the provider: %s", e))
+			return
+		}
+
+		idToken, err := oidcClient.Exchange(r.Context(), q.Get("code"), verifier)
+		if err != nil {
+			fail(status.Error(codes.Unauthenticated, err.Error()))
+			return
+		}
+
+		// Passes the user agent and address of the browser like generated handlers do.
+		ctx, err := runtime2.AnnotateContext(r.Context(), mux, r, "/cryptowatch.v2.Users/LoginOIDC")
+		if err != nil {
+			fail(status.Error(codes.InvalidArgument, err.Error()))
+			return
+		}
+
+		res, err := client.LoginOIDC(ctx, &pbv2.LoginOIDCReq{IdToken: idToken, Nonce: nonce})
+		if err != nil {
+			fail(err)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		runtime2.ForwardResponseMessage(ctx, mux, marshaler, w, r, res)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"cryptowatch/internal/app/token"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+func runPricefeed(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("run-pricefeed", flag.ExitOnError)
+	fs.Parse(args)
+
+	return servePricefeed(ctx, a)
+}
+
+func servePricefeed(ctx context.Context, a *app) error {
+	exchange := token.NewCryptoCompareProvider(
+		a.cfg.CryptoCompareToken,
+		a.cfg.CryptoCompareAPIURL.String(),
+		a.cfg.CryptoCompareStreamURL.String(),
+		&http.Client{Timeout: a.cfg.CryptoCompareTimeout},
+	)
+	tokenRepo := token.NewPostgresRepo(a.db)
+	tokenSvc := token.NewService(tokenRepo, exchange)
+
+	err := tokenSvc.Start(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start price feed: %w", err)
+	}
+
+	log.Println("Price feed started")
+	<-ctx.Done()
+
+	return nil
+}
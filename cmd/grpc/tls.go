@@ -0,0 +1,56 @@
+package main
+
+import (
+	"crypto/tls"
+	"cryptowatch/pkg/config"
+	"cryptowatch/pkg/util/tlsutil"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// grpcServerCredentials returns TLS credentials for the gRPC server or nil when TLS is disabled.
+func grpcServerCredentials(cfg *config.Config) (credentials.TransportCredentials, error) {
+	if cfg.TLSCertFile == "" && cfg.TLSKeyFile == "" {
+		return nil, nil
+	}
+
+	tlsCfg, err := tlsutil.NewServerConfig(
+		cfg.TLSCertFile,
+		cfg.TLSKeyFile,
+		cfg.TLSClientCAFile,
+		tlsutil.ClientAuth(cfg.TLSClientAuth),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return credentials.NewTLS(tlsCfg), nil
+}
+
+// grpcClientCredentials returns credentials used by internal clients of the gRPC server.
+func grpcClientCredentials(cfg *config.Config) (credentials.TransportCredentials, error) {
+	if !cfg.GRPCTLS {
+		return insecure.NewCredentials(), nil
+	}
+
+	tlsCfg, err := tlsutil.NewClientConfig(
+		cfg.GRPCTLSCAFile,
+		cfg.GRPCTLSCertFile,
+		cfg.GRPCTLSKeyFile,
+		cfg.GRPCTLSServerName,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return credentials.NewTLS(tlsCfg), nil
+}
+
+// gatewayTLSConfig returns the gateway HTTPS configuration or nil when HTTPS is disabled.
+func gatewayTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	if cfg.GatewayTLSCertFile == "" && cfg.GatewayTLSKeyFile == "" {
+		return nil, nil
+	}
+
+	return tlsutil.NewServerConfig(cfg.GatewayTLSCertFile, cfg.GatewayTLSKeyFile, "", "")
+}
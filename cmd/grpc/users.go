@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"cryptowatch/internal/app/user"
+	"errors"
+	"fmt"
+	"log"
+)
+
+var (
+	errResetTOTPUsage = errors.New("usage: reset-totp <username>")
+	errSetRoleUsage   = errors.New("usage: set-role <username> <user|support|admin>")
+)
+
+// runResetTOTP turns two-factor authentication off after the identity
+// of the user was checked some other way. The user can log in with the password and enroll again.
+func runResetTOTP(ctx context.Context, a *app, args []string) error {
+	if len(args) != 1 {
+		return errResetTOTPUsage
+	}
+
+	userSvc := user.NewService(user.NewPostgresRepo(a.db), nil, nil)
+	u, err := userSvc.GetByUsername(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("failed to find %s: %w", args[0], err)
+	}
+	err = userSvc.ResetTOTP(ctx, u.ID)
+	if err != nil {
+		return fmt.Errorf("failed to reset two-factor authentication of %s: %w", args[0], err)
+	}
+
+	log.Printf("two-factor authentication of %s is off", args[0])
+
+	return nil
+}
+
+// runSetRole sets the role of a user. Administrators manage roles with the Admin service,
+// this is for the first one. The user has to log in again.
+func runSetRole(ctx context.Context, a *app, args []string) error {
+	if len(args) != 2 || !user.Role(args[1]).Valid() {
+		return errSetRoleUsage
+	}
+
+	userSvc := user.NewService(user.NewPostgresRepo(a.db), nil, nil)
+	u, err := userSvc.GetByUsername(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("failed to find %s: %w", args[0], err)
+	}
+	err = userSvc.SetRole(ctx, u.ID, user.Role(args[1]))
+	if err != nil {
+		return fmt.Errorf("failed to set the role of %s: %w", args[0], err)
+	}
+
+	log.Printf("%s is %s now", args[0], args[1])
+
+	return nil
+}
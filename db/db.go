@@ -0,0 +1,13 @@
+// Package db holds the database schema migrations.
+// The SQL files are embedded so the binary can apply them itself.
+package db
+
+import "embed"
+
+// Migrations contains the *.up.sql and *.down.sql files from the migrations directory.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
+
+// MigrationsDir is the directory inside Migrations where the SQL files are stored.
+const MigrationsDir = "migrations"
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS tokens_added ON tokens;
+DROP FUNCTION IF EXISTS notify_token_added;
+DROP TRIGGER IF EXISTS tokens_price_updated ON tokens;
+DROP FUNCTION IF EXISTS notify_token_price;
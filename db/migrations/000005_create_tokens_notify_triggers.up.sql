@@ -0,0 +1,28 @@
+CREATE FUNCTION notify_token_price() RETURNS trigger AS
+$$
+BEGIN
+    PERFORM pg_notify('token_prices', json_build_object('ticker', NEW.ticker, 'price', NEW.price)::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tokens_price_updated
+    AFTER UPDATE OF price
+    ON tokens
+    FOR EACH ROW
+    WHEN (OLD.price IS DISTINCT FROM NEW.price)
+EXECUTE FUNCTION notify_token_price();
+
+CREATE FUNCTION notify_token_added() RETURNS trigger AS
+$$
+BEGIN
+    PERFORM pg_notify('tokens_added', NEW.ticker);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tokens_added
+    AFTER INSERT
+    ON tokens
+    FOR EACH ROW
+EXECUTE FUNCTION notify_token_added();
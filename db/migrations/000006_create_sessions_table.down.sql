@@ -0,0 +1,3 @@
+ALTER TABLE telegram_accounts DROP COLUMN refresh_token;
+
+DROP TABLE IF EXISTS sessions;
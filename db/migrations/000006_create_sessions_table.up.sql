@@ -0,0 +1,23 @@
+CREATE TABLE sessions
+(
+    id                          uuid,
+    user_id                     bigint      NOT NULL,
+    refresh_token_hash          varchar     NOT NULL,
+    previous_refresh_token_hash varchar,
+    user_agent                  varchar     NOT NULL DEFAULT '',
+    client_ip                   varchar     NOT NULL DEFAULT '',
+    create_time                 timestamptz NOT NULL DEFAULT current_timestamp,
+    refresh_time                timestamptz NOT NULL DEFAULT current_timestamp,
+    expire_time                 timestamptz NOT NULL,
+    revoke_time                 timestamptz,
+
+    CONSTRAINT sessions_pkey PRIMARY KEY (id),
+    CONSTRAINT sessions_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT sessions_refresh_token_hash_key UNIQUE (refresh_token_hash)
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
+CREATE INDEX sessions_previous_refresh_token_hash_idx ON sessions (previous_refresh_token_hash);
+CREATE INDEX sessions_revoke_time_idx ON sessions (revoke_time) WHERE revoke_time IS NOT NULL;
+
+ALTER TABLE telegram_accounts ADD COLUMN refresh_token varchar;
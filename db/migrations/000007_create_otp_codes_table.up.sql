@@ -0,0 +1,11 @@
+CREATE TABLE otp_codes
+(
+    user_id     bigint,
+    code_hash   varchar,
+    attempts    int         NOT NULL DEFAULT 0,
+    create_time timestamptz NOT NULL DEFAULT current_timestamp,
+    expire_time timestamptz NOT NULL,
+
+    CONSTRAINT otp_codes_pkey PRIMARY KEY (user_id),
+    CONSTRAINT otp_codes_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
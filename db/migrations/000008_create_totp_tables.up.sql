@@ -0,0 +1,37 @@
+CREATE TABLE totp_secrets
+(
+    user_id        bigint,
+    secret         varchar     NOT NULL,
+    last_used_step bigint      NOT NULL DEFAULT 0,
+    create_time    timestamptz NOT NULL DEFAULT current_timestamp,
+    confirm_time   timestamptz,
+
+    CONSTRAINT totp_secrets_pkey PRIMARY KEY (user_id),
+    CONSTRAINT totp_secrets_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE recovery_codes
+(
+    user_id   bigint,
+    code_hash varchar,
+    use_time  timestamptz,
+
+    CONSTRAINT recovery_codes_pkey PRIMARY KEY (user_id, code_hash),
+    CONSTRAINT recovery_codes_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE login_challenges
+(
+    token_hash  varchar,
+    user_id     bigint      NOT NULL,
+    user_agent  varchar     NOT NULL DEFAULT '',
+    client_ip   varchar     NOT NULL DEFAULT '',
+    attempts    int         NOT NULL DEFAULT 0,
+    create_time timestamptz NOT NULL DEFAULT current_timestamp,
+    expire_time timestamptz NOT NULL,
+
+    CONSTRAINT login_challenges_pkey PRIMARY KEY (token_hash),
+    CONSTRAINT login_challenges_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX login_challenges_user_id_idx ON login_challenges (user_id);
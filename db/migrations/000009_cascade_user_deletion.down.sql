@@ -0,0 +1,14 @@
+ALTER TABLE telegram_accounts
+    DROP CONSTRAINT IF EXISTS telegram_accounts_user_id_fkey;
+
+ALTER TABLE triggers
+    DROP CONSTRAINT triggers_user_id_fkey,
+    ADD CONSTRAINT triggers_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id);
+
+ALTER TABLE transactions
+    DROP CONSTRAINT transactions_portfolio_id_fkey,
+    ADD CONSTRAINT transactions_portfolio_id_fkey FOREIGN KEY (portfolio_id) REFERENCES portfolios (id);
+
+ALTER TABLE portfolios
+    DROP CONSTRAINT portfolios_user_id_fkey,
+    ADD CONSTRAINT portfolios_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id);
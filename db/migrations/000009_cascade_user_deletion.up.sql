@@ -0,0 +1,19 @@
+ALTER TABLE portfolios
+    DROP CONSTRAINT portfolios_user_id_fkey,
+    ADD CONSTRAINT portfolios_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+
+ALTER TABLE transactions
+    DROP CONSTRAINT transactions_portfolio_id_fkey,
+    ADD CONSTRAINT transactions_portfolio_id_fkey FOREIGN KEY (portfolio_id) REFERENCES portfolios (id) ON DELETE CASCADE;
+
+ALTER TABLE triggers
+    DROP CONSTRAINT triggers_user_id_fkey,
+    ADD CONSTRAINT triggers_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+
+-- Chats linked to users that no longer exist are unlinked before the key is added.
+UPDATE telegram_accounts
+SET user_id = NULL, auth_token = NULL, refresh_token = NULL
+WHERE user_id IS NOT NULL AND user_id NOT IN (SELECT id FROM users);
+
+ALTER TABLE telegram_accounts
+    ADD CONSTRAINT telegram_accounts_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
@@ -0,0 +1,24 @@
+CREATE TABLE rate_limit_buckets
+(
+    key         varchar,
+    tokens      double precision NOT NULL,
+    -- allowed tells whether the last call took a token.
+    allowed     boolean          NOT NULL,
+    update_time timestamptz      NOT NULL,
+    drop_time   timestamptz      NOT NULL,
+
+    CONSTRAINT rate_limit_buckets_pkey PRIMARY KEY (key)
+);
+
+CREATE INDEX rate_limit_buckets_drop_time_idx ON rate_limit_buckets (drop_time);
+
+CREATE TABLE login_failures
+(
+    user_id           bigint,
+    failures          int         NOT NULL,
+    last_failure_time timestamptz NOT NULL,
+    locked_until      timestamptz,
+
+    CONSTRAINT login_failures_pkey PRIMARY KEY (user_id),
+    CONSTRAINT login_failures_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
@@ -0,0 +1,21 @@
+CREATE TABLE api_keys
+(
+    id             uuid,
+    user_id        bigint      NOT NULL,
+    name           varchar     NOT NULL,
+    -- prefix is the start of the key shown to tell keys apart.
+    prefix         varchar     NOT NULL,
+    key_hash       varchar     NOT NULL,
+    scopes         varchar[]   NOT NULL DEFAULT '{}',
+    create_time    timestamptz NOT NULL DEFAULT current_timestamp,
+    expire_time    timestamptz,
+    last_used_time timestamptz,
+    revoke_time    timestamptz,
+
+    CONSTRAINT api_keys_pkey PRIMARY KEY (id),
+    CONSTRAINT api_keys_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT api_keys_key_hash_key UNIQUE (key_hash)
+);
+
+CREATE INDEX api_keys_user_id_idx ON api_keys (user_id);
+CREATE INDEX api_keys_revoke_time_idx ON api_keys (revoke_time) WHERE revoke_time IS NOT NULL;
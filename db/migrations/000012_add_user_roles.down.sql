@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS users_role_idx;
+
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS users_role_valid,
+    DROP COLUMN IF EXISTS disable_time,
+    DROP COLUMN IF EXISTS role;
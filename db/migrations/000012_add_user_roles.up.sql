@@ -0,0 +1,6 @@
+ALTER TABLE users
+    ADD COLUMN role         varchar NOT NULL DEFAULT 'user',
+    ADD COLUMN disable_time timestamptz,
+    ADD CONSTRAINT users_role_valid CHECK (role IN ('user', 'support', 'admin'));
+
+CREATE INDEX users_role_idx ON users (role) WHERE role <> 'user';
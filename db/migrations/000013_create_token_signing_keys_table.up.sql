@@ -0,0 +1,15 @@
+CREATE TABLE token_signing_keys
+(
+    id            varchar,
+    -- private_key is the Ed25519 private key signing v2.public access tokens.
+    private_key   bytea       NOT NULL,
+    create_time   timestamptz NOT NULL DEFAULT current_timestamp,
+    -- activate_time is when the key starts signing, it is published for verification before.
+    activate_time timestamptz NOT NULL,
+    -- expire_time is when tokens signed by the key have expired and the key can be dropped.
+    expire_time   timestamptz NOT NULL,
+
+    CONSTRAINT token_signing_keys_pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX token_signing_keys_expire_time_idx ON token_signing_keys (expire_time);
@@ -0,0 +1,3 @@
+-- scopes limit access tokens of the session, e.g. of the Telegram bot, none limit nothing.
+ALTER TABLE sessions
+    ADD COLUMN scopes varchar[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,15 @@
+-- user_identities link accounts of OpenID providers to users, who log in with them.
+CREATE TABLE user_identities
+(
+    issuer      varchar     NOT NULL,
+    subject     varchar     NOT NULL,
+    user_id     bigint      NOT NULL,
+    -- email is the address the provider knew when the identity was linked.
+    email       varchar     NOT NULL DEFAULT '',
+    create_time timestamptz NOT NULL DEFAULT current_timestamp,
+
+    CONSTRAINT user_identities_pkey PRIMARY KEY (issuer, subject),
+    CONSTRAINT user_identities_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX user_identities_user_id_idx ON user_identities (user_id);
@@ -0,0 +1,11 @@
+-- telegram_updates are ids of updates received by webhook, so an update
+-- Telegram delivers again, or to another replica, is handled once.
+CREATE TABLE telegram_updates
+(
+    update_id    bigint,
+    receive_time timestamptz NOT NULL DEFAULT current_timestamp,
+
+    CONSTRAINT telegram_updates_pkey PRIMARY KEY (update_id)
+);
+
+CREATE INDEX telegram_updates_receive_time_idx ON telegram_updates (receive_time);
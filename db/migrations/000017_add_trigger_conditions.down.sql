@@ -0,0 +1,16 @@
+-- Only one trigger per user and token is kept.
+DELETE
+FROM triggers t
+    USING triggers o
+WHERE t.user_id = o.user_id
+  AND t.token_ticker = o.token_ticker
+  AND t.id > o.id;
+
+ALTER TABLE triggers
+    DROP CONSTRAINT triggers_user_id_token_ticker_condition_price_key,
+    DROP CONSTRAINT triggers_condition_valid,
+    DROP CONSTRAINT triggers_pkey,
+    DROP COLUMN price,
+    DROP COLUMN condition,
+    DROP COLUMN id,
+    ADD CONSTRAINT triggers_pkey PRIMARY KEY (user_id, token_ticker);
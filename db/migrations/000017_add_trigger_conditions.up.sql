@@ -0,0 +1,10 @@
+-- Triggers get an id and a price condition, a user may have several per token.
+ALTER TABLE triggers DROP CONSTRAINT triggers_pkey;
+
+ALTER TABLE triggers
+    ADD COLUMN id        bigserial,
+    ADD COLUMN condition varchar         NOT NULL DEFAULT 'any',
+    ADD COLUMN price     decimal(32, 16) NOT NULL DEFAULT 0,
+    ADD CONSTRAINT triggers_pkey PRIMARY KEY (id),
+    ADD CONSTRAINT triggers_condition_valid CHECK (condition IN ('any', 'above', 'below')),
+    ADD CONSTRAINT triggers_user_id_token_ticker_condition_price_key UNIQUE (user_id, token_ticker, condition, price);
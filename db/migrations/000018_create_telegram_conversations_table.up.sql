@@ -0,0 +1,15 @@
+-- telegram_conversations are multi-step commands of chats waiting for input, like a login
+-- waiting for its code, so they survive restarts and continue on any replica.
+CREATE TABLE telegram_conversations
+(
+    chat_id     bigint,
+    flow        varchar     NOT NULL,
+    state       varchar     NOT NULL,
+    data        jsonb       NOT NULL DEFAULT '{}',
+    expire_time timestamptz NOT NULL,
+
+    CONSTRAINT telegram_conversations_pkey PRIMARY KEY (chat_id),
+    CONSTRAINT telegram_conversations_chat_id_fkey FOREIGN KEY (chat_id) REFERENCES telegram_accounts (id) ON DELETE CASCADE
+);
+
+CREATE INDEX telegram_conversations_expire_time_idx ON telegram_conversations (expire_time);
@@ -0,0 +1,10 @@
+-- telegram_alert_snoozes hold back alerts for a token sent to a chat until snooze_time.
+CREATE TABLE telegram_alert_snoozes
+(
+    chat_id      bigint,
+    token_ticker varchar     NOT NULL,
+    snooze_time  timestamptz NOT NULL,
+
+    CONSTRAINT telegram_alert_snoozes_pkey PRIMARY KEY (chat_id, token_ticker),
+    CONSTRAINT telegram_alert_snoozes_chat_id_fkey FOREIGN KEY (chat_id) REFERENCES telegram_accounts (id) ON DELETE CASCADE
+);
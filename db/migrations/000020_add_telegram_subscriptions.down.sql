@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS telegram_accounts_subscribed_idx;
+
+ALTER TABLE telegram_accounts
+    DROP COLUMN IF EXISTS subscribed,
+    DROP COLUMN IF EXISTS subscription_holder,
+    DROP COLUMN IF EXISTS subscription_lease_time;
@@ -0,0 +1,8 @@
+-- Subscribed chats receive the alerts of their user. The alerts of a chat are streamed by one
+-- replica of the bot, the subscription_holder, which renews its lease until subscription_lease_time.
+ALTER TABLE telegram_accounts
+    ADD COLUMN subscribed              boolean NOT NULL DEFAULT false,
+    ADD COLUMN subscription_holder     varchar,
+    ADD COLUMN subscription_lease_time timestamptz;
+
+CREATE INDEX telegram_accounts_subscribed_idx ON telegram_accounts (id) WHERE subscribed;
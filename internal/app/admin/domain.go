@@ -0,0 +1,26 @@
+package admin
+
+import "time"
+
+// SystemStatus is a snapshot of the state of the API server and its database.
+type SystemStatus struct {
+	StartTime time.Time `json:"start_time"`
+	GoVersion string    `json:"go_version"`
+
+	// SchemaVersion is the last applied migration.
+	SchemaVersion uint64 `json:"schema_version"`
+	SchemaDirty   bool   `json:"schema_dirty"`
+	// PendingMigrations are known to the server but not applied.
+	PendingMigrations int `json:"pending_migrations"`
+
+	Users          int64 `json:"users"`
+	DisabledUsers  int64 `json:"disabled_users"`
+	ActiveSessions int64 `json:"active_sessions"`
+	ActiveAPIKeys  int64 `json:"active_api_keys"`
+	Portfolios     int64 `json:"portfolios"`
+	Triggers       int64 `json:"triggers"`
+
+	DBTotalConns    int32 `json:"db_total_conns"`
+	DBIdleConns     int32 `json:"db_idle_conns"`
+	DBAcquiredConns int32 `json:"db_acquired_conns"`
+}
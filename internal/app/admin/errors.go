@@ -0,0 +1,20 @@
+package admin
+
+import (
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	ErrInternalError = errors.New("internal error")
+)
+
+func ErrToGRPCErr(err error) error {
+	switch {
+	case errors.Is(err, ErrInternalError):
+		return status.New(codes.Internal, err.Error()).Err()
+	default:
+		return status.New(codes.Unknown, err.Error()).Err()
+	}
+}
@@ -0,0 +1,202 @@
+package admin
+
+import (
+	"context"
+	"cryptowatch/internal/app/portfolio"
+	"cryptowatch/internal/app/user"
+	pb "cryptowatch/pkg/api/cryptowatchv2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"time"
+)
+
+// GRPCHandler serves the Admin service. Methods acting on users and portfolios
+// are passed to their services, which don't check roles: the authorizer does.
+type GRPCHandler struct {
+	svc          Service
+	userSvc      user.Service
+	portfolioSvc portfolio.Service
+
+	pb.UnimplementedAdminServer
+}
+
+func NewGRPCHandler(svc Service, userSvc user.Service, portfolioSvc portfolio.Service) *GRPCHandler {
+	return &GRPCHandler{
+		svc:          svc,
+		userSvc:      userSvc,
+		portfolioSvc: portfolioSvc,
+	}
+}
+
+func (h *GRPCHandler) ListUsers(ctx context.Context, req *pb.ListUsersReq) (*pb.ListUsersRes, error) {
+	res, err := h.userSvc.ListUsers(ctx, user.SvcListUsersReq{
+		Query:     req.GetQuery(),
+		Role:      roleFromPB(req.GetRole()),
+		PageSize:  int(req.GetPageSize()),
+		PageToken: req.GetPageToken(),
+	})
+	if err != nil {
+		return nil, user.ErrToGRPCErr(err)
+	}
+
+	users := make([]*pb.AdminUser, 0, len(res.Users))
+	for _, u := range res.Users {
+		users = append(users, userToPB(u))
+	}
+
+	return &pb.ListUsersRes{
+		Users:         users,
+		NextPageToken: res.NextPageToken,
+	}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandler) GetUser(ctx context.Context, req *pb.AdminUserReq) (*pb.AdminUser, error) {
+	u, err := h.userSvc.GetByID(ctx, req.GetUserId())
+	if err != nil {
+		return nil, user.ErrToGRPCErr(err)
+	}
+
+	return userToPB(u), status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandler) ListUserPortfolios(ctx context.Context, req *pb.AdminUserReq) (*pb.ListUserPortfoliosRes, error) {
+	portfolios, err := h.portfolioSvc.UserPortfolios(ctx, req.GetUserId())
+	if err != nil {
+		return nil, portfolio.ErrToGRPCErr(err)
+	}
+
+	res := &pb.ListUserPortfoliosRes{
+		Portfolios: make([]*pb.AdminPortfolio, 0, len(portfolios)),
+	}
+	for _, p := range portfolios {
+		transactions := make([]*pb.AdminTransaction, 0, len(p.Transactions))
+		for _, t := range p.Transactions {
+			transactions = append(transactions, &pb.AdminTransaction{
+				Id:       t.ID,
+				Ticker:   t.TokenTicker,
+				Quantity: t.Quantity,
+				Price:    t.Price,
+				Fee:      t.Fee,
+				Time:     timestamppb.New(t.Timestamp),
+			})
+		}
+		res.Portfolios = append(res.Portfolios, &pb.AdminPortfolio{
+			Id:           p.Portfolio.ID,
+			Name:         p.Portfolio.Name,
+			Profit:       p.Profit,
+			Transactions: transactions,
+		})
+	}
+
+	return res, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandler) LogoutUser(ctx context.Context, req *pb.AdminUserReq) (*emptypb.Empty, error) {
+	err := h.userSvc.LogoutUser(ctx, req.GetUserId())
+	if err != nil {
+		return nil, user.ErrToGRPCErr(err)
+	}
+
+	return &emptypb.Empty{}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandler) ResetTOTP(ctx context.Context, req *pb.AdminUserReq) (*emptypb.Empty, error) {
+	err := h.userSvc.ResetTOTP(ctx, req.GetUserId())
+	if err != nil {
+		return nil, user.ErrToGRPCErr(err)
+	}
+
+	return &emptypb.Empty{}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandler) DisableUser(ctx context.Context, req *pb.AdminUserReq) (*emptypb.Empty, error) {
+	err := h.userSvc.DisableUser(ctx, req.GetUserId())
+	if err != nil {
+		return nil, user.ErrToGRPCErr(err)
+	}
+
+	return &emptypb.Empty{}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandler) EnableUser(ctx context.Context, req *pb.AdminUserReq) (*emptypb.Empty, error) {
+	err := h.userSvc.EnableUser(ctx, req.GetUserId())
+	if err != nil {
+		return nil, user.ErrToGRPCErr(err)
+	}
+
+	return &emptypb.Empty{}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandler) SetRole(ctx context.Context, req *pb.SetRoleReq) (*emptypb.Empty, error) {
+	err := h.userSvc.SetRole(ctx, req.GetUserId(), roleFromPB(req.GetRole()))
+	if err != nil {
+		return nil, user.ErrToGRPCErr(err)
+	}
+
+	return &emptypb.Empty{}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandler) GetSystemStatus(ctx context.Context, _ *emptypb.Empty) (*pb.SystemStatus, error) {
+	st, err := h.svc.SystemStatus(ctx)
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return &pb.SystemStatus{
+		StartTime:         timestamppb.New(st.StartTime),
+		Uptime:            durationpb.New(time.Since(st.StartTime)),
+		GoVersion:         st.GoVersion,
+		SchemaVersion:     st.SchemaVersion,
+		SchemaDirty:       st.SchemaDirty,
+		PendingMigrations: int32(st.PendingMigrations),
+		Users:             st.Users,
+		DisabledUsers:     st.DisabledUsers,
+		ActiveSessions:    st.ActiveSessions,
+		ActiveApiKeys:     st.ActiveAPIKeys,
+		Portfolios:        st.Portfolios,
+		Triggers:          st.Triggers,
+		DbTotalConns:      st.DBTotalConns,
+		DbIdleConns:       st.DBIdleConns,
+		DbAcquiredConns:   st.DBAcquiredConns,
+	}, status.New(codes.OK, "OK").Err()
+}
+
+func userToPB(u *user.User) *pb.AdminUser {
+	res := &pb.AdminUser{
+		Id:         u.ID,
+		Username:   u.Username,
+		FirstName:  u.FirstName,
+		LastName:   u.LastName,
+		CreateTime: timestamppb.New(u.CreateTime),
+		Role:       roleToPB(u.Role),
+	}
+	if u.DisableTime != nil {
+		res.DisableTime = timestamppb.New(*u.DisableTime)
+	}
+
+	return res
+}
+
+var pbRoles = map[user.Role]pb.Role{
+	user.RoleUser:    pb.Role_ROLE_USER,
+	user.RoleSupport: pb.Role_ROLE_SUPPORT,
+	user.RoleAdmin:   pb.Role_ROLE_ADMIN,
+}
+
+func roleToPB(r user.Role) pb.Role {
+	return pbRoles[r]
+}
+
+// roleFromPB returns the role or an empty one for ROLE_UNSPECIFIED.
+func roleFromPB(r pb.Role) user.Role {
+	for role, pbRole := range pbRoles {
+		if pbRole == r {
+			return role
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,24 @@
+package admin
+
+import "context"
+
+type Repository interface {
+	// Counts returns the numbers of rows of the status.
+	Counts(ctx context.Context) (*RepoCounts, error)
+	PoolStats() RepoPoolStats
+}
+
+type RepoCounts struct {
+	Users          int64 `json:"users"`
+	DisabledUsers  int64 `json:"disabled_users"`
+	ActiveSessions int64 `json:"active_sessions"`
+	ActiveAPIKeys  int64 `json:"active_api_keys"`
+	Portfolios     int64 `json:"portfolios"`
+	Triggers       int64 `json:"triggers"`
+}
+
+type RepoPoolStats struct {
+	TotalConns    int32 `json:"total_conns"`
+	IdleConns     int32 `json:"idle_conns"`
+	AcquiredConns int32 `json:"acquired_conns"`
+}
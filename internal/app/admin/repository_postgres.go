@@ -0,0 +1,62 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	usersTable      = "users"
+	sessionsTable   = "sessions"
+	apiKeysTable    = "api_keys"
+	portfoliosTable = "portfolios"
+	triggersTable   = "triggers"
+)
+
+type postgresRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgresRepo(db *pgxpool.Pool) *postgresRepo {
+	return &postgresRepo{
+		db: db,
+	}
+}
+
+var countsQuery = fmt.Sprintf(`
+SELECT
+	(SELECT count(*) FROM %[1]s),
+	(SELECT count(*) FROM %[1]s WHERE disable_time IS NOT NULL),
+	(SELECT count(*) FROM %[2]s WHERE revoke_time IS NULL AND expire_time > current_timestamp),
+	(SELECT count(*) FROM %[3]s WHERE revoke_time IS NULL AND (expire_time IS NULL OR expire_time > current_timestamp)),
+	(SELECT count(*) FROM %[4]s),
+	(SELECT count(*) FROM %[5]s)
+`, usersTable, sessionsTable, apiKeysTable, portfoliosTable, triggersTable)
+
+func (r *postgresRepo) Counts(ctx context.Context) (*RepoCounts, error) {
+	var c RepoCounts
+	err := r.db.QueryRow(ctx, countsQuery).Scan(
+		&c.Users,
+		&c.DisabledUsers,
+		&c.ActiveSessions,
+		&c.ActiveAPIKeys,
+		&c.Portfolios,
+		&c.Triggers,
+	)
+	if err != nil {
+		return nil, ErrInternalError
+	}
+
+	return &c, nil
+}
+
+func (r *postgresRepo) PoolStats() RepoPoolStats {
+	st := r.db.Stat()
+
+	return RepoPoolStats{
+		TotalConns:    st.TotalConns(),
+		IdleConns:     st.IdleConns(),
+		AcquiredConns: st.AcquiredConns(),
+	}
+}
@@ -0,0 +1,70 @@
+package admin
+
+import (
+	"context"
+	"cryptowatch/pkg/migrate"
+	"runtime"
+	"time"
+)
+
+// Service reports the state of the system to administrators.
+// Users and their portfolios are managed with the user and portfolio services.
+type Service interface {
+	SystemStatus(ctx context.Context) (*SystemStatus, error)
+}
+
+// Migrator tells which schema migrations are applied, migrate.Migrator is one.
+type Migrator interface {
+	Status(ctx context.Context) (*migrate.Status, error)
+}
+
+type service struct {
+	repo      Repository
+	migrator  Migrator
+	startTime time.Time
+}
+
+func NewService(repo Repository, migrator Migrator) *service {
+	return &service{
+		repo:      repo,
+		migrator:  migrator,
+		startTime: time.Now(),
+	}
+}
+
+func (s *service) SystemStatus(ctx context.Context) (*SystemStatus, error) {
+	counts, err := s.repo.Counts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := s.migrator.Status(ctx)
+	if err != nil {
+		return nil, ErrInternalError
+	}
+	pending := 0
+	for _, mg := range schema.Migrations {
+		if !mg.Applied {
+			pending++
+		}
+	}
+
+	pool := s.repo.PoolStats()
+
+	return &SystemStatus{
+		StartTime:         s.startTime,
+		GoVersion:         runtime.Version(),
+		SchemaVersion:     schema.Version,
+		SchemaDirty:       schema.Dirty,
+		PendingMigrations: pending,
+		Users:             counts.Users,
+		DisabledUsers:     counts.DisabledUsers,
+		ActiveSessions:    counts.ActiveSessions,
+		ActiveAPIKeys:     counts.ActiveAPIKeys,
+		Portfolios:        counts.Portfolios,
+		Triggers:          counts.Triggers,
+		DBTotalConns:      pool.TotalConns,
+		DBIdleConns:       pool.IdleConns,
+		DBAcquiredConns:   pool.AcquiredConns,
+	}, nil
+}
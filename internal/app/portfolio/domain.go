@@ -1,5 +1,7 @@
 package portfolio
 
+import "time"
+
 type Portfolio struct {
 	ID     uint64 `json:"id"`
 	UserID uint64 `json:"user_id"`
@@ -7,10 +9,22 @@ type Portfolio struct {
 }
 
 type Transaction struct {
-	ID          uint64  `json:"id"`
-	PortfolioID uint64  `json:"portfolio_id"`
-	TokenTicker string  `json:"token_ticker"`
-	Quantity    float64 `json:"quantity"`
-	Price       float64 `json:"price"`
-	Fee         float64 `json:"fee"`
+	ID          uint64    `json:"id"`
+	PortfolioID uint64    `json:"portfolio_id"`
+	TokenTicker string    `json:"token_ticker"`
+	Quantity    float64   `json:"quantity"`
+	Price       float64   `json:"price"`
+	Fee         float64   `json:"fee"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// Holding is the position in a token, summed over the transactions of a portfolio.
+type Holding struct {
+	Ticker   string  `json:"ticker"`
+	Quantity float64 `json:"quantity"`
+	// Cost is the amount paid for the position, fees included, less the amount sold for.
+	Cost float64 `json:"cost"`
+	// Value is the quantity at the current price.
+	Value  float64 `json:"value"`
+	Profit float64 `json:"profit"`
 }
@@ -1,9 +1,27 @@
 package portfolio
 
-import "errors"
+import (
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
 
 var (
 	ErrNotFound           = errors.New("not found")
 	ErrInternalError      = errors.New("internal error")
 	ErrFailedPrecondition = errors.New("failed precondition")
+	ErrUnauthenticated    = errors.New("unauthenticated")
 )
+
+func ErrToGRPCErr(err error) error {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return status.New(codes.NotFound, err.Error()).Err()
+	case errors.Is(err, ErrFailedPrecondition):
+		return status.New(codes.FailedPrecondition, err.Error()).Err()
+	case errors.Is(err, ErrUnauthenticated):
+		return status.New(codes.Unauthenticated, err.Error()).Err()
+	default:
+		return status.New(codes.Internal, err.Error()).Err()
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
+// GRPCHandler serves the v1 API. The authorization interceptor checks
+// user_id of requests matches the token, services take the user from the context.
 type GRPCHandler struct {
 	svc Service
 
@@ -23,7 +25,7 @@ func NewGRPCHandler(svc Service) *GRPCHandler {
 }
 
 func (h *GRPCHandler) CreatePortfolio(ctx context.Context, req *pb.CreatePortfolioReq) (*wrapperspb.UInt64Value, error) {
-	id, err := h.svc.CreatePortfolio(ctx, req.GetUserId(), req.GetName())
+	id, err := h.svc.CreatePortfolio(ctx, req.GetName())
 	if err != nil {
 		if errors.Is(err, ErrFailedPrecondition) {
 			return nil, status.New(codes.FailedPrecondition, err.Error()).Err()
@@ -35,7 +37,7 @@ func (h *GRPCHandler) CreatePortfolio(ctx context.Context, req *pb.CreatePortfol
 }
 
 func (h *GRPCHandler) Buy(ctx context.Context, req *pb.BuySellReq) (*emptypb.Empty, error) {
-	err := h.svc.Buy(ctx, req.GetUserId(), req.GetPortfolioId(), req.GetTicker(), req.GetQuantity(), req.GetPrice(), req.GetFee())
+	err := h.svc.Buy(ctx, req.GetPortfolioId(), req.GetTicker(), req.GetQuantity(), req.GetPrice(), req.GetFee())
 	if err != nil {
 		if errors.Is(err, ErrNotFound) {
 			return nil, status.New(codes.NotFound, err.Error()).Err()
@@ -50,7 +52,7 @@ func (h *GRPCHandler) Buy(ctx context.Context, req *pb.BuySellReq) (*emptypb.Emp
 }
 
 func (h *GRPCHandler) Sell(ctx context.Context, req *pb.BuySellReq) (*emptypb.Empty, error) {
-	err := h.svc.Sell(ctx, req.GetUserId(), req.GetPortfolioId(), req.GetTicker(), req.GetQuantity(), req.GetPrice(), req.GetFee())
+	err := h.svc.Sell(ctx, req.GetPortfolioId(), req.GetTicker(), req.GetQuantity(), req.GetPrice(), req.GetFee())
 	if err != nil {
 		if errors.Is(err, ErrNotFound) {
 			return nil, status.New(codes.NotFound, err.Error()).Err()
@@ -65,7 +67,7 @@ func (h *GRPCHandler) Sell(ctx context.Context, req *pb.BuySellReq) (*emptypb.Em
 }
 
 func (h *GRPCHandler) Info(ctx context.Context, req *pb.InfoReq) (*pb.InfoRes, error) {
-	res, err := h.svc.Info(ctx, req.GetUserId(), req.GetPortfolioId())
+	res, err := h.svc.Info(ctx, req.GetPortfolioId())
 	if err != nil {
 		return nil, status.New(codes.Internal, err.Error()).Err()
 	}
@@ -0,0 +1,108 @@
+package portfolio
+
+import (
+	"context"
+	pb "cryptowatch/pkg/api/cryptowatchv2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// GRPCHandlerV2 serves the v2 API, where the user comes from the access token only.
+type GRPCHandlerV2 struct {
+	svc Service
+
+	pb.UnimplementedPortfoliosServer
+}
+
+func NewGRPCHandlerV2(svc Service) *GRPCHandlerV2 {
+	return &GRPCHandlerV2{
+		svc: svc,
+	}
+}
+
+func (h *GRPCHandlerV2) CreatePortfolio(ctx context.Context, req *pb.CreatePortfolioReq) (*wrapperspb.UInt64Value, error) {
+	id, err := h.svc.CreatePortfolio(ctx, req.GetName())
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return &wrapperspb.UInt64Value{Value: id}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) Buy(ctx context.Context, req *pb.BuySellReq) (*emptypb.Empty, error) {
+	err := h.svc.Buy(ctx, req.GetPortfolioId(), req.GetTicker(), req.GetQuantity(), req.GetPrice(), req.GetFee())
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return &emptypb.Empty{}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) Sell(ctx context.Context, req *pb.BuySellReq) (*emptypb.Empty, error) {
+	err := h.svc.Sell(ctx, req.GetPortfolioId(), req.GetTicker(), req.GetQuantity(), req.GetPrice(), req.GetFee())
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return &emptypb.Empty{}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) Info(ctx context.Context, req *pb.InfoReq) (*pb.InfoRes, error) {
+	res, err := h.svc.Info(ctx, req.GetPortfolioId())
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return &pb.InfoRes{Profit: res.Profit}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) ListPortfolios(ctx context.Context, _ *emptypb.Empty) (*pb.ListPortfoliosRes, error) {
+	portfolios, err := h.svc.ListPortfolios(ctx)
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	res := &pb.ListPortfoliosRes{Portfolios: make([]*pb.Portfolio, 0, len(portfolios))}
+	for _, p := range portfolios {
+		res.Portfolios = append(res.Portfolios, portfolioToPBV2(p.Portfolio, p.Profit))
+	}
+
+	return res, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) GetPortfolio(ctx context.Context, req *pb.GetPortfolioReq) (*pb.PortfolioDetails, error) {
+	if req.GetPortfolioId() == 0 && req.GetName() == "" {
+		return nil, status.New(codes.InvalidArgument, "portfolio_id or name is required").Err()
+	}
+
+	details, err := h.svc.GetPortfolio(ctx, req.GetPortfolioId(), req.GetName())
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	res := &pb.PortfolioDetails{
+		Portfolio: portfolioToPBV2(details.Portfolio, details.Profit),
+		Holdings:  make([]*pb.Holding, 0, len(details.Holdings)),
+	}
+	for _, hld := range details.Holdings {
+		res.Holdings = append(res.Holdings, &pb.Holding{
+			Ticker:   hld.Ticker,
+			Quantity: hld.Quantity,
+			Cost:     hld.Cost,
+			Value:    hld.Value,
+			Profit:   hld.Profit,
+		})
+	}
+
+	return res, status.New(codes.OK, "OK").Err()
+}
+
+func portfolioToPBV2(p *Portfolio, profit float64) *pb.Portfolio {
+	return &pb.Portfolio{
+		Id:     p.ID,
+		Name:   p.Name,
+		Profit: profit,
+	}
+}
@@ -7,6 +7,13 @@ type Repository interface {
 	Sell(ctx context.Context, userID uint64, portfolioID uint64, ticker string, quantity float64, price float64, fee float64) error
 	CreatePortfolio(ctx context.Context, userID uint64, name string) (uint64, error)
 	Info(ctx context.Context, userID uint64, portfolioID uint64) (*RepoInfoRes, error)
+	GetPortfolio(ctx context.Context, userID uint64, portfolioID uint64) (*Portfolio, error)
+	GetPortfolioByName(ctx context.Context, userID uint64, name string) (*Portfolio, error)
+	ListPortfolios(ctx context.Context, userID uint64) ([]*Portfolio, error)
+	// ListHoldings returns holdings of the portfolio by ticker, sold out ones included.
+	ListHoldings(ctx context.Context, portfolioID uint64) ([]*Holding, error)
+	// ListTransactions returns transactions of the portfolio, the latest first.
+	ListTransactions(ctx context.Context, portfolioID uint64) ([]*Transaction, error)
 }
 
 type RepoInfoRes struct {
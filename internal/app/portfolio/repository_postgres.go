@@ -39,7 +39,7 @@ func (p *postgresRepo) Buy(ctx context.Context, userID uint64, portfolioID uint6
 	err := p.execTx(ctx, func(q *postgresQueries) error {
 		_, err := q.GetPortfolio(ctx, userID, portfolioID) // Check whether portfolio belongs to user.
 		if err != nil {
-			return nil
+			return err
 		}
 
 		err = q.createToken(ctx, ticker)
@@ -62,7 +62,7 @@ func (p *postgresRepo) Sell(ctx context.Context, userID uint64, portfolioID uint
 	err := p.execTx(ctx, func(q *postgresQueries) error {
 		_, err := q.GetPortfolio(ctx, userID, portfolioID) // Check whether portfolio belongs to user.
 		if err != nil {
-			return nil
+			return err
 		}
 
 		err = q.createToken(ctx, ticker)
@@ -195,8 +195,27 @@ func (q *postgresQueries) GetPortfolio(ctx context.Context, userID uint64, portf
 	return &p, nil
 }
 
+var getPortfolioByNameQuery = fmt.Sprintf(`
+SELECT id, user_id, name
+FROM %s
+WHERE user_id = $1 AND name = $2
+`, portfoliosTable)
+
+func (q *postgresQueries) GetPortfolioByName(ctx context.Context, userID uint64, name string) (*Portfolio, error) {
+	var p Portfolio
+	err := q.db.QueryRow(ctx, getPortfolioByNameQuery, userID, name).Scan(&p.ID, &p.UserID, &p.Name)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, ErrInternalError
+	}
+
+	return &p, nil
+}
+
 var infoQuery = fmt.Sprintf(`
-SELECT SUM ((tk.price - tr.price) * tr.quantity - tr.fee)
+SELECT coalesce(SUM ((tk.price - tr.price) * tr.quantity - tr.fee), 0)
 FROM %s tr
 INNER JOIN %s tk ON tk.ticker = tr.token_ticker
 WHERE tr.portfolio_id = $1;
@@ -208,7 +227,7 @@ func (r *postgresRepo) Info(ctx context.Context, userID uint64, portfolioID uint
 	err := r.execTx(ctx, func(q *postgresQueries) error {
 		_, err := q.GetPortfolio(ctx, userID, portfolioID) // Check whether portfolio belongs to user.
 		if err != nil {
-			return nil
+			return err
 		}
 
 		err = q.db.QueryRow(ctx, infoQuery, portfolioID).Scan(&res.Profit)
@@ -225,3 +244,97 @@ func (r *postgresRepo) Info(ctx context.Context, userID uint64, portfolioID uint
 
 	return &res, nil
 }
+
+var listPortfoliosQuery = fmt.Sprintf(`
+SELECT id, user_id, name
+FROM %s
+WHERE user_id = $1
+ORDER BY id
+`, portfoliosTable)
+
+func (q *postgresQueries) ListPortfolios(ctx context.Context, userID uint64) ([]*Portfolio, error) {
+	rows, err := q.db.Query(ctx, listPortfoliosQuery, userID)
+	if err != nil {
+		return nil, ErrInternalError
+	}
+	defer rows.Close()
+
+	portfolios := make([]*Portfolio, 0)
+	for rows.Next() {
+		var p Portfolio
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Name); err != nil {
+			return nil, ErrInternalError
+		}
+		portfolios = append(portfolios, &p)
+	}
+	if rows.Err() != nil {
+		return nil, ErrInternalError
+	}
+
+	return portfolios, nil
+}
+
+var listTransactionsQuery = fmt.Sprintf(`
+SELECT id, portfolio_id, token_ticker, quantity, price, fee, timestamp
+FROM %s
+WHERE portfolio_id = $1
+ORDER BY timestamp DESC, id DESC
+`, transactionsTable)
+
+func (q *postgresQueries) ListTransactions(ctx context.Context, portfolioID uint64) ([]*Transaction, error) {
+	rows, err := q.db.Query(ctx, listTransactionsQuery, portfolioID)
+	if err != nil {
+		return nil, ErrInternalError
+	}
+	defer rows.Close()
+
+	transactions := make([]*Transaction, 0)
+	for rows.Next() {
+		var t Transaction
+		err := rows.Scan(&t.ID, &t.PortfolioID, &t.TokenTicker, &t.Quantity, &t.Price, &t.Fee, &t.Timestamp)
+		if err != nil {
+			return nil, ErrInternalError
+		}
+		transactions = append(transactions, &t)
+	}
+	if rows.Err() != nil {
+		return nil, ErrInternalError
+	}
+
+	return transactions, nil
+}
+
+var listHoldingsQuery = fmt.Sprintf(`
+SELECT tr.token_ticker,
+       SUM(tr.quantity),
+       SUM(tr.price * tr.quantity + tr.fee),
+       SUM(tr.quantity) * tk.price,
+       SUM((tk.price - tr.price) * tr.quantity - tr.fee)
+FROM %s tr
+INNER JOIN %s tk ON tk.ticker = tr.token_ticker
+WHERE tr.portfolio_id = $1
+GROUP BY tr.token_ticker, tk.price
+ORDER BY tr.token_ticker
+`, transactionsTable, tokensTable)
+
+func (q *postgresQueries) ListHoldings(ctx context.Context, portfolioID uint64) ([]*Holding, error) {
+	rows, err := q.db.Query(ctx, listHoldingsQuery, portfolioID)
+	if err != nil {
+		return nil, ErrInternalError
+	}
+	defer rows.Close()
+
+	holdings := make([]*Holding, 0)
+	for rows.Next() {
+		var h Holding
+		if err := rows.Scan(&h.Ticker, &h.Quantity, &h.Cost, &h.Value, &h.Profit); err != nil {
+			return nil, ErrInternalError
+		}
+		holdings = append(holdings, &h)
+	}
+	if rows.Err() != nil {
+		return nil, ErrInternalError
+	}
+
+	return holdings, nil
+}
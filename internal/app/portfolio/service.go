@@ -3,19 +3,43 @@ package portfolio
 import (
 	"context"
 	"cryptowatch/internal/app/token"
+	"cryptowatch/pkg/util/authtoken"
 )
 
+// Service manages portfolios of the user authenticated in the context.
 type Service interface {
-	Buy(ctx context.Context, userID uint64, portfolioID uint64, ticker string, quantity float64, price float64, fee float64) error
-	Sell(ctx context.Context, userID uint64, portfolioID uint64, ticker string, quantity float64, price float64, fee float64) error
-	CreatePortfolio(ctx context.Context, userID uint64, name string) (uint64, error)
-	Info(ctx context.Context, usreID uint64, portfolioID uint64) (*SvcInfoRes, error)
+	Buy(ctx context.Context, portfolioID uint64, ticker string, quantity float64, price float64, fee float64) error
+	Sell(ctx context.Context, portfolioID uint64, ticker string, quantity float64, price float64, fee float64) error
+	CreatePortfolio(ctx context.Context, name string) (uint64, error)
+	Info(ctx context.Context, portfolioID uint64) (*SvcInfoRes, error)
+	ListPortfolios(ctx context.Context) ([]*SvcPortfolio, error)
+	// GetPortfolio returns the portfolio with the id or, if it is 0, the name with its holdings.
+	GetPortfolio(ctx context.Context, portfolioID uint64, name string) (*SvcPortfolioDetails, error)
+	// UserPortfolios returns portfolios of any user with their transactions, for support staff.
+	UserPortfolios(ctx context.Context, userID uint64) ([]*SvcUserPortfolio, error)
 }
 
 type SvcInfoRes struct {
 	Profit float64 `json:"profit"`
 }
 
+type SvcPortfolio struct {
+	Portfolio *Portfolio `json:"portfolio"`
+	Profit    float64    `json:"profit"`
+}
+
+type SvcPortfolioDetails struct {
+	Portfolio *Portfolio `json:"portfolio"`
+	Profit    float64    `json:"profit"`
+	Holdings  []*Holding `json:"holdings"`
+}
+
+type SvcUserPortfolio struct {
+	Portfolio    *Portfolio     `json:"portfolio"`
+	Profit       float64        `json:"profit"`
+	Transactions []*Transaction `json:"transactions"`
+}
+
 type service struct {
 	repo     Repository
 	tokenSvc token.Service
@@ -32,7 +56,12 @@ func NewService(repo Repository, tokenSvc token.Service) *service {
 	}
 }
 
-func (s *service) Buy(ctx context.Context, userID uint64, portfolioID uint64, ticker string, quantity float64, price float64, fee float64) error {
+func (s *service) Buy(ctx context.Context, portfolioID uint64, ticker string, quantity float64, price float64, fee float64) error {
+	userID, ok := authtoken.UserIDFromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+
 	_, err := s.tokenSvc.Add(ctx, ticker)
 	if err != nil {
 		return ErrInternalError
@@ -40,7 +69,12 @@ func (s *service) Buy(ctx context.Context, userID uint64, portfolioID uint64, ti
 	return s.repo.Buy(ctx, userID, portfolioID, ticker, quantity, price, fee)
 }
 
-func (s *service) Sell(ctx context.Context, userID uint64, portfolioID uint64, ticker string, quantity float64, price float64, fee float64) error {
+func (s *service) Sell(ctx context.Context, portfolioID uint64, ticker string, quantity float64, price float64, fee float64) error {
+	userID, ok := authtoken.UserIDFromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+
 	_, err := s.tokenSvc.Add(ctx, ticker)
 	if err != nil {
 		return ErrInternalError
@@ -48,15 +82,112 @@ func (s *service) Sell(ctx context.Context, userID uint64, portfolioID uint64, t
 	return s.repo.Sell(ctx, userID, portfolioID, ticker, quantity, price, fee)
 }
 
-func (s *service) CreatePortfolio(ctx context.Context, userID uint64, name string) (uint64, error) {
+func (s *service) CreatePortfolio(ctx context.Context, name string) (uint64, error) {
+	userID, ok := authtoken.UserIDFromContext(ctx)
+	if !ok {
+		return 0, ErrUnauthenticated
+	}
+
 	return s.repo.CreatePortfolio(ctx, userID, name)
 }
 
-func (s *service) Info(ctx context.Context, userId uint64, portfolioID uint64) (*SvcInfoRes, error) {
-	res, err := s.repo.Info(ctx, userId, portfolioID)
+func (s *service) Info(ctx context.Context, portfolioID uint64) (*SvcInfoRes, error) {
+	userID, ok := authtoken.UserIDFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	res, err := s.repo.Info(ctx, userID, portfolioID)
 	if err != nil {
 		return nil, err
 	}
 
 	return &SvcInfoRes{Profit: res.Profit}, nil
 }
+
+func (s *service) ListPortfolios(ctx context.Context) ([]*SvcPortfolio, error) {
+	userID, ok := authtoken.UserIDFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	portfolios, err := s.repo.ListPortfolios(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*SvcPortfolio, 0, len(portfolios))
+	for _, p := range portfolios {
+		info, err := s.repo.Info(ctx, userID, p.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, &SvcPortfolio{
+			Portfolio: p,
+			Profit:    info.Profit,
+		})
+	}
+
+	return res, nil
+}
+
+func (s *service) GetPortfolio(ctx context.Context, portfolioID uint64, name string) (*SvcPortfolioDetails, error) {
+	userID, ok := authtoken.UserIDFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	var p *Portfolio
+	var err error
+	if portfolioID != 0 {
+		p, err = s.repo.GetPortfolio(ctx, userID, portfolioID)
+	} else {
+		p, err = s.repo.GetPortfolioByName(ctx, userID, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	holdings, err := s.repo.ListHoldings(ctx, p.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &SvcPortfolioDetails{
+		Portfolio: p,
+		Holdings:  holdings,
+	}
+	for _, h := range holdings {
+		res.Profit += h.Profit
+	}
+
+	return res, nil
+}
+
+func (s *service) UserPortfolios(ctx context.Context, userID uint64) ([]*SvcUserPortfolio, error) {
+	portfolios, err := s.repo.ListPortfolios(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*SvcUserPortfolio, 0, len(portfolios))
+	for _, p := range portfolios {
+		info, err := s.repo.Info(ctx, userID, p.ID)
+		if err != nil {
+			return nil, err
+		}
+		transactions, err := s.repo.ListTransactions(ctx, p.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, &SvcUserPortfolio{
+			Portfolio:    p,
+			Profit:       info.Profit,
+			Transactions: transactions,
+		})
+	}
+
+	return res, nil
+}
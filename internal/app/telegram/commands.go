@@ -0,0 +1,742 @@
+package telegram
+
+import (
+	"context"
+	"cryptowatch/pkg/botapi"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxPriceTickers limits the tokens of a /price command.
+const maxPriceTickers = 10
+
+// command is a bot command. Its run func gets the arguments following it. Commands
+// asking for more input start a conversation, the next messages of the chat go to its states.
+type command struct {
+	name string
+	// args is the usage of the arguments, shown in /help and on wrong arguments.
+	args        string
+	description string
+	run         func(ctx context.Context, chat *botapi.Chat, args []string) error
+	states      map[string]*state
+}
+
+// usageError is returned by commands called with wrong arguments.
+type usageError struct {
+	cmd *command
+}
+
+func (e *usageError) Error() string {
+	return "usage: " + e.cmd.usage()
+}
+
+func (c *command) usage() string {
+	if c.args == "" {
+		return "/" + c.name
+	}
+	return "/" + c.name + " " + c.args
+}
+
+func (t *telegram) newCommands() []*command {
+	return []*command{
+		{name: "login", args: "[username]", description: "Link this chat to your account", run: t.handleCommandLogin, states: t.loginStates()},
+		{name: "logout", description: "Unlink this chat from your account", run: t.handleCommandLogout},
+		{name: "portfolios", description: "List your portfolios", run: t.handleCommandPortfolios},
+		{name: "portfolio", args: "<name>", description: "Show holdings and P&L of a portfolio", run: t.handleCommandPortfolio},
+		{name: "buy", args: "<portfolio> <ticker> <quantity> <price> [fee]", description: "Record a buy, asks for the details if sent alone", run: t.handleCommandBuy, states: t.tradeStates("buy")},
+		{name: "sell", args: "<portfolio> <ticker> <quantity> <price> [fee]", description: "Record a sale, asks for the details if sent alone", run: t.handleCommandSell, states: t.tradeStates("sell")},
+		{name: "alert", args: "<ticker> [> or < price]", description: "Alert on price changes or when a price is crossed", run: t.handleCommandAlert, states: t.alertStates()},
+		{name: "alerts", description: "List your alerts", run: t.handleCommandAlerts},
+		{name: "unalert", args: "<id or ticker>", description: "Remove an alert or all alerts of a token", run: t.handleCommandUnalert},
+		{name: "price", args: "<ticker>...", description: "Show the latest prices of tokens", run: t.handleCommandPrice},
+		{name: "subscribe", description: "Receive your alerts in this chat", run: t.handleCommandSubscribe},
+		{name: "unsubscribe", description: "Stop receiving your alerts in this chat", run: t.handleCommandUnsubscribe},
+		{name: "cancel", description: "Cancel the command waiting for your input", run: t.handleCommandCancel},
+		{name: "help", description: "Show the commands", run: t.handleCommandHelp},
+	}
+}
+
+func (t *telegram) command(name string) (*command, bool) {
+	for _, cmd := range t.commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+
+	return nil, false
+}
+
+// setCommands registers the commands, so Telegram shows them in the command menu.
+func (t *telegram) setCommands(ctx context.Context) error {
+	params := botapi.SetMyCommandsParams{Commands: make([]botapi.BotCommand, 0, len(t.commands))}
+	for _, cmd := range t.commands {
+		params.Commands = append(params.Commands, botapi.BotCommand{Command: cmd.name, Description: cmd.description})
+	}
+
+	return t.api.SetMyCommands(ctx, params)
+}
+
+var errNotCommand = errors.New("not a command")
+
+// parseCommand splits a message like `/buy "long term" BTC 1 30000` into the command name
+// and its arguments. Arguments are separated by spaces, double quotes keep spaces in one.
+func parseCommand(msg string) (string, []string, error) {
+	if !strings.HasPrefix(msg, "/") {
+		return "", nil, errNotCommand
+	}
+
+	var args []string
+	var arg strings.Builder
+	quoted, inArg := false, false
+	for _, r := range msg[1:] {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inArg = true
+		case !quoted && (r == ' ' || r == '\t' || r == '\n'):
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quoted {
+		return "", nil, errors.New("unbalanced quotes")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	if len(args) == 0 {
+		return "", nil, errNotCommand
+	}
+
+	// In groups commands may be addressed to a bot like /help@cryptowatch_bot.
+	name, _, _ := strings.Cut(args[0], "@")
+
+	return strings.ToLower(name), args[1:], nil
+}
+
+func (t *telegram) handleMessage(ctx context.Context, chat *botapi.Chat, msg string) {
+	name, args, err := parseCommand(msg)
+	isCommand := !errors.Is(err, errNotCommand)
+
+	conv, expired, convErr := t.conversation(ctx, chat)
+	if convErr != nil {
+		log.Printf("get conversation error: %v", convErr)
+		t.reply(ctx, chat, errorMessage(convErr))
+		return
+	}
+
+	if !isCommand {
+		if expired {
+			// The message was the input of the conversation, the user was told it timed out.
+			return
+		}
+		if conv == nil {
+			log.Printf("unexpected message...%q", msg)
+			t.reply(ctx, chat, "Send /help to see what I can do.")
+			return
+		}
+		t.converse(ctx, chat, conv, msg)
+		return
+	}
+	if err != nil {
+		t.reply(ctx, chat, "Unbalanced quotes in the command.")
+		return
+	}
+
+	cmd, ok := t.command(name)
+	if !ok {
+		t.reply(ctx, chat, fmt.Sprintf("Unknown command /%s, send /help to see the commands.", name))
+		return
+	}
+
+	// Another command abandons the conversation.
+	if conv != nil && cmd.name != "cancel" {
+		_, err = t.repository.DeleteConversation(ctx, chat.ID)
+		if err != nil {
+			log.Printf("delete conversation error: %v", err)
+		}
+	}
+
+	err = cmd.run(ctx, chat, args)
+	if err != nil {
+		log.Printf("handle command /%s error: %v", name, err)
+		t.reply(ctx, chat, errorMessage(err))
+	}
+}
+
+// reply sends the message, logging failures, which there is no one to tell about.
+func (t *telegram) reply(ctx context.Context, chat *botapi.Chat, msg string) {
+	err := t.sendMessage(ctx, chat, msg)
+	if err != nil {
+		log.Printf("send message error: %v", err)
+	}
+}
+
+// errorMessage tells the user why a command failed.
+func errorMessage(err error) string {
+	var usageErr *usageError
+	switch {
+	case errors.As(err, &usageErr):
+		return "Usage: " + usageErr.cmd.usage()
+	case errors.Is(err, errCodeRequested):
+		return "A code was requested recently, try again later."
+	case errors.Is(err, ErrNotLoggedIn):
+		return "Link this chat to your account with /login <username> first."
+	case errors.Is(err, ErrUnauthenticated):
+		return "Session expired, /login again."
+	case errors.Is(err, ErrPermissionDenied):
+		return "The bot isn't allowed to do that, /login again to renew its access."
+	case errors.Is(err, ErrNotFound):
+		return "Not found."
+	case errors.Is(err, ErrInvalidArgument):
+		return "Invalid arguments, see /help."
+	case errors.Is(err, ErrFailedPrecondition):
+		return "That isn't possible right now."
+	case errors.Is(err, ErrResourceExhausted):
+		return "Too many requests, try again later."
+	case errors.Is(err, ErrUnavailable):
+		return "The service is unavailable, try again later."
+	default:
+		return "Something went wrong, try again later."
+	}
+}
+
+// withToken calls call with the access token of the chat. A token expired
+// in the meantime is renewed and the call made again.
+func (t *telegram) withToken(ctx context.Context, chat *botapi.Chat, call func(token string) error) error {
+	acc, err := t.repository.GetAccount(ctx, chat.ID)
+	if err != nil {
+		return err
+	}
+	if acc.AuthToken == "" {
+		return ErrNotLoggedIn
+	}
+
+	err = call(acc.AuthToken)
+	if !errors.Is(err, ErrUnauthenticated) || acc.RefreshToken == "" {
+		return err
+	}
+
+	token, err := t.renewToken(ctx, chat.ID, acc.AuthToken)
+	if err != nil {
+		return err
+	}
+
+	return call(token)
+}
+
+var usernameRe = regexp.MustCompile("^[A-Za-z0-9]+$")
+
+func (t *telegram) handleCommandLogin(ctx context.Context, chat *botapi.Chat, args []string) error {
+	if len(args) == 0 {
+		return t.startConversation(ctx, chat, "login", "username", nil)
+	}
+	if len(args) != 1 || !usernameRe.MatchString(args[0]) {
+		return t.usage("login")
+	}
+
+	err := t.generateOTP(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	return t.startConversation(ctx, chat, "login", "code", map[string]string{"username": args[0]})
+}
+
+// errCodeRequested is returned for codes requested again too soon.
+var errCodeRequested = errors.New("a code was requested recently")
+
+// generateOTP has a code sent to the user.
+func (t *telegram) generateOTP(ctx context.Context, username string) error {
+	log.Printf("username: %q", username)
+	err := t.userClient.GenerateOTP(ctx, username)
+	if err != nil {
+		log.Printf("generate otp error: %v", err)
+		if errors.Is(err, ErrResourceExhausted) {
+			return errCodeRequested
+		}
+		return err
+	}
+	log.Printf("waiting for OTP code...")
+
+	return nil
+}
+
+func (t *telegram) loginStates() map[string]*state {
+	return map[string]*state{
+		"username": {
+			prompt: "Enter your username.",
+			handle: func(ctx context.Context, chat *botapi.Chat, conv *Conversation, msg string) (string, error) {
+				username := strings.TrimSpace(msg)
+				if !usernameRe.MatchString(username) {
+					return "", errInput("Usernames have only letters and digits")
+				}
+				err := t.generateOTP(ctx, username)
+				if err != nil {
+					return "", err
+				}
+				conv.Data["username"] = username
+				return "code", nil
+			},
+		},
+		"code": {
+			prompt: "Enter OTP code.",
+			handle: func(ctx context.Context, chat *botapi.Chat, conv *Conversation, msg string) (string, error) {
+				username := conv.Data["username"]
+				err := t.verifyOTP(ctx, chat, username, strings.TrimSpace(msg))
+				if err != nil {
+					switch {
+					case errors.Is(err, ErrUnauthenticated):
+						return "", errInput("Wrong or expired code")
+					case errors.Is(err, ErrResourceExhausted):
+						return "", t.sendMessage(ctx, chat, "Too many wrong codes, /login again later.")
+					}
+					return "", err
+				}
+				log.Printf("OTP code verified")
+
+				return "", t.sendMessage(ctx, chat, "Logged in as "+username+".")
+			},
+		},
+	}
+}
+
+func (t *telegram) handleCommandLogout(ctx context.Context, chat *botapi.Chat, args []string) error {
+	if len(args) != 0 {
+		return t.usage("logout")
+	}
+
+	err := t.withToken(ctx, chat, func(token string) error {
+		return t.userClient.Logout(ctx, token)
+	})
+	switch {
+	case errors.Is(err, ErrNotLoggedIn):
+		return t.sendMessage(ctx, chat, "This chat isn't linked to an account.")
+	case errors.Is(err, ErrUnauthenticated), errors.Is(err, ErrPermissionDenied):
+		// The session ended already or, if it was started before the bot could end
+		// sessions, ends when it expires. The chat is unlinked either way.
+	case err != nil:
+		return err
+	}
+
+	err = t.repository.ClearAuthToken(ctx, chat.ID)
+	if err != nil {
+		return err
+	}
+	t.stopSubscription(chat.ID)
+
+	return t.sendMessage(ctx, chat, "Logged out.")
+}
+
+func (t *telegram) handleCommandPortfolios(ctx context.Context, chat *botapi.Chat, args []string) error {
+	if len(args) != 0 {
+		return t.usage("portfolios")
+	}
+
+	return t.sendList(ctx, chat, "portfolios")
+}
+
+func (t *telegram) portfolioLines(ctx context.Context, chat *botapi.Chat) ([]string, string, error) {
+	portfolios, err := t.listPortfolios(ctx, chat)
+	if err != nil {
+		return nil, "", err
+	}
+
+	lines := make([]string, 0, len(portfolios))
+	for _, p := range portfolios {
+		lines = append(lines, fmt.Sprintf("%s: P&L %s", p.Name, formatProfit(p.Profit)))
+	}
+
+	return lines, "You have no portfolios yet.", nil
+}
+
+func (t *telegram) listPortfolios(ctx context.Context, chat *botapi.Chat) ([]*Portfolio, error) {
+	var portfolios []*Portfolio
+	err := t.withToken(ctx, chat, func(token string) (err error) {
+		portfolios, err = t.userClient.ListPortfolios(ctx, token)
+		return err
+	})
+
+	return portfolios, err
+}
+
+func (t *telegram) handleCommandPortfolio(ctx context.Context, chat *botapi.Chat, args []string) error {
+	if len(args) != 1 {
+		return t.usage("portfolio")
+	}
+
+	details, err := t.getPortfolio(ctx, chat, args[0])
+	if err != nil {
+		return err
+	}
+	if details == nil {
+		return nil
+	}
+
+	if len(details.Holdings) == 0 {
+		return t.sendMessage(ctx, chat, fmt.Sprintf("Portfolio %s has no transactions yet.", details.Portfolio.Name))
+	}
+
+	var b strings.Builder
+	var value, profit float64
+	fmt.Fprintf(&b, "Portfolio %s\n", details.Portfolio.Name)
+	for _, h := range details.Holdings {
+		fmt.Fprintf(&b, "%s: %s, value %.2f, P&L %s\n", h.Ticker, formatNumber(h.Quantity), h.Value, formatProfit(h.Profit))
+		value += h.Value
+		profit += h.Profit
+	}
+	fmt.Fprintf(&b, "Total value %.2f, P&L %s", value, formatProfit(profit))
+
+	return t.sendMessage(ctx, chat, b.String())
+}
+
+// getPortfolio returns the portfolio with the name or, telling the user it doesn't exist, nil.
+func (t *telegram) getPortfolio(ctx context.Context, chat *botapi.Chat, name string) (*PortfolioDetails, error) {
+	var details *PortfolioDetails
+	err := t.withToken(ctx, chat, func(token string) (err error) {
+		details, err = t.userClient.GetPortfolio(ctx, token, name)
+		return err
+	})
+	if errors.Is(err, ErrNotFound) {
+		return nil, t.sendMessage(ctx, chat, fmt.Sprintf("Portfolio %s not found, see /portfolios.", name))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return details, nil
+}
+
+func (t *telegram) handleCommandBuy(ctx context.Context, chat *botapi.Chat, args []string) error {
+	return t.trade(ctx, chat, "buy", args)
+}
+
+func (t *telegram) handleCommandSell(ctx context.Context, chat *botapi.Chat, args []string) error {
+	return t.trade(ctx, chat, "sell", args)
+}
+
+// trade records the buy or sale of the /buy or /sell command with the arguments.
+// Without arguments it asks for them.
+func (t *telegram) trade(ctx context.Context, chat *botapi.Chat, name string, args []string) error {
+	if len(args) == 0 {
+		return t.startConversation(ctx, chat, name, "portfolio", nil)
+	}
+	if len(args) != 4 && len(args) != 5 {
+		return t.usage(name)
+	}
+	trade := Trade{Ticker: strings.ToUpper(args[1])}
+	var ok bool
+	if !tickerRe.MatchString(trade.Ticker) {
+		return t.usage(name)
+	}
+	if trade.Quantity, ok = parseNumber(args[2]); !ok || trade.Quantity == 0 {
+		return t.usage(name)
+	}
+	if trade.Price, ok = parseNumber(args[3]); !ok || trade.Price == 0 {
+		return t.usage(name)
+	}
+	if len(args) == 5 {
+		if trade.Fee, ok = parseNumber(args[4]); !ok {
+			return t.usage(name)
+		}
+	}
+
+	details, err := t.getPortfolio(ctx, chat, args[0])
+	if err != nil {
+		return err
+	}
+	if details == nil {
+		return nil
+	}
+	trade.PortfolioID = details.Portfolio.ID
+
+	verb := "Bought"
+	err = t.withToken(ctx, chat, func(token string) error {
+		if name == "sell" {
+			verb = "Sold"
+			return t.userClient.Sell(ctx, token, &trade)
+		}
+		return t.userClient.Buy(ctx, token, &trade)
+	})
+	if err != nil {
+		return err
+	}
+
+	return t.sendMessage(ctx, chat, fmt.Sprintf("%s %s %s at %s in %s.",
+		verb, formatNumber(trade.Quantity), trade.Ticker, formatNumber(trade.Price), details.Portfolio.Name))
+}
+
+func (t *telegram) tradeStates(name string) map[string]*state {
+	verb := "Buy"
+	if name == "sell" {
+		verb = "Sell"
+	}
+
+	return map[string]*state{
+		"portfolio": {
+			prompt: "Enter the portfolio name.",
+			keyboard: func(ctx context.Context, chat *botapi.Chat, _ *Conversation) (*botapi.InlineKeyboardMarkup, error) {
+				portfolios, err := t.listPortfolios(ctx, chat)
+				if err != nil {
+					return nil, err
+				}
+				names := make([]string, 0, len(portfolios))
+				for _, p := range portfolios {
+					names = append(names, p.Name)
+				}
+				return choiceKeyboard("portfolio", names), nil
+			},
+			handle: func(ctx context.Context, chat *botapi.Chat, conv *Conversation, msg string) (string, error) {
+				name := strings.TrimSpace(msg)
+				err := t.withToken(ctx, chat, func(token string) error {
+					_, err := t.userClient.GetPortfolio(ctx, token, name)
+					return err
+				})
+				if errors.Is(err, ErrNotFound) {
+					return "", errInput(fmt.Sprintf("Portfolio %s not found", name))
+				}
+				if err != nil {
+					return "", err
+				}
+				conv.Data["portfolio"] = name
+				return "ticker", nil
+			},
+		},
+		"ticker":   tickerState("quantity"),
+		"quantity": numberState("Enter the quantity.", "quantity", "price", false),
+		"price":    numberState("Enter the price per token.", "price", "fee", false),
+		"fee":      numberState("Enter the fee, 0 if none.", "fee", "confirm", true),
+		"confirm": {
+			prompt: verb + " $quantity $ticker at $price in $portfolio with a fee of $fee?",
+			keyboard: func(context.Context, *botapi.Chat, *Conversation) (*botapi.InlineKeyboardMarkup, error) {
+				return choiceKeyboard("confirm", []string{"yes", "no"}), nil
+			},
+			handle: func(ctx context.Context, chat *botapi.Chat, conv *Conversation, msg string) (string, error) {
+				switch strings.ToLower(strings.TrimSpace(msg)) {
+				case "yes", "y":
+					d := conv.Data
+					return "", t.trade(ctx, chat, name, []string{d["portfolio"], d["ticker"], d["quantity"], d["price"], d["fee"]})
+				case "no", "n":
+					return "", t.sendMessage(ctx, chat, "Cancelled.")
+				}
+				return "", errInput("Answer yes or no")
+			},
+		},
+	}
+}
+
+var (
+	tickerRe = regexp.MustCompile("^[A-Z0-9]{1,16}$")
+	alertRe  = regexp.MustCompile(`^([A-Za-z0-9]{1,16})(?:([<>])([0-9]*\.?[0-9]+))?$`)
+)
+
+func (t *telegram) handleCommandAlert(ctx context.Context, chat *botapi.Chat, args []string) error {
+	if len(args) == 0 {
+		return t.startConversation(ctx, chat, "alert", "ticker", nil)
+	}
+
+	// The condition may be written with or without spaces, like BTC>30000 or BTC > 30000.
+	alert, ok := parseAlert(strings.Join(args, ""))
+	if !ok {
+		return t.usage("alert")
+	}
+
+	return t.addAlert(ctx, chat, alert)
+}
+
+// parseAlert parses an alert like BTC, BTC>30000 or BTC<1000.
+func parseAlert(s string) (*Alert, bool) {
+	m := alertRe.FindStringSubmatch(s)
+	if m == nil {
+		return nil, false
+	}
+
+	alert := Alert{Ticker: strings.ToUpper(m[1]), Condition: AlertCondition(m[2])}
+	if alert.Condition != AlertOnChange {
+		var ok bool
+		if alert.Price, ok = parseNumber(m[3]); !ok || alert.Price == 0 {
+			return nil, false
+		}
+	}
+
+	return &alert, true
+}
+
+func (t *telegram) addAlert(ctx context.Context, chat *botapi.Chat, alert *Alert) error {
+	err := t.withToken(ctx, chat, func(token string) error {
+		return t.userClient.AddAlert(ctx, token, alert)
+	})
+	if err != nil {
+		return err
+	}
+
+	return t.sendMessage(ctx, chat, fmt.Sprintf("Alert set: %s. Send /subscribe to receive alerts in this chat.", formatAlert(alert)))
+}
+
+func (t *telegram) alertStates() map[string]*state {
+	return map[string]*state{
+		"ticker": tickerState("condition"),
+		"condition": {
+			prompt: "Enter > or < and a price, like > 30000, or any to be alerted on every price change.",
+			keyboard: func(context.Context, *botapi.Chat, *Conversation) (*botapi.InlineKeyboardMarkup, error) {
+				return choiceKeyboard("condition", []string{"any"}), nil
+			},
+			handle: func(ctx context.Context, chat *botapi.Chat, conv *Conversation, msg string) (string, error) {
+				cond := strings.ReplaceAll(strings.TrimSpace(msg), " ", "")
+				if strings.EqualFold(cond, "any") {
+					cond = ""
+				}
+				alert, ok := parseAlert(conv.Data["ticker"] + cond)
+				// Without a condition, digits would be taken for the end of the ticker.
+				if !ok || (cond != "" && alert.Condition == AlertOnChange) {
+					return "", errInput("Not > or < and a price above 0, or any")
+				}
+				return "", t.addAlert(ctx, chat, alert)
+			},
+		},
+	}
+}
+
+func (t *telegram) handleCommandAlerts(ctx context.Context, chat *botapi.Chat, args []string) error {
+	if len(args) != 0 {
+		return t.usage("alerts")
+	}
+
+	return t.sendList(ctx, chat, "alerts")
+}
+
+func (t *telegram) alertLines(ctx context.Context, chat *botapi.Chat) ([]string, string, error) {
+	var alerts []*Alert
+	err := t.withToken(ctx, chat, func(token string) (err error) {
+		alerts, err = t.userClient.ListAlerts(ctx, token)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	lines := make([]string, 0, len(alerts))
+	for _, alert := range alerts {
+		lines = append(lines, fmt.Sprintf("#%d %s", alert.ID, formatAlert(alert)))
+	}
+
+	return lines, "You have no alerts, add one with /alert.", nil
+}
+
+func (t *telegram) handleCommandUnalert(ctx context.Context, chat *botapi.Chat, args []string) error {
+	if len(args) != 1 {
+		return t.usage("unalert")
+	}
+
+	if id, err := strconv.ParseUint(strings.TrimPrefix(args[0], "#"), 10, 64); err == nil {
+		err = t.withToken(ctx, chat, func(token string) error {
+			return t.userClient.DeleteAlert(ctx, token, id)
+		})
+		if errors.Is(err, ErrNotFound) {
+			return t.sendMessage(ctx, chat, fmt.Sprintf("Alert #%d not found, see /alerts.", id))
+		}
+		if err != nil {
+			return err
+		}
+		return t.sendMessage(ctx, chat, fmt.Sprintf("Alert #%d removed.", id))
+	}
+
+	ticker := strings.ToUpper(args[0])
+	if !tickerRe.MatchString(ticker) {
+		return t.usage("unalert")
+	}
+	err := t.withToken(ctx, chat, func(token string) error {
+		return t.userClient.RemoveAlerts(ctx, token, ticker)
+	})
+	if err != nil {
+		return err
+	}
+
+	return t.sendMessage(ctx, chat, fmt.Sprintf("Alerts for %s removed.", ticker))
+}
+
+func (t *telegram) handleCommandPrice(ctx context.Context, chat *botapi.Chat, args []string) error {
+	if len(args) == 0 || len(args) > maxPriceTickers {
+		return t.usage("price")
+	}
+	tickers := make([]string, 0, len(args))
+	for _, arg := range args {
+		ticker := strings.ToUpper(arg)
+		if !tickerRe.MatchString(ticker) {
+			return t.usage("price")
+		}
+		tickers = append(tickers, ticker)
+	}
+
+	var prices []*Price
+	err := t.withToken(ctx, chat, func(token string) (err error) {
+		prices, err = t.userClient.GetPrices(ctx, token, tickers)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	for _, p := range prices {
+		if p.Price == 0 {
+			fmt.Fprintf(&b, "%s: no price yet\n", p.Ticker)
+			continue
+		}
+		fmt.Fprintf(&b, "%s: $%s\n", p.Ticker, formatNumber(p.Price))
+	}
+
+	return t.sendMessage(ctx, chat, strings.TrimSuffix(b.String(), "\n"))
+}
+
+func (t *telegram) handleCommandHelp(ctx context.Context, chat *botapi.Chat, _ []string) error {
+	var b strings.Builder
+	b.WriteString("Commands:\n")
+	for _, cmd := range t.commands {
+		fmt.Fprintf(&b, "%s - %s\n", cmd.usage(), cmd.description)
+	}
+
+	return t.sendMessage(ctx, chat, strings.TrimSuffix(b.String(), "\n"))
+}
+
+func (t *telegram) usage(name string) error {
+	cmd, _ := t.command(name)
+	return &usageError{cmd: cmd}
+}
+
+// parseNumber parses a finite number not below zero.
+func parseNumber(s string) (float64, bool) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || !(f >= 0) || math.IsInf(f, 1) {
+		return 0, false
+	}
+
+	return f, true
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func formatProfit(f float64) string {
+	return fmt.Sprintf("%+.2f", f)
+}
+
+func formatAlert(alert *Alert) string {
+	if alert.Condition == AlertOnChange {
+		return "every " + alert.Ticker + " price change"
+	}
+
+	return fmt.Sprintf("%s %s %s", alert.Ticker, alert.Condition, formatNumber(alert.Price))
+}
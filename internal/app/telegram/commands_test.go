@@ -0,0 +1,295 @@
+package telegram_test
+
+import (
+	"context"
+	"cryptowatch/internal/app/telegram"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+)
+
+// fakeAPIClient is a user with the access token "token" and the refresh token "refresh".
+type fakeAPIClient struct {
+	fakeUserClient
+
+	mu          sync.Mutex
+	trades      []string
+	alerts      []*telegram.Alert
+	loggedOut   bool
+	refreshes   int
+	nextAlertID uint64
+	// prices are streamed to subscribers.
+	prices chan *telegram.Price
+}
+
+// Subscribe streams prices sent to c.prices until ctx is done.
+func (c *fakeAPIClient) Subscribe(ctx context.Context, _ uint64, token string) (chan *telegram.Price, error) {
+	if err := c.auth(token); err != nil {
+		return nil, err
+	}
+
+	out := make(chan *telegram.Price)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case price := <-c.prices:
+				select {
+				case <-ctx.Done():
+					return
+				case out <- price:
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (c *fakeAPIClient) auth(token string) error {
+	if token != "token" {
+		return telegram.ErrUnauthenticated
+	}
+	return nil
+}
+
+func (c *fakeAPIClient) RefreshToken(_ context.Context, refreshToken string) (*telegram.RefreshTokenRes, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if refreshToken != "refresh" {
+		return nil, telegram.ErrUnauthenticated
+	}
+	c.refreshes++
+	return &telegram.RefreshTokenRes{Token: "token", RefreshToken: "refresh"}, nil
+}
+
+func (c *fakeAPIClient) Logout(_ context.Context, token string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.loggedOut = true
+	return c.auth(token)
+}
+
+func (c *fakeAPIClient) ListPortfolios(_ context.Context, token string) ([]*telegram.Portfolio, error) {
+	if err := c.auth(token); err != nil {
+		return nil, err
+	}
+	return []*telegram.Portfolio{{ID: 1, Name: "main", Profit: 400}, {ID: 2, Name: "long term", Profit: -12.5}}, nil
+}
+
+func (c *fakeAPIClient) GetPortfolio(_ context.Context, token string, name string) (*telegram.PortfolioDetails, error) {
+	if err := c.auth(token); err != nil {
+		return nil, err
+	}
+	switch name {
+	case "main":
+		return &telegram.PortfolioDetails{
+			Portfolio: &telegram.Portfolio{ID: 1, Name: "main", Profit: 400},
+			Holdings: []*telegram.Holding{
+				{Ticker: "BTC", Quantity: 0.5, Cost: 14500, Value: 15000, Profit: 500},
+				{Ticker: "ETH", Quantity: 2, Cost: 3100, Value: 3000, Profit: -100},
+			},
+		}, nil
+	case "long term":
+		return &telegram.PortfolioDetails{Portfolio: &telegram.Portfolio{ID: 2, Name: "long term"}}, nil
+	default:
+		return nil, telegram.ErrNotFound
+	}
+}
+
+func (c *fakeAPIClient) Buy(_ context.Context, token string, trade *telegram.Trade) error {
+	return c.trade(token, "buy", trade)
+}
+
+func (c *fakeAPIClient) Sell(_ context.Context, token string, trade *telegram.Trade) error {
+	return c.trade(token, "sell", trade)
+}
+
+func (c *fakeAPIClient) trade(token string, kind string, trade *telegram.Trade) error {
+	if err := c.auth(token); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.trades = append(c.trades, kind+" "+trade.Ticker)
+	return nil
+}
+
+func (c *fakeAPIClient) AddAlert(_ context.Context, token string, alert *telegram.Alert) error {
+	if err := c.auth(token); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextAlertID++
+	added := *alert
+	added.ID = c.nextAlertID
+	c.alerts = append(c.alerts, &added)
+	return nil
+}
+
+func (c *fakeAPIClient) ListAlerts(_ context.Context, token string) ([]*telegram.Alert, error) {
+	if err := c.auth(token); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]*telegram.Alert(nil), c.alerts...), nil
+}
+
+func (c *fakeAPIClient) DeleteAlert(_ context.Context, token string, id uint64) error {
+	if err := c.auth(token); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, alert := range c.alerts {
+		if alert.ID == id {
+			c.alerts = append(c.alerts[:i], c.alerts[i+1:]...)
+			return nil
+		}
+	}
+	return telegram.ErrNotFound
+}
+
+func (c *fakeAPIClient) RemoveAlerts(_ context.Context, token string, ticker string) error {
+	if err := c.auth(token); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	alerts := c.alerts[:0]
+	for _, alert := range c.alerts {
+		if alert.Ticker != ticker {
+			alerts = append(alerts, alert)
+		}
+	}
+	c.alerts = alerts
+	return nil
+}
+
+func (c *fakeAPIClient) GetPrices(_ context.Context, token string, tickers []string) ([]*telegram.Price, error) {
+	if err := c.auth(token); err != nil {
+		return nil, err
+	}
+	if len(tickers) == 1 && tickers[0] == "DOWN" {
+		return nil, telegram.ErrUnavailable
+	}
+
+	prices := map[string]float64{"BTC": 30000.5, "ETH": 1500}
+	res := make([]*telegram.Price, 0, len(tickers))
+	for _, ticker := range tickers {
+		res = append(res, &telegram.Price{Ticker: ticker, Price: prices[ticker]})
+	}
+	return res, nil
+}
+
+func TestTelegram_Commands(t *testing.T) {
+	repo := newMemRepo()
+	client := &fakeAPIClient{}
+	api := serveBot(t, client, repo)
+
+	const chatID = 3
+	sent := 0
+	// send sends the message and returns the reply of the bot.
+	send := func(msg string) string {
+		t.Helper()
+
+		api.SendText(chatID, msg)
+		sent++
+		replies := texts(t, api, chatID, sent+1)
+		if sent == 1 {
+			// The first message of a chat starts it.
+			require.Equal(t, "connected", replies[0])
+		}
+		return replies[len(replies)-1]
+	}
+
+	assert.Equal(t, "Link this chat to your account with /login <username> first.", send("/portfolios"))
+	assert.Equal(t, "Send /help to see what I can do.", send("hello"))
+	assert.Equal(t, "Unknown command /nope, send /help to see the commands.", send("/nope"))
+
+	// The access token expired, it is renewed with the refresh token.
+	require.NoError(t, repo.SetAuthToken(context.Background(), chatID, "expired", "refresh", 7))
+
+	tests := []struct {
+		msg  string
+		want string
+	}{
+		{msg: "/portfolios", want: "main: P&L +400.00\nlong term: P&L -12.50"},
+		{msg: "/portfolio main", want: "Portfolio main\nBTC: 0.5, value 15000.00, P&L +500.00\nETH: 2, value 3000.00, P&L -100.00\nTotal value 18000.00, P&L +400.00"},
+		{msg: `/portfolio "long term"`, want: "Portfolio long term has no transactions yet."},
+		{msg: "/portfolio other", want: "Portfolio other not found, see /portfolios."},
+		{msg: "/portfolio", want: "Usage: /portfolio <name>"},
+		{msg: "/buy main btc 0.5 30000", want: "Bought 0.5 BTC at 30000 in main."},
+		{msg: `/sell "long term" eth 1 1500 2.5`, want: "Sold 1 ETH at 1500 in long term."},
+		{msg: "/buy main BTC -1 30000", want: "Usage: /buy <portfolio> <ticker> <quantity> <price> [fee]"},
+		{msg: `/buy "main BTC 1 30000`, want: "Unbalanced quotes in the command."},
+		{msg: "/alert BTC > 30000", want: "Alert set: BTC > 30000. Send /subscribe to receive alerts in this chat."},
+		{msg: "/alert eth<1000.5", want: "Alert set: ETH < 1000.5. Send /subscribe to receive alerts in this chat."},
+		{msg: "/alert SOL", want: "Alert set: every SOL price change. Send /subscribe to receive alerts in this chat."},
+		{msg: "/alert BTC >= 1", want: "Usage: /alert <ticker> [> or < price]"},
+		{msg: "/alerts", want: "#1 BTC > 30000\n#2 ETH < 1000.5\n#3 every SOL price change"},
+		{msg: "/unalert #2", want: "Alert #2 removed."},
+		{msg: "/unalert 2", want: "Alert #2 not found, see /alerts."},
+		{msg: "/unalert sol", want: "Alerts for SOL removed."},
+		{msg: "/alerts@cryptowatch_bot", want: "#1 BTC > 30000"},
+		{msg: "/price btc ETH XRP", want: "BTC: $30000.5\nETH: $1500\nXRP: no price yet"},
+		{msg: "/price DOWN", want: "The service is unavailable, try again later."},
+		{msg: "/price", want: "Usage: /price <ticker>..."},
+		// Commands sent alone ask for their arguments.
+		{msg: "/buy", want: "Enter the portfolio name."},
+		{msg: "other", want: "Portfolio other not found, enter it again or /cancel."},
+		{msg: "long term", want: "Enter the token ticker, like BTC."},
+		{msg: "eth", want: "Enter the quantity."},
+		{msg: "-1", want: "Not a number above 0, enter it again or /cancel."},
+		{msg: "2", want: "Enter the price per token."},
+		{msg: "1500", want: "Enter the fee, 0 if none."},
+		{msg: "0", want: "Buy 2 ETH at 1500 in long term with a fee of 0?"},
+		{msg: "maybe", want: "Answer yes or no, enter it again or /cancel."},
+		{msg: "yes", want: "Bought 2 ETH at 1500 in long term."},
+		{msg: "/alert", want: "Enter the token ticker, like BTC."},
+		{msg: "ada", want: "Enter > or < and a price, like > 30000, or any to be alerted on every price change."},
+		{msg: "5", want: "Not > or < and a price above 0, or any, enter it again or /cancel."},
+		{msg: "> 0.5", want: "Alert set: ADA > 0.5. Send /subscribe to receive alerts in this chat."},
+		// Another command abandons the conversation.
+		{msg: "/sell", want: "Enter the portfolio name."},
+		{msg: "/price btc", want: "BTC: $30000.5"},
+		{msg: "main", want: "Send /help to see what I can do."},
+		{msg: "/alert", want: "Enter the token ticker, like BTC."},
+		{msg: "/cancel", want: "Cancelled."},
+		{msg: "/cancel", want: "Nothing to cancel."},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, send(tt.msg), tt.msg)
+	}
+	assert.Equal(t, []string{"buy BTC", "sell ETH", "buy ETH"}, client.trades)
+	assert.Equal(t, 1, client.refreshes)
+
+	help := send("/help")
+	assert.Contains(t, help, "/buy <portfolio> <ticker> <quantity> <price> [fee] - Record a buy")
+	assert.Contains(t, help, "/help - Show the commands")
+
+	assert.Equal(t, "Logged out.", send("/logout"))
+	assert.True(t, client.loggedOut)
+	acc, err := repo.GetAccount(context.Background(), chatID)
+	require.NoError(t, err)
+	assert.Equal(t, &telegram.Account{ID: chatID}, acc)
+	assert.Equal(t, "This chat isn't linked to an account.", send("/logout"))
+
+	// The commands are registered for the command menu.
+	var names []string
+	for _, cmd := range api.Commands() {
+		names = append(names, cmd.Command)
+	}
+	assert.Equal(t, []string{"login", "logout", "portfolios", "portfolio", "buy", "sell", "alert", "alerts", "unalert", "price", "subscribe", "unsubscribe", "cancel", "help"}, names)
+}
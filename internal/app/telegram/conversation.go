@@ -0,0 +1,254 @@
+package telegram
+
+import (
+	"context"
+	"cryptowatch/pkg/botapi"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultConversationTimeout = 5 * time.Minute
+	// conversationSweepInterval is how often chats of expired conversations are told about it.
+	conversationSweepInterval = 30 * time.Second
+)
+
+// state is a step of a command asking for its input over several messages.
+type state struct {
+	// prompt asks for the message the state handles, it is sent when the conversation enters the state.
+	// $key in it is replaced with the input stored as key.
+	prompt string
+	// keyboard, if set, returns buttons sent with the prompt, which answer it like messages.
+	keyboard func(ctx context.Context, chat *botapi.Chat, conv *Conversation) (*botapi.InlineKeyboardMarkup, error)
+	// handle handles the message, collecting input in conv.Data, and returns the next state
+	// or "" to end the conversation. Invalid input is asked again returning an inputError.
+	handle func(ctx context.Context, chat *botapi.Chat, conv *Conversation, msg string) (string, error)
+}
+
+// SetConversationTimeout sets how long multi-step commands wait for the next message.
+func (t *telegram) SetConversationTimeout(d time.Duration) {
+	atomic.StoreInt64(&t.conversationTimeout, int64(d))
+}
+
+func (t *telegram) conversationExpireTime() time.Time {
+	return time.Now().Add(time.Duration(atomic.LoadInt64(&t.conversationTimeout)))
+}
+
+// startConversation has the command wait for the next message of the chat in the state.
+// The conversation replaces the previous one of the chat, if any.
+func (t *telegram) startConversation(ctx context.Context, chat *botapi.Chat, name string, state string, data map[string]string) error {
+	if data == nil {
+		data = make(map[string]string)
+	}
+
+	conv := &Conversation{
+		ChatID:     chat.ID,
+		Flow:       name,
+		State:      state,
+		Data:       data,
+		ExpireTime: t.conversationExpireTime(),
+	}
+	err := t.repository.SaveConversation(ctx, conv)
+	if err != nil {
+		return err
+	}
+
+	return t.prompt(ctx, chat, conv)
+}
+
+// prompt sends the prompt of the state of the conversation.
+func (t *telegram) prompt(ctx context.Context, chat *botapi.Chat, conv *Conversation) error {
+	cmd, _ := t.command(conv.Flow)
+	st := cmd.states[conv.State]
+
+	text := os.Expand(st.prompt, func(key string) string {
+		return conv.Data[key]
+	})
+
+	var kb *botapi.InlineKeyboardMarkup
+	if st.keyboard != nil {
+		var err error
+		kb, err = st.keyboard(ctx, chat, conv)
+		if err != nil {
+			// Buttons are a shortcut, the input can be typed.
+			log.Printf("keyboard of /%s %s error: %v", conv.Flow, conv.State, err)
+			kb = nil
+		}
+	}
+
+	return t.sendKeyboard(ctx, chat, text, kb)
+}
+
+// converse passes the message to the conversation, telling the user about errors.
+func (t *telegram) converse(ctx context.Context, chat *botapi.Chat, conv *Conversation, msg string) {
+	err := t.continueConversation(ctx, chat, conv, msg)
+	if err != nil {
+		log.Printf("continue /%s error: %v", conv.Flow, err)
+		t.reply(ctx, chat, errorMessage(err))
+	}
+}
+
+// continueConversation passes the message to the state of the conversation. Conversations
+// end when a state returns no next state or an error.
+func (t *telegram) continueConversation(ctx context.Context, chat *botapi.Chat, conv *Conversation, msg string) error {
+	cmd, ok := t.command(conv.Flow)
+	var st *state
+	if ok {
+		st, ok = cmd.states[conv.State]
+	}
+	if !ok {
+		// The conversation was started by a version of the bot with other commands.
+		_, err := t.repository.DeleteConversation(ctx, chat.ID)
+		if err != nil {
+			return err
+		}
+		return t.sendMessage(ctx, chat, fmt.Sprintf("/%s was interrupted, send it again to start over.", conv.Flow))
+	}
+
+	next, err := st.handle(ctx, chat, conv, msg)
+	var inputErr *inputError
+	if errors.As(err, &inputErr) {
+		conv.ExpireTime = t.conversationExpireTime()
+		err = t.repository.SaveConversation(ctx, conv)
+		if err != nil {
+			return err
+		}
+		return t.sendMessage(ctx, chat, inputErr.msg+", enter it again or /cancel.")
+	}
+	if err != nil || next == "" {
+		_, delErr := t.repository.DeleteConversation(ctx, chat.ID)
+		if err == nil {
+			err = delErr
+		}
+		return err
+	}
+
+	conv.State = next
+	conv.ExpireTime = t.conversationExpireTime()
+	err = t.repository.SaveConversation(ctx, conv)
+	if err != nil {
+		return err
+	}
+
+	return t.prompt(ctx, chat, conv)
+}
+
+// conversation returns the conversation of the chat, nil if there is none. An expired
+// conversation is ended, telling the user, and reported as expired instead.
+func (t *telegram) conversation(ctx context.Context, chat *botapi.Chat) (*Conversation, bool, error) {
+	conv, err := t.repository.GetConversation(ctx, chat.ID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if conv.ExpireTime.After(time.Now()) {
+		return conv, false, nil
+	}
+
+	// The sweeper or another replica may have ended it in the meantime and told the user.
+	deleted, err := t.repository.DeleteConversation(ctx, chat.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	if deleted {
+		t.reply(ctx, chat, timedOutMessage(conv))
+	}
+
+	return nil, true, nil
+}
+
+// ExpireConversations ends expired conversations, telling their chats, until ctx is done.
+// Conversations are also ended when the next message of their chat comes after they expired.
+func (t *telegram) ExpireConversations(ctx context.Context) {
+	ticker := time.NewTicker(conversationSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			convs, err := t.repository.DeleteExpiredConversations(ctx, time.Now())
+			if err != nil {
+				log.Printf("expire conversations error: %v", err)
+				continue
+			}
+			for _, conv := range convs {
+				t.reply(ctx, &botapi.Chat{ID: conv.ChatID}, timedOutMessage(conv))
+			}
+		}
+	}
+}
+
+func timedOutMessage(conv *Conversation) string {
+	return fmt.Sprintf("/%s timed out, send it again to start over.", conv.Flow)
+}
+
+func (t *telegram) handleCommandCancel(ctx context.Context, chat *botapi.Chat, args []string) error {
+	if len(args) != 0 {
+		return t.usage("cancel")
+	}
+
+	deleted, err := t.repository.DeleteConversation(ctx, chat.ID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return t.sendMessage(ctx, chat, "Nothing to cancel.")
+	}
+
+	return t.sendMessage(ctx, chat, "Cancelled.")
+}
+
+// tickerState asks for a token ticker, stored as "ticker".
+func tickerState(next string) *state {
+	return &state{
+		prompt: "Enter the token ticker, like BTC.",
+		handle: func(ctx context.Context, chat *botapi.Chat, conv *Conversation, msg string) (string, error) {
+			ticker := strings.ToUpper(strings.TrimSpace(msg))
+			if !tickerRe.MatchString(ticker) {
+				return "", errInput("Tickers have up to 16 letters and digits")
+			}
+			conv.Data["ticker"] = ticker
+			return next, nil
+		},
+	}
+}
+
+// numberState asks for a number, stored as key. Zero is accepted only if allowZero.
+func numberState(prompt string, key string, next string, allowZero bool) *state {
+	return &state{
+		prompt: prompt,
+		handle: func(ctx context.Context, chat *botapi.Chat, conv *Conversation, msg string) (string, error) {
+			f, ok := parseNumber(strings.TrimSpace(msg))
+			if !ok || (f == 0 && !allowZero) {
+				if allowZero {
+					return "", errInput("Not a number, 0 or above")
+				}
+				return "", errInput("Not a number above 0")
+			}
+			conv.Data[key] = formatNumber(f)
+			return next, nil
+		},
+	}
+}
+
+// inputError is returned by states for invalid input, the message tells what is wrong with it.
+type inputError struct {
+	msg string
+}
+
+func errInput(msg string) error {
+	return &inputError{msg: msg}
+}
+
+func (e *inputError) Error() string {
+	return e.msg
+}
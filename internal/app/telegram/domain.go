@@ -1,29 +1,73 @@
 package telegram
 
+import "time"
+
 type Account struct {
-	ID        int64  `json:"id"`
-	AuthToken string `json:"auth_token"`
-	UserID    uint64 `json:"user_id"`
+	ID           int64  `json:"id"`
+	AuthToken    string `json:"auth_token"`
+	RefreshToken string `json:"refresh_token"`
+	UserID       uint64 `json:"user_id"`
+	// Subscribed chats receive the alerts of their user.
+	Subscribed bool `json:"subscribed"`
+}
+
+// Conversation is the state of a multi-step command of a chat waiting for input.
+type Conversation struct {
+	ChatID int64 `json:"chat_id"`
+	// Flow is the name of the command.
+	Flow  string `json:"flow"`
+	State string `json:"state"`
+	// Data is the input collected so far.
+	Data       map[string]string `json:"data"`
+	ExpireTime time.Time         `json:"expire_time"`
 }
 
-type getUpdatesResponse struct {
-	Ok     bool `json:"ok"`
-	Result []struct {
-		UpdateID int      `json:"update_id"`
-		Message  *Message `json:"message"`
-	} `json:"result"`
+type Portfolio struct {
+	ID     uint64  `json:"id"`
+	Name   string  `json:"name"`
+	Profit float64 `json:"profit"`
 }
 
-type Message struct {
-	MessageID int    `json:"message_id"`
-	Chat      *Chat  `json:"chat"`
-	Date      int    `json:"date"`
-	Text      string `json:"text"`
+type Holding struct {
+	Ticker   string  `json:"ticker"`
+	Quantity float64 `json:"quantity"`
+	Cost     float64 `json:"cost"`
+	Value    float64 `json:"value"`
+	Profit   float64 `json:"profit"`
+}
+
+type PortfolioDetails struct {
+	Portfolio *Portfolio `json:"portfolio"`
+	Holdings  []*Holding `json:"holdings"`
+}
+
+// Trade is a buy or sell of a token in a portfolio.
+type Trade struct {
+	PortfolioID uint64  `json:"portfolio_id"`
+	Ticker      string  `json:"ticker"`
+	Quantity    float64 `json:"quantity"`
+	Price       float64 `json:"price"`
+	Fee         float64 `json:"fee"`
+}
+
+// AlertCondition is when an alert fires.
+type AlertCondition string
+
+const (
+	// AlertOnChange fires on every price change.
+	AlertOnChange AlertCondition = ""
+	AlertAbove    AlertCondition = ">"
+	AlertBelow    AlertCondition = "<"
+)
+
+type Alert struct {
+	ID        uint64         `json:"id"`
+	Ticker    string         `json:"ticker"`
+	Condition AlertCondition `json:"condition"`
+	Price     float64        `json:"price"`
 }
 
-type Chat struct {
-	ID        int64  `json:"id"`
-	FirstName string `json:"first_name"`
-	Username  string `json:"username"`
-	Type      string `json:"type"`
+type Price struct {
+	Ticker string  `json:"ticker"`
+	Price  float64 `json:"price"`
 }
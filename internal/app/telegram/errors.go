@@ -3,8 +3,15 @@ package telegram
 import "errors"
 
 var (
-	ErrInternalError     = errors.New("internal error")
-	ErrUnexpectedMessage = errors.New("unexpected message")
-	ErrUnauthenticated   = errors.New("unauthenticated")
-	ErrNotFound          = errors.New("not found")
+	ErrInternalError      = errors.New("internal error")
+	ErrUnexpectedMessage  = errors.New("unexpected message")
+	ErrUnauthenticated    = errors.New("unauthenticated")
+	ErrNotFound           = errors.New("not found")
+	ErrResourceExhausted  = errors.New("resource exhausted")
+	ErrPermissionDenied   = errors.New("permission denied")
+	ErrInvalidArgument    = errors.New("invalid argument")
+	ErrFailedPrecondition = errors.New("failed precondition")
+	ErrUnavailable        = errors.New("unavailable")
+	// ErrNotLoggedIn is returned for commands of chats not linked to a user.
+	ErrNotLoggedIn = errors.New("not logged in")
 )
@@ -0,0 +1,228 @@
+package telegram
+
+import (
+	"context"
+	"cryptowatch/pkg/botapi"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxCallbackData limits the data of buttons, in bytes.
+	maxCallbackData = 64
+	// maxChoiceButtons limits the buttons offered to pick from, like portfolios.
+	maxChoiceButtons = 20
+	// listPageSize is the number of lines of a page of a list.
+	listPageSize = 10
+	// alertSnoozeDuration is how long the snooze button of an alert holds back alerts for its token.
+	alertSnoozeDuration = time.Hour
+)
+
+// Buttons send their action and argument, separated by a colon, as callback data:
+//
+//	in:<state>:<text>  the text as input of the conversation waiting in the state
+//	page:<list>:<page> edits the message to show the page of the list
+//	snooze:<ticker>    snoozes alerts for the token
+//	unalert:<ticker>   removes alerts for the token
+const (
+	inputAction   = "in"
+	pageAction    = "page"
+	snoozeAction  = "snooze"
+	unalertAction = "unalert"
+)
+
+// list returns the lines of a paginated list or, if it is empty, a message saying so.
+type list func(ctx context.Context, chat *botapi.Chat) ([]string, string, error)
+
+func (t *telegram) newLists() map[string]list {
+	return map[string]list{
+		"alerts":     t.alertLines,
+		"portfolios": t.portfolioLines,
+	}
+}
+
+func button(text string, action string, args ...string) botapi.InlineKeyboardButton {
+	return botapi.InlineKeyboardButton{Text: text, CallbackData: strings.Join(append([]string{action}, args...), ":")}
+}
+
+func keyboard(rows ...[]botapi.InlineKeyboardButton) *botapi.InlineKeyboardMarkup {
+	return &botapi.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// choiceKeyboard has a button for each choice answering the state with it, two in a row.
+// Choices too long for callback data are left out, they can still be typed.
+func choiceKeyboard(state string, choices []string) *botapi.InlineKeyboardMarkup {
+	var rows [][]botapi.InlineKeyboardButton
+	var row []botapi.InlineKeyboardButton
+	for _, choice := range choices {
+		b := button(choice, inputAction, state, choice)
+		if len(b.CallbackData) > maxCallbackData {
+			continue
+		}
+		if len(rows)*2+len(row) == maxChoiceButtons {
+			break
+		}
+		row = append(row, b)
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	return keyboard(rows...)
+}
+
+func alertKeyboard(ticker string) *botapi.InlineKeyboardMarkup {
+	return keyboard([]botapi.InlineKeyboardButton{
+		button("Snooze 1h", snoozeAction, ticker),
+		button("Delete", unalertAction, ticker),
+	})
+}
+
+// handleCallbackQuery does what the pressed button says and answers the query,
+// which Telegram waits for, with a notification if there is something to tell.
+func (t *telegram) handleCallbackQuery(ctx context.Context, chat *botapi.Chat, q *botapi.CallbackQuery) {
+	answer, err := t.handleButton(ctx, chat, q)
+	if err != nil {
+		log.Printf("handle button %q error: %v", q.Data, err)
+		answer = errorMessage(err)
+	}
+
+	err = t.api.AnswerCallbackQuery(ctx, botapi.AnswerCallbackQueryParams{CallbackQueryID: q.ID, Text: answer})
+	if err != nil {
+		log.Printf("answer callback query error: %v", err)
+	}
+}
+
+// handleButton returns the answer to the callback query of the button.
+func (t *telegram) handleButton(ctx context.Context, chat *botapi.Chat, q *botapi.CallbackQuery) (string, error) {
+	const expired = "This button expired."
+
+	action, arg, _ := strings.Cut(q.Data, ":")
+	switch action {
+	case inputAction:
+		state, input, _ := strings.Cut(arg, ":")
+		conv, timedOut, err := t.conversation(ctx, chat)
+		if err != nil {
+			return "", err
+		}
+		if timedOut {
+			return "", nil
+		}
+		// Buttons of earlier prompts don't answer the current one.
+		if conv == nil || conv.State != state {
+			return expired, nil
+		}
+		t.converse(ctx, chat, conv, input)
+		return "", nil
+	case pageAction:
+		name, n, _ := strings.Cut(arg, ":")
+		page, err := strconv.Atoi(n)
+		if _, ok := t.lists[name]; !ok || err != nil {
+			return expired, nil
+		}
+		return "", t.editList(ctx, chat, q.Message.MessageID, name, page)
+	case snoozeAction:
+		if !tickerRe.MatchString(arg) {
+			return expired, nil
+		}
+		err := t.repository.SnoozeAlerts(ctx, chat.ID, arg, time.Now().Add(alertSnoozeDuration))
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Alerts for %s snoozed for an hour.", arg), nil
+	case unalertAction:
+		if !tickerRe.MatchString(arg) {
+			return expired, nil
+		}
+		err := t.withToken(ctx, chat, func(token string) error {
+			return t.userClient.RemoveAlerts(ctx, token, arg)
+		})
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Alerts for %s removed.", arg), nil
+	default:
+		return expired, nil
+	}
+}
+
+// sendList sends the first page of the list, with buttons to turn pages if it has more.
+func (t *telegram) sendList(ctx context.Context, chat *botapi.Chat, name string) error {
+	text, kb, err := t.listPage(ctx, chat, name, 0)
+	if err != nil {
+		return err
+	}
+
+	return t.sendKeyboard(ctx, chat, text, kb)
+}
+
+// editList shows the page of the list in the message, in place of the page it showed.
+func (t *telegram) editList(ctx context.Context, chat *botapi.Chat, messageID int, name string, page int) error {
+	text, kb, err := t.listPage(ctx, chat, name, page)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.api.EditMessageText(ctx, botapi.EditMessageTextParams{
+		ChatID:      chat.ID,
+		MessageID:   messageID,
+		Text:        text,
+		ReplyMarkup: kb,
+	})
+	// The button was pressed twice or the list changed to show the same.
+	var apiErr *botapi.Error
+	if errors.As(err, &apiErr) && strings.Contains(apiErr.Description, "message is not modified") {
+		return nil
+	}
+
+	return err
+}
+
+// listPage returns the page of the list, the last one if it has fewer pages now,
+// and the buttons to turn to the pages before and after it.
+func (t *telegram) listPage(ctx context.Context, chat *botapi.Chat, name string, page int) (string, *botapi.InlineKeyboardMarkup, error) {
+	lines, empty, err := t.lists[name](ctx, chat)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(lines) == 0 {
+		return empty, nil, nil
+	}
+
+	pages := (len(lines) + listPageSize - 1) / listPageSize
+	if page >= pages {
+		page = pages - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+	end := (page + 1) * listPageSize
+	if end > len(lines) {
+		end = len(lines)
+	}
+	text := strings.Join(lines[page*listPageSize:end], "\n")
+	if pages == 1 {
+		return text, nil, nil
+	}
+
+	var row []botapi.InlineKeyboardButton
+	if page > 0 {
+		row = append(row, button("« Previous", pageAction, name, strconv.Itoa(page-1)))
+	}
+	if page < pages-1 {
+		row = append(row, button("Next »", pageAction, name, strconv.Itoa(page+1)))
+	}
+
+	return fmt.Sprintf("%s\nPage %d of %d", text, page+1, pages), keyboard(row), nil
+}
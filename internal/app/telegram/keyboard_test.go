@@ -0,0 +1,126 @@
+package telegram_test
+
+import (
+	"context"
+	"cryptowatch/internal/app/telegram"
+	"cryptowatch/pkg/botapi"
+	"cryptowatch/pkg/botapi/botapitest"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func buttons(msg botapi.Message) []string {
+	var data []string
+	if msg.ReplyMarkup == nil {
+		return data
+	}
+	for _, row := range msg.ReplyMarkup.InlineKeyboard {
+		for _, b := range row {
+			data = append(data, b.CallbackData)
+		}
+	}
+	return data
+}
+
+// press presses the button of the message and returns the answer of the bot.
+func press(t *testing.T, api *botapitest.Server, msg botapi.Message, data string) string {
+	t.Helper()
+
+	id, err := api.PressButton(msg.Chat.ID, msg.MessageID, data)
+	require.NoError(t, err)
+
+	var answer string
+	require.Eventually(t, func() bool {
+		for _, a := range api.Answers() {
+			if a.CallbackQueryID == id {
+				answer = a.Text
+				return true
+			}
+		}
+		return false
+	}, waitTimeout, 10*time.Millisecond)
+	return answer
+}
+
+func TestTelegram_Buttons(t *testing.T) {
+	const chatID = 6
+
+	repo := newMemRepo()
+	require.NoError(t, repo.AddAccount(context.Background(), chatID))
+	require.NoError(t, repo.SetAuthToken(context.Background(), chatID, "token", "refresh", 7))
+	client := &fakeAPIClient{prices: make(chan *telegram.Price)}
+	for i := 0; i < 12; i++ {
+		require.NoError(t, client.AddAlert(context.Background(), "token", &telegram.Alert{Ticker: fmt.Sprintf("T%d", i+1)}))
+	}
+	api := serveBot(t, client, repo, func(bot telegram.Telegram) {
+		bot.SetAlertInterval(0)
+	})
+
+	sent := 1 // The first message of a chat starts it.
+	// send sends the message and returns the reply of the bot.
+	send := func(text string) botapi.Message {
+		t.Helper()
+
+		api.SendText(chatID, text)
+		sent++
+		msgs, err := api.WaitMessages(chatID, sent, waitTimeout)
+		require.NoError(t, err)
+		return msgs[sent-1]
+	}
+	// next waits for the next message of the bot, like one sent after a button was pressed.
+	next := func() botapi.Message {
+		t.Helper()
+
+		sent++
+		msgs, err := api.WaitMessages(chatID, sent, waitTimeout)
+		require.NoError(t, err)
+		return msgs[sent-1]
+	}
+
+	// Long lists are paginated, turning pages edits the message.
+	list := send("/alerts")
+	assert.True(t, strings.HasSuffix(list.Text, "#10 every T10 price change\nPage 1 of 2"), list.Text)
+	assert.Equal(t, []string{"page:alerts:1"}, buttons(list))
+	assert.Empty(t, press(t, api, list, "page:alerts:1"))
+	list = api.Messages(chatID)[sent-1]
+	assert.Equal(t, "#11 every T11 price change\n#12 every T12 price change\nPage 2 of 2", list.Text)
+	assert.Equal(t, []string{"page:alerts:0"}, buttons(list))
+
+	// Conversations are answered with buttons.
+	prompt := send("/buy")
+	assert.Equal(t, "Enter the portfolio name.", prompt.Text)
+	assert.Equal(t, []string{"in:portfolio:main", "in:portfolio:long term"}, buttons(prompt))
+	assert.Empty(t, press(t, api, prompt, "in:portfolio:main"))
+	assert.Equal(t, "Enter the token ticker, like BTC.", next().Text)
+	assert.Equal(t, "This button expired.", press(t, api, prompt, "in:portfolio:main"))
+	send("btc")
+	send("1")
+	send("30000")
+	confirm := send("0")
+	assert.Equal(t, "Buy 1 BTC at 30000 in main with a fee of 0?", confirm.Text)
+	assert.Equal(t, []string{"in:confirm:yes", "in:confirm:no"}, buttons(confirm))
+	press(t, api, confirm, "in:confirm:yes")
+	assert.Equal(t, "Bought 1 BTC at 30000 in main.", next().Text)
+
+	// Alerts can be snoozed or removed with their buttons.
+	assert.Equal(t, "Subscribed, your alerts are sent to this chat.", send("/subscribe").Text)
+	client.prices <- &telegram.Price{Ticker: "T1", Price: 30000}
+	alert := next()
+	assert.Equal(t, "T1: $30000", alert.Text)
+	assert.Equal(t, []string{"snooze:T1", "unalert:T1"}, buttons(alert))
+	assert.Equal(t, "Alerts for T1 snoozed for an hour.", press(t, api, alert, "snooze:T1"))
+
+	client.prices <- &telegram.Price{Ticker: "T1", Price: 31000}
+	client.prices <- &telegram.Price{Ticker: "T2", Price: 1500}
+	alert = next()
+	assert.Equal(t, "T2: $1500", alert.Text)
+	assert.Equal(t, "Alerts for T2 removed.", press(t, api, alert, "unalert:T2"))
+
+	alerts, err := client.ListAlerts(context.Background(), "token")
+	require.NoError(t, err)
+	assert.Len(t, alerts, 11)
+}
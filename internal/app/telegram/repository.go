@@ -2,20 +2,51 @@ package telegram
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"time"
 )
 
 const (
-	telegramAccountsTable = "telegram_accounts"
+	telegramAccountsTable      = "telegram_accounts"
+	telegramUpdatesTable       = "telegram_updates"
+	telegramConversationsTable = "telegram_conversations"
+	telegramAlertSnoozesTable  = "telegram_alert_snoozes"
 )
 
 type Repository interface {
 	AddAccount(ctx context.Context, id int64) error
 	GetAccount(ctx context.Context, id int64) (*Account, error)
-	SetAuthToken(ctx context.Context, id int64, token string, userID uint64) error
+	SetAuthToken(ctx context.Context, id int64, token string, refreshToken string, userID uint64) error
+	// ClearAuthToken unlinks the chat from its user, ending its subscription.
+	ClearAuthToken(ctx context.Context, id int64) error
+	// HoldSubscription subscribes the chat, its alerts streamed by the holder until t.
+	HoldSubscription(ctx context.Context, id int64, holder string, t time.Time) error
+	// Unsubscribe ends the subscription of the chat and reports whether it was subscribed.
+	Unsubscribe(ctx context.Context, id int64) (bool, error)
+	// ClaimSubscriptions extends the leases of subscriptions of the holder until t, taking over
+	// subscriptions without a holder or with a lease expired at now, and returns their chat ids.
+	ClaimSubscriptions(ctx context.Context, holder string, now time.Time, t time.Time) ([]int64, error)
+	// ReleaseSubscriptions gives up the subscriptions of the holder for others to take over.
+	ReleaseSubscriptions(ctx context.Context, holder string) error
+	// RecordUpdate stores the id of a received update and reports whether it wasn't received before.
+	RecordUpdate(ctx context.Context, updateID int) (bool, error)
+	// DeleteUpdatesBefore forgets ids of updates received before t.
+	DeleteUpdatesBefore(ctx context.Context, t time.Time) error
+	GetConversation(ctx context.Context, chatID int64) (*Conversation, error)
+	// SaveConversation stores the conversation, replacing the one of the chat.
+	SaveConversation(ctx context.Context, conv *Conversation) error
+	// DeleteConversation deletes the conversation of the chat and reports whether there was one.
+	DeleteConversation(ctx context.Context, chatID int64) (bool, error)
+	// DeleteExpiredConversations deletes and returns conversations expired at t.
+	DeleteExpiredConversations(ctx context.Context, t time.Time) ([]*Conversation, error)
+	// SnoozeAlerts holds back alerts for the ticker sent to the chat until t.
+	SnoozeAlerts(ctx context.Context, chatID int64, ticker string, t time.Time) error
+	// GetSnoozeTime returns until when alerts for the ticker are held back, the zero time if they aren't.
+	GetSnoozeTime(ctx context.Context, chatID int64, ticker string) (time.Time, error)
 }
 
 type postgresRepo struct {
@@ -44,13 +75,13 @@ func (r *postgresRepo) AddAccount(ctx context.Context, id int64) error {
 }
 
 var getAccountQuery = fmt.Sprintf(`
-SELECT auth_token, user_id FROM %s
+SELECT coalesce(auth_token, ''), coalesce(refresh_token, ''), coalesce(user_id, 0), subscribed FROM %s
 WHERE id = $1
 `, telegramAccountsTable)
 
 func (r *postgresRepo) GetAccount(ctx context.Context, id int64) (*Account, error) {
 	acc := Account{ID: id}
-	err := r.db.QueryRow(ctx, getAccountQuery, id).Scan(&acc.AuthToken, &acc.UserID)
+	err := r.db.QueryRow(ctx, getAccountQuery, id).Scan(&acc.AuthToken, &acc.RefreshToken, &acc.UserID, &acc.Subscribed)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -64,13 +95,14 @@ func (r *postgresRepo) GetAccount(ctx context.Context, id int64) (*Account, erro
 var setAuthTokenQuery = fmt.Sprintf(`
 UPDATE %s
 SET
-	auth_token = $2, 
-	user_id = $3
+	auth_token = $2,
+	refresh_token = $3,
+	user_id = $4
 WHERE id = $1
 `, telegramAccountsTable)
 
-func (r *postgresRepo) SetAuthToken(ctx context.Context, id int64, token string, userID uint64) error {
-	cmd, err := r.db.Exec(ctx, setAuthTokenQuery, id, token, userID)
+func (r *postgresRepo) SetAuthToken(ctx context.Context, id int64, token string, refreshToken string, userID uint64) error {
+	cmd, err := r.db.Exec(ctx, setAuthTokenQuery, id, token, refreshToken, userID)
 	if err != nil {
 		return ErrInternalError
 	}
@@ -80,3 +112,282 @@ func (r *postgresRepo) SetAuthToken(ctx context.Context, id int64, token string,
 
 	return nil
 }
+
+var clearAuthTokenQuery = fmt.Sprintf(`
+UPDATE %s
+SET
+	auth_token = NULL,
+	refresh_token = NULL,
+	user_id = NULL,
+	subscribed = false,
+	subscription_holder = NULL,
+	subscription_lease_time = NULL
+WHERE id = $1
+`, telegramAccountsTable)
+
+func (r *postgresRepo) ClearAuthToken(ctx context.Context, id int64) error {
+	_, err := r.db.Exec(ctx, clearAuthTokenQuery, id)
+	if err != nil {
+		return ErrInternalError
+	}
+
+	return nil
+}
+
+var holdSubscriptionQuery = fmt.Sprintf(`
+UPDATE %s
+SET
+	subscribed = true,
+	subscription_holder = $2,
+	subscription_lease_time = $3
+WHERE id = $1
+`, telegramAccountsTable)
+
+func (r *postgresRepo) HoldSubscription(ctx context.Context, id int64, holder string, t time.Time) error {
+	cmd, err := r.db.Exec(ctx, holdSubscriptionQuery, id, holder, t)
+	if err != nil {
+		return ErrInternalError
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+var unsubscribeQuery = fmt.Sprintf(`
+UPDATE %s
+SET
+	subscribed = false,
+	subscription_holder = NULL,
+	subscription_lease_time = NULL
+WHERE id = $1 AND subscribed
+`, telegramAccountsTable)
+
+func (r *postgresRepo) Unsubscribe(ctx context.Context, id int64) (bool, error) {
+	cmd, err := r.db.Exec(ctx, unsubscribeQuery, id)
+	if err != nil {
+		return false, ErrInternalError
+	}
+
+	return cmd.RowsAffected() == 1, nil
+}
+
+var claimSubscriptionsQuery = fmt.Sprintf(`
+UPDATE %s
+SET
+	subscription_holder = $1,
+	subscription_lease_time = $3
+WHERE subscribed
+	AND (subscription_holder IS NULL OR subscription_holder = $1 OR subscription_lease_time <= $2)
+RETURNING id
+`, telegramAccountsTable)
+
+func (r *postgresRepo) ClaimSubscriptions(ctx context.Context, holder string, now time.Time, t time.Time) ([]int64, error) {
+	rows, err := r.db.Query(ctx, claimSubscriptionsQuery, holder, now, t)
+	if err != nil {
+		return nil, ErrInternalError
+	}
+	defer rows.Close()
+
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, ErrInternalError
+		}
+		ids = append(ids, id)
+	}
+	if rows.Err() != nil {
+		return nil, ErrInternalError
+	}
+
+	return ids, nil
+}
+
+var releaseSubscriptionsQuery = fmt.Sprintf(`
+UPDATE %s
+SET
+	subscription_holder = NULL,
+	subscription_lease_time = NULL
+WHERE subscription_holder = $1
+`, telegramAccountsTable)
+
+func (r *postgresRepo) ReleaseSubscriptions(ctx context.Context, holder string) error {
+	_, err := r.db.Exec(ctx, releaseSubscriptionsQuery, holder)
+	if err != nil {
+		return ErrInternalError
+	}
+
+	return nil
+}
+
+var recordUpdateQuery = fmt.Sprintf(`
+INSERT INTO %s
+(update_id)
+VALUES ($1)
+ON CONFLICT (update_id)
+DO NOTHING
+`, telegramUpdatesTable)
+
+func (r *postgresRepo) RecordUpdate(ctx context.Context, updateID int) (bool, error) {
+	cmd, err := r.db.Exec(ctx, recordUpdateQuery, updateID)
+	if err != nil {
+		return false, ErrInternalError
+	}
+
+	return cmd.RowsAffected() == 1, nil
+}
+
+var deleteUpdatesBeforeQuery = fmt.Sprintf(`
+DELETE FROM %s
+WHERE receive_time < $1
+`, telegramUpdatesTable)
+
+func (r *postgresRepo) DeleteUpdatesBefore(ctx context.Context, t time.Time) error {
+	_, err := r.db.Exec(ctx, deleteUpdatesBeforeQuery, t)
+	if err != nil {
+		return ErrInternalError
+	}
+
+	return nil
+}
+
+var getConversationQuery = fmt.Sprintf(`
+SELECT chat_id, flow, state, data, expire_time
+FROM %s
+WHERE chat_id = $1
+`, telegramConversationsTable)
+
+func (r *postgresRepo) GetConversation(ctx context.Context, chatID int64) (*Conversation, error) {
+	conv, err := scanConversation(r.db.QueryRow(ctx, getConversationQuery, chatID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, ErrInternalError
+	}
+
+	return conv, nil
+}
+
+var saveConversationQuery = fmt.Sprintf(`
+INSERT INTO %s
+(chat_id, flow, state, data, expire_time)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (chat_id)
+DO UPDATE SET
+	flow = EXCLUDED.flow,
+	state = EXCLUDED.state,
+	data = EXCLUDED.data,
+	expire_time = EXCLUDED.expire_time
+`, telegramConversationsTable)
+
+func (r *postgresRepo) SaveConversation(ctx context.Context, conv *Conversation) error {
+	data, err := json.Marshal(conv.Data)
+	if err != nil {
+		return ErrInternalError
+	}
+
+	_, err = r.db.Exec(ctx, saveConversationQuery, conv.ChatID, conv.Flow, conv.State, data, conv.ExpireTime)
+	if err != nil {
+		return ErrInternalError
+	}
+
+	return nil
+}
+
+var deleteConversationQuery = fmt.Sprintf(`
+DELETE FROM %s
+WHERE chat_id = $1
+`, telegramConversationsTable)
+
+func (r *postgresRepo) DeleteConversation(ctx context.Context, chatID int64) (bool, error) {
+	cmd, err := r.db.Exec(ctx, deleteConversationQuery, chatID)
+	if err != nil {
+		return false, ErrInternalError
+	}
+
+	return cmd.RowsAffected() == 1, nil
+}
+
+var deleteExpiredConversationsQuery = fmt.Sprintf(`
+DELETE FROM %s
+WHERE expire_time <= $1
+RETURNING chat_id, flow, state, data, expire_time
+`, telegramConversationsTable)
+
+func (r *postgresRepo) DeleteExpiredConversations(ctx context.Context, t time.Time) ([]*Conversation, error) {
+	rows, err := r.db.Query(ctx, deleteExpiredConversationsQuery, t)
+	if err != nil {
+		return nil, ErrInternalError
+	}
+	defer rows.Close()
+
+	convs := make([]*Conversation, 0)
+	for rows.Next() {
+		conv, err := scanConversation(rows)
+		if err != nil {
+			return nil, ErrInternalError
+		}
+		convs = append(convs, conv)
+	}
+	if rows.Err() != nil {
+		return nil, ErrInternalError
+	}
+
+	return convs, nil
+}
+
+var snoozeAlertsQuery = fmt.Sprintf(`
+INSERT INTO %s
+(chat_id, token_ticker, snooze_time)
+VALUES ($1, $2, $3)
+ON CONFLICT (chat_id, token_ticker)
+DO UPDATE SET snooze_time = EXCLUDED.snooze_time
+`, telegramAlertSnoozesTable)
+
+func (r *postgresRepo) SnoozeAlerts(ctx context.Context, chatID int64, ticker string, t time.Time) error {
+	_, err := r.db.Exec(ctx, snoozeAlertsQuery, chatID, ticker, t)
+	if err != nil {
+		return ErrInternalError
+	}
+
+	return nil
+}
+
+var getSnoozeTimeQuery = fmt.Sprintf(`
+SELECT snooze_time
+FROM %s
+WHERE chat_id = $1 AND token_ticker = $2
+`, telegramAlertSnoozesTable)
+
+func (r *postgresRepo) GetSnoozeTime(ctx context.Context, chatID int64, ticker string) (time.Time, error) {
+	var t time.Time
+	err := r.db.QueryRow(ctx, getSnoozeTimeQuery, chatID, ticker).Scan(&t)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, ErrInternalError
+	}
+
+	return t, nil
+}
+
+func scanConversation(row pgx.Row) (*Conversation, error) {
+	var conv Conversation
+	var data []byte
+	err := row.Scan(&conv.ChatID, &conv.Flow, &conv.State, &data, &conv.ExpireTime)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &conv.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &conv, nil
+}
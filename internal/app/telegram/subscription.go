@@ -0,0 +1,299 @@
+package telegram
+
+import (
+	"context"
+	"cryptowatch/pkg/botapi"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// subscriptionLeaseDuration is how long the alerts of a chat are streamed by a bot process
+	// without renewing its lease, other processes take over the subscription after it.
+	subscriptionLeaseDuration = 90 * time.Second
+	// subscriptionClaimInterval is how often leases are renewed and subscriptions without a holder taken over.
+	subscriptionClaimInterval = 30 * time.Second
+	// releaseTimeout limits giving up the subscriptions when the bot stops.
+	releaseTimeout = 5 * time.Second
+)
+
+// subscription is the stream of alerts of a chat running in this bot process.
+type subscription struct {
+	cancel    context.CancelFunc
+	startTime time.Time
+}
+
+// newHolder returns an id of the bot process, unique among the replicas and their restarts.
+func newHolder() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano())
+}
+
+func (t *telegram) handleCommandSubscribe(ctx context.Context, chat *botapi.Chat, args []string) error {
+	if len(args) != 0 {
+		return t.usage("subscribe")
+	}
+
+	subCtx, cancel := context.WithCancel(ctx)
+	ch, err := t.subscribe(subCtx, chat)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	// The subscription is taken over from the bot process streaming it, if any.
+	err = t.repository.HoldSubscription(ctx, chat.ID, t.holder, time.Now().Add(subscriptionLeaseDuration))
+	if err != nil {
+		cancel()
+		return err
+	}
+	t.runSubscription(subCtx, cancel, chat, ch)
+
+	return t.sendMessage(ctx, chat, "Subscribed, your alerts are sent to this chat.")
+}
+
+func (t *telegram) handleCommandUnsubscribe(ctx context.Context, chat *botapi.Chat, args []string) error {
+	if len(args) != 0 {
+		return t.usage("unsubscribe")
+	}
+
+	// Another bot process streaming the alerts stops when it renews its lease.
+	subscribed, err := t.repository.Unsubscribe(ctx, chat.ID)
+	if err != nil {
+		return err
+	}
+	t.stopSubscription(chat.ID)
+	if !subscribed {
+		return t.sendMessage(ctx, chat, "This chat isn't subscribed.")
+	}
+
+	return t.sendMessage(ctx, chat, "Unsubscribed, alerts aren't sent to this chat anymore.")
+}
+
+// RunSubscriptions claims the subscriptions of chats nobody streams the alerts of, like
+// all of them when the bot starts, and renews the leases of those it streams. Streams of
+// subscriptions ended or taken over elsewhere are stopped. Subscriptions are released
+// when ctx is done, for other bot processes to take over right away.
+func (t *telegram) RunSubscriptions(ctx context.Context) {
+	defer func() {
+		releaseCtx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+		defer cancel()
+		err := t.repository.ReleaseSubscriptions(releaseCtx, t.holder)
+		if err != nil {
+			log.Printf("release subscriptions error: %v", err)
+		}
+	}()
+
+	ticker := time.NewTicker(subscriptionClaimInterval)
+	defer ticker.Stop()
+
+	for {
+		t.claimSubscriptions(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (t *telegram) claimSubscriptions(ctx context.Context) {
+	now := time.Now()
+	ids, err := t.repository.ClaimSubscriptions(ctx, t.holder, now, now.Add(subscriptionLeaseDuration))
+	if err != nil {
+		log.Printf("claim subscriptions error: %v", err)
+		return
+	}
+
+	claimed := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		claimed[id] = true
+		if !t.subscribed(id) {
+			subCtx, cancel := context.WithCancel(ctx)
+			t.runSubscription(subCtx, cancel, &botapi.Chat{ID: id}, nil)
+		}
+	}
+
+	t.subsMu.Lock()
+	defer t.subsMu.Unlock()
+	for id, sub := range t.subs {
+		// Subscriptions started since the claim weren't claimed yet.
+		if !claimed[id] && sub.startTime.Before(now) {
+			sub.cancel()
+			delete(t.subs, id)
+		}
+	}
+}
+
+func (t *telegram) subscribed(chatID int64) bool {
+	t.subsMu.Lock()
+	defer t.subsMu.Unlock()
+
+	_, ok := t.subs[chatID]
+	return ok
+}
+
+// runSubscription streams alerts from ch to the chat until ctx is done or the subscription ends,
+// replacing the stream of the chat running already. Without ch, it subscribes first.
+func (t *telegram) runSubscription(ctx context.Context, cancel context.CancelFunc, chat *botapi.Chat, ch chan *Price) {
+	sub := &subscription{cancel: cancel, startTime: time.Now()}
+
+	t.subsMu.Lock()
+	if running, ok := t.subs[chat.ID]; ok {
+		running.cancel()
+	}
+	t.subs[chat.ID] = sub
+	t.subsMu.Unlock()
+
+	go func() {
+		defer func() {
+			t.subsMu.Lock()
+			if t.subs[chat.ID] == sub {
+				delete(t.subs, chat.ID)
+			}
+			t.subsMu.Unlock()
+			cancel()
+		}()
+
+		t.streamAlerts(ctx, chat, ch)
+	}()
+}
+
+// stopSubscription stops the stream of alerts of the chat running in this bot process, if any.
+func (t *telegram) stopSubscription(chatID int64) {
+	t.subsMu.Lock()
+	defer t.subsMu.Unlock()
+
+	if sub, ok := t.subs[chatID]; ok {
+		sub.cancel()
+		delete(t.subs, chatID)
+	}
+}
+
+func (t *telegram) streamAlerts(ctx context.Context, chat *botapi.Chat, ch chan *Price) {
+	if ch == nil {
+		ch = t.resubscribe(ctx, chat, false)
+	}
+
+	for ch != nil {
+		select {
+		case <-ctx.Done():
+			return
+		case price, ok := <-ch:
+			if !ok {
+				// The server ends streams when the access token expires,
+				// subscribe again with a fresh one.
+				ch = t.resubscribe(ctx, chat, true)
+				continue
+			}
+
+			sent, err := t.sendAlert(ctx, chat, price)
+			if isForbidden(err) {
+				// The user blocked the bot, alerts can't be delivered anymore.
+				log.Printf("chat %d blocked the bot, unsubscribing", chat.ID)
+				t.endSubscription(ctx, chat)
+				return
+			}
+			if err != nil {
+				log.Printf("ERR: %v", err)
+			}
+			if !sent {
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Duration(atomic.LoadInt64(&t.alertInterval))):
+			}
+		}
+	}
+}
+
+func (t *telegram) subscribe(ctx context.Context, chat *botapi.Chat) (chan *Price, error) {
+	acc, err := t.repository.GetAccount(ctx, chat.ID)
+	if err != nil {
+		return nil, err
+	}
+	if acc.AuthToken == "" {
+		return nil, ErrNotLoggedIn
+	}
+
+	// Streams last long, they start with a fresh token.
+	token, err := t.renewToken(ctx, chat.ID, acc.AuthToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.userClient.Subscribe(ctx, acc.UserID, token)
+}
+
+// resubscribe retries subscribe, after a delay if wait, until it succeeds or the session
+// is gone, in which case the subscription ends and nil is returned.
+func (t *telegram) resubscribe(ctx context.Context, chat *botapi.Chat, wait bool) chan *Price {
+	for {
+		if wait {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(resubscribeDelay):
+			}
+		}
+		wait = true
+
+		ch, err := t.subscribe(ctx, chat)
+		if err == nil {
+			return ch
+		}
+		if errors.Is(err, ErrNotLoggedIn) || errors.Is(err, ErrNotFound) {
+			t.endSubscription(ctx, chat)
+			return nil
+		}
+		if errors.Is(err, ErrUnauthenticated) {
+			t.endSubscription(ctx, chat)
+			t.reply(ctx, chat, "Session expired, /login again and /subscribe to receive alerts.")
+			return nil
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		log.Printf("resubscribe error: %v", err)
+	}
+}
+
+// endSubscription unsubscribes the chat, whose alerts can't be streamed anymore.
+func (t *telegram) endSubscription(ctx context.Context, chat *botapi.Chat) {
+	_, err := t.repository.Unsubscribe(ctx, chat.ID)
+	if err != nil {
+		log.Printf("unsubscribe error: %v", err)
+	}
+}
+
+// sendAlert sends the price of an alert with buttons to snooze or remove alerts for the token.
+// It reports whether the alert was sent, alerts snoozed by the user aren't.
+func (t *telegram) sendAlert(ctx context.Context, chat *botapi.Chat, price *Price) (bool, error) {
+	snoozeTime, err := t.repository.GetSnoozeTime(ctx, chat.ID, price.Ticker)
+	if err != nil {
+		log.Printf("get snooze time error: %v", err)
+	}
+	if snoozeTime.After(time.Now()) {
+		return false, nil
+	}
+
+	log.Printf("MESSAGE: %s %v", price.Ticker, price.Price)
+	err = t.sendKeyboard(ctx, chat, fmt.Sprintf("%s: $%s", price.Ticker, formatNumber(price.Price)), alertKeyboard(price.Ticker))
+
+	return true, err
+}
+
+// isForbidden reports whether the Bot API refused to send to a chat, because the user blocked the bot.
+func isForbidden(err error) bool {
+	var apiErr *botapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusForbidden
+}
@@ -0,0 +1,99 @@
+package telegram_test
+
+import (
+	"context"
+	"cryptowatch/internal/app/telegram"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// loggedIn returns a repo with the chats linked to the user of fakeAPIClient.
+func loggedIn(t *testing.T, chatIDs ...int64) *memRepo {
+	t.Helper()
+
+	repo := newMemRepo()
+	for _, id := range chatIDs {
+		require.NoError(t, repo.AddAccount(context.Background(), id))
+		require.NoError(t, repo.SetAuthToken(context.Background(), id, "token", "refresh", 7))
+	}
+	return repo
+}
+
+func noAlertInterval(bot telegram.Telegram) {
+	bot.SetAlertInterval(0)
+}
+
+func TestTelegram_Unsubscribe(t *testing.T) {
+	const chatID = 8
+
+	repo := loggedIn(t, chatID)
+	client := &fakeAPIClient{prices: make(chan *telegram.Price)}
+	api := serveBot(t, client, repo, noAlertInterval)
+
+	api.SendText(chatID, "/subscribe")
+	assert.Equal(t, "Subscribed, your alerts are sent to this chat.", texts(t, api, chatID, 2)[1])
+	acc, err := repo.GetAccount(context.Background(), chatID)
+	require.NoError(t, err)
+	assert.True(t, acc.Subscribed)
+	assert.NotEmpty(t, repo.holder(chatID))
+
+	client.prices <- &telegram.Price{Ticker: "BTC", Price: 30000}
+	assert.Equal(t, "BTC: $30000", texts(t, api, chatID, 3)[2])
+
+	api.SendText(chatID, "/unsubscribe")
+	assert.Equal(t, "Unsubscribed, alerts aren't sent to this chat anymore.", texts(t, api, chatID, 4)[3])
+	acc, err = repo.GetAccount(context.Background(), chatID)
+	require.NoError(t, err)
+	assert.False(t, acc.Subscribed)
+	assert.Empty(t, repo.holder(chatID))
+
+	// Nobody streams the alerts anymore.
+	select {
+	case client.prices <- &telegram.Price{Ticker: "BTC", Price: 31000}:
+	case <-time.After(100 * time.Millisecond):
+	}
+	api.SendText(chatID, "/unsubscribe")
+	assert.Equal(t, []string{"This chat isn't subscribed."}, texts(t, api, chatID, 5)[4:])
+}
+
+func TestTelegram_ResumeSubscriptions(t *testing.T) {
+	repo := loggedIn(t, 9, 10)
+	// The bot process streaming the alerts of chat 9 stopped, another one streams those of chat 10.
+	require.NoError(t, repo.HoldSubscription(context.Background(), 9, "stopped", time.Now().Add(-time.Second)))
+	require.NoError(t, repo.HoldSubscription(context.Background(), 10, "running", time.Now().Add(time.Hour)))
+	t.Cleanup(func() {
+		// The bot released its subscriptions when it stopped.
+		assert.Empty(t, repo.holder(9))
+		assert.Equal(t, "running", repo.holder(10))
+	})
+
+	client := &fakeAPIClient{prices: make(chan *telegram.Price)}
+	api := serveBot(t, client, repo, noAlertInterval)
+
+	client.prices <- &telegram.Price{Ticker: "ETH", Price: 1500}
+	assert.Equal(t, []string{"ETH: $1500"}, texts(t, api, 9, 1))
+	assert.Empty(t, api.Messages(10))
+	assert.NotEqual(t, "stopped", repo.holder(9))
+}
+
+func TestTelegram_BlockedBot(t *testing.T) {
+	const chatID = 11
+
+	repo := loggedIn(t, chatID)
+	require.NoError(t, repo.HoldSubscription(context.Background(), chatID, "stopped", time.Now().Add(-time.Second)))
+	client := &fakeAPIClient{prices: make(chan *telegram.Price)}
+	api := serveBot(t, client, repo, noAlertInterval)
+
+	api.Block(chatID)
+	client.prices <- &telegram.Price{Ticker: "ETH", Price: 1500}
+
+	// The subscription of the chat ends, it isn't resumed anymore.
+	assert.Eventually(t, func() bool {
+		acc, err := repo.GetAccount(context.Background(), chatID)
+		return err == nil && !acc.Subscribed
+	}, waitTimeout, 10*time.Millisecond)
+	assert.Empty(t, repo.holder(chatID))
+	assert.Empty(t, api.Messages(chatID))
+}
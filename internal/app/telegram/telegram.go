@@ -1,188 +1,180 @@
 package telegram
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"fmt"
-	"io"
+	"cryptowatch/pkg/botapi"
 	"log"
 	"net/http"
-	"regexp"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// allowedUpdates are the kinds of updates the bot handles.
+var allowedUpdates = []string{"message", "callback_query"}
+
 const (
-	defaultTimeout = 100
+	defaultTimeout       = 100
+	defaultPollInterval  = 1 * time.Second
+	defaultAlertInterval = 5 * time.Second
+	resubscribeDelay     = 5 * time.Second
 )
 
+// Telegram is the bot. It receives updates by long polling with Serve or,
+// with a webhook set, from Telegram calling the WebhookHandler.
 type Telegram interface {
-	Start(ctx context.Context) error
+	// Serve deletes the webhook, if any, and long-polls updates until ctx is done.
+	Serve(ctx context.Context) error
+	// SetWebhook has Telegram post updates to the URL of the config instead of long polling.
+	SetWebhook(ctx context.Context, cfg WebhookConfig) error
+	DeleteWebhook(ctx context.Context) error
+	// WebhookHandler receives updates posted by Telegram. Chats started by them run until ctx is done.
+	WebhookHandler(ctx context.Context, secretToken string) http.Handler
+	// PruneUpdates forgets received update ids Telegram doesn't retry anymore until ctx is done.
+	PruneUpdates(ctx context.Context)
+	// RunSubscriptions streams alerts to subscribed chats until ctx is done, sharing
+	// the subscriptions with other replicas. Serve runs it.
+	RunSubscriptions(ctx context.Context)
+	// ExpireConversations ends multi-step commands that timed out, telling their chats, until ctx is done.
+	ExpireConversations(ctx context.Context)
+	SetPollInterval(d time.Duration)
+	SetAlertInterval(d time.Duration)
+	SetConversationTimeout(d time.Duration)
 }
 
 type telegram struct {
-	httpClient *http.Client
-	token      string
-	timeout    uint
-	chats      map[int64]chan string
+	api        *botapi.Client
+	timeout    int
+	chats      map[int64]chan *botapi.Update
 	mu         sync.RWMutex
 	userClient UserClient
 	repository Repository
+	commands   []*command
+	lists      map[string]list
+	refreshMu  sync.Mutex
+
+	// holder identifies the bot process holding the subscriptions it streams.
+	holder string
+	subsMu sync.Mutex
+	subs   map[int64]*subscription
+
+	// pollInterval, alertInterval and conversationTimeout are time.Duration values
+	// accessed atomically, so they can be changed while the bot runs.
+	pollInterval        int64
+	alertInterval       int64
+	conversationTimeout int64
 }
 
-func New(token string, userClient UserClient, repository Repository) *telegram {
-	return &telegram{
-		httpClient: &http.Client{
-			Timeout: (defaultTimeout + 10) * time.Second,
-		},
-		token:      token,
+// New returns the bot calling the Bot API with api.
+func New(api *botapi.Client, userClient UserClient, repository Repository) *telegram {
+	t := &telegram{
+		api:        api,
 		timeout:    defaultTimeout,
-		chats:      make(map[int64]chan string),
+		chats:      make(map[int64]chan *botapi.Update),
 		userClient: userClient,
 		repository: repository,
-	}
-}
+		holder:     newHolder(),
+		subs:       make(map[int64]*subscription),
 
-func (t *telegram) getUpdates(ctx context.Context, offset int) chan *getUpdatesResponse {
-	ch := make(chan *getUpdatesResponse, 1)
+		pollInterval:        int64(defaultPollInterval),
+		alertInterval:       int64(defaultAlertInterval),
+		conversationTimeout: int64(defaultConversationTimeout),
+	}
+	t.commands = t.newCommands()
+	t.lists = t.newLists()
 
-	go func() {
-		defer close(ch)
+	return t
+}
 
-		url := fmt.Sprintf(
-			"https://api.telegram.org/bot%s/getUpdates?=%d&offset=%d",
-			t.token,
-			t.timeout,
-			offset,
-		)
+// SetPollInterval sets the minimum time between two requests for updates.
+func (t *telegram) SetPollInterval(d time.Duration) {
+	atomic.StoreInt64(&t.pollInterval, int64(d))
+}
 
-		req, err := http.NewRequest(http.MethodGet, url, nil)
-		if err != nil {
-			log.Printf("create request error: %v", err)
-			return
-		}
+// SetAlertInterval sets the minimum time between two alerts sent to a chat.
+func (t *telegram) SetAlertInterval(d time.Duration) {
+	atomic.StoreInt64(&t.alertInterval, int64(d))
+}
 
-		res, err := t.httpClient.Do(req.WithContext(ctx))
-		if err != nil {
-			log.Printf("make request error: %v", err)
-			return
-		}
+func (t *telegram) getUpdates(ctx context.Context, offset int) chan []botapi.Update {
+	ch := make(chan []botapi.Update, 1)
 
-		defer res.Body.Close()
-		b, err := io.ReadAll(res.Body)
-		if err != nil {
-			if err != nil {
-				log.Printf("response body read error: %v", err)
-				return
-			}
-		}
+	go func() {
+		defer close(ch)
 
-		var data getUpdatesResponse
-		err = json.Unmarshal(b, &data)
+		updates, err := t.api.GetUpdates(ctx, botapi.GetUpdatesParams{
+			Offset:         offset,
+			Timeout:        t.timeout,
+			AllowedUpdates: allowedUpdates,
+		})
 		if err != nil {
-			log.Printf("unmarshal response body error: %v", err)
+			log.Printf("get updates error: %v", err)
 			return
 		}
 
-		ch <- &data
+		ch <- updates
 	}()
 
 	return ch
 }
 
-var loginCommandReg = regexp.MustCompile("^/login ([A-Za-z0-9]+)$")
-
-func (t *telegram) handleCommandLogin(ctx context.Context, chat *Chat, username string, ch chan string) error {
-	err := t.userClient.GenerateOTP(ctx, username)
-	if err != nil {
-		log.Printf("generate otp error: %v", err)
-		return err
-	}
-	log.Printf("waiting for OTP code...")
-	err = t.sendMessage(ctx, chat, "Enter OTP code.")
+func (t *telegram) verifyOTP(ctx context.Context, chat *botapi.Chat, username string, code string) error {
+	res, err := t.userClient.VerifyOTP(ctx, username, code)
 	if err != nil {
 		return err
 	}
-	otpCode := <-ch
-	log.Printf("OTP CODE: %q", otpCode)
-	err = t.verifyOTP(ctx, chat, username, otpCode)
+
+	err = t.repository.SetAuthToken(ctx, chat.ID, res.Token, res.RefreshToken, res.UserID)
 	if err != nil {
 		return err
 	}
-	log.Printf("OTP code verified")
 
 	return nil
 }
 
-func (t *telegram) verifyOTP(ctx context.Context, chat *Chat, username string, code string) error {
-	res, err := t.userClient.VerifyOTP(ctx, username, code)
-	if err != nil {
-		return err
-	}
+// renewToken returns an access token of the chat replacing the stale one. Refreshes are serialized:
+// a refresh token can be used once, and commands and the alert subscription of a chat renew tokens
+// concurrently. A token renewed by another caller in the meantime is returned as is.
+func (t *telegram) renewToken(ctx context.Context, chatID int64, stale string) (string, error) {
+	t.refreshMu.Lock()
+	defer t.refreshMu.Unlock()
 
-	err = t.repository.SetAuthToken(ctx, chat.ID, res.Token, res.UserID)
+	acc, err := t.repository.GetAccount(ctx, chatID)
 	if err != nil {
-		return err
+		return "", err
+	}
+	if acc.AuthToken == "" {
+		return "", ErrNotLoggedIn
+	}
+	if acc.AuthToken != stale {
+		return acc.AuthToken, nil
 	}
 
-	return nil
+	return t.refreshToken(ctx, acc)
 }
 
-var subscribeCommangReg = regexp.MustCompile("^/subscribe$")
+// refreshToken renews the access token of the account, which is short-lived,
+// and stores the rotated refresh token. Accounts linked before refresh tokens
+// were issued keep using their access token.
+func (t *telegram) refreshToken(ctx context.Context, acc *Account) (string, error) {
+	if acc.RefreshToken == "" {
+		return acc.AuthToken, nil
+	}
 
-func (t *telegram) handleCommandSubscribe(ctx context.Context, chat *Chat) error {
-	acc, err := t.repository.GetAccount(ctx, chat.ID)
+	res, err := t.userClient.RefreshToken(ctx, acc.RefreshToken)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	ch, err := t.userClient.Subscribe(ctx, acc.UserID, acc.AuthToken)
+	err = t.repository.SetAuthToken(ctx, acc.ID, res.Token, res.RefreshToken, acc.UserID)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case msg := <-ch:
-				log.Printf("MESSAGE: %q", msg)
-				err := t.sendMessage(ctx, chat, msg)
-				if err != nil {
-					log.Printf("ERR: %v", err)
-				}
-			}
-		}
-	}()
-
-	return nil
+	return res.Token, nil
 }
 
-func (t *telegram) handleMessage(ctx context.Context, chat *Chat, msg string, ch chan string) {
-	if loginCommandReg.MatchString(msg) {
-		submatches := loginCommandReg.FindStringSubmatch(msg)
-		username := submatches[1]
-		log.Printf("username: %q", username)
-		err := t.handleCommandLogin(ctx, chat, username, ch)
-		if err != nil {
-			log.Printf("handler command error: %v", err)
-		}
-		return
-	}
-	if subscribeCommangReg.MatchString(msg) {
-		err := t.handleCommandSubscribe(ctx, chat)
-		if err != nil {
-			log.Printf("handler command error: %v", err)
-			return
-		}
-		return
-	}
-	log.Printf("unexpected message...%q", msg)
-}
-
-func (t *telegram) runChat(ctx context.Context, chat *Chat, ch chan string) error {
+func (t *telegram) runChat(ctx context.Context, chat *botapi.Chat, ch chan *botapi.Update) error {
 	err := t.repository.AddAccount(ctx, chat.ID)
 	if err != nil {
 		return err
@@ -195,9 +187,14 @@ func (t *telegram) runChat(ctx context.Context, chat *Chat, ch chan string) erro
 			select {
 			case <-ctx.Done():
 				return
-			case msg := <-ch:
-				log.Printf("[%s] %q", chat.Username, msg)
-				t.handleMessage(ctx, chat, msg, ch)
+			case u := <-ch:
+				if u.CallbackQuery != nil {
+					log.Printf("[%s] pressed %q", chat.Username, u.CallbackQuery.Data)
+					t.handleCallbackQuery(ctx, chat, u.CallbackQuery)
+					continue
+				}
+				log.Printf("[%s] %q", chat.Username, u.Message.Text)
+				t.handleMessage(ctx, chat, u.Message.Text)
 			}
 		}
 	}()
@@ -205,12 +202,12 @@ func (t *telegram) runChat(ctx context.Context, chat *Chat, ch chan string) erro
 	return nil
 }
 
-func (t *telegram) getChatChan(ctx context.Context, chat *Chat) (chan string, error) {
+func (t *telegram) getChatChan(ctx context.Context, chat *botapi.Chat) (chan *botapi.Update, error) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	ch, ok := t.chats[chat.ID]
 	if !ok {
-		ch = make(chan string, 1)
+		ch = make(chan *botapi.Update, 1)
 		t.chats[chat.ID] = ch
 		if err := t.runChat(ctx, chat, ch); err != nil {
 			log.Printf("err: %v", err)
@@ -221,45 +218,73 @@ func (t *telegram) getChatChan(ctx context.Context, chat *Chat) (chan string, er
 	return ch, nil
 }
 
-func (t *telegram) sendMessage(ctx context.Context, chat *Chat, msg string) error {
-	client := http.Client{Timeout: 10 * time.Second}
+func (t *telegram) sendMessage(ctx context.Context, chat *botapi.Chat, msg string) error {
+	_, err := t.api.SendMessage(ctx, botapi.SendMessageParams{
+		ChatID: chat.ID,
+		Text:   msg,
+	})
+
+	return err
+}
+
+// sendKeyboard sends the message with the inline keyboard below it.
+func (t *telegram) sendKeyboard(ctx context.Context, chat *botapi.Chat, msg string, keyboard *botapi.InlineKeyboardMarkup) error {
+	_, err := t.api.SendMessage(ctx, botapi.SendMessageParams{
+		ChatID:      chat.ID,
+		Text:        msg,
+		ReplyMarkup: keyboard,
+	})
+
+	return err
+}
 
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", t.token)
-	values := map[string]interface{}{
-		"chat_id": chat.ID,
-		"text":    msg,
+// dispatch passes messages and callback queries to their chat, starting the chat on its first update.
+func (t *telegram) dispatch(ctx context.Context, u *botapi.Update) {
+	var chat *botapi.Chat
+	switch {
+	case u.Message != nil:
+		chat = u.Message.Chat
+	case u.CallbackQuery != nil && u.CallbackQuery.Message != nil:
+		chat = u.CallbackQuery.Message.Chat
 	}
-	b, err := json.Marshal(values)
-	if err != nil {
-		return ErrInternalError
+	// Other updates, like edited messages, are ignored.
+	if chat == nil {
+		return
 	}
 
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(b))
+	ch, err := t.getChatChan(ctx, chat)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return
 	}
+	log.Printf("sending update to %s...", chat.Username)
+	ch <- u
+}
 
-	req.Header.Add("Content-Type", "application/json")
-
-	res, err := client.Do(req.WithContext(ctx))
+func (t *telegram) Serve(ctx context.Context) error {
+	// Updates can't be polled while a webhook is set.
+	err := t.DeleteWebhook(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to make request: %w", err)
+		return err
 	}
-	if res.StatusCode > 299 {
-		return ErrInternalError
+	err = t.setCommands(ctx)
+	if err != nil {
+		log.Printf("set commands error: %v", err)
 	}
+	go t.ExpireConversations(ctx)
 
-	defer res.Body.Close()
-
-	return nil
-}
+	subsDone := make(chan struct{})
+	go func() {
+		defer close(subsDone)
+		t.RunSubscriptions(ctx)
+	}()
+	// Subscriptions are released for other bot processes before returning.
+	defer func() { <-subsDone }()
 
-func (t *telegram) Serve(ctx context.Context) error {
 	offset := 0
 
 	for {
 		ch := t.getUpdates(ctx, offset)
-		timer := time.NewTimer(1 * time.Second) // To prevent spam
+		timer := time.NewTimer(time.Duration(atomic.LoadInt64(&t.pollInterval))) // To prevent spam
 
 		select {
 		case <-ctx.Done():
@@ -268,25 +293,18 @@ func (t *telegram) Serve(ctx context.Context) error {
 			if data == nil {
 				continue
 			}
-			for _, r := range data.Result {
-				offset = r.UpdateID + 1
-				ch, err := t.getChatChan(ctx, r.Message.Chat)
-				if err != nil {
-					continue
-				}
-				log.Printf("sending message to %s...", r.Message.Chat.Username)
-				ch <- r.Message.Text
+			for i := range data {
+				offset = data[i].UpdateID + 1
+				t.dispatch(ctx, &data[i])
 			}
 		}
 
 		<-timer.C
 	}
-
-	return nil
 }
 
 //func (t *telegram) Notify(ctx context.Context, user *user.User, msg interface{}) error {
-//	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", t.token)
+//	url := t.methodURL("sendMessage")
 //	//TODO implement me
 //	panic("implement me")
 //}
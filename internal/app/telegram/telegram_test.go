@@ -0,0 +1,369 @@
+package telegram_test
+
+import (
+	"context"
+	"cryptowatch/internal/app/telegram"
+	"cryptowatch/pkg/botapi/botapitest"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+const waitTimeout = 5 * time.Second
+
+// memRepo keeps accounts, update ids, conversations and snoozes in memory.
+type memRepo struct {
+	mu            sync.Mutex
+	accounts      map[int64]*telegram.Account
+	leases        map[int64]lease
+	updates       map[int]bool
+	conversations map[int64]*telegram.Conversation
+	snoozes       map[string]time.Time
+}
+
+// lease is the holder of a subscription and until when it holds it.
+type lease struct {
+	holder string
+	time   time.Time
+}
+
+func newMemRepo() *memRepo {
+	return &memRepo{
+		accounts:      make(map[int64]*telegram.Account),
+		leases:        make(map[int64]lease),
+		updates:       make(map[int]bool),
+		conversations: make(map[int64]*telegram.Conversation),
+		snoozes:       make(map[string]time.Time),
+	}
+}
+
+func (r *memRepo) AddAccount(_ context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.accounts[id]; !ok {
+		r.accounts[id] = &telegram.Account{ID: id}
+	}
+	return nil
+}
+
+func (r *memRepo) GetAccount(_ context.Context, id int64) (*telegram.Account, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	acc, ok := r.accounts[id]
+	if !ok {
+		return nil, telegram.ErrNotFound
+	}
+	copied := *acc
+	return &copied, nil
+}
+
+func (r *memRepo) SetAuthToken(_ context.Context, id int64, token string, refreshToken string, userID uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	acc, ok := r.accounts[id]
+	if !ok {
+		return telegram.ErrNotFound
+	}
+	acc.AuthToken = token
+	acc.RefreshToken = refreshToken
+	acc.UserID = userID
+	return nil
+}
+
+func (r *memRepo) ClearAuthToken(_ context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if acc, ok := r.accounts[id]; ok {
+		*acc = telegram.Account{ID: id}
+	}
+	delete(r.leases, id)
+	return nil
+}
+
+func (r *memRepo) HoldSubscription(_ context.Context, id int64, holder string, t time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	acc, ok := r.accounts[id]
+	if !ok {
+		return telegram.ErrNotFound
+	}
+	acc.Subscribed = true
+	r.leases[id] = lease{holder: holder, time: t}
+	return nil
+}
+
+func (r *memRepo) Unsubscribe(_ context.Context, id int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	acc, ok := r.accounts[id]
+	if !ok || !acc.Subscribed {
+		return false, nil
+	}
+	acc.Subscribed = false
+	delete(r.leases, id)
+	return true, nil
+}
+
+func (r *memRepo) ClaimSubscriptions(_ context.Context, holder string, now time.Time, t time.Time) ([]int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ids []int64
+	for id, acc := range r.accounts {
+		l, held := r.leases[id]
+		if acc.Subscribed && (!held || l.holder == holder || !l.time.After(now)) {
+			r.leases[id] = lease{holder: holder, time: t}
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (r *memRepo) ReleaseSubscriptions(_ context.Context, holder string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, l := range r.leases {
+		if l.holder == holder {
+			delete(r.leases, id)
+		}
+	}
+	return nil
+}
+
+// holder returns the holder of the subscription of the chat, empty if none.
+func (r *memRepo) holder(id int64) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.leases[id].holder
+}
+
+func (r *memRepo) RecordUpdate(_ context.Context, updateID int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.updates[updateID] {
+		return false, nil
+	}
+	r.updates[updateID] = true
+	return true, nil
+}
+
+func (r *memRepo) DeleteUpdatesBefore(_ context.Context, _ time.Time) error {
+	return nil
+}
+
+func (r *memRepo) GetConversation(_ context.Context, chatID int64) (*telegram.Conversation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conv, ok := r.conversations[chatID]
+	if !ok {
+		return nil, telegram.ErrNotFound
+	}
+	return copyConversation(conv), nil
+}
+
+func (r *memRepo) SaveConversation(_ context.Context, conv *telegram.Conversation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.conversations[conv.ChatID] = copyConversation(conv)
+	return nil
+}
+
+func (r *memRepo) DeleteConversation(_ context.Context, chatID int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.conversations[chatID]
+	delete(r.conversations, chatID)
+	return ok, nil
+}
+
+func (r *memRepo) DeleteExpiredConversations(_ context.Context, t time.Time) ([]*telegram.Conversation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var expired []*telegram.Conversation
+	for chatID, conv := range r.conversations {
+		if !conv.ExpireTime.After(t) {
+			expired = append(expired, conv)
+			delete(r.conversations, chatID)
+		}
+	}
+	return expired, nil
+}
+
+func (r *memRepo) SnoozeAlerts(_ context.Context, chatID int64, ticker string, t time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.snoozes[fmt.Sprintf("%d:%s", chatID, ticker)] = t
+	return nil
+}
+
+func (r *memRepo) GetSnoozeTime(_ context.Context, chatID int64, ticker string) (time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.snoozes[fmt.Sprintf("%d:%s", chatID, ticker)], nil
+}
+
+func copyConversation(conv *telegram.Conversation) *telegram.Conversation {
+	copied := *conv
+	copied.Data = make(map[string]string, len(conv.Data))
+	for k, v := range conv.Data {
+		copied.Data[k] = v
+	}
+	return &copied
+}
+
+// fakeUserClient accepts the code "123456" for every user.
+type fakeUserClient struct {
+	telegram.UserClient
+}
+
+func (c *fakeUserClient) GenerateOTP(_ context.Context, _ string) error {
+	return nil
+}
+
+func (c *fakeUserClient) VerifyOTP(_ context.Context, _ string, code string) (*telegram.VerifyOTPRes, error) {
+	if code != "123456" {
+		return nil, telegram.ErrUnauthenticated
+	}
+	return &telegram.VerifyOTPRes{UserID: 7, Token: "token", RefreshToken: "refresh"}, nil
+}
+
+// serveBot runs the bot against a fake Bot API until the test ends.
+func serveBot(t *testing.T, userClient telegram.UserClient, repo *memRepo, opts ...func(telegram.Telegram)) *botapitest.Server {
+	t.Helper()
+
+	api := botapitest.NewServer("123:token")
+	t.Cleanup(api.Close)
+
+	bot := telegram.New(api.BotClient(), userClient, repo)
+	bot.SetPollInterval(0)
+	for _, opt := range opts {
+		opt(bot)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- bot.Serve(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-done)
+	})
+
+	return api
+}
+
+func texts(t *testing.T, api *botapitest.Server, chatID int64, n int) []string {
+	t.Helper()
+
+	msgs, err := api.WaitMessages(chatID, n, waitTimeout)
+	require.NoError(t, err)
+
+	var res []string
+	for _, msg := range msgs {
+		res = append(res, msg.Text)
+	}
+	return res
+}
+
+func TestTelegram_Login(t *testing.T) {
+	repo := newMemRepo()
+	api := serveBot(t, &fakeUserClient{}, repo)
+
+	api.SendText(1, "/login alice")
+	assert.Equal(t, []string{"connected", "Enter OTP code."}, texts(t, api, 1, 2))
+
+	api.SendText(1, "123456")
+	assert.Eventually(t, func() bool {
+		acc, err := repo.GetAccount(context.Background(), 1)
+		return err == nil && acc.AuthToken == "token"
+	}, waitTimeout, 10*time.Millisecond)
+
+	acc, err := repo.GetAccount(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, &telegram.Account{ID: 1, AuthToken: "token", RefreshToken: "refresh", UserID: 7}, acc)
+	assert.Equal(t, "Logged in as alice.", texts(t, api, 1, 3)[2])
+	assert.Equal(t, 0, api.Calls("setWebhook"))
+	assert.Empty(t, api.Webhook())
+}
+
+func TestTelegram_LoginWrongCode(t *testing.T) {
+	repo := newMemRepo()
+	api := serveBot(t, &fakeUserClient{}, repo)
+
+	api.SendText(2, "/login bob")
+	texts(t, api, 2, 2)
+
+	api.SendText(2, "000000")
+	assert.Equal(t, []string{"connected", "Enter OTP code.", "Wrong or expired code, enter it again or /cancel."}, texts(t, api, 2, 3))
+
+	acc, err := repo.GetAccount(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Empty(t, acc.AuthToken)
+
+	// The code can be entered again.
+	api.SendText(2, "123456")
+	assert.Equal(t, "Logged in as bob.", texts(t, api, 2, 4)[3])
+}
+
+func TestTelegram_LoginConversation(t *testing.T) {
+	repo := newMemRepo()
+	api := serveBot(t, &fakeUserClient{}, repo)
+
+	api.SendText(4, "/login")
+	api.SendText(4, "not a username")
+	api.SendText(4, "carol")
+	api.SendText(4, "123456")
+	assert.Equal(t, []string{
+		"connected",
+		"Enter your username.",
+		"Usernames have only letters and digits, enter it again or /cancel.",
+		"Enter OTP code.",
+		"Logged in as carol.",
+	}, texts(t, api, 4, 5))
+
+	assert.Eventually(t, func() bool {
+		_, err := repo.GetConversation(context.Background(), 4)
+		return errors.Is(err, telegram.ErrNotFound)
+	}, waitTimeout, 10*time.Millisecond)
+}
+
+func TestTelegram_ConversationTimeout(t *testing.T) {
+	repo := newMemRepo()
+	api := serveBot(t, &fakeUserClient{}, repo, func(bot telegram.Telegram) {
+		bot.SetConversationTimeout(time.Millisecond)
+	})
+
+	api.SendText(5, "/login dave")
+	texts(t, api, 5, 2)
+	time.Sleep(10 * time.Millisecond)
+
+	// The code comes too late, it isn't taken for anything else.
+	api.SendText(5, "123456")
+	assert.Equal(t, "/login timed out, send it again to start over.", texts(t, api, 5, 3)[2])
+	api.SendText(5, "/cancel")
+	assert.Equal(t, "Nothing to cancel.", texts(t, api, 5, 4)[3])
+
+	acc, err := repo.GetAccount(context.Background(), 5)
+	require.NoError(t, err)
+	assert.Empty(t, acc.AuthToken)
+}
@@ -3,110 +3,186 @@ package telegram
 import (
 	"context"
 	pb "cryptowatch/pkg/api/cryptowatchv1"
+	pbv2 "cryptowatch/pkg/api/cryptowatchv2"
 	"fmt"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 	"io"
 	"log"
-	"time"
 )
 
+// UserClient calls the gRPC server for the bot. Methods taking a token
+// act on behalf of the user of the access token.
 type UserClient interface {
 	GenerateOTP(ctx context.Context, username string) error
 	VerifyOTP(ctx context.Context, username string, code string) (*VerifyOTPRes, error)
-	Subscribe(ctx context.Context, userID uint64, token string) (chan string, error)
+	RefreshToken(ctx context.Context, refreshToken string) (*RefreshTokenRes, error)
+	// Subscribe streams the prices of tokens alerts of the user fire for.
+	Subscribe(ctx context.Context, userID uint64, token string) (chan *Price, error)
+	// Logout ends the session of the token.
+	Logout(ctx context.Context, token string) error
+	ListPortfolios(ctx context.Context, token string) ([]*Portfolio, error)
+	GetPortfolio(ctx context.Context, token string, name string) (*PortfolioDetails, error)
+	Buy(ctx context.Context, token string, trade *Trade) error
+	Sell(ctx context.Context, token string, trade *Trade) error
+	AddAlert(ctx context.Context, token string, alert *Alert) error
+	ListAlerts(ctx context.Context, token string) ([]*Alert, error)
+	DeleteAlert(ctx context.Context, token string, id uint64) error
+	// RemoveAlerts removes all alerts for the ticker.
+	RemoveAlerts(ctx context.Context, token string, ticker string) error
+	GetPrices(ctx context.Context, token string, tickers []string) ([]*Price, error)
 }
 
 type VerifyOTPRes struct {
-	UserID uint64 `json:"user_id"`
-	Token  string `json:"token"`
+	UserID       uint64 `json:"user_id"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type RefreshTokenRes struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 type userClient struct {
-	addr string
+	addr  string
+	creds credentials.TransportCredentials
 }
 
-func NewUserClient(addr string) *userClient {
+// NewUserClient creates a client of the gRPC server at addr connecting with creds.
+func NewUserClient(addr string, creds credentials.TransportCredentials) *userClient {
 	return &userClient{
-		addr: addr,
+		addr:  addr,
+		creds: creds,
 	}
 }
 
-func (c *userClient) GenerateOTP(ctx context.Context, username string) error {
+func (c *userClient) dial(ctx context.Context) (*grpc.ClientConn, error) {
 	opts := []grpc.DialOption{
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithTransportCredentials(c.creds),
 	}
 	conn, err := grpc.DialContext(ctx, c.addr, opts...)
 	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInternalError, err)
+	}
+
+	return conn, nil
+}
+
+// withToken authenticates calls made with the context as the user of the access token.
+func withToken(ctx context.Context, token string) context.Context {
+	return metadata.NewOutgoingContext(ctx, metadata.New(map[string]string{
+		"authorization": token,
+	}))
+}
+
+// errFromStatus maps the status of a failed call to the errors of the package,
+// keeping its message for logs.
+func errFromStatus(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
 		return fmt.Errorf("%w: %v", ErrInternalError, err)
 	}
+
+	var target error
+	switch st.Code() {
+	case codes.Unauthenticated:
+		target = ErrUnauthenticated
+	case codes.PermissionDenied:
+		target = ErrPermissionDenied
+	case codes.NotFound:
+		target = ErrNotFound
+	case codes.InvalidArgument:
+		target = ErrInvalidArgument
+	case codes.FailedPrecondition, codes.AlreadyExists:
+		target = ErrFailedPrecondition
+	case codes.ResourceExhausted:
+		target = ErrResourceExhausted
+	case codes.Unavailable, codes.DeadlineExceeded:
+		target = ErrUnavailable
+	default:
+		target = ErrInternalError
+	}
+
+	return fmt.Errorf("%w: %s", target, st.Message())
+}
+
+func (c *userClient) GenerateOTP(ctx context.Context, username string) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
 	defer conn.Close()
 
 	client := pb.NewUsersClient(conn)
 
 	_, err = client.GenerateOTP(ctx, &wrapperspb.StringValue{Value: username})
 	if err != nil {
-		return fmt.Errorf("%w: %v", ErrInternalError, err)
+		return errFromStatus(err)
 	}
 
 	return nil
 }
 
 func (c *userClient) VerifyOTP(ctx context.Context, username string, code string) (*VerifyOTPRes, error) {
-	opts := []grpc.DialOption{
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	}
-	conn, err := grpc.DialContext(ctx, "localhost:50051", opts...)
+	conn, err := c.dial(ctx)
 	if err != nil {
-		return nil, ErrInternalError
+		return nil, err
 	}
 	defer conn.Close()
 
-	client := pb.NewUsersClient(conn)
+	client := pbv2.NewUsersClient(conn)
 
-	res, err := client.VerifyOTP(ctx, &pb.VerifyOTPReq{
+	res, err := client.VerifyOTP(ctx, &pbv2.VerifyOTPReq{
 		Username: username,
 		Code:     code,
 	})
 	if err != nil {
-		st, ok := status.FromError(err)
-		if !ok {
-			return nil, ErrInternalError
-		}
-		switch st.Code() {
-		case codes.Unauthenticated:
-			return nil, ErrUnauthenticated
-		default:
-			return nil, ErrInternalError
-		}
+		return nil, errFromStatus(err)
 	}
 
 	return &VerifyOTPRes{
-		UserID: res.GetUserId(),
-		Token:  res.GetToken(),
+		UserID:       res.GetUserId(),
+		Token:        res.GetToken(),
+		RefreshToken: res.GetRefreshToken(),
 	}, nil
 }
 
-func (c *userClient) Subscribe(ctx context.Context, userID uint64, token string) (chan string, error) {
-	out := make(chan string, 1)
-
-	opts := []grpc.DialOption{
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+func (c *userClient) RefreshToken(ctx context.Context, refreshToken string) (*RefreshTokenRes, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
 	}
+	defer conn.Close()
 
-	md := metadata.New(map[string]string{
-		"authorization": token,
+	client := pbv2.NewUsersClient(conn)
+
+	res, err := client.RefreshToken(ctx, &pbv2.RefreshTokenReq{
+		RefreshToken: refreshToken,
 	})
+	if err != nil {
+		return nil, errFromStatus(err)
+	}
+
+	return &RefreshTokenRes{
+		Token:        res.GetAccessToken(),
+		RefreshToken: res.GetRefreshToken(),
+	}, nil
+}
+
+func (c *userClient) Subscribe(ctx context.Context, userID uint64, token string) (chan *Price, error) {
+	out := make(chan *Price, 1)
 
-	ctx = metadata.NewOutgoingContext(ctx, md)
+	ctx = withToken(ctx, token)
 
-	conn, err := grpc.DialContext(ctx, "localhost:50051", opts...)
+	conn, err := c.dial(ctx)
 	if err != nil {
-		return nil, ErrInternalError
+		return nil, err
 	}
 
 	client := pb.NewTriggersClient(conn)
@@ -114,22 +190,12 @@ func (c *userClient) Subscribe(ctx context.Context, userID uint64, token string)
 	stream, err := client.Subscribe(ctx, &wrapperspb.UInt64Value{Value: userID})
 	if err != nil {
 		conn.Close()
-		st, ok := status.FromError(err)
-		if !ok {
-			return nil, ErrInternalError
-		}
-		switch st.Code() {
-		case codes.Unauthenticated:
-			return nil, ErrUnauthenticated
-		default:
-			return nil, ErrInternalError
-		}
+		return nil, errFromStatus(err)
 	}
 
 	go func() {
 		defer conn.Close()
 		for {
-			time.Sleep(5 * time.Second)
 			in, err := stream.Recv()
 			log.Printf("RECV: %v", in)
 			if err == io.EOF {
@@ -142,9 +208,236 @@ func (c *userClient) Subscribe(ctx context.Context, userID uint64, token string)
 				return
 			}
 
-			out <- fmt.Sprintf("%s: $%v", in.GetTicker(), in.GetPrice())
+			out <- &Price{Ticker: in.GetTicker(), Price: in.GetPrice()}
 		}
 	}()
 
 	return out, nil
 }
+
+func (c *userClient) Logout(ctx context.Context, token string) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = pbv2.NewUsersClient(conn).Logout(withToken(ctx, token), &emptypb.Empty{})
+	if err != nil {
+		return errFromStatus(err)
+	}
+
+	return nil
+}
+
+func (c *userClient) ListPortfolios(ctx context.Context, token string) ([]*Portfolio, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	res, err := pbv2.NewPortfoliosClient(conn).ListPortfolios(withToken(ctx, token), &emptypb.Empty{})
+	if err != nil {
+		return nil, errFromStatus(err)
+	}
+
+	portfolios := make([]*Portfolio, 0, len(res.GetPortfolios()))
+	for _, p := range res.GetPortfolios() {
+		portfolios = append(portfolios, portfolioFromPB(p))
+	}
+
+	return portfolios, nil
+}
+
+func (c *userClient) GetPortfolio(ctx context.Context, token string, name string) (*PortfolioDetails, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	res, err := pbv2.NewPortfoliosClient(conn).GetPortfolio(withToken(ctx, token), &pbv2.GetPortfolioReq{Name: name})
+	if err != nil {
+		return nil, errFromStatus(err)
+	}
+
+	details := &PortfolioDetails{
+		Portfolio: portfolioFromPB(res.GetPortfolio()),
+		Holdings:  make([]*Holding, 0, len(res.GetHoldings())),
+	}
+	for _, h := range res.GetHoldings() {
+		details.Holdings = append(details.Holdings, &Holding{
+			Ticker:   h.GetTicker(),
+			Quantity: h.GetQuantity(),
+			Cost:     h.GetCost(),
+			Value:    h.GetValue(),
+			Profit:   h.GetProfit(),
+		})
+	}
+
+	return details, nil
+}
+
+func (c *userClient) Buy(ctx context.Context, token string, trade *Trade) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = pbv2.NewPortfoliosClient(conn).Buy(withToken(ctx, token), tradeToPB(trade))
+	if err != nil {
+		return errFromStatus(err)
+	}
+
+	return nil
+}
+
+func (c *userClient) Sell(ctx context.Context, token string, trade *Trade) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = pbv2.NewPortfoliosClient(conn).Sell(withToken(ctx, token), tradeToPB(trade))
+	if err != nil {
+		return errFromStatus(err)
+	}
+
+	return nil
+}
+
+func (c *userClient) AddAlert(ctx context.Context, token string, alert *Alert) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	condition, ok := alertConditionToPB[alert.Condition]
+	if !ok {
+		return ErrInvalidArgument
+	}
+
+	_, err = pbv2.NewTriggersClient(conn).Add(withToken(ctx, token), &pbv2.Req{
+		Ticker:    alert.Ticker,
+		Condition: condition,
+		Price:     alert.Price,
+	})
+	if err != nil {
+		return errFromStatus(err)
+	}
+
+	return nil
+}
+
+func (c *userClient) ListAlerts(ctx context.Context, token string) ([]*Alert, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	res, err := pbv2.NewTriggersClient(conn).List(withToken(ctx, token), &emptypb.Empty{})
+	if err != nil {
+		return nil, errFromStatus(err)
+	}
+
+	alerts := make([]*Alert, 0, len(res.GetTriggers()))
+	for _, t := range res.GetTriggers() {
+		condition, ok := alertConditionFromPB[t.GetCondition()]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown condition %v", ErrInternalError, t.GetCondition())
+		}
+		alerts = append(alerts, &Alert{
+			ID:        t.GetId(),
+			Ticker:    t.GetTicker(),
+			Condition: condition,
+			Price:     t.GetPrice(),
+		})
+	}
+
+	return alerts, nil
+}
+
+func (c *userClient) DeleteAlert(ctx context.Context, token string, id uint64) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = pbv2.NewTriggersClient(conn).Delete(withToken(ctx, token), &pbv2.DeleteReq{Id: id})
+	if err != nil {
+		return errFromStatus(err)
+	}
+
+	return nil
+}
+
+func (c *userClient) RemoveAlerts(ctx context.Context, token string, ticker string) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = pbv2.NewTriggersClient(conn).Remove(withToken(ctx, token), &pbv2.Req{Ticker: ticker})
+	if err != nil {
+		return errFromStatus(err)
+	}
+
+	return nil
+}
+
+func (c *userClient) GetPrices(ctx context.Context, token string, tickers []string) ([]*Price, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	res, err := pbv2.NewPricesClient(conn).GetPrices(withToken(ctx, token), &pbv2.GetPricesReq{Tickers: tickers})
+	if err != nil {
+		return nil, errFromStatus(err)
+	}
+
+	prices := make([]*Price, 0, len(res.GetPrices()))
+	for _, p := range res.GetPrices() {
+		prices = append(prices, &Price{Ticker: p.GetTicker(), Price: p.GetPrice()})
+	}
+
+	return prices, nil
+}
+
+func portfolioFromPB(p *pbv2.Portfolio) *Portfolio {
+	return &Portfolio{
+		ID:     p.GetId(),
+		Name:   p.GetName(),
+		Profit: p.GetProfit(),
+	}
+}
+
+func tradeToPB(trade *Trade) *pbv2.BuySellReq {
+	return &pbv2.BuySellReq{
+		PortfolioId: trade.PortfolioID,
+		Ticker:      trade.Ticker,
+		Quantity:    trade.Quantity,
+		Price:       trade.Price,
+		Fee:         trade.Fee,
+	}
+}
+
+var alertConditionToPB = map[AlertCondition]pbv2.Condition{
+	AlertOnChange: pbv2.Condition_CONDITION_UNSPECIFIED,
+	AlertAbove:    pbv2.Condition_CONDITION_ABOVE,
+	AlertBelow:    pbv2.Condition_CONDITION_BELOW,
+}
+
+var alertConditionFromPB = map[pbv2.Condition]AlertCondition{
+	pbv2.Condition_CONDITION_UNSPECIFIED: AlertOnChange,
+	pbv2.Condition_CONDITION_ABOVE:       AlertAbove,
+	pbv2.Condition_CONDITION_BELOW:       AlertBelow,
+}
@@ -0,0 +1,103 @@
+package telegram
+
+import (
+	"context"
+	"crypto/subtle"
+	"cryptowatch/pkg/botapi"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	// secretTokenHeader carries the secret token of the webhook in updates posted by Telegram.
+	secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+	// maxUpdateSize limits the body of posted updates.
+	maxUpdateSize = 1 << 20
+	// updateRetention is how long update ids are kept, Telegram gives up retrying an update within a day.
+	updateRetention = 24 * time.Hour
+	// pruneInterval is how often old update ids are deleted.
+	pruneInterval = time.Hour
+)
+
+type WebhookConfig struct {
+	// URL is the public HTTPS URL of the WebhookHandler.
+	URL string
+	// SecretToken is sent by Telegram with every update, so nobody else can post updates.
+	// It is 1 to 256 letters, digits, underscores or hyphens.
+	SecretToken string
+	// MaxConnections limits concurrent connections Telegram opens to the webhook, its default if zero.
+	MaxConnections int
+}
+
+func (t *telegram) SetWebhook(ctx context.Context, cfg WebhookConfig) error {
+	err := t.setCommands(ctx)
+	if err != nil {
+		log.Printf("set commands error: %v", err)
+	}
+
+	return t.api.SetWebhook(ctx, botapi.SetWebhookParams{
+		URL:            cfg.URL,
+		SecretToken:    cfg.SecretToken,
+		MaxConnections: cfg.MaxConnections,
+		AllowedUpdates: allowedUpdates,
+	})
+}
+
+func (t *telegram) DeleteWebhook(ctx context.Context) error {
+	return t.api.DeleteWebhook(ctx)
+}
+
+func (t *telegram) WebhookHandler(ctx context.Context, secretToken string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(secretTokenHeader)), []byte(secretToken)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var u botapi.Update
+		err := json.NewDecoder(io.LimitReader(r.Body, maxUpdateSize)).Decode(&u)
+		if err != nil {
+			http.Error(w, "invalid update", http.StatusBadRequest)
+			return
+		}
+
+		// Telegram delivers an update again until it is acknowledged,
+		// and with several replicas another one may have handled it.
+		isNew, err := t.repository.RecordUpdate(r.Context(), u.UpdateID)
+		if err != nil {
+			log.Printf("record update %d error: %v", u.UpdateID, err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if isNew {
+			t.dispatch(ctx, &u)
+		}
+
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func (t *telegram) PruneUpdates(ctx context.Context) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := t.repository.DeleteUpdatesBefore(ctx, time.Now().Add(-updateRetention))
+			if err != nil {
+				log.Printf("prune updates error: %v", err)
+			}
+		}
+	}
+}
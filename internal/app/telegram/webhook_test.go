@@ -0,0 +1,42 @@
+package telegram_test
+
+import (
+	"context"
+	"cryptowatch/internal/app/telegram"
+	"cryptowatch/pkg/botapi"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	repo := newMemRepo()
+	bot := telegram.New(botapi.NewClient("token"), nil, repo)
+	handler := bot.WebhookHandler(context.Background(), "secret")
+
+	post := func(method string, secret string, body string) int {
+		req := httptest.NewRequest(method, "/telegram/webhook", strings.NewReader(body))
+		if secret != "" {
+			req.Header.Set("X-Telegram-Bot-Api-Secret-Token", secret)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Updates without a message are acknowledged without reaching a chat.
+	update := `{"update_id": 7, "edited_message": {"message_id": 1, "text": "hi"}}`
+
+	assert.Equal(t, http.StatusMethodNotAllowed, post(http.MethodGet, "secret", ""))
+	assert.Equal(t, http.StatusUnauthorized, post(http.MethodPost, "", update))
+	assert.Equal(t, http.StatusUnauthorized, post(http.MethodPost, "wrong", update))
+	assert.Equal(t, http.StatusBadRequest, post(http.MethodPost, "secret", "{"))
+	assert.Empty(t, repo.updates)
+
+	assert.Equal(t, http.StatusOK, post(http.MethodPost, "secret", update))
+	// A redelivered update is acknowledged again.
+	assert.Equal(t, http.StatusOK, post(http.MethodPost, "secret", update))
+	assert.Equal(t, map[int]bool{7: true}, repo.updates)
+}
@@ -1,8 +1,24 @@
 package token
 
-import "errors"
+import (
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
 
 var (
-	ErrInternalError = errors.New("internal error")
-	ErrNotFound      = errors.New("not found")
+	ErrInternalError   = errors.New("internal error")
+	ErrNotFound        = errors.New("not found")
+	ErrInvalidArgument = errors.New("invalid argument")
 )
+
+func ErrToGRPCErr(err error) error {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return status.New(codes.NotFound, err.Error()).Err()
+	case errors.Is(err, ErrInvalidArgument):
+		return status.New(codes.InvalidArgument, err.Error()).Err()
+	default:
+		return status.New(codes.Internal, err.Error()).Err()
+	}
+}
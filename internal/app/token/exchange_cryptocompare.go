@@ -12,26 +12,28 @@ import (
 	"strings"
 )
 
-const (
-	apiURL = "https://min-api.cryptocompare.com/data/pricemulti"
-)
-
 type cryptoCompareProvider struct {
 	apiKey     string
+	apiURL     string
+	streamURL  string
 	httpClient *http.Client
 	conn       *websocket.Conn
 }
 
-func NewCryptoCompareProvider(apiKey string, httpClient *http.Client) *cryptoCompareProvider {
+// NewCryptoCompareProvider creates an exchange using the cryptocompare.com
+// price REST API at apiURL and the streaming API at streamURL.
+func NewCryptoCompareProvider(apiKey string, apiURL string, streamURL string, httpClient *http.Client) *cryptoCompareProvider {
 	return &cryptoCompareProvider{
 		apiKey:     apiKey,
+		apiURL:     apiURL,
+		streamURL:  streamURL,
 		httpClient: httpClient,
 	}
 }
 
 func (c *cryptoCompareProvider) Start(ctx context.Context, ch chan<- map[string]float64) error {
 	var err error
-	c.conn, _, err = websocket.Dial(ctx, "wss://streamer.cryptocompare.com/v2?api_key="+c.apiKey, nil)
+	c.conn, _, err = websocket.Dial(ctx, c.streamURL+"?api_key="+c.apiKey, nil)
 	if err != nil {
 		return ErrInternalError
 	}
@@ -58,7 +60,7 @@ func (c *cryptoCompareProvider) Start(ctx context.Context, ch chan<- map[string]
 
 func (c *cryptoCompareProvider) GetPrices(ctx context.Context, tokens []string) (map[string]float64, error) {
 
-	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+	req, err := http.NewRequest(http.MethodGet, c.apiURL, nil)
 	if err != nil {
 		return nil, ErrInternalError
 	}
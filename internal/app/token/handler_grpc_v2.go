@@ -0,0 +1,38 @@
+package token
+
+import (
+	"context"
+	pb "cryptowatch/pkg/api/cryptowatchv2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCHandlerV2 serves token prices of the v2 API.
+type GRPCHandlerV2 struct {
+	svc Service
+
+	pb.UnimplementedPricesServer
+}
+
+func NewGRPCHandlerV2(svc Service) *GRPCHandlerV2 {
+	return &GRPCHandlerV2{
+		svc: svc,
+	}
+}
+
+func (h *GRPCHandlerV2) GetPrices(ctx context.Context, req *pb.GetPricesReq) (*pb.GetPricesRes, error) {
+	tokens, err := h.svc.Prices(ctx, req.GetTickers())
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	res := &pb.GetPricesRes{Prices: make([]*pb.Price, 0, len(tokens))}
+	for _, tkn := range tokens {
+		res.Prices = append(res.Prices, &pb.Price{
+			Ticker: tkn.Ticker,
+			Price:  tkn.Price,
+		})
+	}
+
+	return res, status.New(codes.OK, "OK").Err()
+}
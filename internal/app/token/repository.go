@@ -6,4 +6,10 @@ type Repository interface {
 	Add(ctx context.Context, ticker string) (bool, error)
 	Update(ctx context.Context, ticker string, price float64) error
 	ListTickers(ctx context.Context) ([]string, error)
+	// GetPrices returns prices of the tokens by ticker, unknown tokens are left out.
+	GetPrices(ctx context.Context, tickers []string) (map[string]float64, error)
+	// ListenPrices streams price changes made by any process through the repository.
+	ListenPrices(ctx context.Context) (<-chan *Token, error)
+	// ListenAdded streams tickers of tokens added by any process through the repository.
+	ListenAdded(ctx context.Context) (<-chan string, error)
 }
@@ -2,16 +2,27 @@ package token
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"log"
+	"time"
 )
 
 var (
 	tokensTable = "tokens"
 )
 
+// Notification channels populated by the triggers on the tokens table.
+const (
+	pricesChannel = "token_prices"
+	addedChannel  = "tokens_added"
+
+	listenRetryDelay = time.Second
+)
+
 type postgresRepo struct {
 	db *pgxpool.Pool
 }
@@ -47,6 +58,35 @@ func (r *postgresRepo) Add(ctx context.Context, ticker string) (bool, error) {
 	return true, nil
 }
 
+var getPricesQuery = fmt.Sprintf(`
+SELECT ticker, price
+FROM %s
+WHERE ticker = ANY ($1)
+`, tokensTable)
+
+func (r *postgresRepo) GetPrices(ctx context.Context, tickers []string) (map[string]float64, error) {
+	rows, err := r.db.Query(ctx, getPricesQuery, tickers)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", ErrInternalError)
+	}
+	defer rows.Close()
+
+	prices := make(map[string]float64, len(tickers))
+	for rows.Next() {
+		var ticker string
+		var price float64
+		if err := rows.Scan(&ticker, &price); err != nil {
+			return nil, fmt.Errorf("scan error: %w", ErrInternalError)
+		}
+		prices[ticker] = price
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows error: %w", ErrInternalError)
+	}
+
+	return prices, nil
+}
+
 var updateQuery = fmt.Sprintf(`
 UPDATE %s
 SET price = $2
@@ -97,3 +137,106 @@ func (r *postgresRepo) ListTickers(ctx context.Context) ([]string, error) {
 
 	return tickers, nil
 }
+
+// ListenPrices returns a channel receiving every price change of the tokens table.
+// The channel is closed when ctx is done.
+func (r *postgresRepo) ListenPrices(ctx context.Context) (<-chan *Token, error) {
+	in, err := r.listen(ctx, pricesChannel)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan *Token, 1)
+	go func() {
+		defer close(out)
+		for payload := range in {
+			var tkn Token
+			err := json.Unmarshal([]byte(payload), &tkn)
+			if err != nil {
+				log.Printf("unmarshal token notification error: %v", err)
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case out <- &tkn:
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// ListenAdded returns a channel receiving tickers of newly inserted tokens.
+// The channel is closed when ctx is done.
+func (r *postgresRepo) ListenAdded(ctx context.Context) (<-chan string, error) {
+	return r.listen(ctx, addedChannel)
+}
+
+// listen holds a dedicated connection listening on the channel and
+// re-establishes it if the connection is lost.
+func (r *postgresRepo) listen(ctx context.Context, channel string) (<-chan string, error) {
+	conn, err := r.acquireListener(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan string, 1)
+	go func() {
+		defer close(out)
+		for {
+			n, err := conn.Conn().WaitForNotification(ctx)
+			if err != nil {
+				releaseListener(conn)
+				if ctx.Err() != nil {
+					return
+				}
+				log.Printf("wait for %s notification error: %v", channel, err)
+
+				for err != nil {
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(listenRetryDelay):
+					}
+					conn, err = r.acquireListener(ctx, channel)
+					if err != nil {
+						log.Printf("listen %s error: %v", channel, err)
+					}
+				}
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				releaseListener(conn)
+				return
+			case out <- n.Payload:
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (r *postgresRepo) acquireListener(ctx context.Context, channel string) (*pgxpool.Conn, error) {
+	conn, err := r.db.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acquire connection error: %w", ErrInternalError)
+	}
+
+	_, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize())
+	if err != nil {
+		conn.Release()
+		return nil, fmt.Errorf("exec listen error: %w", ErrInternalError)
+	}
+
+	return conn, nil
+}
+
+// releaseListener stops listening before returning the connection to the pool.
+func releaseListener(conn *pgxpool.Conn) {
+	_, _ = conn.Exec(context.Background(), "UNLISTEN *")
+	conn.Release()
+}
@@ -6,10 +6,19 @@ import (
 	"sync"
 )
 
+// MaxPriceTickers limits the tokens of a Prices call.
+const MaxPriceTickers = 20
+
 type Service interface {
 	Add(ctx context.Context, ticker string) (bool, error)
+	// Prices returns the latest prices of the tokens in order. Unknown tokens are
+	// added, so the price feed tracks them, and have price 0 until it reports one.
+	Prices(ctx context.Context, tickers []string) ([]*Token, error)
 	Subscribe(ctx context.Context) <-chan *Token
+	// Start runs the price feed: it streams prices from the exchange into the repository.
 	Start(ctx context.Context) error
+	// Watch delivers price changes stored in the repository to subscribers.
+	Watch(ctx context.Context) error
 }
 
 type service struct {
@@ -20,6 +29,8 @@ type service struct {
 	subscriptions []chan *Token
 }
 
+// NewService creates token service.
+// Exchange may be nil when the service is only used to Watch prices.
 func NewService(repo Repository, exch Exchange) *service {
 	return &service{
 		repo:     repo,
@@ -28,18 +39,45 @@ func NewService(repo Repository, exch Exchange) *service {
 	}
 }
 
+// Add stores the token. The price feed subscribes to new tokens
+// through repository notifications, whichever process it runs in.
 func (s *service) Add(ctx context.Context, ticker string) (bool, error) {
-	err := s.exchange.Subscribe(ctx, []string{ticker})
+	ok, err := s.repo.Add(ctx, ticker)
 	if err != nil {
 		return false, err
 	}
 
-	ok, err := s.repo.Add(ctx, ticker)
+	return ok, nil
+}
+
+func (s *service) Prices(ctx context.Context, tickers []string) ([]*Token, error) {
+	if len(tickers) == 0 || len(tickers) > MaxPriceTickers {
+		return nil, ErrInvalidArgument
+	}
+	for _, ticker := range tickers {
+		if ticker == "" {
+			return nil, ErrInvalidArgument
+		}
+	}
+
+	prices, err := s.repo.GetPrices(ctx, tickers)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	return ok, nil
+	res := make([]*Token, 0, len(tickers))
+	for _, ticker := range tickers {
+		price, ok := prices[ticker]
+		if !ok {
+			_, err := s.repo.Add(ctx, ticker)
+			if err != nil {
+				return nil, err
+			}
+		}
+		res = append(res, &Token{Ticker: ticker, Price: price})
+	}
+
+	return res, nil
 }
 
 func (s *service) Subscribe(ctx context.Context) <-chan *Token {
@@ -66,7 +104,16 @@ func (s *service) Subscribe(ctx context.Context) <-chan *Token {
 }
 
 func (s *service) Start(ctx context.Context) error {
-	s.exchange.Start(ctx, s.updates)
+	// Listen before listing tickers so that tokens added in between are not missed.
+	added, err := s.repo.ListenAdded(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = s.exchange.Start(ctx, s.updates)
+	if err != nil {
+		return err
+	}
 
 	tickers, err := s.repo.ListTickers(ctx)
 	if err != nil {
@@ -81,27 +128,46 @@ func (s *service) Start(ctx context.Context) error {
 	go func() {
 		for {
 			select {
+			case <-ctx.Done():
+				return
+			case ticker, ok := <-added:
+				if !ok {
+					return
+				}
+				err := s.exchange.Subscribe(ctx, []string{ticker})
+				if err != nil {
+					log.Printf("err: %v", err)
+				}
 			case update := <-s.updates:
-				//log.Printf("update: %v", update)
-
 				for ticker, price := range update {
 					err := s.repo.Update(ctx, ticker, price)
 					if err != nil {
 						log.Printf("err: %v", err)
 					}
 				}
+			}
+		}
+	}()
 
-				s.mu.RLock()
-				for _, sub := range s.subscriptions {
-					for ticker, price := range update {
-						sub <- &Token{
-							Ticker: ticker,
-							Price:  price,
-						}
-					}
+	return nil
+}
+
+func (s *service) Watch(ctx context.Context) error {
+	prices, err := s.repo.ListenPrices(ctx)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for tkn := range prices {
+			s.mu.RLock()
+			for _, sub := range s.subscriptions {
+				sub <- &Token{
+					Ticker: tkn.Ticker,
+					Price:  tkn.Price,
 				}
-				s.mu.RUnlock()
 			}
+			s.mu.RUnlock()
 		}
 	}()
 
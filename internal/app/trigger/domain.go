@@ -4,3 +4,22 @@ type Update struct {
 	Ticker string  `json:"ticker"`
 	Delta  float64 `json:"delta"`
 }
+
+// Condition is when a trigger fires.
+type Condition string
+
+const (
+	// ConditionAny fires on every price change.
+	ConditionAny   Condition = "any"
+	ConditionAbove Condition = "above"
+	ConditionBelow Condition = "below"
+)
+
+type Trigger struct {
+	ID        uint64    `json:"id"`
+	UserID    uint64    `json:"user_id"`
+	Ticker    string    `json:"ticker"`
+	Condition Condition `json:"condition"`
+	// Price is the threshold of ConditionAbove and ConditionBelow.
+	Price float64 `json:"price"`
+}
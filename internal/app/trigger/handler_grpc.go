@@ -9,6 +9,8 @@ import (
 	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
+// GRPCHandler serves the v1 API. The authorization interceptor checks
+// user_id of requests matches the token, services take the user from the context.
 type GRPCHandler struct {
 	svc Service
 	pb.UnimplementedTriggersServer
@@ -21,7 +23,7 @@ func NewGRPCHandler(svc Service) *GRPCHandler {
 }
 
 func (h *GRPCHandler) Add(ctx context.Context, req *pb.Req) (*emptypb.Empty, error) {
-	err := h.svc.Add(ctx, req.GetUserId(), req.GetTicker())
+	err := h.svc.Add(ctx, req.GetTicker(), ConditionAny, 0)
 	if err != nil {
 		return nil, status.New(codes.Internal, err.Error()).Err()
 	}
@@ -30,7 +32,7 @@ func (h *GRPCHandler) Add(ctx context.Context, req *pb.Req) (*emptypb.Empty, err
 }
 
 func (h *GRPCHandler) Remove(ctx context.Context, req *pb.Req) (*emptypb.Empty, error) {
-	err := h.svc.Remove(ctx, req.GetUserId(), req.GetTicker())
+	err := h.svc.Remove(ctx, req.GetTicker())
 	if err != nil {
 		return nil, status.New(codes.Internal, err.Error()).Err()
 	}
@@ -38,8 +40,11 @@ func (h *GRPCHandler) Remove(ctx context.Context, req *pb.Req) (*emptypb.Empty,
 	return &emptypb.Empty{}, status.New(codes.OK, "OK").Err()
 }
 
-func (h *GRPCHandler) Subscribe(value *wrapperspb.UInt64Value, server pb.Triggers_SubscribeServer) error {
-	ch := h.svc.Subcribe(server.Context(), value.GetValue())
+func (h *GRPCHandler) Subscribe(_ *wrapperspb.UInt64Value, server pb.Triggers_SubscribeServer) error {
+	ch, err := h.svc.Subcribe(server.Context())
+	if err != nil {
+		return ErrToGRPCErr(err)
+	}
 
 	for tkn := range ch {
 		err := server.Send(&pb.Token{
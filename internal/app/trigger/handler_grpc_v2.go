@@ -0,0 +1,104 @@
+package trigger
+
+import (
+	"context"
+	pb "cryptowatch/pkg/api/cryptowatchv2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// GRPCHandlerV2 serves the v2 API, where the user comes from the access token only.
+type GRPCHandlerV2 struct {
+	svc Service
+
+	pb.UnimplementedTriggersServer
+}
+
+func NewGRPCHandlerV2(svc Service) *GRPCHandlerV2 {
+	return &GRPCHandlerV2{
+		svc: svc,
+	}
+}
+
+func (h *GRPCHandlerV2) Add(ctx context.Context, req *pb.Req) (*emptypb.Empty, error) {
+	condition, ok := conditionFromPBV2[req.GetCondition()]
+	if !ok {
+		return nil, status.New(codes.InvalidArgument, "unknown condition").Err()
+	}
+
+	err := h.svc.Add(ctx, req.GetTicker(), condition, req.GetPrice())
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return &emptypb.Empty{}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) Remove(ctx context.Context, req *pb.Req) (*emptypb.Empty, error) {
+	err := h.svc.Remove(ctx, req.GetTicker())
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return &emptypb.Empty{}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) List(ctx context.Context, _ *emptypb.Empty) (*pb.ListRes, error) {
+	triggers, err := h.svc.List(ctx)
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	res := &pb.ListRes{Triggers: make([]*pb.Trigger, 0, len(triggers))}
+	for _, t := range triggers {
+		res.Triggers = append(res.Triggers, &pb.Trigger{
+			Id:        t.ID,
+			Ticker:    t.Ticker,
+			Condition: conditionToPBV2[t.Condition],
+			Price:     t.Price,
+		})
+	}
+
+	return res, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) Delete(ctx context.Context, req *pb.DeleteReq) (*emptypb.Empty, error) {
+	err := h.svc.Delete(ctx, req.GetId())
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return &emptypb.Empty{}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) Subscribe(_ *emptypb.Empty, server pb.Triggers_SubscribeServer) error {
+	ch, err := h.svc.Subcribe(server.Context())
+	if err != nil {
+		return ErrToGRPCErr(err)
+	}
+
+	for tkn := range ch {
+		err := server.Send(&pb.Token{
+			Ticker: tkn.Ticker,
+			Price:  tkn.Price,
+		})
+		if err != nil {
+			return status.New(codes.Internal, err.Error()).Err()
+		}
+	}
+
+	return status.New(codes.OK, "OK").Err()
+}
+
+var conditionFromPBV2 = map[pb.Condition]Condition{
+	pb.Condition_CONDITION_UNSPECIFIED: ConditionAny,
+	pb.Condition_CONDITION_ABOVE:       ConditionAbove,
+	pb.Condition_CONDITION_BELOW:       ConditionBelow,
+}
+
+var conditionToPBV2 = map[Condition]pb.Condition{
+	ConditionAny:   pb.Condition_CONDITION_UNSPECIFIED,
+	ConditionAbove: pb.Condition_CONDITION_ABOVE,
+	ConditionBelow: pb.Condition_CONDITION_BELOW,
+}
@@ -3,7 +3,12 @@ package trigger
 import "context"
 
 type Repository interface {
-	Add(ctx context.Context, userID uint64, ticker string) error
+	// Add stores the trigger, adding one the user has already does nothing.
+	Add(ctx context.Context, t *Trigger) error
+	// Remove removes all triggers of the user for the ticker.
 	Remove(ctx context.Context, userID uint64, ticker string) error
-	Check(ctx context.Context, userID uint64, ticker string) (bool, error)
+	Delete(ctx context.Context, userID uint64, id uint64) error
+	List(ctx context.Context, userID uint64) ([]*Trigger, error)
+	// Check reports whether a trigger of the user for the ticker fires at the price.
+	Check(ctx context.Context, userID uint64, ticker string, price float64) (bool, error)
 }
@@ -2,9 +2,7 @@ package trigger
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
@@ -22,12 +20,14 @@ func NewPostgresRepo(db *pgxpool.Pool) *postgresRepo {
 
 var addQuery = fmt.Sprintf(`
 INSERT INTO %s
-(user_id, token_ticker)
-VALUES ($1, $2)
+(user_id, token_ticker, condition, price)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT ON CONSTRAINT triggers_user_id_token_ticker_condition_price_key
+DO NOTHING
 `, triggersTable)
 
-func (r *postgresRepo) Add(ctx context.Context, userID uint64, ticker string) error {
-	_, err := r.db.Exec(ctx, addQuery, userID, ticker)
+func (r *postgresRepo) Add(ctx context.Context, t *Trigger) error {
+	_, err := r.db.Exec(ctx, addQuery, t.UserID, t.Ticker, string(t.Condition), t.Price)
 	if err != nil {
 		return ErrInternalError
 	}
@@ -36,7 +36,7 @@ func (r *postgresRepo) Add(ctx context.Context, userID uint64, ticker string) er
 }
 
 var removeQuery = fmt.Sprintf(`
-REMOVE FROM  %s
+DELETE FROM %s
 WHERE user_id = $1 AND token_ticker = $2
 `, triggersTable)
 
@@ -49,24 +49,71 @@ func (r *postgresRepo) Remove(ctx context.Context, userID uint64, ticker string)
 	return nil
 }
 
-var checkQuery = fmt.Sprintf(`
-SELECT * FROM %s
-WHERE user_id = $1 AND token_ticker = $2
+var deleteQuery = fmt.Sprintf(`
+DELETE FROM %s
+WHERE user_id = $1 AND id = $2
 `, triggersTable)
 
-func (r *postgresRepo) Check(ctx context.Context, userID uint64, ticker string) (bool, error) {
-	rows, err := r.db.Query(ctx, checkQuery, userID, ticker)
+func (r *postgresRepo) Delete(ctx context.Context, userID uint64, id uint64) error {
+	tag, err := r.db.Exec(ctx, deleteQuery, userID, id)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return false, nil
-		}
-		return false, ErrInternalError
+		return ErrInternalError
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
 	}
 
+	return nil
+}
+
+var listQuery = fmt.Sprintf(`
+SELECT id, user_id, token_ticker, condition, price
+FROM %s
+WHERE user_id = $1
+ORDER BY id
+`, triggersTable)
+
+func (r *postgresRepo) List(ctx context.Context, userID uint64) ([]*Trigger, error) {
+	rows, err := r.db.Query(ctx, listQuery, userID)
+	if err != nil {
+		return nil, ErrInternalError
+	}
 	defer rows.Close()
+
+	triggers := make([]*Trigger, 0)
+	for rows.Next() {
+		var t Trigger
+		var condition string
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Ticker, &condition, &t.Price); err != nil {
+			return nil, ErrInternalError
+		}
+		t.Condition = Condition(condition)
+		triggers = append(triggers, &t)
+	}
 	if rows.Err() != nil {
+		return nil, ErrInternalError
+	}
+
+	return triggers, nil
+}
+
+var checkQuery = fmt.Sprintf(`
+SELECT EXISTS (
+    SELECT 1 FROM %s
+    WHERE user_id = $1 AND token_ticker = $2 AND (
+        condition = 'any' OR
+        (condition = 'above' AND price < $3) OR
+        (condition = 'below' AND price > $3)
+    )
+)
+`, triggersTable)
+
+func (r *postgresRepo) Check(ctx context.Context, userID uint64, ticker string, price float64) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow(ctx, checkQuery, userID, ticker, price).Scan(&ok)
+	if err != nil {
 		return false, ErrInternalError
 	}
 
-	return true, nil
+	return ok, nil
 }
@@ -3,13 +3,22 @@ package trigger
 import (
 	"context"
 	"cryptowatch/internal/app/token"
+	"cryptowatch/pkg/util/authtoken"
 	"log"
+	"math"
 )
 
+// Service manages triggers of the user authenticated in the context.
 type Service interface {
-	Add(ctx context.Context, userID uint64, ticker string) error
-	Remove(ctx context.Context, userID uint64, ticker string) error
-	Subcribe(ctx context.Context, userID uint64) chan *token.Token
+	// Add adds a trigger for the ticker firing on the condition, price is ignored for ConditionAny.
+	Add(ctx context.Context, ticker string, condition Condition, price float64) error
+	// Remove removes all triggers for the ticker.
+	Remove(ctx context.Context, ticker string) error
+	Delete(ctx context.Context, id uint64) error
+	List(ctx context.Context) ([]*Trigger, error)
+	// Subcribe sends prices of tokens while a trigger of the user fires
+	// until ctx is done, then closes the channel.
+	Subcribe(ctx context.Context) (chan *token.Token, error)
 }
 
 type service struct {
@@ -24,15 +33,41 @@ func NewService(repo Repository, tokenSvc token.Service) *service {
 	}
 }
 
-func (s *service) Add(ctx context.Context, userID uint64, ticker string) error {
+func (s *service) Add(ctx context.Context, ticker string, condition Condition, price float64) error {
+	userID, ok := authtoken.UserIDFromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+
+	switch condition {
+	case ConditionAny:
+		price = 0
+	case ConditionAbove, ConditionBelow:
+		if !(price > 0) || math.IsInf(price, 1) {
+			return ErrInvalidArgument
+		}
+	default:
+		return ErrInvalidArgument
+	}
+
 	_, err := s.tokenSvc.Add(ctx, ticker)
 	if err != nil {
 		return ErrInternalError
 	}
-	return s.repo.Add(ctx, userID, ticker)
+	return s.repo.Add(ctx, &Trigger{
+		UserID:    userID,
+		Ticker:    ticker,
+		Condition: condition,
+		Price:     price,
+	})
 }
 
-func (s *service) Remove(ctx context.Context, userID uint64, ticker string) error {
+func (s *service) Remove(ctx context.Context, ticker string) error {
+	userID, ok := authtoken.UserIDFromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+
 	_, err := s.tokenSvc.Add(ctx, ticker)
 	if err != nil {
 		return ErrInternalError
@@ -40,33 +75,64 @@ func (s *service) Remove(ctx context.Context, userID uint64, ticker string) erro
 	return s.repo.Remove(ctx, userID, ticker)
 }
 
-func (s *service) Subcribe(ctx context.Context, userID uint64) chan *token.Token {
+func (s *service) Delete(ctx context.Context, id uint64) error {
+	userID, ok := authtoken.UserIDFromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+
+	return s.repo.Delete(ctx, userID, id)
+}
+
+func (s *service) List(ctx context.Context) ([]*Trigger, error) {
+	userID, ok := authtoken.UserIDFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	return s.repo.List(ctx, userID)
+}
+
+func (s *service) Subcribe(ctx context.Context) (chan *token.Token, error) {
+	userID, ok := authtoken.UserIDFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
 	out := make(chan *token.Token, 1)
 	in := s.tokenSvc.Subscribe(ctx)
 
 	go func() {
+		defer close(out)
 		for {
 			select {
 			case <-ctx.Done():
 				return
-			case tkn := <-in:
-				ok, err := s.repo.Check(ctx, userID, tkn.Ticker)
+			case tkn, more := <-in:
+				if !more {
+					return
+				}
+				ok, err := s.repo.Check(ctx, userID, tkn.Ticker, tkn.Price)
 				if err != nil {
 					log.Printf("err: %v", err)
 					continue
 				}
 
 				if ok {
-					out <- &token.Token{
+					select {
+					case <-ctx.Done():
+						return
+					case out <- &token.Token{
 						Ticker: tkn.Ticker,
 						Price:  tkn.Price,
+					}:
 					}
 				}
 			}
 		}
 	}()
 
-	return out
+	return out, nil
 }
 
 func (s *service) SubcribeAll(ctx context.Context, userID uint64) chan *token.Token {
@@ -74,21 +140,29 @@ func (s *service) SubcribeAll(ctx context.Context, userID uint64) chan *token.To
 	in := s.tokenSvc.Subscribe(ctx)
 
 	go func() {
+		defer close(out)
 		for {
 			select {
 			case <-ctx.Done():
 				return
-			case tkn := <-in:
-				ok, err := s.repo.Check(ctx, userID, tkn.Ticker)
+			case tkn, more := <-in:
+				if !more {
+					return
+				}
+				ok, err := s.repo.Check(ctx, userID, tkn.Ticker, tkn.Price)
 				if err != nil {
 					log.Printf("err: %v", err)
 					continue
 				}
 
 				if ok {
-					out <- &token.Token{
+					select {
+					case <-ctx.Done():
+						return
+					case out <- &token.Token{
 						Ticker: tkn.Ticker,
 						Price:  tkn.Price,
+					}:
 					}
 				}
 			}
@@ -0,0 +1,167 @@
+package user
+
+import (
+	"context"
+	"cryptowatch/pkg/util/authtoken"
+	"strconv"
+)
+
+const (
+	DefaultListUsersPageSize = 50
+	MaxListUsersPageSize     = 100
+)
+
+type SvcListUsersReq struct {
+	// Query is text the username or a name contains.
+	Query    string `json:"query"`
+	Role     Role   `json:"role"`
+	PageSize int    `json:"page_size"`
+	// PageToken is the NextPageToken of the previous page.
+	PageToken string `json:"page_token"`
+}
+
+type SvcListUsersRes struct {
+	Users []*User `json:"users"`
+	// NextPageToken is empty on the last page.
+	NextPageToken string `json:"next_page_token"`
+}
+
+func (s *service) GetByID(ctx context.Context, id uint64) (*User, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *service) ListUsers(ctx context.Context, req SvcListUsersReq) (*SvcListUsersRes, error) {
+	var verr ValidationError
+	if req.Role != "" && !req.Role.Valid() {
+		verr.add("role", "unknown role")
+	}
+	if req.PageSize < 0 || req.PageSize > MaxListUsersPageSize {
+		verr.add("page_size", "must be at most 100")
+	}
+	var afterID uint64
+	if req.PageToken != "" {
+		id, err := strconv.ParseUint(req.PageToken, 10, 64)
+		if err != nil {
+			verr.add("page_token", "invalid page token")
+		}
+		afterID = id
+	}
+	if err := verr.err(); err != nil {
+		return nil, err
+	}
+
+	pageSize := req.PageSize
+	if pageSize == 0 {
+		pageSize = DefaultListUsersPageSize
+	}
+
+	// One more user tells whether there is a next page.
+	users, err := s.repo.ListUsers(ctx, RepoListUsersReq{
+		Query:   req.Query,
+		Role:    req.Role,
+		AfterID: afterID,
+		Limit:   pageSize + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := &SvcListUsersRes{Users: users}
+	if len(users) > pageSize {
+		res.Users = users[:pageSize]
+		res.NextPageToken = strconv.FormatUint(res.Users[pageSize-1].ID, 10)
+	}
+
+	return res, nil
+}
+
+func (s *service) SetRole(ctx context.Context, userID uint64, role Role) error {
+	var verr ValidationError
+	if !role.Valid() {
+		verr.add("role", "unknown role")
+	}
+	if err := verr.err(); err != nil {
+		return err
+	}
+
+	err := s.checkNotSelf(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	revoked, err := s.repo.SetRole(ctx, userID, role)
+	if err != nil {
+		return err
+	}
+
+	s.denySessions(revoked)
+
+	return nil
+}
+
+func (s *service) DisableUser(ctx context.Context, userID uint64) error {
+	err := s.checkNotSelf(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	revoked, err := s.repo.Disable(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	s.denySessions(revoked)
+
+	return nil
+}
+
+func (s *service) EnableUser(ctx context.Context, userID uint64) error {
+	return s.repo.Enable(ctx, userID)
+}
+
+func (s *service) LogoutUser(ctx context.Context, userID uint64) error {
+	u, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	err = checkOutranks(ctx, u)
+	if err != nil {
+		return err
+	}
+
+	revoked, err := s.repo.RevokeUserSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	s.denySessions(revoked)
+
+	return nil
+}
+
+// checkNotSelf keeps administrators from locking themselves out.
+func (s *service) checkNotSelf(ctx context.Context, userID uint64) error {
+	callerID, ok := authtoken.UserIDFromContext(ctx)
+	if ok && callerID == userID {
+		return ErrFailedPrecondition
+	}
+
+	return nil
+}
+
+// checkOutranks keeps support staff from acting on administrators and each other: callers
+// act on users of a lower role only, administrators on everyone. Commands run without
+// a caller, like reset-totp, aren't limited.
+func checkOutranks(ctx context.Context, target *User) error {
+	payload, ok := authtoken.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	caller := Role(payload.Role)
+	if caller == RoleAdmin || roleRanks[caller] > roleRanks[target.Role] {
+		return nil
+	}
+
+	return ErrTargetRole
+}
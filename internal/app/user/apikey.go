@@ -0,0 +1,186 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"cryptowatch/pkg/util/authtoken"
+	"encoding/base64"
+	"errors"
+	"github.com/google/uuid"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	ScopePortfoliosRead  = "portfolios:read"
+	ScopePortfoliosWrite = "portfolios:write"
+	ScopeAlerts          = "alerts"
+	// ScopeSession lets a scoped token end its own session.
+	ScopeSession = "session"
+
+	// apiKeyPrefix marks API keys, so leaked ones are easy to find in code and logs.
+	apiKeyPrefix       = "cwk_"
+	apiKeyLen          = 32
+	apiKeyShownLen     = len(apiKeyPrefix) + 8
+	apiKeyNameMaxChars = 64
+)
+
+// APIKeyScopes are the scopes keys may be limited to.
+var APIKeyScopes = []string{ScopePortfoliosRead, ScopePortfoliosWrite, ScopeAlerts}
+
+// TelegramScopes limit access tokens of sessions the Telegram bot logs in with a one-time password.
+var TelegramScopes = []string{ScopePortfoliosRead, ScopePortfoliosWrite, ScopeAlerts, ScopeSession}
+
+// apiKeyNoExpiry is the expiry of payloads of keys that never expire.
+var apiKeyNoExpiry = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+type SvcCreateAPIKeyReq struct {
+	Name       string     `json:"name" validate:"required"`
+	Scopes     []string   `json:"scopes"`
+	ExpireTime *time.Time `json:"expire_time"`
+}
+
+type SvcCreateAPIKeyRes struct {
+	APIKey *APIKey `json:"api_key"`
+	// Key is shown only once, just its hash is stored.
+	Key string `json:"key"`
+}
+
+func (s *service) CreateAPIKey(ctx context.Context, req SvcCreateAPIKeyReq) (*SvcCreateAPIKeyRes, error) {
+	userID, ok := authtoken.UserIDFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	var verr ValidationError
+	if name := strings.TrimSpace(req.Name); name == "" || utf8.RuneCountInString(name) > apiKeyNameMaxChars {
+		verr.add("name", "must be 1 to 64 characters")
+	}
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		if !contains(APIKeyScopes, scope) {
+			verr.add("scopes", "unknown scope "+scope)
+			continue
+		}
+		if !contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if req.ExpireTime != nil && !req.ExpireTime.After(time.Now()) {
+		verr.add("expire_time", "must be in the future")
+	}
+	if err := verr.err(); err != nil {
+		return nil, err
+	}
+
+	key, err := generateAPIKey()
+	if err != nil {
+		return nil, ErrInternalError
+	}
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, ErrInternalError
+	}
+
+	k, err := s.repo.CreateAPIKey(ctx, RepoCreateAPIKeyReq{
+		ID:         id,
+		UserID:     userID,
+		Name:       strings.TrimSpace(req.Name),
+		Prefix:     key[:apiKeyShownLen],
+		KeyHash:    hashRefreshToken(key),
+		Scopes:     scopes,
+		ExpireTime: req.ExpireTime,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &SvcCreateAPIKeyRes{APIKey: k, Key: key}, nil
+}
+
+func (s *service) ListAPIKeys(ctx context.Context) ([]*APIKey, error) {
+	userID, ok := authtoken.UserIDFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	return s.repo.ListAPIKeys(ctx, userID)
+}
+
+func (s *service) RevokeAPIKey(ctx context.Context, id uuid.UUID) error {
+	userID, ok := authtoken.UserIDFromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+
+	err := s.repo.RevokeAPIKey(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	// Streams opened with the key end at their next check, through the denylist
+	// in this process and by looking the key up in the others.
+	if s.denylist != nil {
+		s.denylist.Add(id)
+	}
+
+	return nil
+}
+
+func (s *service) AuthenticateAPIKey(ctx context.Context, key string) (*authtoken.Payload, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, ErrUnauthenticated
+	}
+
+	k, err := s.repo.GetAPIKeyByHash(ctx, hashRefreshToken(key))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrUnauthenticated
+		}
+		return nil, err
+	}
+	if !k.Active(time.Now()) {
+		return nil, ErrUnauthenticated
+	}
+
+	// The last use time is informational, a failed update shouldn't fail the call.
+	err = s.repo.TouchAPIKey(ctx, k.ID)
+	if err != nil {
+		log.Printf("failed to update last use of API key %s: %v", k.ID, err)
+	}
+
+	payload := &authtoken.Payload{
+		ID:        k.ID,
+		UserID:    k.UserID,
+		IssuedAt:  k.CreateTime,
+		ExpiresAt: apiKeyNoExpiry,
+		Scopes:    k.Scopes,
+	}
+	if k.ExpireTime != nil {
+		payload.ExpiresAt = *k.ExpireTime
+	}
+
+	return payload, nil
+}
+
+func generateAPIKey() (string, error) {
+	b := make([]byte, apiKeyLen)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,319 @@
+package user
+
+import (
+	"context"
+	pb "cryptowatch/pkg/api/cryptowatchv1"
+	"cryptowatch/pkg/util/authtoken"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"log"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultOwnerField = "user_id"
+
+	// apiKeyMetadataKey is the metadata carrying API keys, the gateway maps the X-API-Key header to it.
+	apiKeyMetadataKey = "x-api-key"
+)
+
+// methodPolicy is the authorization policy of a single method.
+type methodPolicy struct {
+	policy pb.Policy
+	// owner is the request field holding the owner user id for pb.Policy_POLICY_OWNER.
+	owner protoreflect.FieldDescriptor
+	// scopes let API keys and scoped tokens call the method, they can't if there are none.
+	scopes []string
+}
+
+// APIKeyAuthenticator verifies API keys, the user Service is one.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*authtoken.Payload, error)
+}
+
+// Authorizer enforces authorization policies declared on RPCs
+// with the cryptowatch.authorization method option.
+// Methods without a known policy are denied.
+type Authorizer struct {
+	authtokenMaker authtoken.Maker
+	denylist       *Denylist
+	apiKeys        APIKeyAuthenticator
+	expect         []authtoken.Expectation
+
+	methods map[string]*methodPolicy
+}
+
+// AuthorizerOption configures the authorizer.
+type AuthorizerOption func(a *Authorizer)
+
+// WithAPIKeys accepts API keys verified by apiKeys in place of access tokens.
+func WithAPIKeys(apiKeys APIKeyAuthenticator) AuthorizerOption {
+	return func(a *Authorizer) {
+		a.apiKeys = apiKeys
+	}
+}
+
+// WithTokenExpectations accepts only access tokens with the expected claims,
+// e.g. those issued for this service.
+func WithTokenExpectations(expect ...authtoken.Expectation) AuthorizerOption {
+	return func(a *Authorizer) {
+		a.expect = expect
+	}
+}
+
+// NewAuthorizer creates an authorizer verifying access tokens with authtokenMaker.
+// Tokens of sessions in denylist are rejected, denylist may be nil.
+// Policies must be loaded with Load before serving.
+func NewAuthorizer(authtokenMaker authtoken.Maker, denylist *Denylist, opts ...AuthorizerOption) *Authorizer {
+	a := &Authorizer{
+		authtokenMaker: authtokenMaker,
+		denylist:       denylist,
+		methods:        make(map[string]*methodPolicy),
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+// Load reads policies of all methods of the services from their descriptors,
+// typically grpc.Server.GetServiceInfo after the services are registered.
+// It fails if any method has no policy, so a new method can't be exposed by accident.
+func (a *Authorizer) Load(services map[string]grpc.ServiceInfo) error {
+	methods := make(map[string]*methodPolicy)
+	var missing []string
+
+	for name := range services {
+		desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+		if err != nil {
+			return fmt.Errorf("find service %s: %w", name, err)
+		}
+		sd, ok := desc.(protoreflect.ServiceDescriptor)
+		if !ok {
+			return fmt.Errorf("%s is not a service", name)
+		}
+
+		for i := 0; i < sd.Methods().Len(); i++ {
+			md := sd.Methods().Get(i)
+			fullMethod := fmt.Sprintf("/%s/%s", sd.FullName(), md.Name())
+
+			mp, err := newMethodPolicy(md)
+			if err != nil {
+				return fmt.Errorf("%s: %w", fullMethod, err)
+			}
+			if mp == nil {
+				missing = append(missing, fullMethod)
+				continue
+			}
+			methods[fullMethod] = mp
+		}
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("%w: %s", ErrMissingPolicy, strings.Join(missing, ", "))
+	}
+
+	a.methods = methods
+
+	return nil
+}
+
+// newMethodPolicy returns the declared policy of the method or nil if there is none.
+func newMethodPolicy(md protoreflect.MethodDescriptor) (*methodPolicy, error) {
+	opts, ok := md.Options().(*descriptorpb.MethodOptions)
+	if !ok || opts == nil {
+		return nil, nil
+	}
+
+	authz, ok := proto.GetExtension(opts, pb.E_Authorization).(*pb.Authorization)
+	if !ok || authz == nil || authz.GetPolicy() == pb.Policy_POLICY_UNSPECIFIED {
+		return nil, nil
+	}
+
+	mp := &methodPolicy{
+		policy: authz.GetPolicy(),
+		scopes: authz.GetScopes(),
+	}
+
+	if mp.policy == pb.Policy_POLICY_OWNER {
+		name := authz.GetOwnerField()
+		if name == "" {
+			name = defaultOwnerField
+		}
+		fd := md.Input().Fields().ByName(protoreflect.Name(name))
+		if fd == nil || fd.Kind() != protoreflect.Uint64Kind || fd.Cardinality() == protoreflect.Repeated {
+			return nil, fmt.Errorf("owner field %q must be a uint64 field of %s", name, md.Input().FullName())
+		}
+		mp.owner = fd
+	}
+
+	return mp, nil
+}
+
+// authorize checks the caller of method may send req and returns ctx
+// with the token payload for methods that require a token.
+func (a *Authorizer) authorize(ctx context.Context, method string, req interface{}) (context.Context, error) {
+	mp, payload, _, err := a.authorizeMethod(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+	if payload == nil {
+		return ctx, nil
+	}
+
+	err = mp.checkOwner(payload, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return authtoken.NewContext(ctx, payload), nil
+}
+
+// authorizeMethod checks everything but the request. It returns a nil payload for public methods
+// and the API key the caller authenticated with, if any.
+func (a *Authorizer) authorizeMethod(ctx context.Context, method string) (*methodPolicy, *authtoken.Payload, string, error) {
+	mp, ok := a.methods[method]
+	if !ok {
+		return nil, nil, "", status.New(codes.PermissionDenied, "permission denied").Err()
+	}
+
+	if mp.policy == pb.Policy_POLICY_PUBLIC {
+		return mp, nil, "", nil
+	}
+
+	payload, apiKey, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	err = mp.checkScopes(payload, apiKey != "")
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	var required Role
+	switch mp.policy {
+	case pb.Policy_POLICY_AUTHENTICATED, pb.Policy_POLICY_OWNER:
+		required = RoleUser
+	case pb.Policy_POLICY_SUPPORT:
+		required = RoleSupport
+	case pb.Policy_POLICY_ADMIN:
+		required = RoleAdmin
+	default:
+		return nil, nil, "", status.New(codes.PermissionDenied, "permission denied").Err()
+	}
+	// Tokens issued before roles and API keys carry no role and act as users.
+	role := Role(payload.Role)
+	if role == "" {
+		role = RoleUser
+	}
+	if !role.Includes(required) {
+		return nil, nil, "", status.New(codes.PermissionDenied, "permission denied").Err()
+	}
+
+	return mp, payload, apiKey, nil
+}
+
+// checkOwner checks the request belongs to the token user if the method is owner-only.
+func (mp *methodPolicy) checkOwner(payload *authtoken.Payload, req interface{}) error {
+	if mp.policy != pb.Policy_POLICY_OWNER {
+		return nil
+	}
+
+	msg, ok := req.(proto.Message)
+	if !ok || msg.ProtoReflect().Get(mp.owner).Uint() != payload.UserID {
+		return status.New(codes.PermissionDenied, "permission denied").Err()
+	}
+
+	return nil
+}
+
+// checkScopes checks API keys may call the method and the payload has one of its scopes if it is limited,
+// like API keys and tokens of the Telegram bot.
+func (mp *methodPolicy) checkScopes(payload *authtoken.Payload, apiKey bool) error {
+	if apiKey && len(mp.scopes) == 0 {
+		return status.New(codes.PermissionDenied, "method can't be called with an API key").Err()
+	}
+	if len(payload.Scopes) == 0 {
+		return nil
+	}
+
+	for _, scope := range payload.Scopes {
+		if contains(mp.scopes, scope) {
+			return nil
+		}
+	}
+
+	return status.New(codes.PermissionDenied, "insufficient scope").Err()
+}
+
+// validate checks a token or API key accepted earlier is still valid. API keys are looked up
+// again, the denylist only has the keys revoked through this process.
+func (a *Authorizer) validate(ctx context.Context, payload *authtoken.Payload, apiKey string) error {
+	if err := payload.Valid(); err != nil {
+		return status.New(codes.Unauthenticated, "token expired").Err()
+	}
+	if a.denylist != nil && a.denylist.Contains(payload.ID) {
+		return status.New(codes.Unauthenticated, "session revoked").Err()
+	}
+
+	if apiKey != "" {
+		_, err := a.apiKeys.AuthenticateAPIKey(ctx, apiKey)
+		if errors.Is(err, ErrUnauthenticated) {
+			return status.New(codes.Unauthenticated, "API key revoked").Err()
+		}
+		if err != nil {
+			// The key store may be unavailable for a moment, the key is checked again next time.
+			log.Printf("failed to revalidate API key %s: %v", payload.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// authenticate verifies the access token in the authorization metadata or,
+// if there is none, the API key. It returns the API key if the payload is of one.
+func (a *Authorizer) authenticate(ctx context.Context) (*authtoken.Payload, string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, "", status.New(codes.Unauthenticated, "unauthenticated").Err()
+	}
+
+	tokens, keys := md.Get("authorization"), md.Get(apiKeyMetadataKey)
+
+	var payload *authtoken.Payload
+	var apiKey string
+	var err error
+	switch {
+	case len(tokens) > 0:
+		payload, err = a.authtokenMaker.VerifyToken(tokens[0], a.expect...)
+	case len(keys) > 0 && a.apiKeys != nil:
+		payload, err = a.apiKeys.AuthenticateAPIKey(ctx, keys[0])
+		apiKey = keys[0]
+		if err != nil && !errors.Is(err, ErrUnauthenticated) {
+			return nil, "", ErrToGRPCErr(err)
+		}
+	default:
+		return nil, "", status.New(codes.Unauthenticated, "unauthenticated").Err()
+	}
+	if err != nil {
+		return nil, "", status.New(codes.Unauthenticated, "unauthenticated").Err()
+	}
+
+	if a.denylist != nil && a.denylist.Contains(payload.ID) {
+		return nil, "", status.New(codes.Unauthenticated, "session revoked").Err()
+	}
+
+	return payload, apiKey, nil
+}
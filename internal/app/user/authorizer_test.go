@@ -0,0 +1,614 @@
+package user_test
+
+import (
+	"context"
+	"cryptowatch/internal/app/user"
+	pb "cryptowatch/pkg/api/cryptowatchv1"
+	pbv2 "cryptowatch/pkg/api/cryptowatchv2"
+	"cryptowatch/pkg/util"
+	"cryptowatch/pkg/util/authtoken"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestAuthorizer(t *testing.T, maker authtoken.Maker, denylist *user.Denylist) *user.Authorizer {
+	srv := grpc.NewServer()
+	pb.RegisterUsersServer(srv, &pb.UnimplementedUsersServer{})
+	pb.RegisterPortfoliosServer(srv, &pb.UnimplementedPortfoliosServer{})
+	pb.RegisterTriggersServer(srv, &pb.UnimplementedTriggersServer{})
+	pbv2.RegisterUsersServer(srv, &pbv2.UnimplementedUsersServer{})
+	pbv2.RegisterPortfoliosServer(srv, &pbv2.UnimplementedPortfoliosServer{})
+	pbv2.RegisterTriggersServer(srv, &pbv2.UnimplementedTriggersServer{})
+	pbv2.RegisterAdminServer(srv, &pbv2.UnimplementedAdminServer{})
+
+	authorizer := user.NewAuthorizer(maker, denylist)
+	require.NoError(t, authorizer.Load(srv.GetServiceInfo()))
+
+	return authorizer
+}
+
+func TestAuthorizer_Load(t *testing.T) {
+	srv := grpc.NewServer()
+	pb.RegisterUsersServer(srv, &pb.UnimplementedUsersServer{})
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+
+	err := user.NewAuthorizer(nil, nil).Load(srv.GetServiceInfo())
+	require.ErrorIs(t, err, user.ErrMissingPolicy)
+	assert.Contains(t, err.Error(), "/grpc.health.v1.Health/Check")
+	assert.Contains(t, err.Error(), "/grpc.health.v1.Health/Watch")
+}
+
+func TestAuthUnaryInterceptor(t *testing.T) {
+	maker, err := authtoken.NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	revokedID := uuid.New()
+	denylist := user.NewDenylist(nil, time.Minute)
+	denylist.Add(revokedID)
+
+	interceptor := user.AuthUnaryInterceptor(newTestAuthorizer(t, maker, denylist))
+
+	token, err := maker.CreateToken(1, time.Minute)
+	require.NoError(t, err)
+	revokedToken, err := maker.CreateToken(1, time.Minute, authtoken.WithID(revokedID))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		req    interface{}
+		code   codes.Code
+	}{
+		{
+			name:   "Public without token",
+			method: "/cryptowatch.Users/Login",
+			req:    &pb.LoginReq{},
+			code:   codes.OK,
+		},
+		{
+			name:   "Authenticated without token",
+			method: "/cryptowatch.Users/ListSessions",
+			req:    &emptypb.Empty{},
+			code:   codes.Unauthenticated,
+		},
+		{
+			name:   "Authenticated",
+			method: "/cryptowatch.Users/ListSessions",
+			token:  token,
+			req:    &emptypb.Empty{},
+			code:   codes.OK,
+		},
+		{
+			name:   "Invalid token",
+			method: "/cryptowatch.Users/ListSessions",
+			token:  "invalid",
+			req:    &emptypb.Empty{},
+			code:   codes.Unauthenticated,
+		},
+		{
+			name:   "Revoked session",
+			method: "/cryptowatch.Users/ListSessions",
+			token:  revokedToken,
+			req:    &emptypb.Empty{},
+			code:   codes.Unauthenticated,
+		},
+		{
+			name:   "Owner",
+			method: "/cryptowatch.Portfolios/Info",
+			token:  token,
+			req:    &pb.InfoReq{UserId: 1},
+			code:   codes.OK,
+		},
+		{
+			name:   "Not owner",
+			method: "/cryptowatch.Portfolios/Info",
+			token:  token,
+			req:    &pb.InfoReq{UserId: 2},
+			code:   codes.PermissionDenied,
+		},
+		{
+			name:   "Owner in custom field",
+			method: "/cryptowatch.Users/GetOTP",
+			token:  token,
+			req:    &wrapperspb.UInt64Value{Value: 1},
+			code:   codes.OK,
+		},
+		{
+			name:   "Not owner in custom field",
+			method: "/cryptowatch.Users/GetOTP",
+			token:  token,
+			req:    &wrapperspb.UInt64Value{Value: 2},
+			code:   codes.PermissionDenied,
+		},
+		{
+			name:   "V2 without token",
+			method: "/cryptowatch.v2.Portfolios/Info",
+			req:    &pbv2.InfoReq{},
+			code:   codes.Unauthenticated,
+		},
+		{
+			name:   "V2",
+			method: "/cryptowatch.v2.Portfolios/Info",
+			token:  token,
+			req:    &pbv2.InfoReq{},
+			code:   codes.OK,
+		},
+		{
+			name:   "Unknown method",
+			method: "/cryptowatch.Users/Unknown",
+			token:  token,
+			req:    &emptypb.Empty{},
+			code:   codes.PermissionDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.token != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", tt.token))
+			}
+
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				userID, ok := authtoken.UserIDFromContext(ctx)
+				if tt.token != "" {
+					assert.True(t, ok)
+					assert.Equal(t, uint64(1), userID)
+				}
+				return req, nil
+			}
+
+			_, err := interceptor(ctx, tt.req, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func TestAuthUnaryInterceptor_Roles(t *testing.T) {
+	maker, err := authtoken.NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	interceptor := user.AuthUnaryInterceptor(newTestAuthorizer(t, maker, nil))
+
+	tokens := make(map[user.Role]string)
+	for _, role := range []user.Role{"", user.RoleUser, user.RoleSupport, user.RoleAdmin, "root"} {
+		tokens[role], err = maker.CreateToken(1, time.Minute, authtoken.WithRole(string(role)))
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		role   user.Role
+		req    interface{}
+		code   codes.Code
+	}{
+		{
+			name:   "User on support method",
+			method: "/cryptowatch.v2.Admin/ListUsers",
+			role:   user.RoleUser,
+			req:    &pbv2.ListUsersReq{},
+			code:   codes.PermissionDenied,
+		},
+		{
+			name:   "No role on support method",
+			method: "/cryptowatch.v2.Admin/ListUsers",
+			req:    &pbv2.ListUsersReq{},
+			code:   codes.PermissionDenied,
+		},
+		{
+			name:   "Unknown role on support method",
+			method: "/cryptowatch.v2.Admin/ListUsers",
+			role:   "root",
+			req:    &pbv2.ListUsersReq{},
+			code:   codes.PermissionDenied,
+		},
+		{
+			name:   "Support on support method",
+			method: "/cryptowatch.v2.Admin/ListUsers",
+			role:   user.RoleSupport,
+			req:    &pbv2.ListUsersReq{},
+			code:   codes.OK,
+		},
+		{
+			name:   "Admin on support method",
+			method: "/cryptowatch.v2.Admin/ListUsers",
+			role:   user.RoleAdmin,
+			req:    &pbv2.ListUsersReq{},
+			code:   codes.OK,
+		},
+		{
+			name:   "Support on admin method",
+			method: "/cryptowatch.v2.Admin/DisableUser",
+			role:   user.RoleSupport,
+			req:    &pbv2.AdminUserReq{UserId: 2},
+			code:   codes.PermissionDenied,
+		},
+		{
+			name:   "Admin on admin method",
+			method: "/cryptowatch.v2.Admin/DisableUser",
+			role:   user.RoleAdmin,
+			req:    &pbv2.AdminUserReq{UserId: 2},
+			code:   codes.OK,
+		},
+		{
+			name:   "No role on authenticated method",
+			method: "/cryptowatch.v2.Portfolios/Info",
+			req:    &pbv2.InfoReq{},
+			code:   codes.OK,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", tokens[tt.role]))
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return req, nil
+			}
+
+			_, err := interceptor(ctx, tt.req, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func TestAuthUnaryInterceptor_Claims(t *testing.T) {
+	maker, err := authtoken.NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	srv := grpc.NewServer()
+	pb.RegisterUsersServer(srv, &pb.UnimplementedUsersServer{})
+	pb.RegisterTriggersServer(srv, &pb.UnimplementedTriggersServer{})
+	authorizer := user.NewAuthorizer(
+		maker,
+		nil,
+		user.WithTokenExpectations(authtoken.ExpectIssuer("cryptowatch"), authtoken.ExpectAudience("api")),
+	)
+	require.NoError(t, authorizer.Load(srv.GetServiceInfo()))
+	interceptor := user.AuthUnaryInterceptor(authorizer)
+
+	newToken := func(opts ...authtoken.Option) string {
+		token, err := maker.CreateToken(1, time.Minute, opts...)
+		require.NoError(t, err)
+		return token
+	}
+	token := newToken(authtoken.WithIssuer("cryptowatch"), authtoken.WithAudience("api"))
+	botToken := newToken(authtoken.WithIssuer("cryptowatch"), authtoken.WithAudience("api"), authtoken.WithScopes(user.TelegramScopes...))
+
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		req    interface{}
+		code   codes.Code
+	}{
+		{
+			name:   "OK",
+			method: "/cryptowatch.Users/ListSessions",
+			token:  token,
+			req:    &emptypb.Empty{},
+			code:   codes.OK,
+		},
+		{
+			name:   "Other audience",
+			method: "/cryptowatch.Users/ListSessions",
+			token:  newToken(authtoken.WithIssuer("cryptowatch"), authtoken.WithAudience("other")),
+			req:    &emptypb.Empty{},
+			code:   codes.Unauthenticated,
+		},
+		{
+			name:   "Other issuer",
+			method: "/cryptowatch.Users/ListSessions",
+			token:  newToken(authtoken.WithIssuer("other"), authtoken.WithAudience("api")),
+			req:    &emptypb.Empty{},
+			code:   codes.Unauthenticated,
+		},
+		{
+			name:   "No claims",
+			method: "/cryptowatch.Users/ListSessions",
+			token:  newToken(),
+			req:    &emptypb.Empty{},
+			code:   codes.Unauthenticated,
+		},
+		{
+			name:   "Not yet valid",
+			method: "/cryptowatch.Users/ListSessions",
+			token:  newToken(authtoken.WithIssuer("cryptowatch"), authtoken.WithAudience("api"), authtoken.WithNotBefore(time.Now().Add(time.Minute))),
+			req:    &emptypb.Empty{},
+			code:   codes.Unauthenticated,
+		},
+		{
+			name:   "Scoped token in scope",
+			method: "/cryptowatch.Triggers/Add",
+			token:  botToken,
+			req:    &pb.Req{UserId: 1},
+			code:   codes.OK,
+		},
+		{
+			name:   "Scoped token out of scope",
+			method: "/cryptowatch.Users/ListSessions",
+			token:  botToken,
+			req:    &emptypb.Empty{},
+			code:   codes.PermissionDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", tt.token))
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return req, nil
+			}
+
+			_, err := interceptor(ctx, tt.req, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+// testAPIKeys accepts the keys of the map.
+type testAPIKeys map[string]*authtoken.Payload
+
+func (k testAPIKeys) AuthenticateAPIKey(_ context.Context, key string) (*authtoken.Payload, error) {
+	payload, ok := k[key]
+	if !ok {
+		return nil, user.ErrUnauthenticated
+	}
+
+	return payload, nil
+}
+
+func TestAuthUnaryInterceptor_APIKey(t *testing.T) {
+	maker, err := authtoken.NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	srv := grpc.NewServer()
+	pb.RegisterUsersServer(srv, &pb.UnimplementedUsersServer{})
+	pb.RegisterPortfoliosServer(srv, &pb.UnimplementedPortfoliosServer{})
+	pbv2.RegisterPortfoliosServer(srv, &pbv2.UnimplementedPortfoliosServer{})
+	pbv2.RegisterTriggersServer(srv, &pbv2.UnimplementedTriggersServer{})
+
+	authorizer := user.NewAuthorizer(maker, nil, user.WithAPIKeys(testAPIKeys{
+		"full":   {ID: uuid.New(), UserID: 1},
+		"read":   {ID: uuid.New(), UserID: 1, Scopes: []string{user.ScopePortfoliosRead}},
+		"alerts": {ID: uuid.New(), UserID: 1, Scopes: []string{user.ScopeAlerts}},
+	}))
+	require.NoError(t, authorizer.Load(srv.GetServiceInfo()))
+	interceptor := user.AuthUnaryInterceptor(authorizer)
+
+	tests := []struct {
+		name   string
+		method string
+		key    string
+		req    interface{}
+		code   codes.Code
+	}{
+		{
+			name:   "Key without scopes",
+			method: "/cryptowatch.v2.Portfolios/Buy",
+			key:    "full",
+			req:    &pbv2.BuySellReq{},
+			code:   codes.OK,
+		},
+		{
+			name:   "Scope",
+			method: "/cryptowatch.v2.Portfolios/Info",
+			key:    "read",
+			req:    &pbv2.InfoReq{},
+			code:   codes.OK,
+		},
+		{
+			name:   "Owner",
+			method: "/cryptowatch.Portfolios/Info",
+			key:    "read",
+			req:    &pb.InfoReq{UserId: 1},
+			code:   codes.OK,
+		},
+		{
+			name:   "Missing scope",
+			method: "/cryptowatch.v2.Portfolios/Buy",
+			key:    "read",
+			req:    &pbv2.BuySellReq{},
+			code:   codes.PermissionDenied,
+		},
+		{
+			name:   "Other scope",
+			method: "/cryptowatch.v2.Portfolios/Info",
+			key:    "alerts",
+			req:    &pbv2.InfoReq{},
+			code:   codes.PermissionDenied,
+		},
+		{
+			name:   "Method closed to keys",
+			method: "/cryptowatch.Users/ListSessions",
+			key:    "full",
+			req:    &emptypb.Empty{},
+			code:   codes.PermissionDenied,
+		},
+		{
+			name:   "Unknown key",
+			method: "/cryptowatch.v2.Portfolios/Info",
+			key:    "unknown",
+			req:    &pbv2.InfoReq{},
+			code:   codes.Unauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", tt.key))
+
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				userID, ok := authtoken.UserIDFromContext(ctx)
+				assert.True(t, ok)
+				assert.Equal(t, uint64(1), userID)
+				return req, nil
+			}
+
+			_, err := interceptor(ctx, tt.req, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+	req *wrapperspb.UInt64Value
+}
+
+func (s *testServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *testServerStream) RecvMsg(m interface{}) error {
+	proto.Merge(m.(proto.Message), s.req)
+	return nil
+}
+
+func TestAuthStreamInterceptor(t *testing.T) {
+	maker, err := authtoken.NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	denylist := user.NewDenylist(nil, time.Minute)
+	interceptor := user.AuthStreamInterceptor(newTestAuthorizer(t, maker, denylist), 10*time.Millisecond)
+	info := &grpc.StreamServerInfo{FullMethod: "/cryptowatch.Triggers/Subscribe", IsServerStream: true}
+
+	// handler receives the request and streams until the context is done.
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		var req wrapperspb.UInt64Value
+		err := ss.RecvMsg(&req)
+		if err != nil {
+			return err
+		}
+
+		payload, ok := authtoken.FromContext(ss.Context())
+		require.True(t, ok)
+		assert.Equal(t, req.GetValue(), payload.UserID)
+
+		select {
+		case <-ss.Context().Done():
+		case <-time.After(time.Second):
+		}
+		return nil
+	}
+
+	newStream := func(token string, userID uint64) *testServerStream {
+		ctx := context.Background()
+		if token != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", token))
+		}
+		return &testServerStream{ctx: ctx, req: &wrapperspb.UInt64Value{Value: userID}}
+	}
+
+	t.Run("Without token", func(t *testing.T) {
+		err := interceptor(nil, newStream("", 1), info, handler)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Not owner", func(t *testing.T) {
+		token, err := maker.CreateToken(1, time.Minute)
+		require.NoError(t, err)
+
+		err = interceptor(nil, newStream(token, 2), info, handler)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("Token expires", func(t *testing.T) {
+		token, err := maker.CreateToken(1, 50*time.Millisecond)
+		require.NoError(t, err)
+
+		start := time.Now()
+		err = interceptor(nil, newStream(token, 1), info, handler)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("Session revoked", func(t *testing.T) {
+		id := uuid.New()
+		token, err := maker.CreateToken(1, time.Minute, authtoken.WithID(id))
+		require.NoError(t, err)
+
+		time.AfterFunc(50*time.Millisecond, func() {
+			denylist.Add(id)
+		})
+
+		start := time.Now()
+		err = interceptor(nil, newStream(token, 1), info, handler)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
+
+// revocableAPIKey accepts the key until it is revoked.
+type revocableAPIKey struct {
+	key     string
+	payload *authtoken.Payload
+	revoked int32
+}
+
+func (k *revocableAPIKey) AuthenticateAPIKey(_ context.Context, key string) (*authtoken.Payload, error) {
+	if key != k.key || atomic.LoadInt32(&k.revoked) == 1 {
+		return nil, user.ErrUnauthenticated
+	}
+
+	return k.payload, nil
+}
+
+func TestAuthStreamInterceptor_APIKeyRevoked(t *testing.T) {
+	maker, err := authtoken.NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	srv := grpc.NewServer()
+	pbv2.RegisterTriggersServer(srv, &pbv2.UnimplementedTriggersServer{})
+
+	// Keys revoked through another process aren't in the denylist of this one.
+	apiKey := &revocableAPIKey{key: "key", payload: &authtoken.Payload{
+		ID:        uuid.New(),
+		UserID:    1,
+		ExpiresAt: time.Now().Add(time.Hour),
+		Scopes:    []string{user.ScopeAlerts},
+	}}
+	authorizer := user.NewAuthorizer(maker, user.NewDenylist(nil, time.Minute), user.WithAPIKeys(apiKey))
+	require.NoError(t, authorizer.Load(srv.GetServiceInfo()))
+	interceptor := user.AuthStreamInterceptor(authorizer, 10*time.Millisecond)
+	info := &grpc.StreamServerInfo{FullMethod: "/cryptowatch.v2.Triggers/Subscribe", IsServerStream: true}
+
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		select {
+		case <-ss.Context().Done():
+		case <-time.After(time.Second):
+		}
+		return nil
+	}
+
+	time.AfterFunc(50*time.Millisecond, func() {
+		atomic.StoreInt32(&apiKey.revoked, 1)
+	})
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", "key"))
+	start := time.Now()
+	err = interceptor(nil, &testServerStream{ctx: ctx}, info, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.EqualError(t, err, "rpc error: code = Unauthenticated desc = API key revoked")
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,94 @@
+package user
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"log"
+	"sync"
+	"time"
+)
+
+// Denylist caches ids of revoked sessions so that access tokens
+// can be checked for revocation without a database round trip.
+//
+// Access tokens are short-lived, so only sessions revoked within the
+// access token lifetime are kept.
+type Denylist struct {
+	repo   Repository
+	window time.Duration
+
+	mu  sync.RWMutex
+	ids map[uuid.UUID]time.Time
+}
+
+// NewDenylist creates a denylist keeping sessions revoked within window,
+// which should be at least the access token lifetime.
+func NewDenylist(repo Repository, window time.Duration) *Denylist {
+	return &Denylist{
+		repo:   repo,
+		window: window,
+		ids:    make(map[uuid.UUID]time.Time),
+	}
+}
+
+// Add denies the session immediately, without waiting for the next refresh.
+func (d *Denylist) Add(id uuid.UUID) {
+	d.mu.Lock()
+	d.ids[id] = time.Now()
+	d.mu.Unlock()
+}
+
+// Contains reports whether the session is revoked.
+func (d *Denylist) Contains(id uuid.UUID) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	_, ok := d.ids[id]
+	return ok
+}
+
+// Refresh loads sessions revoked within the window and forgets older ones.
+func (d *Denylist) Refresh(ctx context.Context) error {
+	now := time.Now()
+	since := now.Add(-d.window)
+
+	ids, err := d.repo.ListRevokedSessions(ctx, since)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for id, t := range d.ids {
+		if t.Before(since) {
+			delete(d.ids, id)
+		}
+	}
+	for _, id := range ids {
+		if _, ok := d.ids[id]; !ok {
+			d.ids[id] = now
+		}
+	}
+
+	return nil
+}
+
+// Run refreshes the denylist every interval until ctx is done.
+// Revocations made by other instances are seen after at most one interval.
+func (d *Denylist) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := d.Refresh(ctx)
+			if err != nil {
+				log.Printf("failed to refresh session denylist: %v", err)
+			}
+		}
+	}
+}
@@ -1,12 +1,151 @@
 package user
 
-import "time"
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Role grants a user access to methods beyond the user's own data.
+type Role string
+
+const (
+	RoleUser Role = "user"
+	// RoleSupport may look up users and their portfolios, and log out users of the user role.
+	RoleSupport Role = "support"
+	// RoleAdmin may do everything support may and manage accounts and roles.
+	RoleAdmin Role = "admin"
+)
+
+var roleRanks = map[Role]int{
+	RoleUser:    1,
+	RoleSupport: 2,
+	RoleAdmin:   3,
+}
+
+// Valid reports whether the role is known.
+func (r Role) Valid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// Includes reports whether the role grants everything other does.
+func (r Role) Includes(other Role) bool {
+	return r.Valid() && roleRanks[r] >= roleRanks[other]
+}
 
 type User struct {
-	ID           uint64    `json:"id"`
-	Username     string    `json:"username"`
+	ID       uint64 `json:"id"`
+	Username string `json:"username"`
+	// PasswordHash is empty for users provisioned by an OpenID provider, who can't log in with a password.
 	PasswordHash string    `json:"password_hash"`
 	FirstName    string    `json:"first_name"`
 	LastName     string    `json:"last_name"`
 	CreateTime   time.Time `json:"create_time"`
+	Role         Role      `json:"role"`
+	// DisableTime is when an administrator disabled the account, the user can't log in since.
+	DisableTime *time.Time `json:"disable_time"`
+}
+
+// Disabled reports whether the user is locked out by an administrator.
+func (u *User) Disabled() bool {
+	return u.DisableTime != nil
+}
+
+// HasPassword reports whether the user can log in with a password.
+func (u *User) HasPassword() bool {
+	return u.PasswordHash != ""
+}
+
+// Identity links an account of an OpenID provider to a user, who can log in with it.
+type Identity struct {
+	Issuer  string `json:"issuer"`
+	Subject string `json:"subject"`
+	UserID  uint64 `json:"user_id"`
+	// Email is the address the provider knew when the identity was linked.
+	Email      string    `json:"email"`
+	CreateTime time.Time `json:"create_time"`
+}
+
+// Session is a login of a user. Access tokens of a session carry its ID
+// and are renewed with the session refresh token until it expires or is revoked.
+type Session struct {
+	ID                       uuid.UUID  `json:"id"`
+	UserID                   uint64     `json:"user_id"`
+	RefreshTokenHash         string     `json:"refresh_token_hash"`
+	PreviousRefreshTokenHash string     `json:"previous_refresh_token_hash"`
+	UserAgent                string     `json:"user_agent"`
+	ClientIP                 string     `json:"client_ip"`
+	CreateTime               time.Time  `json:"create_time"`
+	RefreshTime              time.Time  `json:"refresh_time"`
+	ExpireTime               time.Time  `json:"expire_time"`
+	RevokeTime               *time.Time `json:"revoke_time"`
+	// Scopes limit access tokens of the session, nothing is limited if empty.
+	Scopes []string `json:"scopes"`
+}
+
+// Active reports whether the session can still be refreshed.
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokeTime == nil && now.Before(s.ExpireTime)
+}
+
+// TOTP is the authenticator app secret of a user.
+// It is pending until confirmed with a first code, then logins require a second factor.
+type TOTP struct {
+	UserID uint64 `json:"user_id"`
+	Secret string `json:"secret"`
+	// LastUsedStep is the time step of the last accepted code, so a code can't be replayed.
+	LastUsedStep int64      `json:"last_used_step"`
+	CreateTime   time.Time  `json:"create_time"`
+	ConfirmTime  *time.Time `json:"confirm_time"`
+}
+
+// Enabled reports whether logins of the user require a second factor.
+func (t *TOTP) Enabled() bool {
+	return t.ConfirmTime != nil
+}
+
+// LoginChallenge is a password login waiting for the second factor.
+type LoginChallenge struct {
+	TokenHash  string    `json:"token_hash"`
+	UserID     uint64    `json:"user_id"`
+	UserAgent  string    `json:"user_agent"`
+	ClientIP   string    `json:"client_ip"`
+	Attempts   int       `json:"attempts"`
+	ExpireTime time.Time `json:"expire_time"`
+}
+
+// LoginFailures counts failed password logins of a user since the last successful one.
+type LoginFailures struct {
+	UserID          uint64    `json:"user_id"`
+	Failures        int       `json:"failures"`
+	LastFailureTime time.Time `json:"last_failure_time"`
+	// LockedUntil is when the user may try to log in again.
+	LockedUntil *time.Time `json:"locked_until"`
+}
+
+// Locked reports whether logins of the user are rejected at now.
+func (f *LoginFailures) Locked(now time.Time) bool {
+	return f.LockedUntil != nil && now.Before(*f.LockedUntil)
+}
+
+// APIKey authenticates scripts and bots of a user instead of access tokens.
+// Only a hash of the key is stored, the key itself is shown once on creation.
+type APIKey struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uint64    `json:"user_id"`
+	Name   string    `json:"name"`
+	// Prefix is the start of the key, so users can tell keys apart.
+	Prefix  string `json:"prefix"`
+	KeyHash string `json:"key_hash"`
+	// Scopes limit the methods the key may call, a key without scopes may call all methods open to API keys.
+	Scopes       []string   `json:"scopes"`
+	CreateTime   time.Time  `json:"create_time"`
+	ExpireTime   *time.Time `json:"expire_time"`
+	LastUsedTime *time.Time `json:"last_used_time"`
+	RevokeTime   *time.Time `json:"revoke_time"`
+}
+
+// Active reports whether the key is accepted at now.
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokeTime == nil && (k.ExpireTime == nil || now.Before(*k.ExpireTime))
 }
@@ -2,8 +2,13 @@ package user
 
 import (
 	"errors"
+	"fmt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"strings"
+	"time"
 )
 
 var (
@@ -12,10 +17,23 @@ var (
 	ErrFailedPrecondition = errors.New("failed precondition")
 	ErrNotFound           = errors.New("not found")
 	ErrUnauthenticated    = errors.New("unauthenticated")
+	ErrResourceExhausted  = errors.New("resource exhausted")
+	ErrPermissionDenied   = errors.New("permission denied")
+	ErrMissingPolicy      = errors.New("missing authorization policy")
+
+	ErrAccountDisabled = fmt.Errorf("%w: account disabled", ErrPermissionDenied)
+	// ErrTargetRole is returned when support staff act on users of their own role or above.
+	ErrTargetRole = fmt.Errorf("%w: the user's role isn't below yours", ErrPermissionDenied)
 )
 
 func ErrToGRPCErr(err error) error {
+	var verr *ValidationError
+	var rerr *RetryError
 	switch {
+	case errors.As(err, &verr):
+		return validationErrToGRPCErr(verr)
+	case errors.As(err, &rerr):
+		return retryErrToGRPCErr(rerr)
 	case errors.Is(err, ErrInternalError):
 		return status.New(codes.Internal, err.Error()).Err()
 	case errors.Is(err, ErrInvalidArgument):
@@ -26,7 +44,94 @@ func ErrToGRPCErr(err error) error {
 		return status.New(codes.NotFound, err.Error()).Err()
 	case errors.Is(err, ErrUnauthenticated):
 		return status.New(codes.Unauthenticated, err.Error()).Err()
+	case errors.Is(err, ErrResourceExhausted):
+		return status.New(codes.ResourceExhausted, err.Error()).Err()
+	case errors.Is(err, ErrPermissionDenied):
+		return status.New(codes.PermissionDenied, err.Error()).Err()
 	default:
 		return status.New(codes.Unknown, err.Error()).Err()
 	}
 }
+
+// FieldViolation describes why a field of a request is invalid.
+type FieldViolation struct {
+	Field       string
+	Description string
+}
+
+// ValidationError is an ErrInvalidArgument listing the invalid fields of a request.
+type ValidationError struct {
+	Violations []FieldViolation
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		msgs = append(msgs, v.Field+": "+v.Description)
+	}
+
+	return ErrInvalidArgument.Error() + ": " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidArgument
+}
+
+// add records the problems of a field.
+func (e *ValidationError) add(field string, descriptions ...string) {
+	for _, d := range descriptions {
+		e.Violations = append(e.Violations, FieldViolation{Field: field, Description: d})
+	}
+}
+
+// err returns e if any field is invalid and nil otherwise.
+func (e *ValidationError) err() error {
+	if len(e.Violations) == 0 {
+		return nil
+	}
+	return e
+}
+
+// validationErrToGRPCErr returns an InvalidArgument status with a BadRequest detail of the violations.
+func validationErrToGRPCErr(verr *ValidationError) error {
+	br := &errdetails.BadRequest{
+		FieldViolations: make([]*errdetails.BadRequest_FieldViolation, 0, len(verr.Violations)),
+	}
+	for _, v := range verr.Violations {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Description,
+		})
+	}
+
+	st := status.New(codes.InvalidArgument, verr.Error())
+	if withDetails, err := st.WithDetails(br); err == nil {
+		st = withDetails
+	}
+
+	return st.Err()
+}
+
+// RetryError is an error, like ErrResourceExhausted, of a request that may succeed after a delay.
+type RetryError struct {
+	Err   error
+	After time.Duration
+}
+
+func (e *RetryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// retryErrToGRPCErr returns a ResourceExhausted status with a RetryInfo detail.
+func retryErrToGRPCErr(rerr *RetryError) error {
+	st := status.New(codes.ResourceExhausted, rerr.Error())
+	if withDetails, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(rerr.After)}); err == nil {
+		st = withDetails
+	}
+
+	return st.Err()
+}
@@ -3,23 +3,34 @@ package user
 import (
 	"context"
 	pb "cryptowatch/pkg/api/cryptowatchv1"
+	"cryptowatch/pkg/util/authtoken"
 	"errors"
+	"fmt"
+	"github.com/google/uuid"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
+	"net"
 )
 
+// GRPCHandler serves the v1 API. The authorization interceptor checks
+// user ids of requests match the token, services take the user from the context.
 type GRPCHandler struct {
-	svc Service
+	svc            Service
+	trustedProxies []*net.IPNet
 
 	pb.UnimplementedUsersServer
 }
 
-func NewGRPCHandler(svc Service) *GRPCHandler {
+// NewGRPCHandler creates the handler. Sessions record the client address forwarded
+// by trustedProxies, like the gateway, or else the peer address.
+func NewGRPCHandler(svc Service, trustedProxies []*net.IPNet) *GRPCHandler {
 	return &GRPCHandler{
-		svc: svc,
+		svc:            svc,
+		trustedProxies: trustedProxies,
 	}
 }
 
@@ -37,16 +48,24 @@ func (h *GRPCHandler) CreateUser(ctx context.Context, req *pb.CreateUserReq) (*w
 	return &wrapperspb.UInt64Value{Value: u.ID}, status.New(codes.OK, "OK").Err()
 }
 
+// Login returns the access token only. Users with two-factor authentication log in with v2,
+// which returns the refresh token as well.
 func (h *GRPCHandler) Login(ctx context.Context, req *pb.LoginReq) (*wrapperspb.StringValue, error) {
-	accessToken, err := h.svc.Login(ctx, SvcLoginReq{
-		Username: req.GetUsername(),
-		Password: req.GetPassword(),
+	userAgent, clientIP := clientInfo(ctx, h.trustedProxies)
+	tokens, err := h.svc.Login(ctx, SvcLoginReq{
+		Username:  req.GetUsername(),
+		Password:  req.GetPassword(),
+		UserAgent: userAgent,
+		ClientIP:  clientIP,
 	})
 	if err != nil {
 		return nil, ErrToGRPCErr(err)
 	}
+	if tokens.MFARequired {
+		return nil, ErrToGRPCErr(fmt.Errorf("%w: two-factor authentication is enabled, log in with the v2 API", ErrFailedPrecondition))
+	}
 
-	return &wrapperspb.StringValue{Value: accessToken}, status.New(codes.OK, "OK").Err()
+	return &wrapperspb.StringValue{Value: tokens.AccessToken}, status.New(codes.OK, "OK").Err()
 }
 
 func (h *GRPCHandler) GetUser(ctx context.Context, req *wrapperspb.StringValue) (*pb.User, error) {
@@ -67,14 +86,14 @@ func (h *GRPCHandler) GetUser(ctx context.Context, req *wrapperspb.StringValue)
 func (h *GRPCHandler) GenerateOTP(ctx context.Context, value *wrapperspb.StringValue) (*emptypb.Empty, error) {
 	err := h.svc.GenerateOTP(ctx, value.GetValue())
 	if err != nil {
-		return nil, status.New(codes.Internal, err.Error()).Err()
+		return nil, ErrToGRPCErr(err)
 	}
 
 	return &emptypb.Empty{}, status.New(codes.OK, "OK").Err()
 }
 
-func (h *GRPCHandler) GetOTP(ctx context.Context, value *wrapperspb.UInt64Value) (*wrapperspb.StringValue, error) {
-	code, err := h.svc.GetOTP(ctx, value.GetValue())
+func (h *GRPCHandler) GetOTP(ctx context.Context, _ *wrapperspb.UInt64Value) (*wrapperspb.StringValue, error) {
+	code, err := h.svc.GetOTP(ctx)
 	if err != nil {
 		if errors.Is(err, ErrNotFound) {
 			return nil, status.New(codes.NotFound, err.Error()).Err()
@@ -90,7 +109,7 @@ func (h *GRPCHandler) VerifyOTP(ctx context.Context, req *pb.VerifyOTPReq) (*pb.
 		if errors.Is(err, ErrNotFound) {
 			return nil, status.New(codes.Unauthenticated, err.Error()).Err()
 		}
-		return nil, status.New(codes.Internal, err.Error()).Err()
+		return nil, ErrToGRPCErr(err)
 	}
 
 	return &pb.VerifyOTPRes{
@@ -98,3 +117,75 @@ func (h *GRPCHandler) VerifyOTP(ctx context.Context, req *pb.VerifyOTPReq) (*pb.
 		Token:  res.Token,
 	}, status.New(codes.OK, "OK").Err()
 }
+
+func (h *GRPCHandler) Logout(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	payload, ok := authtoken.FromContext(ctx)
+	if !ok {
+		return nil, ErrToGRPCErr(ErrUnauthenticated)
+	}
+
+	err := h.svc.RevokeSession(ctx, payload.ID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return &emptypb.Empty{}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandler) ListSessions(ctx context.Context, _ *emptypb.Empty) (*pb.ListSessionsRes, error) {
+	payload, ok := authtoken.FromContext(ctx)
+	if !ok {
+		return nil, ErrToGRPCErr(ErrUnauthenticated)
+	}
+
+	sessions, err := h.svc.ListSessions(ctx)
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	res := &pb.ListSessionsRes{
+		Sessions: make([]*pb.Session, 0, len(sessions)),
+	}
+	for _, sess := range sessions {
+		res.Sessions = append(res.Sessions, &pb.Session{
+			Id:          sess.ID.String(),
+			UserAgent:   sess.UserAgent,
+			ClientIp:    sess.ClientIP,
+			CreateTime:  timestamppb.New(sess.CreateTime),
+			RefreshTime: timestamppb.New(sess.RefreshTime),
+			ExpireTime:  timestamppb.New(sess.ExpireTime),
+			Current:     sess.ID == payload.ID,
+		})
+	}
+
+	return res, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandler) RevokeSession(ctx context.Context, req *pb.RevokeSessionReq) (*emptypb.Empty, error) {
+	id, err := uuid.Parse(req.GetId())
+	if err != nil {
+		return nil, ErrToGRPCErr(ErrInvalidArgument)
+	}
+
+	err = h.svc.RevokeSession(ctx, id)
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return &emptypb.Empty{}, status.New(codes.OK, "OK").Err()
+}
+
+// clientInfo returns the user agent of the client, preferring the one forwarded by the gateway,
+// and its address forwarded by trustedProxies, see clientIP.
+func clientInfo(ctx context.Context, trustedProxies []*net.IPNet) (string, string) {
+	var userAgent string
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get("grpcgateway-user-agent"); len(v) > 0 {
+		userAgent = v[0]
+	} else if v := md.Get("user-agent"); len(v) > 0 {
+		userAgent = v[0]
+	}
+
+	return userAgent, clientIP(ctx, trustedProxies)
+}
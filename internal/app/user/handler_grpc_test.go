@@ -0,0 +1,79 @@
+package user_test
+
+import (
+	"context"
+	"cryptowatch/internal/app/user"
+	pb "cryptowatch/pkg/api/cryptowatchv1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"net"
+	"testing"
+)
+
+// loginService records the login request of the handler.
+type loginService struct {
+	user.Service
+
+	req user.SvcLoginReq
+}
+
+func (s *loginService) Login(_ context.Context, req user.SvcLoginReq) (*user.SvcTokens, error) {
+	s.req = req
+	return &user.SvcTokens{AccessToken: "token"}, nil
+}
+
+func TestGRPCHandler_LoginClientIP(t *testing.T) {
+	proxies, err := user.ParseNetworks("10.0.0.0/8")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		addr         string
+		forwardedFor string
+		clientIP     string
+	}{
+		{
+			name:         "Client claiming an address",
+			addr:         "2.2.2.2",
+			forwardedFor: "9.9.9.9",
+			clientIP:     "2.2.2.2",
+		},
+		{
+			name:         "Trusted proxy",
+			addr:         "10.0.0.1",
+			forwardedFor: "9.9.9.9, 3.3.3.3",
+			clientIP:     "3.3.3.3",
+		},
+		{
+			name:         "Trusted proxies",
+			addr:         "10.0.0.1",
+			forwardedFor: "9.9.9.9, 3.3.3.3, 10.0.0.2",
+			clientIP:     "3.3.3.3",
+		},
+		{
+			name:     "Proxy without forwarded address",
+			addr:     "10.0.0.1",
+			clientIP: "10.0.0.1",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &loginService{}
+			handler := user.NewGRPCHandler(svc, proxies)
+
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(tt.addr), Port: 1234}})
+			if tt.forwardedFor != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-forwarded-for", tt.forwardedFor))
+			}
+
+			res, err := handler.Login(ctx, &pb.LoginReq{Username: "alice", Password: "password"})
+			require.NoError(t, err)
+			assert.Equal(t, "token", res.GetValue())
+			assert.Equal(t, tt.clientIP, svc.req.ClientIP)
+		})
+	}
+}
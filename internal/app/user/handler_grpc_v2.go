@@ -0,0 +1,426 @@
+package user
+
+import (
+	"context"
+	pb "cryptowatch/pkg/api/cryptowatchv2"
+	"cryptowatch/pkg/util/authtoken"
+	"encoding/base64"
+	"errors"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"net"
+	"time"
+)
+
+// GRPCHandlerV2 serves the v2 API, where the user comes from the access token only.
+type GRPCHandlerV2 struct {
+	svc            Service
+	trustedProxies []*net.IPNet
+
+	pb.UnimplementedUsersServer
+}
+
+// NewGRPCHandlerV2 creates the handler, see NewGRPCHandler.
+func NewGRPCHandlerV2(svc Service, trustedProxies []*net.IPNet) *GRPCHandlerV2 {
+	return &GRPCHandlerV2{
+		svc:            svc,
+		trustedProxies: trustedProxies,
+	}
+}
+
+func (h *GRPCHandlerV2) CreateUser(ctx context.Context, req *pb.CreateUserReq) (*wrapperspb.UInt64Value, error) {
+	u, err := h.svc.Create(ctx, SvcCreateReq{
+		Username:  req.GetUsername(),
+		Password:  req.GetPassword(),
+		FirstName: req.GetFirstName(),
+		LastName:  req.GetLastName(),
+	})
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return &wrapperspb.UInt64Value{Value: u.ID}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) Login(ctx context.Context, req *pb.LoginReq) (*pb.Tokens, error) {
+	userAgent, clientIP := clientInfo(ctx, h.trustedProxies)
+	tokens, err := h.svc.Login(ctx, SvcLoginReq{
+		Username:  req.GetUsername(),
+		Password:  req.GetPassword(),
+		UserAgent: userAgent,
+		ClientIP:  clientIP,
+	})
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return tokensToPBV2(tokens), status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) VerifyLogin(ctx context.Context, req *pb.VerifyLoginReq) (*pb.Tokens, error) {
+	tokens, err := h.svc.VerifyLogin(ctx, SvcVerifyLoginReq{
+		MFAToken: req.GetMfaToken(),
+		Code:     req.GetCode(),
+	})
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return tokensToPBV2(tokens), status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) EnrollTOTP(ctx context.Context, _ *emptypb.Empty) (*pb.TOTPEnrollment, error) {
+	enrollment, err := h.svc.EnrollTOTP(ctx)
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return &pb.TOTPEnrollment{
+		Secret: enrollment.Secret,
+		Uri:    enrollment.URI,
+	}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) ConfirmTOTP(ctx context.Context, req *pb.TOTPCodeReq) (*pb.RecoveryCodes, error) {
+	recoveryCodes, err := h.svc.ConfirmTOTP(ctx, req.GetCode())
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return &pb.RecoveryCodes{Codes: recoveryCodes}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) DisableTOTP(ctx context.Context, req *pb.TOTPCodeReq) (*emptypb.Empty, error) {
+	err := h.svc.DisableTOTP(ctx, req.GetCode())
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return &emptypb.Empty{}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) GetUser(ctx context.Context, req *wrapperspb.StringValue) (*pb.User, error) {
+	u, err := h.svc.GetByUsername(ctx, req.GetValue())
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return &pb.User{
+		Id:         u.ID,
+		Username:   u.Username,
+		FirstName:  u.FirstName,
+		LastName:   u.LastName,
+		CreateTime: timestamppb.New(u.CreateTime),
+	}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) ChangePassword(ctx context.Context, req *pb.ChangePasswordReq) (*emptypb.Empty, error) {
+	err := h.svc.ChangePassword(ctx, SvcChangePasswordReq{
+		OldPassword: req.GetOldPassword(),
+		NewPassword: req.GetNewPassword(),
+	})
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return &emptypb.Empty{}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) UpdateProfile(ctx context.Context, req *pb.UpdateProfileReq) (*pb.User, error) {
+	u, err := h.svc.UpdateProfile(ctx, SvcUpdateProfileReq{
+		FirstName: req.GetFirstName(),
+		LastName:  req.GetLastName(),
+	})
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return &pb.User{
+		Id:         u.ID,
+		Username:   u.Username,
+		FirstName:  u.FirstName,
+		LastName:   u.LastName,
+		CreateTime: timestamppb.New(u.CreateTime),
+	}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) DeleteAccount(ctx context.Context, req *pb.DeleteAccountReq) (*emptypb.Empty, error) {
+	err := h.svc.DeleteAccount(ctx, req.GetPassword())
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return &emptypb.Empty{}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) GenerateOTP(ctx context.Context, value *wrapperspb.StringValue) (*emptypb.Empty, error) {
+	err := h.svc.GenerateOTP(ctx, value.GetValue())
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return &emptypb.Empty{}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) GetOTP(ctx context.Context, _ *emptypb.Empty) (*wrapperspb.StringValue, error) {
+	code, err := h.svc.GetOTP(ctx)
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return &wrapperspb.StringValue{Value: code}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) VerifyOTP(ctx context.Context, req *pb.VerifyOTPReq) (*pb.VerifyOTPRes, error) {
+	res, err := h.svc.VerifyOTP(ctx, req.GetUsername(), req.GetCode())
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrToGRPCErr(ErrUnauthenticated)
+		}
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return &pb.VerifyOTPRes{
+		UserId:       res.UserID,
+		Token:        res.Token,
+		RefreshToken: res.RefreshToken,
+	}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) RefreshToken(ctx context.Context, req *pb.RefreshTokenReq) (*pb.Tokens, error) {
+	tokens, err := h.svc.RefreshToken(ctx, req.GetRefreshToken())
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return tokensToPBV2(tokens), status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) Logout(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	payload, ok := authtoken.FromContext(ctx)
+	if !ok {
+		return nil, ErrToGRPCErr(ErrUnauthenticated)
+	}
+
+	err := h.svc.RevokeSession(ctx, payload.ID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return &emptypb.Empty{}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) ListSessions(ctx context.Context, _ *emptypb.Empty) (*pb.ListSessionsRes, error) {
+	payload, ok := authtoken.FromContext(ctx)
+	if !ok {
+		return nil, ErrToGRPCErr(ErrUnauthenticated)
+	}
+
+	sessions, err := h.svc.ListSessions(ctx)
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	res := &pb.ListSessionsRes{
+		Sessions: make([]*pb.Session, 0, len(sessions)),
+	}
+	for _, sess := range sessions {
+		res.Sessions = append(res.Sessions, &pb.Session{
+			Id:          sess.ID.String(),
+			UserAgent:   sess.UserAgent,
+			ClientIp:    sess.ClientIP,
+			CreateTime:  timestamppb.New(sess.CreateTime),
+			RefreshTime: timestamppb.New(sess.RefreshTime),
+			ExpireTime:  timestamppb.New(sess.ExpireTime),
+			Current:     sess.ID == payload.ID,
+		})
+	}
+
+	return res, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) RevokeSession(ctx context.Context, req *pb.RevokeSessionReq) (*emptypb.Empty, error) {
+	id, err := uuid.Parse(req.GetId())
+	if err != nil {
+		return nil, ErrToGRPCErr(ErrInvalidArgument)
+	}
+
+	err = h.svc.RevokeSession(ctx, id)
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return &emptypb.Empty{}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) CreateAPIKey(ctx context.Context, req *pb.CreateAPIKeyReq) (*pb.CreateAPIKeyRes, error) {
+	var expireTime *time.Time
+	if req.GetExpireTime() != nil {
+		t := req.GetExpireTime().AsTime()
+		expireTime = &t
+	}
+
+	res, err := h.svc.CreateAPIKey(ctx, SvcCreateAPIKeyReq{
+		Name:       req.GetName(),
+		Scopes:     req.GetScopes(),
+		ExpireTime: expireTime,
+	})
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return &pb.CreateAPIKeyRes{
+		ApiKey: apiKeyToPBV2(res.APIKey),
+		Key:    res.Key,
+	}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) ListAPIKeys(ctx context.Context, _ *emptypb.Empty) (*pb.ListAPIKeysRes, error) {
+	keys, err := h.svc.ListAPIKeys(ctx)
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	res := &pb.ListAPIKeysRes{
+		ApiKeys: make([]*pb.APIKey, 0, len(keys)),
+	}
+	for _, k := range keys {
+		res.ApiKeys = append(res.ApiKeys, apiKeyToPBV2(k))
+	}
+
+	return res, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) RevokeAPIKey(ctx context.Context, req *pb.RevokeAPIKeyReq) (*emptypb.Empty, error) {
+	id, err := uuid.Parse(req.GetId())
+	if err != nil {
+		return nil, ErrToGRPCErr(ErrInvalidArgument)
+	}
+
+	err = h.svc.RevokeAPIKey(ctx, id)
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return &emptypb.Empty{}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) LoginOIDC(ctx context.Context, req *pb.LoginOIDCReq) (*pb.Tokens, error) {
+	userAgent, clientIP := clientInfo(ctx, h.trustedProxies)
+	tokens, err := h.svc.LoginOIDC(ctx, SvcLoginOIDCReq{
+		IDToken:   req.GetIdToken(),
+		Nonce:     req.GetNonce(),
+		UserAgent: userAgent,
+		ClientIP:  clientIP,
+	})
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return tokensToPBV2(tokens), status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) LinkIdentity(ctx context.Context, req *pb.LinkIdentityReq) (*pb.Identity, error) {
+	identity, err := h.svc.LinkIdentity(ctx, SvcLinkIdentityReq{
+		IDToken: req.GetIdToken(),
+		Nonce:   req.GetNonce(),
+	})
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return identityToPBV2(identity), status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) ListIdentities(ctx context.Context, _ *emptypb.Empty) (*pb.ListIdentitiesRes, error) {
+	identities, err := h.svc.ListIdentities(ctx)
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	res := &pb.ListIdentitiesRes{
+		Identities: make([]*pb.Identity, 0, len(identities)),
+	}
+	for _, i := range identities {
+		res.Identities = append(res.Identities, identityToPBV2(i))
+	}
+
+	return res, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) UnlinkIdentity(ctx context.Context, req *pb.UnlinkIdentityReq) (*emptypb.Empty, error) {
+	err := h.svc.UnlinkIdentity(ctx, req.GetIssuer(), req.GetSubject())
+	if err != nil {
+		return nil, ErrToGRPCErr(err)
+	}
+
+	return &emptypb.Empty{}, status.New(codes.OK, "OK").Err()
+}
+
+func (h *GRPCHandlerV2) GetTokenKeys(ctx context.Context, _ *emptypb.Empty) (*pb.TokenKeySet, error) {
+	keys := h.svc.TokenKeys(ctx)
+
+	res := &pb.TokenKeySet{
+		Keys: make([]*pb.TokenKey, 0, len(keys)),
+	}
+	for _, k := range keys {
+		res.Keys = append(res.Keys, &pb.TokenKey{
+			Kid:        k.ID,
+			Kty:        "OKP",
+			Crv:        "Ed25519",
+			Alg:        "EdDSA",
+			Use:        "sig",
+			X:          base64.RawURLEncoding.EncodeToString(k.Key),
+			ExpireTime: timestamppb.New(k.ExpireTime),
+		})
+	}
+
+	return res, status.New(codes.OK, "OK").Err()
+}
+
+func apiKeyToPBV2(k *APIKey) *pb.APIKey {
+	res := &pb.APIKey{
+		Id:         k.ID.String(),
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.Scopes,
+		CreateTime: timestamppb.New(k.CreateTime),
+	}
+	if k.ExpireTime != nil {
+		res.ExpireTime = timestamppb.New(*k.ExpireTime)
+	}
+	if k.LastUsedTime != nil {
+		res.LastUsedTime = timestamppb.New(*k.LastUsedTime)
+	}
+
+	return res
+}
+
+func identityToPBV2(i *Identity) *pb.Identity {
+	return &pb.Identity{
+		Issuer:     i.Issuer,
+		Subject:    i.Subject,
+		Email:      i.Email,
+		CreateTime: timestamppb.New(i.CreateTime),
+	}
+}
+
+func tokensToPBV2(tokens *SvcTokens) *pb.Tokens {
+	if tokens.MFARequired {
+		return &pb.Tokens{
+			MfaRequired: true,
+			MfaToken:    tokens.MFAToken,
+		}
+	}
+
+	return &pb.Tokens{
+		AccessToken:            tokens.AccessToken,
+		AccessTokenExpireTime:  timestamppb.New(tokens.AccessTokenExpireTime),
+		RefreshToken:           tokens.RefreshToken,
+		RefreshTokenExpireTime: timestamppb.New(tokens.RefreshTokenExpireTime),
+	}
+}
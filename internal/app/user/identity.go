@@ -0,0 +1,242 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"cryptowatch/pkg/util/authtoken"
+	"cryptowatch/pkg/util/oidc"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+)
+
+const (
+	// provisionAttempts limits usernames tried for a new user before giving up.
+	provisionAttempts = 5
+	// provisionUsernameBaseLen leaves room for a numeric suffix within 16 characters.
+	provisionUsernameBaseLen = 12
+)
+
+// IDTokenVerifier verifies ID tokens of the OpenID provider users log in with.
+type IDTokenVerifier interface {
+	Verify(ctx context.Context, raw string, nonce string) (*oidc.IDToken, error)
+}
+
+type SvcLoginOIDCReq struct {
+	IDToken string `json:"id_token" validate:"required"`
+	// Nonce is the value the login was started with, the token has to carry it.
+	Nonce     string `json:"nonce"`
+	UserAgent string `json:"user_agent"`
+	ClientIP  string `json:"client_ip"`
+}
+
+type SvcLinkIdentityReq struct {
+	IDToken string `json:"id_token" validate:"required"`
+	Nonce   string `json:"nonce"`
+}
+
+func (s *service) LoginOIDC(ctx context.Context, req SvcLoginOIDCReq) (*SvcTokens, error) {
+	token, err := s.verifyIDToken(ctx, req.IDToken, req.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := s.identityUser(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Disabled() {
+		return nil, ErrAccountDisabled
+	}
+
+	// The provider vouches for the first factor only.
+	t, err := s.repo.GetTOTP(ctx, u.ID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	if t != nil && t.Enabled() {
+		return s.createLoginChallenge(ctx, u.ID, req.UserAgent, req.ClientIP)
+	}
+
+	return s.createSession(ctx, u, req.UserAgent, req.ClientIP, nil)
+}
+
+func (s *service) LinkIdentity(ctx context.Context, req SvcLinkIdentityReq) (*Identity, error) {
+	userID, ok := authtoken.UserIDFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	token, err := s.verifyIDToken(ctx, req.IDToken, req.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := s.repo.CreateIdentity(ctx, RepoCreateIdentityReq{
+		Issuer:  token.Issuer,
+		Subject: token.Subject,
+		UserID:  userID,
+		Email:   token.Email,
+	})
+	if err != nil {
+		if errors.Is(err, ErrFailedPrecondition) {
+			return nil, fmt.Errorf("%w: identity is linked already", ErrFailedPrecondition)
+		}
+		return nil, err
+	}
+
+	return identity, nil
+}
+
+func (s *service) ListIdentities(ctx context.Context) ([]*Identity, error) {
+	userID, ok := authtoken.UserIDFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	return s.repo.ListIdentities(ctx, userID)
+}
+
+func (s *service) UnlinkIdentity(ctx context.Context, issuer string, subject string) error {
+	userID, ok := authtoken.UserIDFromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+
+	u, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// Users without a password would be locked out without their last identity.
+	if !u.HasPassword() {
+		identities, err := s.repo.ListIdentities(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if len(identities) <= 1 {
+			return fmt.Errorf("%w: the only way to log in can't be unlinked", ErrFailedPrecondition)
+		}
+	}
+
+	return s.repo.DeleteIdentity(ctx, userID, issuer, subject)
+}
+
+func (s *service) verifyIDToken(ctx context.Context, raw string, nonce string) (*oidc.IDToken, error) {
+	if s.idTokenVerifier == nil {
+		return nil, fmt.Errorf("%w: OpenID Connect login is disabled", ErrFailedPrecondition)
+	}
+
+	token, err := s.idTokenVerifier.Verify(ctx, raw, nonce)
+	if err != nil {
+		if errors.Is(err, oidc.ErrInvalidIDToken) {
+			return nil, ErrUnauthenticated
+		}
+		log.Printf("failed to verify ID token: %v", err)
+		return nil, ErrInternalError
+	}
+
+	return token, nil
+}
+
+// identityUser returns the user linked to the identity of the token. A new user
+// is created for an unknown identity if provisioning is enabled.
+func (s *service) identityUser(ctx context.Context, token *oidc.IDToken) (*User, error) {
+	for attempt := 0; attempt < provisionAttempts; attempt++ {
+		identity, err := s.repo.GetIdentity(ctx, token.Issuer, token.Subject)
+		if err == nil {
+			return s.repo.GetByID(ctx, identity.UserID)
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+
+		if !s.provisionUsers {
+			return nil, fmt.Errorf("%w: no account is linked to the identity", ErrUnauthenticated)
+		}
+
+		username, err := provisionUsername(token, attempt)
+		if err != nil {
+			return nil, ErrInternalError
+		}
+		firstName, lastName := identityNames(token)
+
+		u, err := s.repo.CreateWithIdentity(ctx, RepoCreateReq{
+			Username:  username,
+			FirstName: firstName,
+			LastName:  lastName,
+		}, RepoCreateIdentityReq{
+			Issuer:  token.Issuer,
+			Subject: token.Subject,
+			Email:   token.Email,
+		})
+		if err == nil {
+			return u, nil
+		}
+		if !errors.Is(err, ErrFailedPrecondition) {
+			return nil, err
+		}
+		// The username is taken or a concurrent login linked the identity, so look again.
+	}
+
+	return nil, fmt.Errorf("%w: no free username for the identity", ErrFailedPrecondition)
+}
+
+// provisionUsername derives the username of a new user from the claims of the token.
+// The first attempt takes the claims as they are, later ones add a random suffix.
+func provisionUsername(token *oidc.IDToken, attempt int) (string, error) {
+	email := token.Email
+	if i := strings.IndexByte(email, '@'); i >= 0 {
+		email = email[:i]
+	}
+
+	var base string
+	for _, candidate := range []string{token.PreferredUsername, email, token.GivenName} {
+		base = usernameChars(candidate)
+		if len(base) >= 4 {
+			break
+		}
+	}
+	if len(base) > provisionUsernameBaseLen {
+		base = base[:provisionUsernameBaseLen]
+	}
+
+	if attempt == 0 && len(base) >= 4 {
+		return base, nil
+	}
+	if base == "" {
+		base = "user"
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(10000))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s%04d", base, n.Int64()), nil
+}
+
+// usernameChars drops the characters usernames can't have.
+func usernameChars(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// identityNames returns the first and last name of the token, split from the full name if needed.
+func identityNames(token *oidc.IDToken) (string, string) {
+	if token.GivenName != "" || token.FamilyName != "" {
+		return token.GivenName, token.FamilyName
+	}
+
+	first, last, _ := strings.Cut(strings.TrimSpace(token.Name), " ")
+	return first, strings.TrimSpace(last)
+}
@@ -4,48 +4,110 @@ import (
 	"context"
 	"cryptowatch/pkg/util/authtoken"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/metadata"
-	"google.golang.org/grpc/status"
+	"sync"
+	"time"
 )
 
-type WithUserID interface {
-	GetUserId() uint64
+// AuthUnaryInterceptor authorizes unary calls with authorizer.
+// Handlers of methods requiring a token get its payload from the context.
+func AuthUnaryInterceptor(authorizer *Authorizer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authorizer.authorize(ctx, info.FullMethod, req)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
 }
 
-func AuthUnaryInterceptor(authtokenMaker authtoken.Maker) grpc.UnaryServerInterceptor {
-	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		method := info.FullMethod
-		if method == "/cryptowatch.Portfolios/CreatePortfolio" ||
-			method == "/cryptowatch.Portfolios/Buy" ||
-			method == "/cryptowatch.Portfolios/Sell" ||
-			method == "/cryptowatch.Portfolios/Info" ||
-			method == "/cryptowatch.Triggers/Add" ||
-			method == "/cryptowatch.Triggers/Remove" {
-
-			md, ok := metadata.FromIncomingContext(ctx)
-			if !ok {
-				return nil, status.New(codes.PermissionDenied, "permission denied").Err()
-			}
-			values, ok := md["authorization"]
-			if !ok || len(values) == 0 {
-				return nil, status.New(codes.PermissionDenied, "permission denied").Err()
-			}
-			token := values[0]
-			claims, err := authtokenMaker.VerifyToken(token)
-			if err != nil {
-				return nil, status.New(codes.PermissionDenied, "permission denied").Err()
-			}
+// AuthStreamInterceptor authorizes streaming calls with authorizer. Owner-only
+// methods check every received message. The token or API key is validated again every
+// revalidateInterval and the stream ends once it expires or its session or key is revoked,
+// so handlers must return when the stream context is done.
+func AuthStreamInterceptor(authorizer *Authorizer, revalidateInterval time.Duration) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		mp, payload, apiKey, err := authorizer.authorizeMethod(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		if payload == nil {
+			return handler(srv, ss)
+		}
 
-			wuid, ok := req.(WithUserID)
-			if !ok {
-				return nil, status.New(codes.PermissionDenied, "permission denied").Err()
-			}
-			if wuid.GetUserId() != claims.UserID {
-				return nil, status.New(codes.PermissionDenied, "permission denied").Err()
+		ctx, cancel := context.WithCancel(authtoken.NewContext(ss.Context(), payload))
+		defer cancel()
+
+		stream := &authServerStream{
+			ServerStream: ss,
+			ctx:          ctx,
+			policy:       mp,
+			payload:      payload,
+		}
+
+		go func() {
+			ticker := time.NewTicker(revalidateInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					err := authorizer.validate(ctx, payload, apiKey)
+					if err != nil {
+						stream.setErr(err)
+						cancel()
+						return
+					}
+				}
 			}
+		}()
+
+		err = handler(srv, stream)
+		if revalidateErr := stream.getErr(); revalidateErr != nil {
+			return revalidateErr
 		}
 
-		return handler(ctx, req)
+		return err
+	}
+}
+
+// authServerStream carries the token payload in its context
+// and checks the owner of received messages.
+type authServerStream struct {
+	grpc.ServerStream
+
+	ctx     context.Context
+	policy  *methodPolicy
+	payload *authtoken.Payload
+
+	mu  sync.Mutex
+	err error
+}
+
+func (s *authServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *authServerStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err != nil {
+		return err
 	}
+
+	return s.policy.checkOwner(s.payload, m)
+}
+
+func (s *authServerStream) setErr(err error) {
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+func (s *authServerStream) getErr() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
 }
@@ -2,89 +2,110 @@ package user
 
 import (
 	"context"
-	"log"
-	"math/rand"
-	"strings"
-	"sync"
+	"crypto/rand"
+	"cryptowatch/pkg/util"
+	"fmt"
+	"math/big"
 	"time"
 )
 
 const (
 	codeLen = 6
-)
 
-func init() {
-	rand.Seed(time.Now().Unix())
-}
+	DefaultOTPTTL              = 5 * time.Minute
+	DefaultOTPMaxAttempts      = 5
+	DefaultOTPGenerateInterval = 30 * time.Second
+)
 
+// OTPManager manages one-time passwords used to link other clients, like the bot, to an account.
 type OTPManager interface {
+	// Add starts a new OTP request for the user.
 	Add(ctx context.Context, userID uint64) error
+	// Get issues the code of a pending request. A code is issued only once.
 	Get(ctx context.Context, userID uint64) (string, error)
 	Verify(ctx context.Context, userID uint64, code string) error
 }
 
-type inMemOTPManager struct {
-	codes map[uint64]string
-	mu    sync.RWMutex
+type OTPConfig struct {
+	// TTL is how long a request and its code are valid.
+	TTL time.Duration
+	// MaxAttempts is the number of failed verifications after which the code is locked.
+	MaxAttempts int
+	// GenerateInterval is the minimum time between two requests of a user.
+	GenerateInterval time.Duration
 }
 
-func NewInMemOTPManager() *inMemOTPManager {
-	return &inMemOTPManager{
-		codes: make(map[uint64]string),
-	}
+// otpManager keeps only hashes of codes in repo.
+type otpManager struct {
+	repo OTPRepository
+	cfg  OTPConfig
 }
 
-func (m *inMemOTPManager) Add(ctx context.Context, userID uint64) error {
-	m.mu.Lock()
-
-	code := m.generateCode()
-
-	m.codes[userID] = code
-
-	log.Printf("OTP: %v", m.codes)
+// NewOTPManager creates an OTP manager storing requests in repo.
+// Zero fields of cfg take default values.
+func NewOTPManager(repo OTPRepository, cfg OTPConfig) *otpManager {
+	if cfg.TTL == 0 {
+		cfg.TTL = DefaultOTPTTL
+	}
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = DefaultOTPMaxAttempts
+	}
+	if cfg.GenerateInterval == 0 {
+		cfg.GenerateInterval = DefaultOTPGenerateInterval
+	}
 
-	m.mu.Unlock()
+	return &otpManager{
+		repo: repo,
+		cfg:  cfg,
+	}
+}
 
-	return nil
+func (m *otpManager) Add(ctx context.Context, userID uint64) error {
+	now := time.Now()
+	return m.repo.CreateOTP(ctx, userID, now.Add(m.cfg.TTL), now.Add(-m.cfg.GenerateInterval))
 }
 
-func (m *inMemOTPManager) Get(ctx context.Context, userID uint64) (string, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+func (m *otpManager) Get(ctx context.Context, userID uint64) (string, error) {
+	code, err := generateCode()
+	if err != nil {
+		return "", ErrInternalError
+	}
+
+	codeHash, err := util.HashPassword(code)
+	if err != nil {
+		return "", ErrInternalError
+	}
 
-	code, ok := m.codes[userID]
-	if !ok {
-		return "", ErrNotFound
+	err = m.repo.IssueOTP(ctx, userID, codeHash)
+	if err != nil {
+		return "", err
 	}
 
 	return code, nil
 }
 
-func (m *inMemOTPManager) Verify(ctx context.Context, userID uint64, code string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	c, ok := m.codes[userID]
-	if !ok {
-		return ErrNotFound
+func (m *otpManager) Verify(ctx context.Context, userID uint64, code string) error {
+	// The attempt is counted before the check, so concurrent guesses can't exceed the limit.
+	codeHash, err := m.repo.UseOTPAttempt(ctx, userID, m.cfg.MaxAttempts)
+	if err != nil {
+		return err
 	}
 
-	if c != code {
+	err = util.CheckPassword(code, codeHash)
+	if err != nil {
 		return ErrNotFound
 	}
 
-	delete(m.codes, userID)
-	return nil
+	return m.repo.DeleteOTP(ctx, userID)
 }
 
-var digits = "0123456789"
+var maxCode = new(big.Int).Exp(big.NewInt(10), big.NewInt(codeLen), nil)
 
-func (m *inMemOTPManager) generateCode() string {
-	var result strings.Builder
-	for i := 0; i < codeLen; i++ {
-		j := rand.Intn(len(digits))
-		result.WriteByte(digits[j])
+func generateCode() (string, error) {
+	n, err := rand.Int(rand.Reader, maxCode)
+	if err != nil {
+		return "", err
 	}
 
-	return result.String()
+	return fmt.Sprintf("%0*d", codeLen, n), nil
 }
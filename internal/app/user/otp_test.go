@@ -0,0 +1,105 @@
+package user_test
+
+import (
+	"context"
+	"cryptowatch/internal/app/user"
+	"cryptowatch/internal/app/user/mock"
+	"cryptowatch/pkg/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestOTPManager_Add(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockOTPRepository(ctrl)
+	m := user.NewOTPManager(repo, user.OTPConfig{TTL: time.Minute, GenerateInterval: 30 * time.Second})
+
+	repo.EXPECT().
+		CreateOTP(gomock.Any(), uint64(1), gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, _ uint64, expireTime time.Time, notBefore time.Time) error {
+			assert.WithinDuration(t, time.Now().Add(time.Minute), expireTime, time.Second)
+			assert.WithinDuration(t, time.Now().Add(-30*time.Second), notBefore, time.Second)
+			return nil
+		})
+
+	require.NoError(t, m.Add(context.Background(), 1))
+}
+
+func TestOTPManager_Get(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockOTPRepository(ctrl)
+	m := user.NewOTPManager(repo, user.OTPConfig{})
+
+	var codeHash string
+	repo.EXPECT().
+		IssueOTP(gomock.Any(), uint64(1), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, _ uint64, hash string) error {
+			codeHash = hash
+			return nil
+		})
+
+	code, err := m.Get(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile("^[0-9]{6}$"), code)
+	assert.NotEqual(t, code, codeHash)
+	assert.NoError(t, util.CheckPassword(code, codeHash))
+}
+
+func TestOTPManager_Verify(t *testing.T) {
+	code := "123456"
+	codeHash, err := util.HashPassword(code)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		code       string
+		buildStubs func(repo *mock.MockOTPRepository)
+		err        error
+	}{
+		{
+			name: "OK",
+			code: code,
+			buildStubs: func(repo *mock.MockOTPRepository) {
+				repo.EXPECT().UseOTPAttempt(gomock.Any(), uint64(1), 3).Times(1).Return(codeHash, nil)
+				repo.EXPECT().DeleteOTP(gomock.Any(), uint64(1)).Times(1).Return(nil)
+			},
+		},
+		{
+			name: "Wrong code",
+			code: "654321",
+			buildStubs: func(repo *mock.MockOTPRepository) {
+				repo.EXPECT().UseOTPAttempt(gomock.Any(), uint64(1), 3).Times(1).Return(codeHash, nil)
+				repo.EXPECT().DeleteOTP(gomock.Any(), gomock.Any()).Times(0)
+			},
+			err: user.ErrNotFound,
+		},
+		{
+			name: "Locked",
+			code: code,
+			buildStubs: func(repo *mock.MockOTPRepository) {
+				repo.EXPECT().UseOTPAttempt(gomock.Any(), uint64(1), 3).Times(1).Return("", user.ErrResourceExhausted)
+				repo.EXPECT().DeleteOTP(gomock.Any(), gomock.Any()).Times(0)
+			},
+			err: user.ErrResourceExhausted,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock.NewMockOTPRepository(ctrl)
+			tt.buildStubs(repo)
+
+			m := user.NewOTPManager(repo, user.OTPConfig{MaxAttempts: 3})
+			err := m.Verify(context.Background(), 1, tt.code)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package user
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	DefaultPasswordMinLength      = 8
+	DefaultPasswordMinCharClasses = 2
+
+	// passwordMaxBytes is the most bcrypt hashes, the rest would be ignored.
+	passwordMaxBytes = 72
+)
+
+// PasswordPolicy lists the rules new passwords must satisfy.
+// Passwords on the bundled list of common and breached passwords are always rejected.
+type PasswordPolicy struct {
+	MinLength int
+	// MinCharClasses is how many of lowercase letters, uppercase letters, digits and symbols are required.
+	MinCharClasses int
+	// AllowUsername allows passwords containing the username.
+	AllowUsername bool
+}
+
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:      DefaultPasswordMinLength,
+	MinCharClasses: DefaultPasswordMinCharClasses,
+}
+
+// Check returns descriptions of the rules the password of the user breaks.
+func (p PasswordPolicy) Check(username string, password string) []string {
+	var problems []string
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+	if len(password) > passwordMaxBytes {
+		problems = append(problems, fmt.Sprintf("must be at most %d bytes", passwordMaxBytes))
+	}
+	if charClasses(password) < p.MinCharClasses {
+		problems = append(problems, fmt.Sprintf(
+			"must contain at least %d of lowercase letters, uppercase letters, digits and symbols",
+			p.MinCharClasses,
+		))
+	}
+	if !p.AllowUsername && username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		problems = append(problems, "must not contain the username")
+	}
+	if commonPasswords[strings.ToLower(password)] {
+		problems = append(problems, "is too common or appeared in a data breach")
+	}
+
+	return problems
+}
+
+func charClasses(password string) int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+
+	n := 0
+	for _, ok := range []bool{lower, upper, digit, other} {
+		if ok {
+			n++
+		}
+	}
+
+	return n
+}
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+var commonPasswords = parseCommonPasswords(commonPasswordsFile)
+
+func parseCommonPasswords(file string) map[string]bool {
+	passwords := make(map[string]bool)
+
+	sc := bufio.NewScanner(strings.NewReader(file))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		passwords[strings.ToLower(line)] = true
+	}
+
+	return passwords
+}
@@ -0,0 +1,80 @@
+package user_test
+
+import (
+	"cryptowatch/internal/app/user"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	policy := user.DefaultPasswordPolicy
+
+	tests := []struct {
+		name     string
+		password string
+		problems []string
+	}{
+		{
+			name:     "OK",
+			password: "c0rrect-h0rse",
+		},
+		{
+			name:     "Empty",
+			password: "",
+			problems: []string{
+				"must be at least 8 characters",
+				"must contain at least 2 of lowercase letters, uppercase letters, digits and symbols",
+			},
+		},
+		{
+			name:     "One class",
+			password: "correcthorse",
+			problems: []string{"must contain at least 2 of lowercase letters, uppercase letters, digits and symbols"},
+		},
+		{
+			name:     "Too long for bcrypt",
+			password: strings.Repeat("a1", 40),
+			problems: []string{"must be at most 72 bytes"},
+		},
+		{
+			name:     "Username",
+			password: "xAlice1990",
+			problems: []string{"must not contain the username"},
+		},
+		{
+			name:     "Common",
+			password: "Password123",
+			problems: []string{"is too common or appeared in a data breach"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.problems, policy.Check("alice", tt.password))
+		})
+	}
+
+	policy.AllowUsername = true
+	assert.Empty(t, policy.Check("alice", "xAlice1990"))
+}
+
+func TestErrToGRPCErr_ValidationError(t *testing.T) {
+	err := user.ErrToGRPCErr(&user.ValidationError{Violations: []user.FieldViolation{
+		{Field: "password", Description: "must be at least 8 characters"},
+	}})
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	if assert.Len(t, st.Details(), 1) {
+		br, ok := st.Details()[0].(*errdetails.BadRequest)
+		if assert.True(t, ok) {
+			assert.Equal(t, "password", br.GetFieldViolations()[0].GetField())
+			assert.Equal(t, "must be at least 8 characters", br.GetFieldViolations()[0].GetDescription())
+		}
+	}
+}
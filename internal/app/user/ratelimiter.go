@@ -0,0 +1,236 @@
+package user
+
+import (
+	"context"
+	pb "cryptowatch/pkg/api/cryptowatchv1"
+	"cryptowatch/pkg/ratelimit"
+	"fmt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"log"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimits are the limits of methods with the cryptowatch.rate_limit option.
+type RateLimits struct {
+	// IP limits calls of a client address to all such methods together.
+	IP ratelimit.Limit
+	// Key limits calls for a value of the key field, like attempts to log in as a user.
+	Key ratelimit.Limit
+	// TrustedProxies are networks of proxies, like the gateway, whose x-forwarded-for is the client address.
+	TrustedProxies []*net.IPNet
+}
+
+// RateLimiter limits calls of methods declaring the cryptowatch.rate_limit option.
+type RateLimiter struct {
+	store  ratelimit.Store
+	limits RateLimits
+
+	// methods maps rate limited methods to their key field, nil if they have none.
+	methods map[string]protoreflect.FieldDescriptor
+}
+
+// NewRateLimiter creates a rate limiter keeping buckets in store.
+// Methods must be loaded with Load before serving.
+func NewRateLimiter(store ratelimit.Store, limits RateLimits) *RateLimiter {
+	return &RateLimiter{
+		store:   store,
+		limits:  limits,
+		methods: make(map[string]protoreflect.FieldDescriptor),
+	}
+}
+
+// Load finds rate limited methods of the services, like Authorizer.Load.
+func (l *RateLimiter) Load(services map[string]grpc.ServiceInfo) error {
+	methods := make(map[string]protoreflect.FieldDescriptor)
+
+	for name := range services {
+		desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+		if err != nil {
+			return fmt.Errorf("find service %s: %w", name, err)
+		}
+		sd, ok := desc.(protoreflect.ServiceDescriptor)
+		if !ok {
+			return fmt.Errorf("%s is not a service", name)
+		}
+
+		for i := 0; i < sd.Methods().Len(); i++ {
+			md := sd.Methods().Get(i)
+			opts, ok := md.Options().(*descriptorpb.MethodOptions)
+			if !ok || opts == nil || !proto.HasExtension(opts, pb.E_RateLimit) {
+				continue
+			}
+			fullMethod := fmt.Sprintf("/%s/%s", sd.FullName(), md.Name())
+
+			var key protoreflect.FieldDescriptor
+			if name := proto.GetExtension(opts, pb.E_RateLimit).(*pb.RateLimit).GetKeyField(); name != "" {
+				key = md.Input().Fields().ByName(protoreflect.Name(name))
+				if key == nil || key.Kind() != protoreflect.StringKind || key.Cardinality() == protoreflect.Repeated {
+					return fmt.Errorf("%s: key field %q must be a string field of %s", fullMethod, name, md.Input().FullName())
+				}
+			}
+			methods[fullMethod] = key
+		}
+	}
+
+	l.methods = methods
+
+	return nil
+}
+
+// allow takes tokens for a call from the buckets of the client address and the key.
+func (l *RateLimiter) allow(ctx context.Context, method string, req interface{}) error {
+	key, ok := l.methods[method]
+	if !ok {
+		return nil
+	}
+
+	err := l.take(ctx, "ip:"+l.clientIP(ctx), l.limits.IP)
+	if err != nil {
+		return err
+	}
+
+	if key == nil {
+		return nil
+	}
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return nil
+	}
+	value := strings.ToLower(msg.ProtoReflect().Get(key).String())
+	if value == "" {
+		return nil
+	}
+
+	return l.take(ctx, "key:"+value, l.limits.Key)
+}
+
+// clientIP returns the peer address or, if the peer is a trusted proxy, the address it forwards.
+// Unlike the x-forwarded-for metadata alone it can't be spoofed by clients.
+func (l *RateLimiter) clientIP(ctx context.Context) string {
+	return clientIP(ctx, l.limits.TrustedProxies)
+}
+
+// clientIP returns the peer address or, if the peer is in trustedProxies, the rightmost
+// x-forwarded-for address that isn't a trusted proxy. Proxies append the address they see,
+// so the addresses left of it may be made up by the client.
+func clientIP(ctx context.Context, trustedProxies []*net.IPNet) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	ip := p.Addr.String()
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	var hops []string
+	for _, v := range md.Get("x-forwarded-for") {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0 && trusted(trustedProxies, net.ParseIP(ip)); i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		ip = hop
+	}
+
+	return ip
+}
+
+func trusted(networks []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range networks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseNetworks parses a comma separated list of CIDR networks.
+func ParseNetworks(s string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, cidr := range strings.Split(s, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, n)
+	}
+
+	return networks, nil
+}
+
+func (l *RateLimiter) take(ctx context.Context, key string, limit ratelimit.Limit) error {
+	res, err := l.store.Take(ctx, key, limit)
+	if err != nil {
+		// An unavailable store shouldn't lock everybody out.
+		log.Printf("rate limit %s: %v", key, err)
+		return nil
+	}
+	if !res.Allowed {
+		return retryErrToGRPCErr(&RetryError{Err: ErrResourceExhausted, After: res.RetryAfter})
+	}
+
+	return nil
+}
+
+// RateLimitUnaryInterceptor rejects calls over the limits of rate limiter with ResourceExhausted.
+// Such errors, from the limiter or a handler, carry a RetryInfo detail and a retry-after header in seconds.
+func RateLimitUnaryInterceptor(limiter *RateLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		err := limiter.allow(ctx, info.FullMethod, req)
+		if err == nil {
+			var res interface{}
+			res, err = handler(ctx, req)
+			if err == nil {
+				return res, nil
+			}
+		}
+
+		if after, ok := retryAfter(err); ok {
+			seconds := int(math.Ceil(after.Seconds()))
+			_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(seconds)))
+		}
+
+		return nil, err
+	}
+}
+
+// retryAfter returns the delay of the RetryInfo detail of err.
+func retryAfter(err error) (time.Duration, bool) {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.ResourceExhausted {
+		return 0, false
+	}
+
+	for _, d := range st.Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok {
+			return ri.GetRetryDelay().AsDuration(), true
+		}
+	}
+
+	return 0, false
+}
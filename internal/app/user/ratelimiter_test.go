@@ -0,0 +1,78 @@
+package user_test
+
+import (
+	"context"
+	"cryptowatch/internal/app/user"
+	pb "cryptowatch/pkg/api/cryptowatchv1"
+	"cryptowatch/pkg/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRateLimitUnaryInterceptor(t *testing.T) {
+	srv := grpc.NewServer()
+	pb.RegisterUsersServer(srv, &pb.UnimplementedUsersServer{})
+
+	proxies, err := user.ParseNetworks("10.0.0.0/8")
+	require.NoError(t, err)
+
+	limiter := user.NewRateLimiter(ratelimit.NewMemoryStore(), user.RateLimits{
+		IP:             ratelimit.Limit{Burst: 3, Interval: time.Minute},
+		Key:            ratelimit.Limit{Burst: 2, Interval: time.Minute},
+		TrustedProxies: proxies,
+	})
+	require.NoError(t, limiter.Load(srv.GetServiceInfo()))
+	interceptor := user.RateLimitUnaryInterceptor(limiter)
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return req, nil
+	}
+	call := func(addr string, forwardedFor string, method string, req interface{}) error {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(addr), Port: 1234}})
+		if forwardedFor != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-forwarded-for", forwardedFor))
+		}
+		_, err := interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	login := "/cryptowatch.Users/Login"
+
+	// Limited by username.
+	assert.NoError(t, call("1.1.1.1", "", login, &pb.LoginReq{Username: "alice"}))
+	assert.NoError(t, call("1.1.1.2", "", login, &pb.LoginReq{Username: "Alice"}))
+	err = call("1.1.1.3", "", login, &pb.LoginReq{Username: "alice"})
+	st := status.Convert(err)
+	require.Equal(t, codes.ResourceExhausted, st.Code())
+	require.Len(t, st.Details(), 1)
+	ri, ok := st.Details()[0].(*errdetails.RetryInfo)
+	require.True(t, ok)
+	assert.InDelta(t, float64(time.Minute), float64(ri.GetRetryDelay().AsDuration()), float64(time.Second))
+
+	// Limited by address, whatever the client forwards.
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, call("2.2.2.2", "9.9.9.9", "/cryptowatch.Users/CreateUser", &pb.CreateUserReq{}))
+	}
+	assert.Equal(t, codes.ResourceExhausted, status.Code(call("2.2.2.2", "8.8.8.8", "/cryptowatch.Users/CreateUser", &pb.CreateUserReq{})))
+
+	// Trusted proxies forward the client address.
+	assert.NoError(t, call("10.0.0.1", "2.2.2.2, 3.3.3.3", "/cryptowatch.Users/CreateUser", &pb.CreateUserReq{}))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(call("10.0.0.1", "9.9.9.9, 2.2.2.2", "/cryptowatch.Users/CreateUser", &pb.CreateUserReq{})))
+	// Addresses of trusted proxies are skipped, the client made up those left of its own.
+	assert.Equal(t, codes.ResourceExhausted, status.Code(call("10.0.0.1", "2.2.2.2, 10.0.0.2", "/cryptowatch.Users/CreateUser", &pb.CreateUserReq{})))
+	assert.NoError(t, call("10.0.0.1", "2.2.2.2, 6.6.6.6, 10.0.0.2", "/cryptowatch.Users/CreateUser", &pb.CreateUserReq{}))
+
+	// Other methods aren't limited.
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, call("2.2.2.2", "", "/cryptowatch.Users/RevokeSession", &pb.RevokeSessionReq{}))
+	}
+}
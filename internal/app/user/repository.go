@@ -1,16 +1,168 @@
-//go:generate mockgen -destination=mock/user.go -package=mock . Repository
+//go:generate mockgen -destination=mock/user.go -package=mock . Repository,OTPRepository
 package user
 
-import "context"
+import (
+	"context"
+	"github.com/google/uuid"
+	"time"
+)
 
 type Repository interface {
 	Create(ctx context.Context, req RepoCreateReq) (*User, error)
 	GetByUsername(ctx context.Context, username string) (*User, error)
+	GetByID(ctx context.Context, id uint64) (*User, error)
+	// UpdatePassword sets the password hash of the user and revokes the user's sessions
+	// except keepSessionID. It returns ids of the revoked sessions.
+	UpdatePassword(ctx context.Context, userID uint64, passwordHash string, keepSessionID uuid.UUID) ([]uuid.UUID, error)
+	// UpdateProfile sets the non-empty names of the request.
+	UpdateProfile(ctx context.Context, req RepoUpdateProfileReq) (*User, error)
+	// Delete removes the user with everything the user owns and returns ids of the user's
+	// active sessions and API keys.
+	Delete(ctx context.Context, userID uint64) ([]uuid.UUID, error)
+	// ListUsers returns users matching the request ordered by id.
+	ListUsers(ctx context.Context, req RepoListUsersReq) ([]*User, error)
+	// SetRole sets the role of the user and revokes the user's sessions, whose tokens carry the old role.
+	// It returns ids of the revoked sessions.
+	SetRole(ctx context.Context, userID uint64, role Role) ([]uuid.UUID, error)
+	// Disable locks the user out, revoking the user's sessions and API keys, and returns their ids.
+	Disable(ctx context.Context, userID uint64) ([]uuid.UUID, error)
+	Enable(ctx context.Context, userID uint64) error
+
+	// CreateWithIdentity creates a user logging in with the identity. It fails with
+	// ErrFailedPrecondition if the username is taken or the identity is linked already.
+	CreateWithIdentity(ctx context.Context, user RepoCreateReq, identity RepoCreateIdentityReq) (*User, error)
+	GetIdentity(ctx context.Context, issuer string, subject string) (*Identity, error)
+	// CreateIdentity links the identity to a user. It fails with ErrFailedPrecondition
+	// if the identity is linked already.
+	CreateIdentity(ctx context.Context, req RepoCreateIdentityReq) (*Identity, error)
+	ListIdentities(ctx context.Context, userID uint64) ([]*Identity, error)
+	DeleteIdentity(ctx context.Context, userID uint64, issuer string, subject string) error
+
+	CreateSession(ctx context.Context, req RepoCreateSessionReq) (*Session, error)
+	// GetSessionByRefreshTokenHash returns the session whose current or previous refresh token has the hash.
+	GetSessionByRefreshTokenHash(ctx context.Context, hash string) (*Session, error)
+	// RotateRefreshToken replaces the refresh token of an active session if it still is oldHash.
+	RotateRefreshToken(ctx context.Context, req RepoRotateRefreshTokenReq) (*Session, error)
+	ListSessions(ctx context.Context, userID uint64) ([]*Session, error)
+	RevokeSession(ctx context.Context, userID uint64, id uuid.UUID) error
+	// RevokeUserSessions revokes all sessions of the user and returns their ids.
+	RevokeUserSessions(ctx context.Context, userID uint64) ([]uuid.UUID, error)
+	// ListRevokedSessions returns ids of sessions and API keys revoked after since.
+	ListRevokedSessions(ctx context.Context, since time.Time) ([]uuid.UUID, error)
+
+	CreateAPIKey(ctx context.Context, req RepoCreateAPIKeyReq) (*APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error)
+	// ListAPIKeys returns unrevoked keys of the user, expired ones included.
+	ListAPIKeys(ctx context.Context, userID uint64) ([]*APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID uint64, id uuid.UUID) error
+	// TouchAPIKey sets the last use time of the key, at most once a minute.
+	TouchAPIKey(ctx context.Context, id uuid.UUID) error
+
+	// CreateTOTP sets a pending secret of the user. It fails with ErrFailedPrecondition
+	// if the user already has a confirmed one.
+	CreateTOTP(ctx context.Context, userID uint64, secret string) error
+	GetTOTP(ctx context.Context, userID uint64) (*TOTP, error)
+	// ConfirmTOTP enables a pending secret, accepting its code of step,
+	// and replaces recovery codes of the user.
+	ConfirmTOTP(ctx context.Context, userID uint64, step int64, recoveryCodeHashes []string) error
+	// UseTOTPStep accepts a code of step of a confirmed secret. It fails with ErrNotFound
+	// if a code of the same or a later step was accepted already.
+	UseTOTPStep(ctx context.Context, userID uint64, step int64) error
+	// UseRecoveryCode marks an unused recovery code used.
+	UseRecoveryCode(ctx context.Context, userID uint64, codeHash string) error
+	// DeleteTOTP removes the secret and recovery codes of the user.
+	DeleteTOTP(ctx context.Context, userID uint64) error
+
+	GetLoginFailures(ctx context.Context, userID uint64) (*LoginFailures, error)
+	// RecordLoginFailure counts a failed login and returns the failures. Failures
+	// are counted from one again if the last one was before resetBefore.
+	RecordLoginFailure(ctx context.Context, userID uint64, resetBefore time.Time) (*LoginFailures, error)
+	LockLogin(ctx context.Context, userID uint64, until time.Time) error
+	ResetLoginFailures(ctx context.Context, userID uint64) error
+
+	CreateLoginChallenge(ctx context.Context, req RepoCreateLoginChallengeReq) error
+	// UseLoginChallengeAttempt counts an attempt of an unexpired challenge and returns it.
+	// It fails with ErrNotFound once maxAttempts were used.
+	UseLoginChallengeAttempt(ctx context.Context, tokenHash string, maxAttempts int) (*LoginChallenge, error)
+	DeleteLoginChallenge(ctx context.Context, tokenHash string) error
 }
 
 type RepoCreateReq struct {
-	Username     string `json:"username" validate:"required"`
-	PasswordHash string `json:"password_hash" validate:"required"`
+	Username string `json:"username" validate:"required"`
+	// PasswordHash is empty for users who log in with an OpenID provider only.
+	PasswordHash string `json:"password_hash"`
 	FirstName    string `json:"first_name" validate:"required"`
 	LastName     string `json:"last_name" validate:"required"`
 }
+
+type RepoCreateIdentityReq struct {
+	Issuer  string `json:"issuer" validate:"required"`
+	Subject string `json:"subject" validate:"required"`
+	// UserID is set by CreateWithIdentity to the created user.
+	UserID uint64 `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+type RepoUpdateProfileReq struct {
+	ID        uint64 `json:"id" validate:"required"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+type RepoListUsersReq struct {
+	// Query is text the username or a name contains, any user matches if empty.
+	Query string `json:"query"`
+	// Role limits users to the role if set.
+	Role Role `json:"role"`
+	// AfterID skips users up to the id, the last one of the previous page.
+	AfterID uint64 `json:"after_id"`
+	Limit   int    `json:"limit" validate:"required"`
+}
+
+type RepoCreateSessionReq struct {
+	ID               uuid.UUID `json:"id" validate:"required"`
+	UserID           uint64    `json:"user_id" validate:"required"`
+	RefreshTokenHash string    `json:"refresh_token_hash" validate:"required"`
+	UserAgent        string    `json:"user_agent"`
+	ClientIP         string    `json:"client_ip"`
+	Scopes           []string  `json:"scopes"`
+	ExpireTime       time.Time `json:"expire_time" validate:"required"`
+}
+
+type RepoRotateRefreshTokenReq struct {
+	ID         uuid.UUID `json:"id" validate:"required"`
+	OldHash    string    `json:"old_hash" validate:"required"`
+	NewHash    string    `json:"new_hash" validate:"required"`
+	ExpireTime time.Time `json:"expire_time" validate:"required"`
+}
+
+type RepoCreateAPIKeyReq struct {
+	ID         uuid.UUID  `json:"id" validate:"required"`
+	UserID     uint64     `json:"user_id" validate:"required"`
+	Name       string     `json:"name" validate:"required"`
+	Prefix     string     `json:"prefix" validate:"required"`
+	KeyHash    string     `json:"key_hash" validate:"required"`
+	Scopes     []string   `json:"scopes"`
+	ExpireTime *time.Time `json:"expire_time"`
+}
+
+type RepoCreateLoginChallengeReq struct {
+	TokenHash  string    `json:"token_hash" validate:"required"`
+	UserID     uint64    `json:"user_id" validate:"required"`
+	UserAgent  string    `json:"user_agent"`
+	ClientIP   string    `json:"client_ip"`
+	ExpireTime time.Time `json:"expire_time" validate:"required"`
+}
+
+// OTPRepository stores one-time password requests, at most one per user.
+type OTPRepository interface {
+	// CreateOTP starts a new request without a code, replacing the previous one
+	// unless it was created after notBefore, then it fails with ErrResourceExhausted.
+	CreateOTP(ctx context.Context, userID uint64, expireTime time.Time, notBefore time.Time) error
+	// IssueOTP sets the code of a pending request that has none yet.
+	IssueOTP(ctx context.Context, userID uint64, codeHash string) error
+	// UseOTPAttempt counts a verification attempt and returns the code hash. It fails
+	// with ErrResourceExhausted once maxAttempts were used.
+	UseOTPAttempt(ctx context.Context, userID uint64, maxAttempts int) (string, error)
+	DeleteOTP(ctx context.Context, userID uint64) error
+}
@@ -4,15 +4,33 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/google/uuid"
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"strings"
+	"time"
 )
 
 const (
-	usersTable = "users"
+	usersTable    = "users"
+	sessionsTable = "sessions"
+	apiKeysTable  = "api_keys"
+	otpCodesTable = "otp_codes"
+
+	totpSecretsTable     = "totp_secrets"
+	recoveryCodesTable   = "recovery_codes"
+	loginChallengesTable = "login_challenges"
+	loginFailuresTable   = "login_failures"
+	userIdentitiesTable  = "user_identities"
 )
 
+type DBTX interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+}
+
 type postgresRepo struct {
 	db *pgxpool.Pool
 }
@@ -23,24 +41,36 @@ func NewPostgresRepo(db *pgxpool.Pool) *postgresRepo {
 	}
 }
 
+const userColumns = `id, username, password_hash, first_name, last_name, create_time, role, disable_time`
+
+func scanUser(row pgx.Row) (*User, error) {
+	var u User
+	err := row.Scan(
+		&u.ID,
+		&u.Username,
+		&u.PasswordHash,
+		&u.FirstName,
+		&u.LastName,
+		&u.CreateTime,
+		&u.Role,
+		&u.DisableTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &u, nil
+}
+
 var createQuery = fmt.Sprintf(`
 INSERT INTO %s
 (username, password_hash, first_name, last_name)
 VALUES ($1, $2, $3, $4)
-RETURNING id, username, password_hash, first_name, last_name, create_time
-`, usersTable)
+RETURNING %s
+`, usersTable, userColumns)
 
 func (r *postgresRepo) Create(ctx context.Context, req RepoCreateReq) (*User, error) {
-	var newUser User
-	err := r.db.QueryRow(ctx, createQuery, req.Username, req.PasswordHash, req.FirstName, req.LastName).
-		Scan(
-			&newUser.ID,
-			&newUser.Username,
-			&newUser.PasswordHash,
-			&newUser.FirstName,
-			&newUser.LastName,
-			&newUser.CreateTime,
-		)
+	newUser, err := scanUser(r.db.QueryRow(ctx, createQuery, req.Username, req.PasswordHash, req.FirstName, req.LastName))
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
@@ -54,25 +84,963 @@ func (r *postgresRepo) Create(ctx context.Context, req RepoCreateReq) (*User, er
 		return nil, ErrInternalError
 	}
 
-	return &newUser, nil
+	return newUser, nil
 }
 
 var getByUsernameQuery = fmt.Sprintf(`
-SELECT id, username, password_hash, first_name, last_name, create_time
+SELECT %s
 FROM %s
 WHERE username = $1
-`, usersTable)
+`, userColumns, usersTable)
 
 func (r *postgresRepo) GetByUsername(ctx context.Context, username string) (*User, error) {
-	var u User
-	err := r.db.QueryRow(ctx, getByUsernameQuery, username).
+	u, err := scanUser(r.db.QueryRow(ctx, getByUsernameQuery, username))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, ErrInternalError
+	}
+
+	return u, nil
+}
+
+var getByIDQuery = fmt.Sprintf(`
+SELECT %s
+FROM %s
+WHERE id = $1
+`, userColumns, usersTable)
+
+func (r *postgresRepo) GetByID(ctx context.Context, id uint64) (*User, error) {
+	u, err := scanUser(r.db.QueryRow(ctx, getByIDQuery, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, ErrInternalError
+	}
+
+	return u, nil
+}
+
+var updatePasswordQuery = fmt.Sprintf(`
+UPDATE %s
+SET password_hash = $2
+WHERE id = $1
+`, usersTable)
+
+var revokeOtherSessionsQuery = fmt.Sprintf(`
+UPDATE %s
+SET revoke_time = current_timestamp
+WHERE user_id = $1 AND
+	id <> $2 AND
+	revoke_time IS NULL
+RETURNING id::text
+`, sessionsTable)
+
+var deleteUserLoginChallengesQuery = fmt.Sprintf(`
+DELETE FROM %s
+WHERE user_id = $1
+`, loginChallengesTable)
+
+func (r *postgresRepo) UpdatePassword(ctx context.Context, userID uint64, passwordHash string, keepSessionID uuid.UUID) ([]uuid.UUID, error) {
+	var revoked []uuid.UUID
+	err := r.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		cmd, err := tx.Exec(ctx, updatePasswordQuery, userID, passwordHash)
+		if err != nil {
+			return ErrInternalError
+		}
+		if cmd.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+
+		revoked, err = queryIDs(ctx, tx, revokeOtherSessionsQuery, userID, keepSessionID.String())
+		if err != nil {
+			return err
+		}
+
+		// Logins waiting for the second factor were started with the old password.
+		_, err = tx.Exec(ctx, deleteUserLoginChallengesQuery, userID)
+		if err != nil {
+			return ErrInternalError
+		}
+
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, ErrInternalError
+	}
+
+	return revoked, nil
+}
+
+var updateProfileQuery = fmt.Sprintf(`
+UPDATE %s
+SET
+	first_name = coalesce(nullif($2, ''), first_name),
+	last_name = coalesce(nullif($3, ''), last_name)
+WHERE id = $1
+RETURNING %s
+`, usersTable, userColumns)
+
+func (r *postgresRepo) UpdateProfile(ctx context.Context, req RepoUpdateProfileReq) (*User, error) {
+	u, err := scanUser(r.db.QueryRow(ctx, updateProfileQuery, req.ID, req.FirstName, req.LastName))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, ErrInternalError
+	}
+
+	return u, nil
+}
+
+var activeSessionIDsQuery = fmt.Sprintf(`
+SELECT id::text
+FROM %s
+WHERE user_id = $1 AND
+	revoke_time IS NULL AND
+	expire_time > current_timestamp
+UNION ALL
+SELECT id::text
+FROM %s
+WHERE user_id = $1 AND revoke_time IS NULL
+`, sessionsTable, apiKeysTable)
+
+// Portfolios with their transactions, triggers, linked Telegram chats
+// and everything else referencing the user are removed by ON DELETE CASCADE.
+var deleteUserQuery = fmt.Sprintf(`
+DELETE FROM %s
+WHERE id = $1
+`, usersTable)
+
+func (r *postgresRepo) Delete(ctx context.Context, userID uint64) ([]uuid.UUID, error) {
+	var sessions []uuid.UUID
+	err := r.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var err error
+		sessions, err = queryIDs(ctx, tx, activeSessionIDsQuery, userID)
+		if err != nil {
+			return err
+		}
+
+		cmd, err := tx.Exec(ctx, deleteUserQuery, userID)
+		if err != nil {
+			return ErrInternalError
+		}
+		if cmd.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, ErrInternalError
+	}
+
+	return sessions, nil
+}
+
+// listUsersQuery pages users by id. $1 is a pattern matching the username or names.
+var listUsersQuery = fmt.Sprintf(`
+SELECT %s
+FROM %s
+WHERE id > $3 AND
+	($1 = '' OR username ILIKE $1 OR first_name ILIKE $1 OR last_name ILIKE $1) AND
+	($2 = '' OR role = $2)
+ORDER BY id
+LIMIT $4
+`, userColumns, usersTable)
+
+// likeEscaper escapes LIKE wildcards, so searched text matches literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (r *postgresRepo) ListUsers(ctx context.Context, req RepoListUsersReq) ([]*User, error) {
+	var pattern string
+	if req.Query != "" {
+		pattern = "%" + likeEscaper.Replace(req.Query) + "%"
+	}
+
+	rows, err := r.db.Query(ctx, listUsersQuery, pattern, string(req.Role), req.AfterID, req.Limit)
+	if err != nil {
+		return nil, ErrInternalError
+	}
+	defer rows.Close()
+
+	users := make([]*User, 0)
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, ErrInternalError
+		}
+		users = append(users, u)
+	}
+	if rows.Err() != nil {
+		return nil, ErrInternalError
+	}
+
+	return users, nil
+}
+
+var revokeUserSessionsQuery = fmt.Sprintf(`
+UPDATE %s
+SET revoke_time = current_timestamp
+WHERE user_id = $1 AND revoke_time IS NULL
+RETURNING id::text
+`, sessionsTable)
+
+var revokeUserAPIKeysQuery = fmt.Sprintf(`
+UPDATE %s
+SET revoke_time = current_timestamp
+WHERE user_id = $1 AND revoke_time IS NULL
+RETURNING id::text
+`, apiKeysTable)
+
+func (r *postgresRepo) RevokeUserSessions(ctx context.Context, userID uint64) ([]uuid.UUID, error) {
+	return queryIDs(ctx, r.db, revokeUserSessionsQuery, userID)
+}
+
+var setRoleQuery = fmt.Sprintf(`
+UPDATE %s
+SET role = $2
+WHERE id = $1
+`, usersTable)
+
+func (r *postgresRepo) SetRole(ctx context.Context, userID uint64, role Role) ([]uuid.UUID, error) {
+	var revoked []uuid.UUID
+	err := r.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		cmd, err := tx.Exec(ctx, setRoleQuery, userID, string(role))
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.ConstraintName == "users_role_valid" {
+				return ErrInvalidArgument
+			}
+			return ErrInternalError
+		}
+		if cmd.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+
+		// Access tokens carry the role, so sessions with the old one end.
+		revoked, err = queryIDs(ctx, tx, revokeUserSessionsQuery, userID)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrInvalidArgument) {
+			return nil, err
+		}
+		return nil, ErrInternalError
+	}
+
+	return revoked, nil
+}
+
+var disableUserQuery = fmt.Sprintf(`
+UPDATE %s
+SET disable_time = coalesce(disable_time, current_timestamp)
+WHERE id = $1
+`, usersTable)
+
+var enableUserQuery = fmt.Sprintf(`
+UPDATE %s
+SET disable_time = NULL
+WHERE id = $1
+`, usersTable)
+
+func (r *postgresRepo) Disable(ctx context.Context, userID uint64) ([]uuid.UUID, error) {
+	var revoked []uuid.UUID
+	err := r.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		cmd, err := tx.Exec(ctx, disableUserQuery, userID)
+		if err != nil {
+			return ErrInternalError
+		}
+		if cmd.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+
+		revoked, err = queryIDs(ctx, tx, revokeUserSessionsQuery, userID)
+		if err != nil {
+			return err
+		}
+		keys, err := queryIDs(ctx, tx, revokeUserAPIKeysQuery, userID)
+		if err != nil {
+			return err
+		}
+		revoked = append(revoked, keys...)
+
+		_, err = tx.Exec(ctx, deleteUserLoginChallengesQuery, userID)
+		if err != nil {
+			return ErrInternalError
+		}
+
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, ErrInternalError
+	}
+
+	return revoked, nil
+}
+
+func (r *postgresRepo) Enable(ctx context.Context, userID uint64) error {
+	cmd, err := r.db.Exec(ctx, enableUserQuery, userID)
+	if err != nil {
+		return ErrInternalError
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *postgresRepo) CreateWithIdentity(ctx context.Context, user RepoCreateReq, identity RepoCreateIdentityReq) (*User, error) {
+	var newUser *User
+	err := r.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var err error
+		newUser, err = scanUser(tx.QueryRow(ctx, createQuery, user.Username, user.PasswordHash, user.FirstName, user.LastName))
+		if err != nil {
+			return err
+		}
+
+		identity.UserID = newUser.ID
+		_, err = scanIdentity(tx.QueryRow(ctx, createIdentityQuery, identity.Issuer, identity.Subject, identity.UserID, identity.Email))
+		return err
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.ConstraintName {
+			case "users_username_key", "user_identities_pkey":
+				return nil, ErrFailedPrecondition
+			case "users_username_valid":
+				return nil, ErrInvalidArgument
+			}
+		}
+		return nil, ErrInternalError
+	}
+
+	return newUser, nil
+}
+
+const identityColumns = `issuer, subject, user_id, email, create_time`
+
+func scanIdentity(row pgx.Row) (*Identity, error) {
+	var i Identity
+	err := row.Scan(
+		&i.Issuer,
+		&i.Subject,
+		&i.UserID,
+		&i.Email,
+		&i.CreateTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &i, nil
+}
+
+var getIdentityQuery = fmt.Sprintf(`
+SELECT %s
+FROM %s
+WHERE issuer = $1 AND subject = $2
+`, identityColumns, userIdentitiesTable)
+
+func (r *postgresRepo) GetIdentity(ctx context.Context, issuer string, subject string) (*Identity, error) {
+	i, err := scanIdentity(r.db.QueryRow(ctx, getIdentityQuery, issuer, subject))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, ErrInternalError
+	}
+
+	return i, nil
+}
+
+var createIdentityQuery = fmt.Sprintf(`
+INSERT INTO %s
+(issuer, subject, user_id, email)
+VALUES ($1, $2, $3, $4)
+RETURNING %s
+`, userIdentitiesTable, identityColumns)
+
+func (r *postgresRepo) CreateIdentity(ctx context.Context, req RepoCreateIdentityReq) (*Identity, error) {
+	i, err := scanIdentity(r.db.QueryRow(ctx, createIdentityQuery, req.Issuer, req.Subject, req.UserID, req.Email))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.ConstraintName {
+			case "user_identities_pkey":
+				return nil, ErrFailedPrecondition
+			case "user_identities_user_id_fkey":
+				return nil, ErrNotFound
+			}
+		}
+		return nil, ErrInternalError
+	}
+
+	return i, nil
+}
+
+var listIdentitiesQuery = fmt.Sprintf(`
+SELECT %s
+FROM %s
+WHERE user_id = $1
+ORDER BY create_time
+`, identityColumns, userIdentitiesTable)
+
+func (r *postgresRepo) ListIdentities(ctx context.Context, userID uint64) ([]*Identity, error) {
+	rows, err := r.db.Query(ctx, listIdentitiesQuery, userID)
+	if err != nil {
+		return nil, ErrInternalError
+	}
+	defer rows.Close()
+
+	identities := make([]*Identity, 0)
+	for rows.Next() {
+		i, err := scanIdentity(rows)
+		if err != nil {
+			return nil, ErrInternalError
+		}
+		identities = append(identities, i)
+	}
+	if rows.Err() != nil {
+		return nil, ErrInternalError
+	}
+
+	return identities, nil
+}
+
+var deleteIdentityQuery = fmt.Sprintf(`
+DELETE FROM %s
+WHERE user_id = $1 AND issuer = $2 AND subject = $3
+`, userIdentitiesTable)
+
+func (r *postgresRepo) DeleteIdentity(ctx context.Context, userID uint64, issuer string, subject string) error {
+	cmd, err := r.db.Exec(ctx, deleteIdentityQuery, userID, issuer, subject)
+	if err != nil {
+		return ErrInternalError
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+const sessionColumns = `id::text, user_id, refresh_token_hash, coalesce(previous_refresh_token_hash, ''),
+user_agent, client_ip, scopes, create_time, refresh_time, expire_time, revoke_time`
+
+func scanSession(row pgx.Row) (*Session, error) {
+	var sess Session
+	var id string
+	err := row.Scan(
+		&id,
+		&sess.UserID,
+		&sess.RefreshTokenHash,
+		&sess.PreviousRefreshTokenHash,
+		&sess.UserAgent,
+		&sess.ClientIP,
+		&sess.Scopes,
+		&sess.CreateTime,
+		&sess.RefreshTime,
+		&sess.ExpireTime,
+		&sess.RevokeTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sess.ID, err = uuid.Parse(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sess, nil
+}
+
+var createSessionQuery = fmt.Sprintf(`
+INSERT INTO %s
+(id, user_id, refresh_token_hash, user_agent, client_ip, scopes, expire_time)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING %s
+`, sessionsTable, sessionColumns)
+
+func (r *postgresRepo) CreateSession(ctx context.Context, req RepoCreateSessionReq) (*Session, error) {
+	scopes := req.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	sess, err := scanSession(r.db.QueryRow(
+		ctx,
+		createSessionQuery,
+		req.ID.String(),
+		req.UserID,
+		req.RefreshTokenHash,
+		req.UserAgent,
+		req.ClientIP,
+		scopes,
+		req.ExpireTime,
+	))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.ConstraintName == "sessions_user_id_fkey" {
+			return nil, ErrNotFound
+		}
+		return nil, ErrInternalError
+	}
+
+	return sess, nil
+}
+
+var getSessionByRefreshTokenHashQuery = fmt.Sprintf(`
+SELECT %s
+FROM %s
+WHERE refresh_token_hash = $1 OR previous_refresh_token_hash = $1
+LIMIT 1
+`, sessionColumns, sessionsTable)
+
+func (r *postgresRepo) GetSessionByRefreshTokenHash(ctx context.Context, hash string) (*Session, error) {
+	sess, err := scanSession(r.db.QueryRow(ctx, getSessionByRefreshTokenHashQuery, hash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, ErrInternalError
+	}
+
+	return sess, nil
+}
+
+var rotateRefreshTokenQuery = fmt.Sprintf(`
+UPDATE %s
+SET
+	previous_refresh_token_hash = refresh_token_hash,
+	refresh_token_hash = $3,
+	refresh_time = current_timestamp,
+	expire_time = $4
+WHERE id = $1 AND
+	refresh_token_hash = $2 AND
+	revoke_time IS NULL AND
+	expire_time > current_timestamp
+RETURNING %s
+`, sessionsTable, sessionColumns)
+
+func (r *postgresRepo) RotateRefreshToken(ctx context.Context, req RepoRotateRefreshTokenReq) (*Session, error) {
+	sess, err := scanSession(r.db.QueryRow(
+		ctx,
+		rotateRefreshTokenQuery,
+		req.ID.String(),
+		req.OldHash,
+		req.NewHash,
+		req.ExpireTime,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, ErrInternalError
+	}
+
+	return sess, nil
+}
+
+var listSessionsQuery = fmt.Sprintf(`
+SELECT %s
+FROM %s
+WHERE user_id = $1 AND
+	revoke_time IS NULL AND
+	expire_time > current_timestamp
+ORDER BY create_time
+`, sessionColumns, sessionsTable)
+
+func (r *postgresRepo) ListSessions(ctx context.Context, userID uint64) ([]*Session, error) {
+	rows, err := r.db.Query(ctx, listSessionsQuery, userID)
+	if err != nil {
+		return nil, ErrInternalError
+	}
+	defer rows.Close()
+
+	sessions := make([]*Session, 0)
+	for rows.Next() {
+		sess, err := scanSession(rows)
+		if err != nil {
+			return nil, ErrInternalError
+		}
+		sessions = append(sessions, sess)
+	}
+	if rows.Err() != nil {
+		return nil, ErrInternalError
+	}
+
+	return sessions, nil
+}
+
+var revokeSessionQuery = fmt.Sprintf(`
+UPDATE %s
+SET revoke_time = current_timestamp
+WHERE id = $1 AND user_id = $2 AND revoke_time IS NULL
+`, sessionsTable)
+
+func (r *postgresRepo) RevokeSession(ctx context.Context, userID uint64, id uuid.UUID) error {
+	cmd, err := r.db.Exec(ctx, revokeSessionQuery, id.String(), userID)
+	if err != nil {
+		return ErrInternalError
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+var listRevokedSessionsQuery = fmt.Sprintf(`
+SELECT id::text
+FROM %s
+WHERE revoke_time > $1
+UNION ALL
+SELECT id::text
+FROM %s
+WHERE revoke_time > $1
+`, sessionsTable, apiKeysTable)
+
+func (r *postgresRepo) ListRevokedSessions(ctx context.Context, since time.Time) ([]uuid.UUID, error) {
+	return queryIDs(ctx, r.db, listRevokedSessionsQuery, since)
+}
+
+const apiKeyColumns = `id::text, user_id, name, prefix, key_hash, scopes,
+create_time, expire_time, last_used_time, revoke_time`
+
+func scanAPIKey(row pgx.Row) (*APIKey, error) {
+	var k APIKey
+	var id string
+	err := row.Scan(
+		&id,
+		&k.UserID,
+		&k.Name,
+		&k.Prefix,
+		&k.KeyHash,
+		&k.Scopes,
+		&k.CreateTime,
+		&k.ExpireTime,
+		&k.LastUsedTime,
+		&k.RevokeTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	k.ID, err = uuid.Parse(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &k, nil
+}
+
+var createAPIKeyQuery = fmt.Sprintf(`
+INSERT INTO %s
+(id, user_id, name, prefix, key_hash, scopes, expire_time)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING %s
+`, apiKeysTable, apiKeyColumns)
+
+func (r *postgresRepo) CreateAPIKey(ctx context.Context, req RepoCreateAPIKeyReq) (*APIKey, error) {
+	scopes := req.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	k, err := scanAPIKey(r.db.QueryRow(
+		ctx,
+		createAPIKeyQuery,
+		req.ID.String(),
+		req.UserID,
+		req.Name,
+		req.Prefix,
+		req.KeyHash,
+		scopes,
+		req.ExpireTime,
+	))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.ConstraintName == "api_keys_user_id_fkey" {
+			return nil, ErrNotFound
+		}
+		return nil, ErrInternalError
+	}
+
+	return k, nil
+}
+
+var getAPIKeyByHashQuery = fmt.Sprintf(`
+SELECT %s
+FROM %s
+WHERE key_hash = $1
+`, apiKeyColumns, apiKeysTable)
+
+func (r *postgresRepo) GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error) {
+	k, err := scanAPIKey(r.db.QueryRow(ctx, getAPIKeyByHashQuery, hash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, ErrInternalError
+	}
+
+	return k, nil
+}
+
+var listAPIKeysQuery = fmt.Sprintf(`
+SELECT %s
+FROM %s
+WHERE user_id = $1 AND revoke_time IS NULL
+ORDER BY create_time
+`, apiKeyColumns, apiKeysTable)
+
+func (r *postgresRepo) ListAPIKeys(ctx context.Context, userID uint64) ([]*APIKey, error) {
+	rows, err := r.db.Query(ctx, listAPIKeysQuery, userID)
+	if err != nil {
+		return nil, ErrInternalError
+	}
+	defer rows.Close()
+
+	keys := make([]*APIKey, 0)
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, ErrInternalError
+		}
+		keys = append(keys, k)
+	}
+	if rows.Err() != nil {
+		return nil, ErrInternalError
+	}
+
+	return keys, nil
+}
+
+var revokeAPIKeyQuery = fmt.Sprintf(`
+UPDATE %s
+SET revoke_time = current_timestamp
+WHERE id = $1 AND user_id = $2 AND revoke_time IS NULL
+`, apiKeysTable)
+
+func (r *postgresRepo) RevokeAPIKey(ctx context.Context, userID uint64, id uuid.UUID) error {
+	cmd, err := r.db.Exec(ctx, revokeAPIKeyQuery, id.String(), userID)
+	if err != nil {
+		return ErrInternalError
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+var touchAPIKeyQuery = fmt.Sprintf(`
+UPDATE %s
+SET last_used_time = current_timestamp
+WHERE id = $1 AND
+	(last_used_time IS NULL OR last_used_time < current_timestamp - interval '1 minute')
+`, apiKeysTable)
+
+func (r *postgresRepo) TouchAPIKey(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.Exec(ctx, touchAPIKeyQuery, id.String())
+	if err != nil {
+		return ErrInternalError
+	}
+
+	return nil
+}
+
+// queryIDs runs a query selecting session ids as text.
+func queryIDs(ctx context.Context, db DBTX, query string, args ...interface{}) ([]uuid.UUID, error) {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, ErrInternalError
+	}
+	defer rows.Close()
+
+	ids := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, ErrInternalError
+		}
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return nil, ErrInternalError
+		}
+		ids = append(ids, id)
+	}
+	if rows.Err() != nil {
+		return nil, ErrInternalError
+	}
+
+	return ids, nil
+}
+
+var createOTPQuery = fmt.Sprintf(`
+INSERT INTO %[1]s
+(user_id, expire_time)
+VALUES ($1, $2)
+ON CONFLICT (user_id)
+DO UPDATE SET
+	code_hash = NULL,
+	attempts = 0,
+	create_time = current_timestamp,
+	expire_time = EXCLUDED.expire_time
+WHERE %[1]s.create_time <= $3
+`, otpCodesTable)
+
+func (r *postgresRepo) CreateOTP(ctx context.Context, userID uint64, expireTime time.Time, notBefore time.Time) error {
+	cmd, err := r.db.Exec(ctx, createOTPQuery, userID, expireTime, notBefore)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.ConstraintName == "otp_codes_user_id_fkey" {
+			return ErrNotFound
+		}
+		return ErrInternalError
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrResourceExhausted
+	}
+
+	return nil
+}
+
+var issueOTPQuery = fmt.Sprintf(`
+UPDATE %s
+SET code_hash = $2
+WHERE user_id = $1 AND
+	code_hash IS NULL AND
+	expire_time > current_timestamp
+`, otpCodesTable)
+
+func (r *postgresRepo) IssueOTP(ctx context.Context, userID uint64, codeHash string) error {
+	cmd, err := r.db.Exec(ctx, issueOTPQuery, userID, codeHash)
+	if err != nil {
+		return ErrInternalError
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+var useOTPAttemptQuery = fmt.Sprintf(`
+UPDATE %s
+SET attempts = attempts + 1
+WHERE user_id = $1 AND
+	code_hash IS NOT NULL AND
+	expire_time > current_timestamp AND
+	attempts < $2
+RETURNING code_hash
+`, otpCodesTable)
+
+var otpLockedQuery = fmt.Sprintf(`
+SELECT attempts >= $2
+FROM %s
+WHERE user_id = $1 AND
+	code_hash IS NOT NULL AND
+	expire_time > current_timestamp
+`, otpCodesTable)
+
+func (r *postgresRepo) UseOTPAttempt(ctx context.Context, userID uint64, maxAttempts int) (string, error) {
+	var codeHash string
+	err := r.db.QueryRow(ctx, useOTPAttemptQuery, userID, maxAttempts).Scan(&codeHash)
+	if err == nil {
+		return codeHash, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrInternalError
+	}
+
+	var locked bool
+	err = r.db.QueryRow(ctx, otpLockedQuery, userID, maxAttempts).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrNotFound
+		}
+		return "", ErrInternalError
+	}
+	if locked {
+		return "", ErrResourceExhausted
+	}
+
+	return "", ErrNotFound
+}
+
+var deleteOTPQuery = fmt.Sprintf(`
+DELETE FROM %s
+WHERE user_id = $1
+`, otpCodesTable)
+
+func (r *postgresRepo) DeleteOTP(ctx context.Context, userID uint64) error {
+	_, err := r.db.Exec(ctx, deleteOTPQuery, userID)
+	if err != nil {
+		return ErrInternalError
+	}
+
+	return nil
+}
+
+var createTOTPQuery = fmt.Sprintf(`
+INSERT INTO %[1]s
+(user_id, secret)
+VALUES ($1, $2)
+ON CONFLICT (user_id)
+DO UPDATE SET
+	secret = EXCLUDED.secret,
+	last_used_step = 0,
+	create_time = current_timestamp
+WHERE %[1]s.confirm_time IS NULL
+`, totpSecretsTable)
+
+func (r *postgresRepo) CreateTOTP(ctx context.Context, userID uint64, secret string) error {
+	cmd, err := r.db.Exec(ctx, createTOTPQuery, userID, secret)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.ConstraintName == "totp_secrets_user_id_fkey" {
+			return ErrNotFound
+		}
+		return ErrInternalError
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrFailedPrecondition
+	}
+
+	return nil
+}
+
+var getTOTPQuery = fmt.Sprintf(`
+SELECT user_id, secret, last_used_step, create_time, confirm_time
+FROM %s
+WHERE user_id = $1
+`, totpSecretsTable)
+
+func (r *postgresRepo) GetTOTP(ctx context.Context, userID uint64) (*TOTP, error) {
+	var t TOTP
+	err := r.db.QueryRow(ctx, getTOTPQuery, userID).
 		Scan(
-			&u.ID,
-			&u.Username,
-			&u.PasswordHash,
-			&u.FirstName,
-			&u.LastName,
-			&u.CreateTime,
+			&t.UserID,
+			&t.Secret,
+			&t.LastUsedStep,
+			&t.CreateTime,
+			&t.ConfirmTime,
 		)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -81,5 +1049,297 @@ func (r *postgresRepo) GetByUsername(ctx context.Context, username string) (*Use
 		return nil, ErrInternalError
 	}
 
-	return &u, nil
+	return &t, nil
+}
+
+var confirmTOTPQuery = fmt.Sprintf(`
+UPDATE %s
+SET
+	confirm_time = current_timestamp,
+	last_used_step = $2
+WHERE user_id = $1 AND
+	confirm_time IS NULL
+`, totpSecretsTable)
+
+var deleteRecoveryCodesQuery = fmt.Sprintf(`
+DELETE FROM %s
+WHERE user_id = $1
+`, recoveryCodesTable)
+
+var createRecoveryCodeQuery = fmt.Sprintf(`
+INSERT INTO %s
+(user_id, code_hash)
+VALUES ($1, $2)
+`, recoveryCodesTable)
+
+func (r *postgresRepo) ConfirmTOTP(ctx context.Context, userID uint64, step int64, recoveryCodeHashes []string) error {
+	err := r.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		cmd, err := tx.Exec(ctx, confirmTOTPQuery, userID, step)
+		if err != nil {
+			return ErrInternalError
+		}
+		if cmd.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+
+		_, err = tx.Exec(ctx, deleteRecoveryCodesQuery, userID)
+		if err != nil {
+			return ErrInternalError
+		}
+
+		for _, hash := range recoveryCodeHashes {
+			_, err = tx.Exec(ctx, createRecoveryCodeQuery, userID, hash)
+			if err != nil {
+				return ErrInternalError
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrNotFound
+		}
+		return ErrInternalError
+	}
+
+	return nil
+}
+
+var useTOTPStepQuery = fmt.Sprintf(`
+UPDATE %s
+SET last_used_step = $2
+WHERE user_id = $1 AND
+	confirm_time IS NOT NULL AND
+	last_used_step < $2
+`, totpSecretsTable)
+
+func (r *postgresRepo) UseTOTPStep(ctx context.Context, userID uint64, step int64) error {
+	cmd, err := r.db.Exec(ctx, useTOTPStepQuery, userID, step)
+	if err != nil {
+		return ErrInternalError
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+var useRecoveryCodeQuery = fmt.Sprintf(`
+UPDATE %s
+SET use_time = current_timestamp
+WHERE user_id = $1 AND
+	code_hash = $2 AND
+	use_time IS NULL
+`, recoveryCodesTable)
+
+func (r *postgresRepo) UseRecoveryCode(ctx context.Context, userID uint64, codeHash string) error {
+	cmd, err := r.db.Exec(ctx, useRecoveryCodeQuery, userID, codeHash)
+	if err != nil {
+		return ErrInternalError
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+var deleteTOTPQuery = fmt.Sprintf(`
+DELETE FROM %s
+WHERE user_id = $1
+`, totpSecretsTable)
+
+func (r *postgresRepo) DeleteTOTP(ctx context.Context, userID uint64) error {
+	err := r.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		cmd, err := tx.Exec(ctx, deleteTOTPQuery, userID)
+		if err != nil {
+			return ErrInternalError
+		}
+		if cmd.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+
+		_, err = tx.Exec(ctx, deleteRecoveryCodesQuery, userID)
+		if err != nil {
+			return ErrInternalError
+		}
+
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrNotFound
+		}
+		return ErrInternalError
+	}
+
+	return nil
+}
+
+const loginFailuresColumns = `user_id, failures, last_failure_time, locked_until`
+
+func scanLoginFailures(row pgx.Row) (*LoginFailures, error) {
+	var f LoginFailures
+	err := row.Scan(
+		&f.UserID,
+		&f.Failures,
+		&f.LastFailureTime,
+		&f.LockedUntil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+var getLoginFailuresQuery = fmt.Sprintf(`
+SELECT %s
+FROM %s
+WHERE user_id = $1
+`, loginFailuresColumns, loginFailuresTable)
+
+func (r *postgresRepo) GetLoginFailures(ctx context.Context, userID uint64) (*LoginFailures, error) {
+	f, err := scanLoginFailures(r.db.QueryRow(ctx, getLoginFailuresQuery, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, ErrInternalError
+	}
+
+	return f, nil
+}
+
+var recordLoginFailureQuery = fmt.Sprintf(`
+INSERT INTO %[1]s AS f
+(user_id, failures, last_failure_time)
+VALUES ($1, 1, current_timestamp)
+ON CONFLICT (user_id)
+DO UPDATE SET
+	failures = CASE
+		WHEN f.last_failure_time < $2 THEN 1
+		ELSE f.failures + 1
+	END,
+	last_failure_time = current_timestamp
+RETURNING %[2]s
+`, loginFailuresTable, loginFailuresColumns)
+
+func (r *postgresRepo) RecordLoginFailure(ctx context.Context, userID uint64, resetBefore time.Time) (*LoginFailures, error) {
+	f, err := scanLoginFailures(r.db.QueryRow(ctx, recordLoginFailureQuery, userID, resetBefore))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.ConstraintName == "login_failures_user_id_fkey" {
+			return nil, ErrNotFound
+		}
+		return nil, ErrInternalError
+	}
+
+	return f, nil
+}
+
+var lockLoginQuery = fmt.Sprintf(`
+UPDATE %s
+SET locked_until = $2
+WHERE user_id = $1
+`, loginFailuresTable)
+
+func (r *postgresRepo) LockLogin(ctx context.Context, userID uint64, until time.Time) error {
+	cmd, err := r.db.Exec(ctx, lockLoginQuery, userID, until)
+	if err != nil {
+		return ErrInternalError
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+var resetLoginFailuresQuery = fmt.Sprintf(`
+DELETE FROM %s
+WHERE user_id = $1
+`, loginFailuresTable)
+
+func (r *postgresRepo) ResetLoginFailures(ctx context.Context, userID uint64) error {
+	_, err := r.db.Exec(ctx, resetLoginFailuresQuery, userID)
+	if err != nil {
+		return ErrInternalError
+	}
+
+	return nil
+}
+
+var createLoginChallengeQuery = fmt.Sprintf(`
+INSERT INTO %s
+(token_hash, user_id, user_agent, client_ip, expire_time)
+VALUES ($1, $2, $3, $4, $5)
+`, loginChallengesTable)
+
+func (r *postgresRepo) CreateLoginChallenge(ctx context.Context, req RepoCreateLoginChallengeReq) error {
+	_, err := r.db.Exec(
+		ctx,
+		createLoginChallengeQuery,
+		req.TokenHash,
+		req.UserID,
+		req.UserAgent,
+		req.ClientIP,
+		req.ExpireTime,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.ConstraintName == "login_challenges_user_id_fkey" {
+			return ErrNotFound
+		}
+		return ErrInternalError
+	}
+
+	return nil
+}
+
+var useLoginChallengeAttemptQuery = fmt.Sprintf(`
+UPDATE %s
+SET attempts = attempts + 1
+WHERE token_hash = $1 AND
+	expire_time > current_timestamp AND
+	attempts < $2
+RETURNING token_hash, user_id, user_agent, client_ip, attempts, expire_time
+`, loginChallengesTable)
+
+func (r *postgresRepo) UseLoginChallengeAttempt(ctx context.Context, tokenHash string, maxAttempts int) (*LoginChallenge, error) {
+	var c LoginChallenge
+	err := r.db.QueryRow(ctx, useLoginChallengeAttemptQuery, tokenHash, maxAttempts).
+		Scan(
+			&c.TokenHash,
+			&c.UserID,
+			&c.UserAgent,
+			&c.ClientIP,
+			&c.Attempts,
+			&c.ExpireTime,
+		)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, ErrInternalError
+	}
+
+	return &c, nil
+}
+
+var deleteLoginChallengeQuery = fmt.Sprintf(`
+DELETE FROM %s
+WHERE token_hash = $1 OR expire_time <= current_timestamp
+`, loginChallengesTable)
+
+// DeleteLoginChallenge also removes expired challenges of all users.
+func (r *postgresRepo) DeleteLoginChallenge(ctx context.Context, tokenHash string) error {
+	_, err := r.db.Exec(ctx, deleteLoginChallengeQuery, tokenHash)
+	if err != nil {
+		return ErrInternalError
+	}
+
+	return nil
 }
@@ -9,6 +9,7 @@ import (
 	"cryptowatch/pkg/config"
 	"cryptowatch/pkg/util"
 	"fmt"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -22,7 +23,10 @@ import (
 
 type PostgresRepoTestSuite struct {
 	db   *pgxpool.Pool
-	repo user.Repository
+	repo interface {
+		user.Repository
+		user.OTPRepository
+	}
 
 	suite.Suite
 }
@@ -31,18 +35,10 @@ func (s *PostgresRepoTestSuite) SetupSuite() {
 	_, filename, _, _ := runtime.Caller(0)
 	rootDir := path.Join(path.Dir(filename), "../../..")
 
-	cfg, err := config.LoadConfig(
-		path.Join(rootDir, "configs"),
-		"test",
-	)
+	cfg, err := config.Load(path.Join(rootDir, "configs", "test.env"), config.DBKeys...)
 	require.NoError(s.T(), err)
 
-	dbSource := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBSSLMode,
-	)
-
-	s.db, err = util.OpenDB(dbSource)
+	s.db, err = util.OpenDB(cfg.DBSource())
 	require.NoError(s.T(), err)
 
 	s.repo = user.NewPostgresRepo(s.db)
@@ -235,3 +231,585 @@ func (s *PostgresRepoTestSuite) TestGetByUsername() {
 func TestRepositoryPostgresTestSuite(t *testing.T) {
 	suite.Run(t, new(PostgresRepoTestSuite))
 }
+
+func (s *PostgresRepoTestSuite) TestSessions() {
+	ctx := context.Background()
+	users := s.seedUsers([]user.RepoCreateReq{
+		{
+			Username:     "username1",
+			PasswordHash: "password1",
+			FirstName:    "firstname1",
+			LastName:     "lastname1",
+		},
+	})
+	u := users[0]
+	since := time.Now().Add(-time.Second)
+
+	sess, err := s.repo.CreateSession(ctx, user.RepoCreateSessionReq{
+		ID:               uuid.New(),
+		UserID:           u.ID,
+		RefreshTokenHash: "hash1",
+		UserAgent:        "agent",
+		ClientIP:         "127.0.0.1",
+		Scopes:           user.TelegramScopes,
+		ExpireTime:       time.Now().Add(time.Hour),
+	})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), u.ID, sess.UserID)
+	assert.Equal(s.T(), user.TelegramScopes, sess.Scopes)
+	assert.Nil(s.T(), sess.RevokeTime)
+
+	rotated, err := s.repo.RotateRefreshToken(ctx, user.RepoRotateRefreshTokenReq{
+		ID:         sess.ID,
+		OldHash:    "hash1",
+		NewHash:    "hash2",
+		ExpireTime: time.Now().Add(2 * time.Hour),
+	})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "hash2", rotated.RefreshTokenHash)
+	assert.Equal(s.T(), "hash1", rotated.PreviousRefreshTokenHash)
+	assert.Equal(s.T(), user.TelegramScopes, rotated.Scopes)
+
+	// The old token can't be rotated twice.
+	_, err = s.repo.RotateRefreshToken(ctx, user.RepoRotateRefreshTokenReq{
+		ID:         sess.ID,
+		OldHash:    "hash1",
+		NewHash:    "hash3",
+		ExpireTime: time.Now().Add(2 * time.Hour),
+	})
+	assert.ErrorIs(s.T(), err, user.ErrNotFound)
+
+	found, err := s.repo.GetSessionByRefreshTokenHash(ctx, "hash1")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), sess.ID, found.ID)
+
+	sessions, err := s.repo.ListSessions(ctx, u.ID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), sessions, 1)
+
+	assert.ErrorIs(s.T(), s.repo.RevokeSession(ctx, u.ID+1, sess.ID), user.ErrNotFound)
+	require.NoError(s.T(), s.repo.RevokeSession(ctx, u.ID, sess.ID))
+
+	sessions, err = s.repo.ListSessions(ctx, u.ID)
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), sessions)
+
+	revoked, err := s.repo.ListRevokedSessions(ctx, since)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []uuid.UUID{sess.ID}, revoked)
+}
+
+func (s *PostgresRepoTestSuite) TestOTP() {
+	ctx := context.Background()
+	users := s.seedUsers([]user.RepoCreateReq{
+		{
+			Username:     "username1",
+			PasswordHash: "password1",
+			FirstName:    "firstname1",
+			LastName:     "lastname1",
+		},
+	})
+	u := users[0]
+	expireTime := time.Now().Add(time.Minute)
+
+	_, err := s.repo.UseOTPAttempt(ctx, u.ID, 2)
+	assert.ErrorIs(s.T(), err, user.ErrNotFound)
+
+	require.NoError(s.T(), s.repo.CreateOTP(ctx, u.ID, expireTime, time.Now().Add(-time.Minute)))
+	// Another request within the interval is rejected.
+	err = s.repo.CreateOTP(ctx, u.ID, expireTime, time.Now().Add(-time.Minute))
+	assert.ErrorIs(s.T(), err, user.ErrResourceExhausted)
+
+	require.NoError(s.T(), s.repo.IssueOTP(ctx, u.ID, "hash"))
+	// The code is issued once.
+	assert.ErrorIs(s.T(), s.repo.IssueOTP(ctx, u.ID, "hash2"), user.ErrNotFound)
+
+	for i := 0; i < 2; i++ {
+		codeHash, err := s.repo.UseOTPAttempt(ctx, u.ID, 2)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), "hash", codeHash)
+	}
+	_, err = s.repo.UseOTPAttempt(ctx, u.ID, 2)
+	assert.ErrorIs(s.T(), err, user.ErrResourceExhausted)
+
+	// A new request after the interval resets attempts.
+	require.NoError(s.T(), s.repo.CreateOTP(ctx, u.ID, expireTime, time.Now().Add(time.Minute)))
+	require.NoError(s.T(), s.repo.IssueOTP(ctx, u.ID, "hash3"))
+	codeHash, err := s.repo.UseOTPAttempt(ctx, u.ID, 2)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "hash3", codeHash)
+
+	require.NoError(s.T(), s.repo.DeleteOTP(ctx, u.ID))
+	_, err = s.repo.UseOTPAttempt(ctx, u.ID, 2)
+	assert.ErrorIs(s.T(), err, user.ErrNotFound)
+}
+
+func (s *PostgresRepoTestSuite) TestTOTP() {
+	ctx := context.Background()
+	users := s.seedUsers([]user.RepoCreateReq{
+		{
+			Username:     "username1",
+			PasswordHash: "password1",
+			FirstName:    "firstname1",
+			LastName:     "lastname1",
+		},
+	})
+	u := users[0]
+
+	_, err := s.repo.GetTOTP(ctx, u.ID)
+	assert.ErrorIs(s.T(), err, user.ErrNotFound)
+
+	require.NoError(s.T(), s.repo.CreateTOTP(ctx, u.ID, "secret1"))
+	// A pending secret is replaced.
+	require.NoError(s.T(), s.repo.CreateTOTP(ctx, u.ID, "secret2"))
+	t, err := s.repo.GetTOTP(ctx, u.ID)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "secret2", t.Secret)
+	assert.False(s.T(), t.Enabled())
+
+	assert.ErrorIs(s.T(), s.repo.UseTOTPStep(ctx, u.ID, 10), user.ErrNotFound)
+
+	require.NoError(s.T(), s.repo.ConfirmTOTP(ctx, u.ID, 10, []string{"hash1", "hash2"}))
+	assert.ErrorIs(s.T(), s.repo.ConfirmTOTP(ctx, u.ID, 11, nil), user.ErrNotFound)
+	assert.ErrorIs(s.T(), s.repo.CreateTOTP(ctx, u.ID, "secret3"), user.ErrFailedPrecondition)
+
+	t, err = s.repo.GetTOTP(ctx, u.ID)
+	require.NoError(s.T(), err)
+	assert.True(s.T(), t.Enabled())
+
+	// Codes of the confirmation step and earlier are spent.
+	assert.ErrorIs(s.T(), s.repo.UseTOTPStep(ctx, u.ID, 10), user.ErrNotFound)
+	require.NoError(s.T(), s.repo.UseTOTPStep(ctx, u.ID, 11))
+
+	require.NoError(s.T(), s.repo.UseRecoveryCode(ctx, u.ID, "hash1"))
+	assert.ErrorIs(s.T(), s.repo.UseRecoveryCode(ctx, u.ID, "hash1"), user.ErrNotFound)
+
+	require.NoError(s.T(), s.repo.DeleteTOTP(ctx, u.ID))
+	assert.ErrorIs(s.T(), s.repo.DeleteTOTP(ctx, u.ID), user.ErrNotFound)
+	assert.ErrorIs(s.T(), s.repo.UseRecoveryCode(ctx, u.ID, "hash2"), user.ErrNotFound)
+}
+
+func (s *PostgresRepoTestSuite) TestLoginChallenges() {
+	ctx := context.Background()
+	users := s.seedUsers([]user.RepoCreateReq{
+		{
+			Username:     "username1",
+			PasswordHash: "password1",
+			FirstName:    "firstname1",
+			LastName:     "lastname1",
+		},
+	})
+	u := users[0]
+
+	require.NoError(s.T(), s.repo.CreateLoginChallenge(ctx, user.RepoCreateLoginChallengeReq{
+		TokenHash:  "hash",
+		UserID:     u.ID,
+		UserAgent:  "agent",
+		ExpireTime: time.Now().Add(time.Minute),
+	}))
+
+	for i := 1; i <= 2; i++ {
+		c, err := s.repo.UseLoginChallengeAttempt(ctx, "hash", 2)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), u.ID, c.UserID)
+		assert.Equal(s.T(), "agent", c.UserAgent)
+		assert.Equal(s.T(), i, c.Attempts)
+	}
+	_, err := s.repo.UseLoginChallengeAttempt(ctx, "hash", 2)
+	assert.ErrorIs(s.T(), err, user.ErrNotFound)
+
+	require.NoError(s.T(), s.repo.DeleteLoginChallenge(ctx, "hash"))
+	_, err = s.repo.UseLoginChallengeAttempt(ctx, "hash", 5)
+	assert.ErrorIs(s.T(), err, user.ErrNotFound)
+}
+
+func (s *PostgresRepoTestSuite) TestUpdatePassword() {
+	ctx := context.Background()
+	users := s.seedUsers([]user.RepoCreateReq{
+		{
+			Username:     "username1",
+			PasswordHash: "password1",
+			FirstName:    "firstname1",
+			LastName:     "lastname1",
+		},
+	})
+	u := users[0]
+
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+	for i, id := range ids {
+		_, err := s.repo.CreateSession(ctx, user.RepoCreateSessionReq{
+			ID:               id,
+			UserID:           u.ID,
+			RefreshTokenHash: fmt.Sprintf("hash%d", i),
+			ExpireTime:       time.Now().Add(time.Hour),
+		})
+		require.NoError(s.T(), err)
+	}
+
+	revoked, err := s.repo.UpdatePassword(ctx, u.ID, "password2", ids[0])
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []uuid.UUID{ids[1]}, revoked)
+
+	found, err := s.repo.GetByID(ctx, u.ID)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "password2", found.PasswordHash)
+
+	sessions, err := s.repo.ListSessions(ctx, u.ID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), sessions, 1)
+	assert.Equal(s.T(), ids[0], sessions[0].ID)
+
+	_, err = s.repo.UpdatePassword(ctx, u.ID+1, "password2", ids[0])
+	assert.ErrorIs(s.T(), err, user.ErrNotFound)
+}
+
+func (s *PostgresRepoTestSuite) TestUpdateProfile() {
+	ctx := context.Background()
+	users := s.seedUsers([]user.RepoCreateReq{
+		{
+			Username:     "username1",
+			PasswordHash: "password1",
+			FirstName:    "firstname1",
+			LastName:     "lastname1",
+		},
+	})
+	u := users[0]
+
+	updated, err := s.repo.UpdateProfile(ctx, user.RepoUpdateProfileReq{ID: u.ID, FirstName: "firstname2"})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "firstname2", updated.FirstName)
+	assert.Equal(s.T(), "lastname1", updated.LastName)
+
+	_, err = s.repo.UpdateProfile(ctx, user.RepoUpdateProfileReq{ID: u.ID + 1, FirstName: "firstname2"})
+	assert.ErrorIs(s.T(), err, user.ErrNotFound)
+}
+
+func (s *PostgresRepoTestSuite) TestDelete() {
+	ctx := context.Background()
+	users := s.seedUsers([]user.RepoCreateReq{
+		{
+			Username:     "username1",
+			PasswordHash: "password1",
+			FirstName:    "firstname1",
+			LastName:     "lastname1",
+		},
+	})
+	u := users[0]
+
+	sess, err := s.repo.CreateSession(ctx, user.RepoCreateSessionReq{
+		ID:               uuid.New(),
+		UserID:           u.ID,
+		RefreshTokenHash: "hash",
+		ExpireTime:       time.Now().Add(time.Hour),
+	})
+	require.NoError(s.T(), err)
+
+	// Data of other packages referencing the user.
+	for _, query := range []string{
+		`INSERT INTO tokens (ticker) VALUES ('BTC')`,
+		fmt.Sprintf(`INSERT INTO portfolios (id, user_id, name) VALUES (1, %d, 'main')`, u.ID),
+		`INSERT INTO transactions (portfolio_id, token_ticker, quantity, price, fee) VALUES (1, 'BTC', 1, 1, 0)`,
+		fmt.Sprintf(`INSERT INTO triggers (user_id, token_ticker) VALUES (%d, 'BTC')`, u.ID),
+		fmt.Sprintf(`INSERT INTO telegram_accounts (id, user_id) VALUES (1, %d)`, u.ID),
+	} {
+		_, err = s.db.Exec(ctx, query)
+		require.NoError(s.T(), err)
+	}
+
+	sessions, err := s.repo.Delete(ctx, u.ID)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []uuid.UUID{sess.ID}, sessions)
+
+	_, err = s.repo.GetByID(ctx, u.ID)
+	assert.ErrorIs(s.T(), err, user.ErrNotFound)
+
+	for _, table := range []string{"portfolios", "transactions", "triggers", "telegram_accounts"} {
+		var n int
+		require.NoError(s.T(), s.db.QueryRow(ctx, "SELECT count(*) FROM "+table).Scan(&n))
+		assert.Zero(s.T(), n, table)
+	}
+
+	_, err = s.repo.Delete(ctx, u.ID)
+	assert.ErrorIs(s.T(), err, user.ErrNotFound)
+}
+
+func (s *PostgresRepoTestSuite) TestLoginFailures() {
+	ctx := context.Background()
+	users := s.seedUsers([]user.RepoCreateReq{
+		{
+			Username:     "username1",
+			PasswordHash: "password1",
+			FirstName:    "firstname1",
+			LastName:     "lastname1",
+		},
+	})
+	u := users[0]
+
+	_, err := s.repo.GetLoginFailures(ctx, u.ID)
+	assert.ErrorIs(s.T(), err, user.ErrNotFound)
+	assert.ErrorIs(s.T(), s.repo.LockLogin(ctx, u.ID, time.Now()), user.ErrNotFound)
+
+	for i := 1; i <= 2; i++ {
+		f, err := s.repo.RecordLoginFailure(ctx, u.ID, time.Now().Add(-time.Hour))
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), i, f.Failures)
+	}
+
+	until := time.Now().Add(time.Minute)
+	require.NoError(s.T(), s.repo.LockLogin(ctx, u.ID, until))
+	f, err := s.repo.GetLoginFailures(ctx, u.ID)
+	require.NoError(s.T(), err)
+	assert.True(s.T(), f.Locked(time.Now()))
+	assert.WithinDuration(s.T(), until, *f.LockedUntil, time.Millisecond)
+
+	// Failures before resetBefore are forgotten.
+	f, err = s.repo.RecordLoginFailure(ctx, u.ID, time.Now().Add(time.Hour))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 1, f.Failures)
+
+	require.NoError(s.T(), s.repo.ResetLoginFailures(ctx, u.ID))
+	_, err = s.repo.GetLoginFailures(ctx, u.ID)
+	assert.ErrorIs(s.T(), err, user.ErrNotFound)
+}
+
+func (s *PostgresRepoTestSuite) TestAPIKeys() {
+	ctx := context.Background()
+	users := s.seedUsers([]user.RepoCreateReq{
+		{
+			Username:     "username1",
+			PasswordHash: "password1",
+			FirstName:    "firstname1",
+			LastName:     "lastname1",
+		},
+	})
+	u := users[0]
+	since := time.Now().Add(-time.Minute)
+	expireTime := time.Now().Add(time.Hour)
+
+	k, err := s.repo.CreateAPIKey(ctx, user.RepoCreateAPIKeyReq{
+		ID:         uuid.New(),
+		UserID:     u.ID,
+		Name:       "script",
+		Prefix:     "cwk_abcdefgh",
+		KeyHash:    "hash1",
+		Scopes:     []string{user.ScopePortfoliosRead},
+		ExpireTime: &expireTime,
+	})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{user.ScopePortfoliosRead}, k.Scopes)
+	assert.True(s.T(), k.Active(time.Now()))
+
+	_, err = s.repo.CreateAPIKey(ctx, user.RepoCreateAPIKeyReq{
+		ID:      uuid.New(),
+		UserID:  u.ID + 1,
+		Name:    "script",
+		Prefix:  "cwk_abcdefgh",
+		KeyHash: "hash2",
+	})
+	assert.ErrorIs(s.T(), err, user.ErrNotFound)
+
+	got, err := s.repo.GetAPIKeyByHash(ctx, "hash1")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), k.ID, got.ID)
+	assert.Nil(s.T(), got.LastUsedTime)
+
+	require.NoError(s.T(), s.repo.TouchAPIKey(ctx, k.ID))
+	got, err = s.repo.GetAPIKeyByHash(ctx, "hash1")
+	require.NoError(s.T(), err)
+	assert.NotNil(s.T(), got.LastUsedTime)
+
+	keys, err := s.repo.ListAPIKeys(ctx, u.ID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), keys, 1)
+
+	assert.ErrorIs(s.T(), s.repo.RevokeAPIKey(ctx, u.ID+1, k.ID), user.ErrNotFound)
+	require.NoError(s.T(), s.repo.RevokeAPIKey(ctx, u.ID, k.ID))
+	assert.ErrorIs(s.T(), s.repo.RevokeAPIKey(ctx, u.ID, k.ID), user.ErrNotFound)
+
+	keys, err = s.repo.ListAPIKeys(ctx, u.ID)
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), keys)
+
+	revoked, err := s.repo.ListRevokedSessions(ctx, since)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []uuid.UUID{k.ID}, revoked)
+}
+
+func (s *PostgresRepoTestSuite) TestListUsers() {
+	ctx := context.Background()
+	users := s.seedUsers([]user.RepoCreateReq{
+		{Username: "alice", PasswordHash: "password1", FirstName: "Alice", LastName: "Smith"},
+		{Username: "bob", PasswordHash: "password2", FirstName: "Bob", LastName: "Smith"},
+		{Username: "carol_1", PasswordHash: "password3", FirstName: "Carol", LastName: "Jones"},
+	})
+	for _, u := range users {
+		assert.Equal(s.T(), user.RoleUser, u.Role)
+	}
+
+	got, err := s.repo.ListUsers(ctx, user.RepoListUsersReq{Query: "smith", Limit: 10})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), got, 2)
+	assert.Equal(s.T(), users[0].ID, got[0].ID)
+	assert.Equal(s.T(), users[1].ID, got[1].ID)
+
+	got, err = s.repo.ListUsers(ctx, user.RepoListUsersReq{Query: "smith", AfterID: users[0].ID, Limit: 10})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), got, 1)
+	assert.Equal(s.T(), users[1].ID, got[0].ID)
+
+	// Wildcards of the query are matched literally.
+	got, err = s.repo.ListUsers(ctx, user.RepoListUsersReq{Query: "_", Limit: 10})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), got, 1)
+	assert.Equal(s.T(), users[2].ID, got[0].ID)
+
+	got, err = s.repo.ListUsers(ctx, user.RepoListUsersReq{Limit: 2})
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), got, 2)
+
+	_, err = s.repo.SetRole(ctx, users[2].ID, user.RoleSupport)
+	require.NoError(s.T(), err)
+	got, err = s.repo.ListUsers(ctx, user.RepoListUsersReq{Role: user.RoleSupport, Limit: 10})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), got, 1)
+	assert.Equal(s.T(), users[2].ID, got[0].ID)
+	assert.Equal(s.T(), user.RoleSupport, got[0].Role)
+}
+
+func (s *PostgresRepoTestSuite) TestSetRole() {
+	ctx := context.Background()
+	users := s.seedUsers([]user.RepoCreateReq{
+		{Username: "username1", PasswordHash: "password1", FirstName: "firstname1", LastName: "lastname1"},
+	})
+	u := users[0]
+
+	sess, err := s.repo.CreateSession(ctx, user.RepoCreateSessionReq{
+		ID:               uuid.New(),
+		UserID:           u.ID,
+		RefreshTokenHash: "hash1",
+		ExpireTime:       time.Now().Add(time.Hour),
+	})
+	require.NoError(s.T(), err)
+
+	_, err = s.repo.SetRole(ctx, u.ID, "root")
+	assert.ErrorIs(s.T(), err, user.ErrInvalidArgument)
+	_, err = s.repo.SetRole(ctx, u.ID+1, user.RoleAdmin)
+	assert.ErrorIs(s.T(), err, user.ErrNotFound)
+
+	revoked, err := s.repo.SetRole(ctx, u.ID, user.RoleAdmin)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []uuid.UUID{sess.ID}, revoked)
+
+	got, err := s.repo.GetByID(ctx, u.ID)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), user.RoleAdmin, got.Role)
+}
+
+func (s *PostgresRepoTestSuite) TestDisable() {
+	ctx := context.Background()
+	users := s.seedUsers([]user.RepoCreateReq{
+		{Username: "username1", PasswordHash: "password1", FirstName: "firstname1", LastName: "lastname1"},
+	})
+	u := users[0]
+
+	sess, err := s.repo.CreateSession(ctx, user.RepoCreateSessionReq{
+		ID:               uuid.New(),
+		UserID:           u.ID,
+		RefreshTokenHash: "hash1",
+		ExpireTime:       time.Now().Add(time.Hour),
+	})
+	require.NoError(s.T(), err)
+	k, err := s.repo.CreateAPIKey(ctx, user.RepoCreateAPIKeyReq{
+		ID:      uuid.New(),
+		UserID:  u.ID,
+		Name:    "script",
+		Prefix:  "cwk_abcdefgh",
+		KeyHash: "hash1",
+	})
+	require.NoError(s.T(), err)
+
+	_, err = s.repo.Disable(ctx, u.ID+1)
+	assert.ErrorIs(s.T(), err, user.ErrNotFound)
+
+	revoked, err := s.repo.Disable(ctx, u.ID)
+	require.NoError(s.T(), err)
+	assert.ElementsMatch(s.T(), []uuid.UUID{sess.ID, k.ID}, revoked)
+
+	got, err := s.repo.GetByID(ctx, u.ID)
+	require.NoError(s.T(), err)
+	assert.True(s.T(), got.Disabled())
+
+	require.NoError(s.T(), s.repo.Enable(ctx, u.ID))
+	got, err = s.repo.GetByID(ctx, u.ID)
+	require.NoError(s.T(), err)
+	assert.False(s.T(), got.Disabled())
+	assert.ErrorIs(s.T(), s.repo.Enable(ctx, u.ID+1), user.ErrNotFound)
+}
+
+func (s *PostgresRepoTestSuite) TestIdentities() {
+	ctx := context.Background()
+	users := s.seedUsers([]user.RepoCreateReq{
+		{Username: "username1", PasswordHash: "password1", FirstName: "firstname1", LastName: "lastname1"},
+	})
+	u := users[0]
+
+	_, err := s.repo.GetIdentity(ctx, "https://idp.example.com", "sub1")
+	assert.ErrorIs(s.T(), err, user.ErrNotFound)
+
+	identity, err := s.repo.CreateIdentity(ctx, user.RepoCreateIdentityReq{
+		Issuer:  "https://idp.example.com",
+		Subject: "sub1",
+		UserID:  u.ID,
+		Email:   "user1@example.com",
+	})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), u.ID, identity.UserID)
+	assert.Equal(s.T(), "user1@example.com", identity.Email)
+
+	_, err = s.repo.CreateIdentity(ctx, user.RepoCreateIdentityReq{Issuer: "https://idp.example.com", Subject: "sub1", UserID: u.ID})
+	assert.ErrorIs(s.T(), err, user.ErrFailedPrecondition)
+	_, err = s.repo.CreateIdentity(ctx, user.RepoCreateIdentityReq{Issuer: "https://idp.example.com", Subject: "sub2", UserID: u.ID + 100})
+	assert.ErrorIs(s.T(), err, user.ErrNotFound)
+
+	got, err := s.repo.GetIdentity(ctx, "https://idp.example.com", "sub1")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), identity, got)
+
+	// A provisioned user has no password and is created with the identity.
+	provisioned, err := s.repo.CreateWithIdentity(
+		ctx,
+		user.RepoCreateReq{Username: "username2", FirstName: "firstname2"},
+		user.RepoCreateIdentityReq{Issuer: "https://idp.example.com", Subject: "sub2"},
+	)
+	require.NoError(s.T(), err)
+	assert.False(s.T(), provisioned.HasPassword())
+	got, err = s.repo.GetIdentity(ctx, "https://idp.example.com", "sub2")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), provisioned.ID, got.UserID)
+
+	// Nothing is created if the username is taken or the identity is linked.
+	_, err = s.repo.CreateWithIdentity(
+		ctx,
+		user.RepoCreateReq{Username: "username2"},
+		user.RepoCreateIdentityReq{Issuer: "https://idp.example.com", Subject: "sub3"},
+	)
+	assert.ErrorIs(s.T(), err, user.ErrFailedPrecondition)
+	_, err = s.repo.CreateWithIdentity(
+		ctx,
+		user.RepoCreateReq{Username: "username3"},
+		user.RepoCreateIdentityReq{Issuer: "https://idp.example.com", Subject: "sub1"},
+	)
+	assert.ErrorIs(s.T(), err, user.ErrFailedPrecondition)
+	_, err = s.repo.GetByUsername(ctx, "username3")
+	assert.ErrorIs(s.T(), err, user.ErrNotFound)
+
+	identities, err := s.repo.ListIdentities(ctx, u.ID)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []*user.Identity{identity}, identities)
+
+	assert.ErrorIs(s.T(), s.repo.DeleteIdentity(ctx, provisioned.ID, "https://idp.example.com", "sub1"), user.ErrNotFound)
+	require.NoError(s.T(), s.repo.DeleteIdentity(ctx, u.ID, "https://idp.example.com", "sub1"))
+	identities, err = s.repo.ListIdentities(ctx, u.ID)
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), identities)
+}
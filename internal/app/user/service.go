@@ -2,24 +2,200 @@ package user
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
 	"cryptowatch/pkg/util"
 	"cryptowatch/pkg/util/authtoken"
+	"cryptowatch/pkg/util/totp"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"github.com/google/uuid"
+	"math/big"
+	"regexp"
+	"strings"
 	"time"
 )
 
+const (
+	DefaultAccessTokenDuration  = 15 * time.Minute
+	DefaultRefreshTokenDuration = 30 * 24 * time.Hour
+
+	DefaultTOTPIssuer = "cryptowatch"
+
+	refreshTokenLen = 32
+
+	// totpSkew is the number of time steps a code may be off to allow for clock drift.
+	totpSkew = 1
+	// loginChallengeTTL is how long the second factor of a login may take.
+	loginChallengeTTL         = 5 * time.Minute
+	loginChallengeMaxAttempts = 5
+	recoveryCodeCount         = 10
+
+	DefaultLoginDelayAfter      = 3
+	DefaultLoginLockoutAfter    = 10
+	DefaultLoginLockoutDuration = 15 * time.Minute
+	DefaultLoginFailureWindow   = 24 * time.Hour
+	// maxLoginDelay caps the delays between failed logins, also when the lockout duration is longer.
+	maxLoginDelay = 24 * time.Hour
+	// maxLoginDelayShift doublings of a second exceed maxLoginDelay, more would overflow eventually.
+	maxLoginDelayShift = 17
+)
+
+// LoginThrottle slows down guessing the password of a user.
+type LoginThrottle struct {
+	// DelayAfter is the number of failed logins after which the user has to wait
+	// before trying again, one second first and twice as long after every next failure.
+	DelayAfter int
+	// LockoutAfter is the number of failed logins that lock the user out for LockoutDuration.
+	LockoutAfter    int
+	LockoutDuration time.Duration
+	// Window is how long failures are remembered after the last one.
+	Window time.Duration
+}
+
+var DefaultLoginThrottle = LoginThrottle{
+	DelayAfter:      DefaultLoginDelayAfter,
+	LockoutAfter:    DefaultLoginLockoutAfter,
+	LockoutDuration: DefaultLoginLockoutDuration,
+	Window:          DefaultLoginFailureWindow,
+}
+
+// lockTime returns how long logins are rejected after the failures, zero if they aren't.
+func (t LoginThrottle) lockTime(failures int) time.Duration {
+	switch {
+	case failures >= t.LockoutAfter:
+		return t.LockoutDuration
+	case failures >= t.DelayAfter:
+		max := t.LockoutDuration
+		if max <= 0 || max > maxLoginDelay {
+			max = maxLoginDelay
+		}
+		n := failures - t.DelayAfter
+		if n > maxLoginDelayShift {
+			n = maxLoginDelayShift
+		}
+		d := time.Second << n
+		if d > max {
+			d = max
+		}
+		return d
+	default:
+		return 0
+	}
+}
+
 type Service interface {
 	Create(ctx context.Context, req SvcCreateReq) (*User, error)
-	Login(ctx context.Context, req SvcLoginReq) (string, error)
+	Login(ctx context.Context, req SvcLoginReq) (*SvcTokens, error)
 	GetByUsername(ctx context.Context, username string) (*User, error)
 	GenerateOTP(ctx context.Context, username string) error
-	GetOTP(ctx context.Context, userID uint64) (string, error)
+	// GetOTP returns the pending code of the user authenticated in the context.
+	GetOTP(ctx context.Context) (string, error)
 	VerifyOTP(ctx context.Context, username string, code string) (*SvcVerifyOTPRes, error)
+	// RefreshToken exchanges a refresh token for new access and refresh tokens of the same session.
+	RefreshToken(ctx context.Context, refreshToken string) (*SvcTokens, error)
+	// ListSessions returns active sessions of the user authenticated in the context.
+	ListSessions(ctx context.Context) ([]*Session, error)
+	// RevokeSession revokes a session of the user authenticated in the context.
+	RevokeSession(ctx context.Context, sessionID uuid.UUID) error
+	// VerifyLogin finishes a login of a user with two-factor authentication
+	// with a code of the authenticator app or a recovery code.
+	VerifyLogin(ctx context.Context, req SvcVerifyLoginReq) (*SvcTokens, error)
+	// EnrollTOTP creates a new authenticator secret of the user authenticated in the context.
+	// It protects logins once confirmed with ConfirmTOTP.
+	EnrollTOTP(ctx context.Context) (*SvcTOTPEnrollment, error)
+	// ConfirmTOTP enables two-factor authentication with the first code of the
+	// authenticator app and returns recovery codes.
+	ConfirmTOTP(ctx context.Context, code string) ([]string, error)
+	// DisableTOTP turns two-factor authentication off, code is one more proof the user has the second factor.
+	DisableTOTP(ctx context.Context, code string) error
+	// ResetTOTP turns two-factor authentication off for a user who lost the second factor.
+	// Callers other than administrators may reset it for users of a lower role only.
+	// It is meant for support staff and checks no credentials.
+	ResetTOTP(ctx context.Context, userID uint64) error
+	// ChangePassword sets a new password of the user authenticated in the context
+	// and revokes all the user's sessions but the current one.
+	ChangePassword(ctx context.Context, req SvcChangePasswordReq) error
+	// UpdateProfile sets the non-empty names of the request for the user authenticated in the context.
+	UpdateProfile(ctx context.Context, req SvcUpdateProfileReq) (*User, error)
+	// DeleteAccount removes the user authenticated in the context with the user's portfolios,
+	// triggers and linked Telegram chats. The password confirms the request.
+	DeleteAccount(ctx context.Context, password string) error
+	// CreateAPIKey creates an API key of the user authenticated in the context.
+	CreateAPIKey(ctx context.Context, req SvcCreateAPIKeyReq) (*SvcCreateAPIKeyRes, error)
+	// ListAPIKeys returns unrevoked API keys of the user authenticated in the context.
+	ListAPIKeys(ctx context.Context) ([]*APIKey, error)
+	// RevokeAPIKey revokes an API key of the user authenticated in the context.
+	RevokeAPIKey(ctx context.Context, id uuid.UUID) error
+	// AuthenticateAPIKey returns the payload of an active API key
+	// as if it was an access token of its user.
+	AuthenticateAPIKey(ctx context.Context, key string) (*authtoken.Payload, error)
+	// LoginOIDC logs in the user linked to the identity of an ID token of the OpenID provider.
+	// Unknown identities get a new account if provisioning is enabled.
+	LoginOIDC(ctx context.Context, req SvcLoginOIDCReq) (*SvcTokens, error)
+	// LinkIdentity links the identity of an ID token to the user authenticated in the context,
+	// who can log in with it since.
+	LinkIdentity(ctx context.Context, req SvcLinkIdentityReq) (*Identity, error)
+	// ListIdentities returns identities linked to the user authenticated in the context.
+	ListIdentities(ctx context.Context) ([]*Identity, error)
+	// UnlinkIdentity removes an identity of the user authenticated in the context.
+	// The last identity of a user without a password can't be removed.
+	UnlinkIdentity(ctx context.Context, issuer string, subject string) error
+	// TokenKeys returns the public keys verifying access tokens,
+	// none if tokens are encrypted with a symmetric key.
+	TokenKeys(ctx context.Context) []authtoken.PublicKey
+
+	// The methods below are meant for support staff and administrators and act on any user.
+
+	GetByID(ctx context.Context, id uint64) (*User, error)
+	ListUsers(ctx context.Context, req SvcListUsersReq) (*SvcListUsersRes, error)
+	// SetRole sets the role of the user and logs the user out, so new tokens carry the role.
+	// The caller's own role can't be changed.
+	SetRole(ctx context.Context, userID uint64, role Role) error
+	// DisableUser locks the user out, ending the user's sessions and revoking API keys.
+	// The caller can't be disabled.
+	DisableUser(ctx context.Context, userID uint64) error
+	// EnableUser lets a disabled user log in again. Revoked API keys stay revoked.
+	EnableUser(ctx context.Context, userID uint64) error
+	// LogoutUser ends all sessions of the user, of a lower role than the caller unless an administrator calls.
+	LogoutUser(ctx context.Context, userID uint64) error
 }
 
 type SvcVerifyOTPRes struct {
-	UserID uint64 `json:"user_id"`
-	Token  string `json:"token"`
+	UserID       uint64 `json:"user_id"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type SvcTokens struct {
+	AccessToken            string    `json:"access_token"`
+	AccessTokenExpireTime  time.Time `json:"access_token_expire_time"`
+	RefreshToken           string    `json:"refresh_token"`
+	RefreshTokenExpireTime time.Time `json:"refresh_token_expire_time"`
+	// MFARequired is set instead of the tokens when the login needs the second factor.
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+type SvcVerifyLoginReq struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type SvcChangePasswordReq struct {
+	OldPassword string `json:"old_password" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"`
+}
+
+type SvcUpdateProfileReq struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+type SvcTOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
 }
 
 type SvcCreateReq struct {
@@ -30,25 +206,124 @@ type SvcCreateReq struct {
 }
 
 type SvcLoginReq struct {
-	Username string `json:"username" validate:"required"`
-	Password string `json:"password" validate:"required"`
+	Username  string `json:"username" validate:"required"`
+	Password  string `json:"password" validate:"required"`
+	UserAgent string `json:"user_agent"`
+	ClientIP  string `json:"client_ip"`
 }
 
 type service struct {
 	repo           Repository
 	authtokenMaker authtoken.Maker
 	otpManager     OTPManager
+	denylist       *Denylist
+
+	accessTokenDuration  time.Duration
+	refreshTokenDuration time.Duration
+	totpIssuer           string
+	passwordPolicy       PasswordPolicy
+	loginThrottle        LoginThrottle
+	tokenIssuer          string
+	tokenAudience        string
+	idTokenVerifier      IDTokenVerifier
+	provisionUsers       bool
+}
+
+// Option configures the service.
+type Option func(s *service)
+
+// WithTokenDurations sets lifetimes of access and refresh tokens.
+func WithTokenDurations(access time.Duration, refresh time.Duration) Option {
+	return func(s *service) {
+		s.accessTokenDuration = access
+		s.refreshTokenDuration = refresh
+	}
+}
+
+// WithDenylist makes revoked sessions denied right away on this instance.
+func WithDenylist(d *Denylist) Option {
+	return func(s *service) {
+		s.denylist = d
+	}
+}
+
+// WithTOTPIssuer sets the name authenticator apps show for the account.
+func WithTOTPIssuer(issuer string) Option {
+	return func(s *service) {
+		s.totpIssuer = issuer
+	}
+}
+
+// WithPasswordPolicy sets the rules of new and changed passwords.
+func WithPasswordPolicy(p PasswordPolicy) Option {
+	return func(s *service) {
+		s.passwordPolicy = p
+	}
 }
 
-func NewService(repo Repository, authtokenMaker authtoken.Maker, otpManager OTPManager) *service {
-	return &service{
+// WithLoginThrottle sets the delays and lockout after failed logins.
+func WithLoginThrottle(t LoginThrottle) Option {
+	return func(s *service) {
+		s.loginThrottle = t
+	}
+}
+
+// WithTokenClaims sets the issuer and audience claims of access tokens.
+func WithTokenClaims(issuer string, audience string) Option {
+	return func(s *service) {
+		s.tokenIssuer = issuer
+		s.tokenAudience = audience
+	}
+}
+
+// WithOIDC lets users log in with ID tokens of an OpenID provider. Unknown identities
+// get a new account if provision is set, otherwise they have to be linked to an account first.
+func WithOIDC(verifier IDTokenVerifier, provision bool) Option {
+	return func(s *service) {
+		s.idTokenVerifier = verifier
+		s.provisionUsers = provision
+	}
+}
+
+func NewService(repo Repository, authtokenMaker authtoken.Maker, otpManager OTPManager, opts ...Option) *service {
+	s := &service{
 		repo:           repo,
 		authtokenMaker: authtokenMaker,
 		otpManager:     otpManager,
+
+		accessTokenDuration:  DefaultAccessTokenDuration,
+		refreshTokenDuration: DefaultRefreshTokenDuration,
+		totpIssuer:           DefaultTOTPIssuer,
+		passwordPolicy:       DefaultPasswordPolicy,
+		loginThrottle:        DefaultLoginThrottle,
+	}
+
+	for _, opt := range opts {
+		opt(s)
 	}
+
+	return s
 }
 
+// usernameRe mirrors the users_username_valid constraint.
+var usernameRe = regexp.MustCompile(`^[a-zA-Z0-9]{4,16}$`)
+
 func (s *service) Create(ctx context.Context, req SvcCreateReq) (*User, error) {
+	var verr ValidationError
+	if !usernameRe.MatchString(req.Username) {
+		verr.add("username", "must be 4 to 16 letters or digits")
+	}
+	verr.add("password", s.passwordPolicy.Check(req.Username, req.Password)...)
+	if req.FirstName == "" {
+		verr.add("first_name", "must not be empty")
+	}
+	if req.LastName == "" {
+		verr.add("last_name", "must not be empty")
+	}
+	if err := verr.err(); err != nil {
+		return nil, err
+	}
+
 	passwordHash, err := util.HashPassword(req.Password)
 	if err != nil {
 		return nil, ErrInternalError
@@ -67,26 +342,69 @@ func (s *service) Create(ctx context.Context, req SvcCreateReq) (*User, error) {
 	return u, nil
 }
 
-func (s *service) Login(ctx context.Context, req SvcLoginReq) (string, error) {
+func (s *service) Login(ctx context.Context, req SvcLoginReq) (*SvcTokens, error) {
 	u, err := s.repo.GetByUsername(ctx, req.Username)
 	if err != nil {
 		if errors.Is(err, ErrNotFound) {
-			return "", ErrUnauthenticated
+			return nil, ErrUnauthenticated
 		}
-		return "", err
+		return nil, err
+	}
+
+	failures, err := s.repo.GetLoginFailures(ctx, u.ID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	now := time.Now()
+	if failures != nil && failures.Locked(now) {
+		return nil, &RetryError{Err: ErrResourceExhausted, After: failures.LockedUntil.Sub(now)}
 	}
 
 	err = util.CheckPassword(req.Password, u.PasswordHash)
 	if err != nil {
-		return "", ErrUnauthenticated
+		return nil, s.recordLoginFailure(ctx, u.ID)
 	}
 
-	token, err := s.authtokenMaker.CreateToken(u.ID, time.Hour*24)
+	if failures != nil {
+		err = s.repo.ResetLoginFailures(ctx, u.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Checked after the password, so only the user learns the account is disabled.
+	if u.Disabled() {
+		return nil, ErrAccountDisabled
+	}
+
+	t, err := s.repo.GetTOTP(ctx, u.ID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	if t != nil && t.Enabled() {
+		return s.createLoginChallenge(ctx, u.ID, req.UserAgent, req.ClientIP)
+	}
+
+	return s.createSession(ctx, u, req.UserAgent, req.ClientIP, nil)
+}
+
+// recordLoginFailure counts a wrong password and locks logins of the user if there were too many.
+// It returns the error of the login.
+func (s *service) recordLoginFailure(ctx context.Context, userID uint64) error {
+	now := time.Now()
+	failures, err := s.repo.RecordLoginFailure(ctx, userID, now.Add(-s.loginThrottle.Window))
 	if err != nil {
-		return "", ErrInternalError
+		return err
 	}
 
-	return token, nil
+	if d := s.loginThrottle.lockTime(failures.Failures); d > 0 {
+		err = s.repo.LockLogin(ctx, userID, now.Add(d))
+		if err != nil {
+			return err
+		}
+	}
+
+	return ErrUnauthenticated
 }
 
 func (s *service) GetByUsername(ctx context.Context, username string) (*User, error) {
@@ -99,12 +417,15 @@ func (s *service) GenerateOTP(ctx context.Context, username string) error {
 		return err
 	}
 
-	s.otpManager.Add(ctx, u.ID)
-
-	return nil
+	return s.otpManager.Add(ctx, u.ID)
 }
 
-func (s *service) GetOTP(ctx context.Context, userID uint64) (string, error) {
+func (s *service) GetOTP(ctx context.Context) (string, error) {
+	userID, ok := authtoken.UserIDFromContext(ctx)
+	if !ok {
+		return "", ErrUnauthenticated
+	}
+
 	return s.otpManager.Get(ctx, userID)
 }
 
@@ -117,14 +438,496 @@ func (s *service) VerifyOTP(ctx context.Context, username string, code string) (
 	if err = s.otpManager.Verify(ctx, u.ID, code); err != nil {
 		return nil, err
 	}
+	if u.Disabled() {
+		return nil, ErrAccountDisabled
+	}
 
-	token, err := s.authtokenMaker.CreateToken(u.ID, time.Hour*999) // TODO implement endless token
+	// The bot gets tokens limited to what its commands need, so they can't manage the account.
+	tokens, err := s.createSession(ctx, u, "", "", TelegramScopes)
 	if err != nil {
 		return nil, err
 	}
 
 	return &SvcVerifyOTPRes{
-		UserID: u.ID,
-		Token:  token,
+		UserID:       u.ID,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+func (s *service) TokenKeys(_ context.Context) []authtoken.PublicKey {
+	lister, ok := s.authtokenMaker.(authtoken.PublicKeyLister)
+	if !ok {
+		return nil
+	}
+
+	return lister.PublicKeys()
+}
+
+func (s *service) RefreshToken(ctx context.Context, refreshToken string) (*SvcTokens, error) {
+	hash := hashRefreshToken(refreshToken)
+
+	sess, err := s.repo.GetSessionByRefreshTokenHash(ctx, hash)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrUnauthenticated
+		}
+		return nil, err
+	}
+
+	// A replaced refresh token is used again, so it has leaked.
+	// Revoke the session to lock out whoever holds the current one.
+	if hash != sess.RefreshTokenHash {
+		if sess.RevokeTime == nil {
+			err = s.revokeSession(ctx, sess.UserID, sess.ID)
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return nil, err
+			}
+		}
+		return nil, ErrUnauthenticated
+	}
+
+	if !sess.Active(time.Now()) {
+		return nil, ErrUnauthenticated
+	}
+
+	// New access tokens carry the current role.
+	u, err := s.repo.GetByID(ctx, sess.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if u.Disabled() {
+		return nil, ErrAccountDisabled
+	}
+
+	newRefreshToken, err := generateRefreshToken()
+	if err != nil {
+		return nil, ErrInternalError
+	}
+
+	sess, err = s.repo.RotateRefreshToken(ctx, RepoRotateRefreshTokenReq{
+		ID:         sess.ID,
+		OldHash:    hash,
+		NewHash:    hashRefreshToken(newRefreshToken),
+		ExpireTime: time.Now().Add(s.refreshTokenDuration),
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrUnauthenticated
+		}
+		return nil, err
+	}
+
+	return s.issueTokens(sess, u.Role, newRefreshToken)
+}
+
+func (s *service) ListSessions(ctx context.Context) ([]*Session, error) {
+	userID, ok := authtoken.UserIDFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	return s.repo.ListSessions(ctx, userID)
+}
+
+func (s *service) RevokeSession(ctx context.Context, sessionID uuid.UUID) error {
+	userID, ok := authtoken.UserIDFromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+
+	return s.revokeSession(ctx, userID, sessionID)
+}
+
+func (s *service) revokeSession(ctx context.Context, userID uint64, sessionID uuid.UUID) error {
+	err := s.repo.RevokeSession(ctx, userID, sessionID)
+	if err != nil {
+		return err
+	}
+
+	if s.denylist != nil {
+		s.denylist.Add(sessionID)
+	}
+
+	return nil
+}
+
+func (s *service) VerifyLogin(ctx context.Context, req SvcVerifyLoginReq) (*SvcTokens, error) {
+	tokenHash := hashRefreshToken(req.MFAToken)
+
+	// The attempt is counted before the check, so concurrent guesses can't exceed the limit.
+	challenge, err := s.repo.UseLoginChallengeAttempt(ctx, tokenHash, loginChallengeMaxAttempts)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrUnauthenticated
+		}
+		return nil, err
+	}
+
+	err = s.checkSecondFactor(ctx, challenge.UserID, req.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.DeleteLoginChallenge(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := s.repo.GetByID(ctx, challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if u.Disabled() {
+		return nil, ErrAccountDisabled
+	}
+
+	return s.createSession(ctx, u, challenge.UserAgent, challenge.ClientIP, nil)
+}
+
+func (s *service) EnrollTOTP(ctx context.Context) (*SvcTOTPEnrollment, error) {
+	userID, ok := authtoken.UserIDFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	u, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, ErrInternalError
+	}
+
+	err = s.repo.CreateTOTP(ctx, userID, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SvcTOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(s.totpIssuer, u.Username, secret),
 	}, nil
 }
+
+func (s *service) ConfirmTOTP(ctx context.Context, code string) ([]string, error) {
+	userID, ok := authtoken.UserIDFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	t, err := s.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrFailedPrecondition
+		}
+		return nil, err
+	}
+	if t.Enabled() {
+		return nil, ErrFailedPrecondition
+	}
+
+	step, ok := totp.Validate(t.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidArgument
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		c, err := generateRecoveryCode()
+		if err != nil {
+			return nil, ErrInternalError
+		}
+		codes = append(codes, c)
+		hashes = append(hashes, hashRecoveryCode(c))
+	}
+
+	err = s.repo.ConfirmTOTP(ctx, userID, step, hashes)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrFailedPrecondition
+		}
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (s *service) DisableTOTP(ctx context.Context, code string) error {
+	userID, ok := authtoken.UserIDFromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+
+	err := s.checkSecondFactor(ctx, userID, code)
+	if err != nil {
+		if errors.Is(err, ErrUnauthenticated) {
+			return ErrInvalidArgument
+		}
+		return err
+	}
+
+	return s.repo.DeleteTOTP(ctx, userID)
+}
+
+func (s *service) ResetTOTP(ctx context.Context, userID uint64) error {
+	u, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	err = checkOutranks(ctx, u)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.DeleteTOTP(ctx, userID)
+}
+
+func (s *service) ChangePassword(ctx context.Context, req SvcChangePasswordReq) error {
+	payload, ok := authtoken.FromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+
+	u, err := s.checkPassword(ctx, payload.UserID, "old_password", req.OldPassword)
+	if err != nil {
+		return err
+	}
+
+	var verr ValidationError
+	verr.add("new_password", s.passwordPolicy.Check(u.Username, req.NewPassword)...)
+	if err := verr.err(); err != nil {
+		return err
+	}
+
+	passwordHash, err := util.HashPassword(req.NewPassword)
+	if err != nil {
+		return ErrInternalError
+	}
+
+	revoked, err := s.repo.UpdatePassword(ctx, payload.UserID, passwordHash, payload.ID)
+	if err != nil {
+		return err
+	}
+
+	s.denySessions(revoked)
+
+	return nil
+}
+
+func (s *service) UpdateProfile(ctx context.Context, req SvcUpdateProfileReq) (*User, error) {
+	userID, ok := authtoken.UserIDFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	return s.repo.UpdateProfile(ctx, RepoUpdateProfileReq{
+		ID:        userID,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+	})
+}
+
+func (s *service) DeleteAccount(ctx context.Context, password string) error {
+	userID, ok := authtoken.UserIDFromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+
+	_, err := s.checkPassword(ctx, userID, "password", password)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := s.repo.Delete(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// Sessions are deleted with the user, so other instances don't learn about them
+	// from the database. Their tokens stop working there once they expire.
+	s.denySessions(sessions)
+
+	return nil
+}
+
+// checkPassword confirms a request with the password of the user sent in field and returns the user.
+func (s *service) checkPassword(ctx context.Context, userID uint64, field string, password string) (*User, error) {
+	u, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrUnauthenticated
+		}
+		return nil, err
+	}
+
+	err = util.CheckPassword(password, u.PasswordHash)
+	if err != nil {
+		return nil, &ValidationError{Violations: []FieldViolation{{Field: field, Description: "wrong password"}}}
+	}
+
+	return u, nil
+}
+
+func (s *service) denySessions(ids []uuid.UUID) {
+	if s.denylist == nil {
+		return
+	}
+	for _, id := range ids {
+		s.denylist.Add(id)
+	}
+}
+
+// checkSecondFactor accepts a code of the authenticator app or an unused recovery code of the user,
+// each only once. It fails with ErrFailedPrecondition if two-factor authentication is disabled.
+func (s *service) checkSecondFactor(ctx context.Context, userID uint64, code string) error {
+	t, err := s.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrFailedPrecondition
+		}
+		return err
+	}
+	if !t.Enabled() {
+		return ErrFailedPrecondition
+	}
+
+	if step, ok := totp.Validate(t.Secret, code, time.Now(), totpSkew); ok {
+		err = s.repo.UseTOTPStep(ctx, userID, step)
+	} else {
+		err = s.repo.UseRecoveryCode(ctx, userID, hashRecoveryCode(code))
+	}
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrUnauthenticated
+		}
+		return err
+	}
+
+	return nil
+}
+
+// createLoginChallenge starts the second step of a login instead of creating a session.
+func (s *service) createLoginChallenge(ctx context.Context, userID uint64, userAgent string, clientIP string) (*SvcTokens, error) {
+	token, err := generateRefreshToken()
+	if err != nil {
+		return nil, ErrInternalError
+	}
+
+	err = s.repo.CreateLoginChallenge(ctx, RepoCreateLoginChallengeReq{
+		TokenHash:  hashRefreshToken(token),
+		UserID:     userID,
+		UserAgent:  userAgent,
+		ClientIP:   clientIP,
+		ExpireTime: time.Now().Add(loginChallengeTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &SvcTokens{
+		MFARequired: true,
+		MFAToken:    token,
+	}, nil
+}
+
+// createSession logs the user in, scopes limit access tokens of the session unless empty.
+func (s *service) createSession(ctx context.Context, u *User, userAgent string, clientIP string, scopes []string) (*SvcTokens, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, ErrInternalError
+	}
+
+	refreshToken, err := generateRefreshToken()
+	if err != nil {
+		return nil, ErrInternalError
+	}
+
+	sess, err := s.repo.CreateSession(ctx, RepoCreateSessionReq{
+		ID:               id,
+		UserID:           u.ID,
+		RefreshTokenHash: hashRefreshToken(refreshToken),
+		UserAgent:        userAgent,
+		ClientIP:         clientIP,
+		Scopes:           scopes,
+		ExpireTime:       time.Now().Add(s.refreshTokenDuration),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(sess, u.Role, refreshToken)
+}
+
+// issueTokens creates an access token of a user with the role bound to the session.
+func (s *service) issueTokens(sess *Session, role Role, refreshToken string) (*SvcTokens, error) {
+	accessToken, err := s.authtokenMaker.CreateToken(
+		sess.UserID,
+		s.accessTokenDuration,
+		authtoken.WithID(sess.ID),
+		authtoken.WithRole(string(role)),
+		authtoken.WithIssuer(s.tokenIssuer),
+		authtoken.WithAudience(s.tokenAudience),
+		authtoken.WithScopes(sess.Scopes...),
+	)
+	if err != nil {
+		return nil, ErrInternalError
+	}
+
+	return &SvcTokens{
+		AccessToken:            accessToken,
+		AccessTokenExpireTime:  time.Now().Add(s.accessTokenDuration),
+		RefreshToken:           refreshToken,
+		RefreshTokenExpireTime: sess.ExpireTime,
+	}, nil
+}
+
+func generateRefreshToken() (string, error) {
+	b := make([]byte, refreshTokenLen)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashRefreshToken returns the form of a refresh token stored in the database.
+// Refresh tokens are random, so a fast hash is enough.
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// generateRecoveryCode returns a code like "abcd-efgh-jkmn" without easily confused characters.
+func generateRecoveryCode() (string, error) {
+	var sb strings.Builder
+	max := big.NewInt(int64(len(recoveryCodeAlphabet)))
+
+	for i := 0; i < 12; i++ {
+		if i > 0 && i%4 == 0 {
+			sb.WriteByte('-')
+		}
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(recoveryCodeAlphabet[n.Int64()])
+	}
+
+	return sb.String(), nil
+}
+
+// hashRecoveryCode returns the form of a recovery code stored in the database,
+// ignoring case and separators the user may type differently.
+// Codes have about 59 bits of entropy, so a fast hash is enough.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
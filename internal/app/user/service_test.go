@@ -5,15 +5,22 @@ import (
 	"cryptowatch/internal/app/user"
 	"cryptowatch/internal/app/user/mock"
 	"cryptowatch/pkg/util"
+	"cryptowatch/pkg/util/authtoken"
+	"cryptowatch/pkg/util/oidc"
+	"cryptowatch/pkg/util/oidc/oidctest"
+	"cryptowatch/pkg/util/totp"
 	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 )
 
 func TestService_Create(t *testing.T) {
-	password := "password1"
+	password := "Tr0ub4dor&3"
 	passwordHash, err := util.HashPassword(password)
 	require.NoError(t, err)
 	require.NotEmpty(t, passwordHash)
@@ -68,6 +75,19 @@ func TestService_Create(t *testing.T) {
 			res: nil,
 			err: user.ErrInternalError,
 		},
+		{
+			name: "Invalid fields",
+			buildStubs: func(repo *mock.MockRepository) {
+				repo.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+			},
+			req: user.SvcCreateReq{
+				Username:  "u",
+				Password:  "password1",
+				FirstName: u.FirstName,
+			},
+			res: nil,
+			err: user.ErrInvalidArgument,
+		},
 	}
 
 	for _, tt := range tests {
@@ -98,3 +118,949 @@ func TestService_Create(t *testing.T) {
 		})
 	}
 }
+
+func TestService_RefreshToken(t *testing.T) {
+	maker, err := authtoken.NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	refreshToken := "refresh-token"
+	refreshTokenHash := "0eb17643d4e9261163783a420859c92c7d212fa9624106a12b510afbec266120"
+	revokeTime := time.Now().Add(-time.Minute)
+
+	newSession := func() *user.Session {
+		return &user.Session{
+			ID:               uuid.New(),
+			UserID:           1,
+			RefreshTokenHash: refreshTokenHash,
+			ExpireTime:       time.Now().Add(time.Hour),
+		}
+	}
+
+	tests := []struct {
+		name       string
+		buildStubs func(repo *mock.MockRepository, sess *user.Session)
+		revoked    bool
+		err        error
+	}{
+		{
+			name: "OK",
+			buildStubs: func(repo *mock.MockRepository, sess *user.Session) {
+				repo.EXPECT().
+					GetSessionByRefreshTokenHash(gomock.Any(), refreshTokenHash).
+					Times(1).
+					Return(sess, nil)
+				repo.EXPECT().GetByID(gomock.Any(), sess.UserID).Times(1).Return(&user.User{ID: sess.UserID, Role: user.RoleUser}, nil)
+				repo.EXPECT().
+					RotateRefreshToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, req user.RepoRotateRefreshTokenReq) (*user.Session, error) {
+						assert.Equal(t, sess.ID, req.ID)
+						assert.Equal(t, refreshTokenHash, req.OldHash)
+						assert.NotEqual(t, refreshTokenHash, req.NewHash)
+						s := *sess
+						s.PreviousRefreshTokenHash = req.OldHash
+						s.RefreshTokenHash = req.NewHash
+						s.ExpireTime = req.ExpireTime
+						return &s, nil
+					})
+			},
+		},
+		{
+			name: "Unknown token",
+			buildStubs: func(repo *mock.MockRepository, sess *user.Session) {
+				repo.EXPECT().
+					GetSessionByRefreshTokenHash(gomock.Any(), refreshTokenHash).
+					Times(1).
+					Return(nil, user.ErrNotFound)
+			},
+			err: user.ErrUnauthenticated,
+		},
+		{
+			name: "Revoked session",
+			buildStubs: func(repo *mock.MockRepository, sess *user.Session) {
+				sess.RevokeTime = &revokeTime
+				repo.EXPECT().
+					GetSessionByRefreshTokenHash(gomock.Any(), refreshTokenHash).
+					Times(1).
+					Return(sess, nil)
+				repo.EXPECT().RotateRefreshToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			err: user.ErrUnauthenticated,
+		},
+		{
+			name: "Reused token revokes session",
+			buildStubs: func(repo *mock.MockRepository, sess *user.Session) {
+				sess.PreviousRefreshTokenHash = refreshTokenHash
+				sess.RefreshTokenHash = "newer"
+				repo.EXPECT().
+					GetSessionByRefreshTokenHash(gomock.Any(), refreshTokenHash).
+					Times(1).
+					Return(sess, nil)
+				repo.EXPECT().
+					RevokeSession(gomock.Any(), sess.UserID, sess.ID).
+					Times(1).
+					Return(nil)
+				repo.EXPECT().RotateRefreshToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			revoked: true,
+			err:     user.ErrUnauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock.NewMockRepository(ctrl)
+			sess := newSession()
+			tt.buildStubs(repo, sess)
+
+			denylist := user.NewDenylist(repo, time.Minute)
+			svc := user.NewService(repo, maker, nil, user.WithDenylist(denylist))
+
+			res, err := svc.RefreshToken(context.Background(), refreshToken)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.revoked, denylist.Contains(sess.ID))
+			if tt.err != nil {
+				assert.Nil(t, res)
+				return
+			}
+
+			require.NotNil(t, res)
+			assert.NotEqual(t, refreshToken, res.RefreshToken)
+
+			payload, err := maker.VerifyToken(res.AccessToken)
+			require.NoError(t, err)
+			assert.Equal(t, sess.ID, payload.ID)
+			assert.Equal(t, sess.UserID, payload.UserID)
+		})
+	}
+}
+
+func TestService_Login_TOTP(t *testing.T) {
+	maker, err := authtoken.NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	passwordHash, err := util.HashPassword("password1")
+	require.NoError(t, err)
+	u := &user.User{ID: 1, Username: "user1", PasswordHash: passwordHash}
+	confirmTime := time.Now()
+
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockRepository(ctrl)
+	repo.EXPECT().GetByUsername(gomock.Any(), u.Username).Times(1).Return(u, nil)
+	repo.EXPECT().GetLoginFailures(gomock.Any(), u.ID).Times(1).Return(nil, user.ErrNotFound)
+	repo.EXPECT().
+		GetTOTP(gomock.Any(), u.ID).
+		Times(1).
+		Return(&user.TOTP{UserID: u.ID, Secret: "SECRET", ConfirmTime: &confirmTime}, nil)
+	repo.EXPECT().
+		CreateLoginChallenge(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, req user.RepoCreateLoginChallengeReq) error {
+			assert.Equal(t, u.ID, req.UserID)
+			assert.Equal(t, "agent", req.UserAgent)
+			return nil
+		})
+	repo.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+
+	svc := user.NewService(repo, maker, nil)
+	res, err := svc.Login(context.Background(), user.SvcLoginReq{
+		Username:  u.Username,
+		Password:  "password1",
+		UserAgent: "agent",
+	})
+	require.NoError(t, err)
+	assert.True(t, res.MFARequired)
+	assert.NotEmpty(t, res.MFAToken)
+	assert.Empty(t, res.AccessToken)
+	assert.Empty(t, res.RefreshToken)
+}
+
+func TestService_VerifyLogin(t *testing.T) {
+	maker, err := authtoken.NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	step := totp.Step(time.Now())
+	code, err := totp.Code(secret, step)
+	require.NoError(t, err)
+
+	confirmTime := time.Now()
+	challenge := &user.LoginChallenge{UserID: 1, UserAgent: "agent", ExpireTime: time.Now().Add(time.Minute)}
+	secretTOTP := &user.TOTP{UserID: 1, Secret: secret, ConfirmTime: &confirmTime}
+
+	tests := []struct {
+		name       string
+		code       string
+		buildStubs func(repo *mock.MockRepository)
+		err        error
+	}{
+		{
+			name: "OK",
+			code: code,
+			buildStubs: func(repo *mock.MockRepository) {
+				repo.EXPECT().UseLoginChallengeAttempt(gomock.Any(), gomock.Any(), 5).Times(1).Return(challenge, nil)
+				repo.EXPECT().GetTOTP(gomock.Any(), uint64(1)).Times(1).Return(secretTOTP, nil)
+				repo.EXPECT().UseTOTPStep(gomock.Any(), uint64(1), step).Times(1).Return(nil)
+				repo.EXPECT().DeleteLoginChallenge(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				repo.EXPECT().GetByID(gomock.Any(), uint64(1)).Times(1).Return(&user.User{ID: 1, Role: user.RoleUser}, nil)
+				repo.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, req user.RepoCreateSessionReq) (*user.Session, error) {
+						assert.Equal(t, "agent", req.UserAgent)
+						return &user.Session{ID: req.ID, UserID: req.UserID, ExpireTime: req.ExpireTime}, nil
+					})
+			},
+		},
+		{
+			name: "Recovery code",
+			code: "ABCD-EFGH-JKMN",
+			buildStubs: func(repo *mock.MockRepository) {
+				repo.EXPECT().UseLoginChallengeAttempt(gomock.Any(), gomock.Any(), 5).Times(1).Return(challenge, nil)
+				repo.EXPECT().GetTOTP(gomock.Any(), uint64(1)).Times(1).Return(secretTOTP, nil)
+				repo.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				repo.EXPECT().
+					UseRecoveryCode(gomock.Any(), uint64(1), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, _ uint64, hash string) error {
+						assert.NotContains(t, hash, "abcd")
+						return nil
+					})
+				repo.EXPECT().DeleteLoginChallenge(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				repo.EXPECT().GetByID(gomock.Any(), uint64(1)).Times(1).Return(&user.User{ID: 1, Role: user.RoleUser}, nil)
+				repo.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, req user.RepoCreateSessionReq) (*user.Session, error) {
+						return &user.Session{ID: req.ID, UserID: req.UserID, ExpireTime: req.ExpireTime}, nil
+					})
+			},
+		},
+		{
+			name: "Replayed code",
+			code: code,
+			buildStubs: func(repo *mock.MockRepository) {
+				repo.EXPECT().UseLoginChallengeAttempt(gomock.Any(), gomock.Any(), 5).Times(1).Return(challenge, nil)
+				repo.EXPECT().GetTOTP(gomock.Any(), uint64(1)).Times(1).Return(secretTOTP, nil)
+				repo.EXPECT().UseTOTPStep(gomock.Any(), uint64(1), step).Times(1).Return(user.ErrNotFound)
+				repo.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			err: user.ErrUnauthenticated,
+		},
+		{
+			name: "Unknown challenge",
+			code: code,
+			buildStubs: func(repo *mock.MockRepository) {
+				repo.EXPECT().UseLoginChallengeAttempt(gomock.Any(), gomock.Any(), 5).Times(1).Return(nil, user.ErrNotFound)
+				repo.EXPECT().GetTOTP(gomock.Any(), gomock.Any()).Times(0)
+			},
+			err: user.ErrUnauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock.NewMockRepository(ctrl)
+			tt.buildStubs(repo)
+
+			svc := user.NewService(repo, maker, nil)
+			res, err := svc.VerifyLogin(context.Background(), user.SvcVerifyLoginReq{MFAToken: "mfa-token", Code: tt.code})
+			assert.ErrorIs(t, err, tt.err)
+			if tt.err != nil {
+				assert.Nil(t, res)
+				return
+			}
+
+			require.NotNil(t, res)
+			assert.NotEmpty(t, res.AccessToken)
+			assert.NotEmpty(t, res.RefreshToken)
+		})
+	}
+}
+
+func TestService_ConfirmTOTP(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	step := totp.Step(time.Now())
+	code, err := totp.Code(secret, step)
+	require.NoError(t, err)
+
+	ctx := authtoken.NewContext(context.Background(), &authtoken.Payload{UserID: 1})
+
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockRepository(ctrl)
+	repo.EXPECT().GetTOTP(gomock.Any(), uint64(1)).Times(2).Return(&user.TOTP{UserID: 1, Secret: secret}, nil)
+	repo.EXPECT().
+		ConfirmTOTP(gomock.Any(), uint64(1), step, gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, _ uint64, _ int64, hashes []string) error {
+			assert.Len(t, hashes, 10)
+			return nil
+		})
+
+	svc := user.NewService(repo, nil, nil)
+
+	_, err = svc.ConfirmTOTP(ctx, "000000"+code)
+	assert.ErrorIs(t, err, user.ErrInvalidArgument)
+
+	recoveryCodes, err := svc.ConfirmTOTP(ctx, code)
+	require.NoError(t, err)
+	require.Len(t, recoveryCodes, 10)
+	assert.Regexp(t, "^[a-z2-9]{4}-[a-z2-9]{4}-[a-z2-9]{4}$", recoveryCodes[0])
+}
+
+func TestService_ChangePassword(t *testing.T) {
+	passwordHash, err := util.HashPassword("password1")
+	require.NoError(t, err)
+	u := &user.User{ID: 1, Username: "user1", PasswordHash: passwordHash}
+	current, other := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name       string
+		req        user.SvcChangePasswordReq
+		buildStubs func(repo *mock.MockRepository)
+		revoked    bool
+		err        error
+	}{
+		{
+			name: "OK",
+			req:  user.SvcChangePasswordReq{OldPassword: "password1", NewPassword: "c0rrect-h0rse"},
+			buildStubs: func(repo *mock.MockRepository) {
+				repo.EXPECT().GetByID(gomock.Any(), u.ID).Times(1).Return(u, nil)
+				repo.EXPECT().
+					UpdatePassword(gomock.Any(), u.ID, gomock.Any(), current).
+					Times(1).
+					DoAndReturn(func(_ context.Context, _ uint64, hash string, _ uuid.UUID) ([]uuid.UUID, error) {
+						assert.NoError(t, util.CheckPassword("c0rrect-h0rse", hash))
+						return []uuid.UUID{other}, nil
+					})
+			},
+			revoked: true,
+		},
+		{
+			name: "Wrong old password",
+			req:  user.SvcChangePasswordReq{OldPassword: "wrong", NewPassword: "c0rrect-h0rse"},
+			buildStubs: func(repo *mock.MockRepository) {
+				repo.EXPECT().GetByID(gomock.Any(), u.ID).Times(1).Return(u, nil)
+				repo.EXPECT().UpdatePassword(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			err: user.ErrInvalidArgument,
+		},
+		{
+			name: "Weak new password",
+			req:  user.SvcChangePasswordReq{OldPassword: "password1", NewPassword: "user1234"},
+			buildStubs: func(repo *mock.MockRepository) {
+				repo.EXPECT().GetByID(gomock.Any(), u.ID).Times(1).Return(u, nil)
+				repo.EXPECT().UpdatePassword(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			err: user.ErrInvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock.NewMockRepository(ctrl)
+			tt.buildStubs(repo)
+
+			denylist := user.NewDenylist(repo, time.Minute)
+			svc := user.NewService(repo, nil, nil, user.WithDenylist(denylist))
+
+			ctx := authtoken.NewContext(context.Background(), &authtoken.Payload{ID: current, UserID: u.ID})
+			err := svc.ChangePassword(ctx, tt.req)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.revoked, denylist.Contains(other))
+			assert.False(t, denylist.Contains(current))
+		})
+	}
+}
+
+func TestService_DeleteAccount(t *testing.T) {
+	passwordHash, err := util.HashPassword("password1")
+	require.NoError(t, err)
+	u := &user.User{ID: 1, Username: "user1", PasswordHash: passwordHash}
+	sessionID := uuid.New()
+
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockRepository(ctrl)
+	repo.EXPECT().GetByID(gomock.Any(), u.ID).Times(2).Return(u, nil)
+	repo.EXPECT().Delete(gomock.Any(), u.ID).Times(1).Return([]uuid.UUID{sessionID}, nil)
+
+	denylist := user.NewDenylist(repo, time.Minute)
+	svc := user.NewService(repo, nil, nil, user.WithDenylist(denylist))
+	ctx := authtoken.NewContext(context.Background(), &authtoken.Payload{ID: sessionID, UserID: u.ID})
+
+	assert.ErrorIs(t, svc.DeleteAccount(ctx, "wrong"), user.ErrInvalidArgument)
+	require.NoError(t, svc.DeleteAccount(ctx, "password1"))
+	assert.True(t, denylist.Contains(sessionID))
+}
+
+func TestService_Login_Throttle(t *testing.T) {
+	maker, err := authtoken.NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	passwordHash, err := util.HashPassword("password1")
+	require.NoError(t, err)
+	u := &user.User{ID: 1, Username: "user1", PasswordHash: passwordHash}
+	lockedUntil := time.Now().Add(time.Minute)
+	throttle := user.LoginThrottle{DelayAfter: 2, LockoutAfter: 4, LockoutDuration: time.Hour, Window: time.Hour}
+
+	tests := []struct {
+		name       string
+		password   string
+		buildStubs func(repo *mock.MockRepository)
+		err        error
+	}{
+		{
+			name:     "Locked",
+			password: "password1",
+			buildStubs: func(repo *mock.MockRepository) {
+				repo.EXPECT().
+					GetLoginFailures(gomock.Any(), u.ID).
+					Times(1).
+					Return(&user.LoginFailures{UserID: u.ID, Failures: 4, LockedUntil: &lockedUntil}, nil)
+				repo.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			err: user.ErrResourceExhausted,
+		},
+		{
+			name:     "First failure",
+			password: "wrong",
+			buildStubs: func(repo *mock.MockRepository) {
+				repo.EXPECT().GetLoginFailures(gomock.Any(), u.ID).Times(1).Return(nil, user.ErrNotFound)
+				repo.EXPECT().
+					RecordLoginFailure(gomock.Any(), u.ID, gomock.Any()).
+					Times(1).
+					Return(&user.LoginFailures{UserID: u.ID, Failures: 1}, nil)
+				repo.EXPECT().LockLogin(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			err: user.ErrUnauthenticated,
+		},
+		{
+			name:     "Delay",
+			password: "wrong",
+			buildStubs: func(repo *mock.MockRepository) {
+				repo.EXPECT().GetLoginFailures(gomock.Any(), u.ID).Times(1).Return(&user.LoginFailures{UserID: u.ID, Failures: 2}, nil)
+				repo.EXPECT().
+					RecordLoginFailure(gomock.Any(), u.ID, gomock.Any()).
+					Times(1).
+					Return(&user.LoginFailures{UserID: u.ID, Failures: 3}, nil)
+				repo.EXPECT().
+					LockLogin(gomock.Any(), u.ID, gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, _ uint64, until time.Time) error {
+						assert.WithinDuration(t, time.Now().Add(2*time.Second), until, time.Second)
+						return nil
+					})
+			},
+			err: user.ErrUnauthenticated,
+		},
+		{
+			name:     "Lockout",
+			password: "wrong",
+			buildStubs: func(repo *mock.MockRepository) {
+				repo.EXPECT().GetLoginFailures(gomock.Any(), u.ID).Times(1).Return(&user.LoginFailures{UserID: u.ID, Failures: 3}, nil)
+				repo.EXPECT().
+					RecordLoginFailure(gomock.Any(), u.ID, gomock.Any()).
+					Times(1).
+					Return(&user.LoginFailures{UserID: u.ID, Failures: 4}, nil)
+				repo.EXPECT().
+					LockLogin(gomock.Any(), u.ID, gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, _ uint64, until time.Time) error {
+						assert.WithinDuration(t, time.Now().Add(time.Hour), until, time.Second)
+						return nil
+					})
+			},
+			err: user.ErrUnauthenticated,
+		},
+		{
+			name:     "Success resets failures",
+			password: "password1",
+			buildStubs: func(repo *mock.MockRepository) {
+				repo.EXPECT().GetLoginFailures(gomock.Any(), u.ID).Times(1).Return(&user.LoginFailures{UserID: u.ID, Failures: 3}, nil)
+				repo.EXPECT().ResetLoginFailures(gomock.Any(), u.ID).Times(1).Return(nil)
+				repo.EXPECT().GetTOTP(gomock.Any(), u.ID).Times(1).Return(nil, user.ErrNotFound)
+				repo.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, req user.RepoCreateSessionReq) (*user.Session, error) {
+						return &user.Session{ID: req.ID, UserID: req.UserID, ExpireTime: req.ExpireTime}, nil
+					})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock.NewMockRepository(ctrl)
+			repo.EXPECT().GetByUsername(gomock.Any(), u.Username).Times(1).Return(u, nil)
+			tt.buildStubs(repo)
+
+			svc := user.NewService(repo, maker, nil, user.WithLoginThrottle(throttle))
+			res, err := svc.Login(context.Background(), user.SvcLoginReq{Username: u.Username, Password: tt.password})
+			assert.ErrorIs(t, err, tt.err)
+			if tt.err != nil {
+				assert.Nil(t, res)
+				return
+			}
+			assert.NotEmpty(t, res.AccessToken)
+		})
+	}
+}
+
+func TestService_CreateAPIKey(t *testing.T) {
+	ctx := authtoken.NewContext(context.Background(), &authtoken.Payload{UserID: 1})
+
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockRepository(ctrl)
+	repo.EXPECT().
+		CreateAPIKey(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, req user.RepoCreateAPIKeyReq) (*user.APIKey, error) {
+			assert.Equal(t, uint64(1), req.UserID)
+			assert.Equal(t, "script", req.Name)
+			assert.Equal(t, []string{user.ScopePortfoliosRead}, req.Scopes)
+			return &user.APIKey{
+				ID:      req.ID,
+				UserID:  req.UserID,
+				Name:    req.Name,
+				Prefix:  req.Prefix,
+				KeyHash: req.KeyHash,
+				Scopes:  req.Scopes,
+			}, nil
+		})
+
+	svc := user.NewService(repo, nil, nil)
+
+	past := time.Now().Add(-time.Minute)
+	_, err := svc.CreateAPIKey(ctx, user.SvcCreateAPIKeyReq{Name: "script", Scopes: []string{"admin"}, ExpireTime: &past})
+	assert.ErrorIs(t, err, user.ErrInvalidArgument)
+
+	res, err := svc.CreateAPIKey(ctx, user.SvcCreateAPIKeyReq{
+		Name:   " script ",
+		Scopes: []string{user.ScopePortfoliosRead, user.ScopePortfoliosRead},
+	})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(res.Key, res.APIKey.Prefix))
+	assert.NotContains(t, res.APIKey.KeyHash, res.Key)
+}
+
+func TestService_AuthenticateAPIKey(t *testing.T) {
+	key := "cwk_key"
+	expired := time.Now().Add(-time.Minute)
+	id := uuid.New()
+
+	tests := []struct {
+		name       string
+		key        string
+		buildStubs func(repo *mock.MockRepository)
+		err        error
+	}{
+		{
+			name: "OK",
+			key:  key,
+			buildStubs: func(repo *mock.MockRepository) {
+				repo.EXPECT().
+					GetAPIKeyByHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&user.APIKey{ID: id, UserID: 1, Scopes: []string{user.ScopeAlerts}}, nil)
+				repo.EXPECT().TouchAPIKey(gomock.Any(), id).Times(1).Return(nil)
+			},
+		},
+		{
+			name: "Unknown",
+			key:  key,
+			buildStubs: func(repo *mock.MockRepository) {
+				repo.EXPECT().GetAPIKeyByHash(gomock.Any(), gomock.Any()).Times(1).Return(nil, user.ErrNotFound)
+			},
+			err: user.ErrUnauthenticated,
+		},
+		{
+			name: "Expired",
+			key:  key,
+			buildStubs: func(repo *mock.MockRepository) {
+				repo.EXPECT().
+					GetAPIKeyByHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&user.APIKey{ID: id, UserID: 1, ExpireTime: &expired}, nil)
+			},
+			err: user.ErrUnauthenticated,
+		},
+		{
+			name: "Not a key",
+			key:  "token",
+			buildStubs: func(repo *mock.MockRepository) {
+				repo.EXPECT().GetAPIKeyByHash(gomock.Any(), gomock.Any()).Times(0)
+			},
+			err: user.ErrUnauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock.NewMockRepository(ctrl)
+			tt.buildStubs(repo)
+
+			svc := user.NewService(repo, nil, nil)
+
+			payload, err := svc.AuthenticateAPIKey(context.Background(), tt.key)
+			assert.ErrorIs(t, err, tt.err)
+			if tt.err == nil {
+				require.NotNil(t, payload)
+				assert.Equal(t, id, payload.ID)
+				assert.Equal(t, uint64(1), payload.UserID)
+				assert.Equal(t, []string{user.ScopeAlerts}, payload.Scopes)
+				assert.NoError(t, payload.Valid())
+			}
+		})
+	}
+}
+
+func TestService_Login_ThrottleManyFailures(t *testing.T) {
+	maker, err := authtoken.NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	u := &user.User{ID: 1, Username: "user1", PasswordHash: "hash"}
+	// Far more failures before the lockout than doublings of the delay fit in a duration.
+	throttle := user.LoginThrottle{DelayAfter: 1, LockoutAfter: 1000, LockoutDuration: time.Hour, Window: time.Hour}
+
+	for _, failures := range []int{35, 64, 999} {
+		ctrl := gomock.NewController(t)
+		repo := mock.NewMockRepository(ctrl)
+		repo.EXPECT().GetByUsername(gomock.Any(), u.Username).Times(1).Return(u, nil)
+		repo.EXPECT().GetLoginFailures(gomock.Any(), u.ID).Times(1).Return(&user.LoginFailures{UserID: u.ID, Failures: failures - 1}, nil)
+		repo.EXPECT().
+			RecordLoginFailure(gomock.Any(), u.ID, gomock.Any()).
+			Times(1).
+			Return(&user.LoginFailures{UserID: u.ID, Failures: failures}, nil)
+		repo.EXPECT().
+			LockLogin(gomock.Any(), u.ID, gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, _ uint64, until time.Time) error {
+				assert.WithinDuration(t, time.Now().Add(time.Hour), until, time.Second, "%d failures", failures)
+				return nil
+			})
+
+		svc := user.NewService(repo, maker, nil, user.WithLoginThrottle(throttle))
+		_, err := svc.Login(context.Background(), user.SvcLoginReq{Username: u.Username, Password: "wrong"})
+		assert.ErrorIs(t, err, user.ErrUnauthenticated)
+	}
+}
+
+func TestService_Login_Disabled(t *testing.T) {
+	passwordHash, err := util.HashPassword("password1")
+	require.NoError(t, err)
+	disableTime := time.Now()
+	u := &user.User{ID: 1, Username: "user1", PasswordHash: passwordHash, DisableTime: &disableTime}
+
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockRepository(ctrl)
+	repo.EXPECT().GetByUsername(gomock.Any(), u.Username).Times(1).Return(u, nil)
+	repo.EXPECT().GetLoginFailures(gomock.Any(), u.ID).Times(1).Return(nil, user.ErrNotFound)
+	repo.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+
+	svc := user.NewService(repo, nil, nil)
+	_, err = svc.Login(context.Background(), user.SvcLoginReq{Username: u.Username, Password: "password1"})
+	assert.ErrorIs(t, err, user.ErrAccountDisabled)
+	assert.ErrorIs(t, err, user.ErrPermissionDenied)
+}
+
+func TestService_ListUsers(t *testing.T) {
+	users := []*user.User{{ID: 3}, {ID: 5}, {ID: 8}}
+
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockRepository(ctrl)
+	repo.EXPECT().
+		ListUsers(gomock.Any(), user.RepoListUsersReq{Query: "bob", Limit: 3}).
+		Times(1).
+		Return(users, nil)
+	repo.EXPECT().
+		ListUsers(gomock.Any(), user.RepoListUsersReq{Query: "bob", AfterID: 5, Limit: 3}).
+		Times(1).
+		Return(users[2:], nil)
+
+	svc := user.NewService(repo, nil, nil)
+
+	res, err := svc.ListUsers(context.Background(), user.SvcListUsersReq{Query: "bob", PageSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, users[:2], res.Users)
+	assert.Equal(t, "5", res.NextPageToken)
+
+	res, err = svc.ListUsers(context.Background(), user.SvcListUsersReq{Query: "bob", PageSize: 2, PageToken: res.NextPageToken})
+	require.NoError(t, err)
+	assert.Equal(t, users[2:], res.Users)
+	assert.Empty(t, res.NextPageToken)
+
+	_, err = svc.ListUsers(context.Background(), user.SvcListUsersReq{Role: "root", PageToken: "x"})
+	assert.ErrorIs(t, err, user.ErrInvalidArgument)
+}
+
+func TestService_DisableUser(t *testing.T) {
+	sessionID := uuid.New()
+	apiKeyID := uuid.New()
+
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockRepository(ctrl)
+	repo.EXPECT().Disable(gomock.Any(), uint64(2)).Times(1).Return([]uuid.UUID{sessionID, apiKeyID}, nil)
+
+	denylist := user.NewDenylist(repo, time.Minute)
+	svc := user.NewService(repo, nil, nil, user.WithDenylist(denylist))
+	ctx := authtoken.NewContext(context.Background(), &authtoken.Payload{UserID: 1, Role: string(user.RoleAdmin)})
+
+	assert.ErrorIs(t, svc.DisableUser(ctx, 1), user.ErrFailedPrecondition)
+	require.NoError(t, svc.DisableUser(ctx, 2))
+	assert.True(t, denylist.Contains(sessionID))
+	assert.True(t, denylist.Contains(apiKeyID))
+}
+
+func TestService_SetRole(t *testing.T) {
+	sessionID := uuid.New()
+
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockRepository(ctrl)
+	repo.EXPECT().SetRole(gomock.Any(), uint64(2), user.RoleSupport).Times(1).Return([]uuid.UUID{sessionID}, nil)
+
+	denylist := user.NewDenylist(repo, time.Minute)
+	svc := user.NewService(repo, nil, nil, user.WithDenylist(denylist))
+	ctx := authtoken.NewContext(context.Background(), &authtoken.Payload{UserID: 1, Role: string(user.RoleAdmin)})
+
+	assert.ErrorIs(t, svc.SetRole(ctx, 2, "root"), user.ErrInvalidArgument)
+	assert.ErrorIs(t, svc.SetRole(ctx, 1, user.RoleUser), user.ErrFailedPrecondition)
+	require.NoError(t, svc.SetRole(ctx, 2, user.RoleSupport))
+	assert.True(t, denylist.Contains(sessionID))
+}
+
+func TestService_AdminTargetRole(t *testing.T) {
+	admin := &user.User{ID: 2, Username: "admin", Role: user.RoleAdmin}
+	colleague := &user.User{ID: 3, Username: "support", Role: user.RoleSupport}
+	customer := &user.User{ID: 4, Username: "customer", Role: user.RoleUser}
+
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockRepository(ctrl)
+	for _, u := range []*user.User{admin, colleague, customer} {
+		repo.EXPECT().GetByID(gomock.Any(), u.ID).AnyTimes().Return(u, nil)
+	}
+	repo.EXPECT().DeleteTOTP(gomock.Any(), customer.ID).Times(1).Return(nil)
+	repo.EXPECT().DeleteTOTP(gomock.Any(), admin.ID).Times(1).Return(nil)
+	repo.EXPECT().RevokeUserSessions(gomock.Any(), customer.ID).Times(1).Return(nil, nil)
+
+	svc := user.NewService(repo, nil, nil)
+	support := authtoken.NewContext(context.Background(), &authtoken.Payload{UserID: 1, Role: string(user.RoleSupport)})
+
+	// Support staff can't strip the second factor of administrators or end their sessions.
+	assert.ErrorIs(t, svc.ResetTOTP(support, admin.ID), user.ErrPermissionDenied)
+	assert.ErrorIs(t, svc.LogoutUser(support, admin.ID), user.ErrPermissionDenied)
+	assert.ErrorIs(t, svc.ResetTOTP(support, colleague.ID), user.ErrPermissionDenied)
+	assert.ErrorIs(t, svc.LogoutUser(support, colleague.ID), user.ErrPermissionDenied)
+	require.NoError(t, svc.ResetTOTP(support, customer.ID))
+	require.NoError(t, svc.LogoutUser(support, customer.ID))
+
+	// The reset-totp command runs without a caller.
+	require.NoError(t, svc.ResetTOTP(context.Background(), admin.ID))
+}
+
+// testOTPManager accepts only its code.
+type testOTPManager struct {
+	user.OTPManager
+	code string
+}
+
+func (m testOTPManager) Verify(_ context.Context, _ uint64, code string) error {
+	if code != m.code {
+		return user.ErrInvalidArgument
+	}
+	return nil
+}
+
+func TestService_TokenClaims(t *testing.T) {
+	maker, err := authtoken.NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	u := &user.User{ID: 1, Username: "user1", Role: user.RoleUser}
+
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockRepository(ctrl)
+	repo.EXPECT().GetByUsername(gomock.Any(), u.Username).Times(1).Return(u, nil)
+	repo.EXPECT().
+		CreateSession(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, req user.RepoCreateSessionReq) (*user.Session, error) {
+			assert.Equal(t, user.TelegramScopes, req.Scopes)
+			return &user.Session{ID: req.ID, UserID: req.UserID, Scopes: req.Scopes, ExpireTime: req.ExpireTime}, nil
+		})
+
+	svc := user.NewService(repo, maker, testOTPManager{code: "123456"}, user.WithTokenClaims("issuer", "audience"))
+
+	res, err := svc.VerifyOTP(context.Background(), u.Username, "123456")
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(res.Token, authtoken.ExpectIssuer("issuer"), authtoken.ExpectAudience("audience"))
+	require.NoError(t, err)
+	assert.Equal(t, user.TelegramScopes, payload.Scopes)
+
+	_, err = maker.VerifyToken(res.Token, authtoken.ExpectAudience("other"))
+	assert.ErrorIs(t, err, authtoken.ErrInvalidAudience)
+}
+
+// newTestIDTokenVerifier starts a mock OpenID provider and returns it with a verifier of its tokens.
+func newTestIDTokenVerifier(t *testing.T) (*oidctest.Server, *oidc.IDTokenVerifier) {
+	t.Helper()
+
+	srv, err := oidctest.NewServer("cryptowatch", "secret")
+	require.NoError(t, err)
+	t.Cleanup(srv.Close)
+
+	provider, err := oidc.Discover(context.Background(), srv.Client(), srv.Issuer())
+	require.NoError(t, err)
+
+	return srv, oidc.NewIDTokenVerifier(provider, "cryptowatch", srv.Client())
+}
+
+func TestService_LoginOIDC(t *testing.T) {
+	srv, verifier := newTestIDTokenVerifier(t)
+	maker, err := authtoken.NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	linked := &user.User{ID: 1, Username: "alice", Role: user.RoleUser}
+	createSession := func(_ context.Context, req user.RepoCreateSessionReq) (*user.Session, error) {
+		return &user.Session{ID: req.ID, UserID: req.UserID, ExpireTime: req.ExpireTime}, nil
+	}
+
+	t.Run("linked", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := mock.NewMockRepository(ctrl)
+		repo.EXPECT().
+			GetIdentity(gomock.Any(), srv.Issuer(), "1234567890").
+			Times(1).
+			Return(&user.Identity{Issuer: srv.Issuer(), Subject: "1234567890", UserID: linked.ID}, nil)
+		repo.EXPECT().GetByID(gomock.Any(), linked.ID).Times(1).Return(linked, nil)
+		repo.EXPECT().GetTOTP(gomock.Any(), linked.ID).Times(1).Return(nil, user.ErrNotFound)
+		repo.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1).DoAndReturn(createSession)
+		repo.EXPECT().CreateWithIdentity(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+		svc := user.NewService(repo, maker, nil, user.WithOIDC(verifier, true))
+		res, err := svc.LoginOIDC(context.Background(), user.SvcLoginOIDCReq{IDToken: srv.IDToken("nonce", nil), Nonce: "nonce"})
+		require.NoError(t, err)
+
+		payload, err := maker.VerifyToken(res.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, linked.ID, payload.UserID)
+	})
+
+	t.Run("provisioned", func(t *testing.T) {
+		srv.SetClaims(oidctest.Claims{Subject: "42", Email: "bob.smith@example.com", Name: "Bob Smith"})
+		t.Cleanup(func() { srv.SetClaims(oidctest.Claims{Subject: "1234567890", PreferredUsername: "alice"}) })
+
+		ctrl := gomock.NewController(t)
+		repo := mock.NewMockRepository(ctrl)
+		repo.EXPECT().GetIdentity(gomock.Any(), srv.Issuer(), "42").Times(2).Return(nil, user.ErrNotFound)
+		var usernames []string
+		repo.EXPECT().
+			CreateWithIdentity(gomock.Any(), gomock.Any(), gomock.Any()).
+			Times(2).
+			DoAndReturn(func(_ context.Context, req user.RepoCreateReq, identity user.RepoCreateIdentityReq) (*user.User, error) {
+				assert.Empty(t, req.PasswordHash)
+				assert.Equal(t, "Bob", req.FirstName)
+				assert.Equal(t, "Smith", req.LastName)
+				assert.Equal(t, srv.Issuer(), identity.Issuer)
+				assert.Equal(t, "42", identity.Subject)
+				assert.Equal(t, "bob.smith@example.com", identity.Email)
+
+				usernames = append(usernames, req.Username)
+				if len(usernames) == 1 {
+					// The username is taken.
+					return nil, user.ErrFailedPrecondition
+				}
+				return &user.User{ID: 2, Username: req.Username, Role: user.RoleUser}, nil
+			})
+		repo.EXPECT().GetTOTP(gomock.Any(), uint64(2)).Times(1).Return(nil, user.ErrNotFound)
+		repo.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1).DoAndReturn(createSession)
+
+		svc := user.NewService(repo, maker, nil, user.WithOIDC(verifier, true))
+		_, err := svc.LoginOIDC(context.Background(), user.SvcLoginOIDCReq{IDToken: srv.IDToken("nonce", nil), Nonce: "nonce"})
+		require.NoError(t, err)
+
+		require.Len(t, usernames, 2)
+		assert.Equal(t, "bobsmith", usernames[0])
+		assert.Regexp(t, regexp.MustCompile(`^bobsmith[0-9]{4}$`), usernames[1])
+	})
+
+	t.Run("provisioning disabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := mock.NewMockRepository(ctrl)
+		repo.EXPECT().GetIdentity(gomock.Any(), gomock.Any(), gomock.Any()).Times(1).Return(nil, user.ErrNotFound)
+		repo.EXPECT().CreateWithIdentity(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+		svc := user.NewService(repo, maker, nil, user.WithOIDC(verifier, false))
+		_, err := svc.LoginOIDC(context.Background(), user.SvcLoginOIDCReq{IDToken: srv.IDToken("nonce", nil), Nonce: "nonce"})
+		assert.ErrorIs(t, err, user.ErrUnauthenticated)
+	})
+
+	t.Run("disabled user", func(t *testing.T) {
+		disableTime := time.Now()
+		disabled := &user.User{ID: 3, Username: "carol", DisableTime: &disableTime}
+
+		ctrl := gomock.NewController(t)
+		repo := mock.NewMockRepository(ctrl)
+		repo.EXPECT().
+			GetIdentity(gomock.Any(), gomock.Any(), gomock.Any()).
+			Times(1).
+			Return(&user.Identity{UserID: disabled.ID}, nil)
+		repo.EXPECT().GetByID(gomock.Any(), disabled.ID).Times(1).Return(disabled, nil)
+		repo.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+
+		svc := user.NewService(repo, maker, nil, user.WithOIDC(verifier, true))
+		_, err := svc.LoginOIDC(context.Background(), user.SvcLoginOIDCReq{IDToken: srv.IDToken("nonce", nil), Nonce: "nonce"})
+		assert.ErrorIs(t, err, user.ErrAccountDisabled)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := mock.NewMockRepository(ctrl)
+
+		svc := user.NewService(repo, maker, nil, user.WithOIDC(verifier, true))
+		_, err := svc.LoginOIDC(context.Background(), user.SvcLoginOIDCReq{IDToken: srv.IDToken("nonce", nil), Nonce: "other"})
+		assert.ErrorIs(t, err, user.ErrUnauthenticated)
+
+		svc = user.NewService(repo, maker, nil)
+		_, err = svc.LoginOIDC(context.Background(), user.SvcLoginOIDCReq{IDToken: srv.IDToken("nonce", nil), Nonce: "nonce"})
+		assert.ErrorIs(t, err, user.ErrFailedPrecondition)
+	})
+}
+
+func TestService_UnlinkIdentity(t *testing.T) {
+	withoutPassword := &user.User{ID: 1, Username: "alice"}
+	identities := []*user.Identity{{Issuer: "https://a.example.com", Subject: "1", UserID: 1}}
+
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockRepository(ctrl)
+	repo.EXPECT().GetByID(gomock.Any(), withoutPassword.ID).Times(2).Return(withoutPassword, nil)
+	gomock.InOrder(
+		repo.EXPECT().ListIdentities(gomock.Any(), withoutPassword.ID).Times(1).Return(identities, nil),
+		repo.EXPECT().
+			ListIdentities(gomock.Any(), withoutPassword.ID).
+			Times(1).
+			Return(append(identities, &user.Identity{Issuer: "https://b.example.com", Subject: "2", UserID: 1}), nil),
+	)
+	repo.EXPECT().DeleteIdentity(gomock.Any(), withoutPassword.ID, "https://a.example.com", "1").Times(1).Return(nil)
+
+	svc := user.NewService(repo, nil, nil)
+	ctx := authtoken.NewContext(context.Background(), &authtoken.Payload{UserID: withoutPassword.ID})
+
+	// The last way to log in stays.
+	err := svc.UnlinkIdentity(ctx, "https://a.example.com", "1")
+	assert.ErrorIs(t, err, user.ErrFailedPrecondition)
+
+	require.NoError(t, svc.UnlinkIdentity(ctx, "https://a.example.com", "1"))
+}
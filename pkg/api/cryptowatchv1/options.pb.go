@@ -0,0 +1,360 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.0
+// 	protoc        v3.17.3
+// source: api/proto/v1/options.proto
+
+package cryptowatchv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Policy tells who may call a method.
+type Policy int32
+
+const (
+	// Methods without a policy can't be called and fail server startup.
+	Policy_POLICY_UNSPECIFIED Policy = 0
+	// Anyone, no token required.
+	Policy_POLICY_PUBLIC Policy = 1
+	// Any user with a valid access token.
+	Policy_POLICY_AUTHENTICATED Policy = 2
+	// A user with a valid access token for the user id in the request.
+	Policy_POLICY_OWNER Policy = 3
+	// Administrators only.
+	Policy_POLICY_ADMIN Policy = 4
+	// Support staff and administrators.
+	Policy_POLICY_SUPPORT Policy = 5
+)
+
+// Enum value maps for Policy.
+var (
+	Policy_name = map[int32]string{
+		0: "POLICY_UNSPECIFIED",
+		1: "POLICY_PUBLIC",
+		2: "POLICY_AUTHENTICATED",
+		3: "POLICY_OWNER",
+		4: "POLICY_ADMIN",
+		5: "POLICY_SUPPORT",
+	}
+	Policy_value = map[string]int32{
+		"POLICY_UNSPECIFIED":   0,
+		"POLICY_PUBLIC":        1,
+		"POLICY_AUTHENTICATED": 2,
+		"POLICY_OWNER":         3,
+		"POLICY_ADMIN":         4,
+		"POLICY_SUPPORT":       5,
+	}
+)
+
+func (x Policy) Enum() *Policy {
+	p := new(Policy)
+	*p = x
+	return p
+}
+
+func (x Policy) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Policy) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_proto_v1_options_proto_enumTypes[0].Descriptor()
+}
+
+func (Policy) Type() protoreflect.EnumType {
+	return &file_api_proto_v1_options_proto_enumTypes[0]
+}
+
+func (x Policy) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Policy.Descriptor instead.
+func (Policy) EnumDescriptor() ([]byte, []int) {
+	return file_api_proto_v1_options_proto_rawDescGZIP(), []int{0}
+}
+
+type Authorization struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Policy Policy `protobuf:"varint,1,opt,name=policy,proto3,enum=cryptowatch.Policy" json:"policy,omitempty"`
+	// owner_field is the uint64 request field holding the user id checked by POLICY_OWNER.
+	// Defaults to user_id.
+	OwnerField string `protobuf:"bytes,2,opt,name=owner_field,json=ownerField,proto3" json:"owner_field,omitempty"`
+	// scopes let API keys and scoped access tokens, like those of the Telegram bot, call the method.
+	// A key or token needs one of the scopes unless it has none. Methods without scopes, like
+	// account management, can't be called with API keys or scoped tokens.
+	Scopes []string `protobuf:"bytes,3,rep,name=scopes,proto3" json:"scopes,omitempty"`
+}
+
+func (x *Authorization) Reset() {
+	*x = Authorization{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_proto_v1_options_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Authorization) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Authorization) ProtoMessage() {}
+
+func (x *Authorization) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_v1_options_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Authorization.ProtoReflect.Descriptor instead.
+func (*Authorization) Descriptor() ([]byte, []int) {
+	return file_api_proto_v1_options_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Authorization) GetPolicy() Policy {
+	if x != nil {
+		return x.Policy
+	}
+	return Policy_POLICY_UNSPECIFIED
+}
+
+func (x *Authorization) GetOwnerField() string {
+	if x != nil {
+		return x.OwnerField
+	}
+	return ""
+}
+
+func (x *Authorization) GetScopes() []string {
+	if x != nil {
+		return x.Scopes
+	}
+	return nil
+}
+
+// RateLimit protects a method against brute force. Calls are limited per client
+// address and, if key_field is set, per value of that string request field, like a username.
+type RateLimit struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	KeyField string `protobuf:"bytes,1,opt,name=key_field,json=keyField,proto3" json:"key_field,omitempty"`
+}
+
+func (x *RateLimit) Reset() {
+	*x = RateLimit{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_proto_v1_options_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RateLimit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RateLimit) ProtoMessage() {}
+
+func (x *RateLimit) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_v1_options_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RateLimit.ProtoReflect.Descriptor instead.
+func (*RateLimit) Descriptor() ([]byte, []int) {
+	return file_api_proto_v1_options_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *RateLimit) GetKeyField() string {
+	if x != nil {
+		return x.KeyField
+	}
+	return ""
+}
+
+var file_api_proto_v1_options_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*Authorization)(nil),
+		Field:         51000,
+		Name:          "cryptowatch.authorization",
+		Tag:           "bytes,51000,opt,name=authorization",
+		Filename:      "api/proto/v1/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*RateLimit)(nil),
+		Field:         51001,
+		Name:          "cryptowatch.rate_limit",
+		Tag:           "bytes,51001,opt,name=rate_limit",
+		Filename:      "api/proto/v1/options.proto",
+	},
+}
+
+// Extension fields to descriptorpb.MethodOptions.
+var (
+	// optional cryptowatch.Authorization authorization = 51000;
+	E_Authorization = &file_api_proto_v1_options_proto_extTypes[0]
+	// optional cryptowatch.RateLimit rate_limit = 51001;
+	E_RateLimit = &file_api_proto_v1_options_proto_extTypes[1]
+)
+
+var File_api_proto_v1_options_proto protoreflect.FileDescriptor
+
+var file_api_proto_v1_options_proto_rawDesc = []byte{
+	0x0a, 0x1a, 0x61, 0x70, 0x69, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x76, 0x31, 0x2f, 0x6f,
+	0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0b, 0x63, 0x72,
+	0x79, 0x70, 0x74, 0x6f, 0x77, 0x61, 0x74, 0x63, 0x68, 0x1a, 0x20, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
+	0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x64, 0x65, 0x73, 0x63, 0x72,
+	0x69, 0x70, 0x74, 0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x75, 0x0a, 0x0d, 0x41,
+	0x75, 0x74, 0x68, 0x6f, 0x72, 0x69, 0x7a, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x2b, 0x0a, 0x06,
+	0x70, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x13, 0x2e, 0x63,
+	0x72, 0x79, 0x70, 0x74, 0x6f, 0x77, 0x61, 0x74, 0x63, 0x68, 0x2e, 0x50, 0x6f, 0x6c, 0x69, 0x63,
+	0x79, 0x52, 0x06, 0x70, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x12, 0x1f, 0x0a, 0x0b, 0x6f, 0x77, 0x6e,
+	0x65, 0x72, 0x5f, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a,
+	0x6f, 0x77, 0x6e, 0x65, 0x72, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x63,
+	0x6f, 0x70, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x73, 0x63, 0x6f, 0x70,
+	0x65, 0x73, 0x22, 0x28, 0x0a, 0x09, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x12,
+	0x1b, 0x0a, 0x09, 0x6b, 0x65, 0x79, 0x5f, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x08, 0x6b, 0x65, 0x79, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x2a, 0x85, 0x01, 0x0a,
+	0x06, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x12, 0x16, 0x0a, 0x12, 0x50, 0x4f, 0x4c, 0x49, 0x43,
+	0x59, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12,
+	0x11, 0x0a, 0x0d, 0x50, 0x4f, 0x4c, 0x49, 0x43, 0x59, 0x5f, 0x50, 0x55, 0x42, 0x4c, 0x49, 0x43,
+	0x10, 0x01, 0x12, 0x18, 0x0a, 0x14, 0x50, 0x4f, 0x4c, 0x49, 0x43, 0x59, 0x5f, 0x41, 0x55, 0x54,
+	0x48, 0x45, 0x4e, 0x54, 0x49, 0x43, 0x41, 0x54, 0x45, 0x44, 0x10, 0x02, 0x12, 0x10, 0x0a, 0x0c,
+	0x50, 0x4f, 0x4c, 0x49, 0x43, 0x59, 0x5f, 0x4f, 0x57, 0x4e, 0x45, 0x52, 0x10, 0x03, 0x12, 0x10,
+	0x0a, 0x0c, 0x50, 0x4f, 0x4c, 0x49, 0x43, 0x59, 0x5f, 0x41, 0x44, 0x4d, 0x49, 0x4e, 0x10, 0x04,
+	0x12, 0x12, 0x0a, 0x0e, 0x50, 0x4f, 0x4c, 0x49, 0x43, 0x59, 0x5f, 0x53, 0x55, 0x50, 0x50, 0x4f,
+	0x52, 0x54, 0x10, 0x05, 0x3a, 0x62, 0x0a, 0x0d, 0x61, 0x75, 0x74, 0x68, 0x6f, 0x72, 0x69, 0x7a,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1e, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x4f, 0x70,
+	0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0xb8, 0x8e, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e,
+	0x63, 0x72, 0x79, 0x70, 0x74, 0x6f, 0x77, 0x61, 0x74, 0x63, 0x68, 0x2e, 0x41, 0x75, 0x74, 0x68,
+	0x6f, 0x72, 0x69, 0x7a, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0d, 0x61, 0x75, 0x74, 0x68, 0x6f,
+	0x72, 0x69, 0x7a, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x3a, 0x57, 0x0a, 0x0a, 0x72, 0x61, 0x74, 0x65,
+	0x5f, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x1e, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x4f,
+	0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0xb9, 0x8e, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16,
+	0x2e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x6f, 0x77, 0x61, 0x74, 0x63, 0x68, 0x2e, 0x52, 0x61, 0x74,
+	0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x52, 0x09, 0x72, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69,
+	0x74, 0x42, 0x17, 0x5a, 0x15, 0x70, 0x6b, 0x67, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x63, 0x72, 0x79,
+	0x70, 0x74, 0x6f, 0x77, 0x61, 0x74, 0x63, 0x68, 0x76, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x33,
+}
+
+var (
+	file_api_proto_v1_options_proto_rawDescOnce sync.Once
+	file_api_proto_v1_options_proto_rawDescData = file_api_proto_v1_options_proto_rawDesc
+)
+
+func file_api_proto_v1_options_proto_rawDescGZIP() []byte {
+	file_api_proto_v1_options_proto_rawDescOnce.Do(func() {
+		file_api_proto_v1_options_proto_rawDescData = protoimpl.X.CompressGZIP(file_api_proto_v1_options_proto_rawDescData)
+	})
+	return file_api_proto_v1_options_proto_rawDescData
+}
+
+var file_api_proto_v1_options_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_api_proto_v1_options_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_api_proto_v1_options_proto_goTypes = []interface{}{
+	(Policy)(0),                        // 0: cryptowatch.Policy
+	(*Authorization)(nil),              // 1: cryptowatch.Authorization
+	(*RateLimit)(nil),                  // 2: cryptowatch.RateLimit
+	(*descriptorpb.MethodOptions)(nil), // 3: google.protobuf.MethodOptions
+}
+var file_api_proto_v1_options_proto_depIdxs = []int32{
+	0, // 0: cryptowatch.Authorization.policy:type_name -> cryptowatch.Policy
+	3, // 1: cryptowatch.authorization:extendee -> google.protobuf.MethodOptions
+	3, // 2: cryptowatch.rate_limit:extendee -> google.protobuf.MethodOptions
+	1, // 3: cryptowatch.authorization:type_name -> cryptowatch.Authorization
+	2, // 4: cryptowatch.rate_limit:type_name -> cryptowatch.RateLimit
+	5, // [5:5] is the sub-list for method output_type
+	5, // [5:5] is the sub-list for method input_type
+	3, // [3:5] is the sub-list for extension type_name
+	1, // [1:3] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_api_proto_v1_options_proto_init() }
+func file_api_proto_v1_options_proto_init() {
+	if File_api_proto_v1_options_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_api_proto_v1_options_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Authorization); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_proto_v1_options_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RateLimit); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_api_proto_v1_options_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   2,
+			NumExtensions: 2,
+			NumServices:   0,
+		},
+		GoTypes:           file_api_proto_v1_options_proto_goTypes,
+		DependencyIndexes: file_api_proto_v1_options_proto_depIdxs,
+		EnumInfos:         file_api_proto_v1_options_proto_enumTypes,
+		MessageInfos:      file_api_proto_v1_options_proto_msgTypes,
+		ExtensionInfos:    file_api_proto_v1_options_proto_extTypes,
+	}.Build()
+	File_api_proto_v1_options_proto = out.File
+	file_api_proto_v1_options_proto_rawDesc = nil
+	file_api_proto_v1_options_proto_goTypes = nil
+	file_api_proto_v1_options_proto_depIdxs = nil
+}
@@ -0,0 +1,293 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.0
+// 	protoc        v3.17.3
+// source: api/proto/v2/prices.proto
+
+package cryptowatchv2
+
+import (
+	_ "cryptowatch/pkg/api/cryptowatchv1"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetPricesReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Tickers []string `protobuf:"bytes,1,rep,name=tickers,proto3" json:"tickers,omitempty"`
+}
+
+func (x *GetPricesReq) Reset() {
+	*x = GetPricesReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_proto_v2_prices_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetPricesReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPricesReq) ProtoMessage() {}
+
+func (x *GetPricesReq) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_v2_prices_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPricesReq.ProtoReflect.Descriptor instead.
+func (*GetPricesReq) Descriptor() ([]byte, []int) {
+	return file_api_proto_v2_prices_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GetPricesReq) GetTickers() []string {
+	if x != nil {
+		return x.Tickers
+	}
+	return nil
+}
+
+type Price struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ticker string  `protobuf:"bytes,1,opt,name=ticker,proto3" json:"ticker,omitempty"`
+	Price  float64 `protobuf:"fixed64,2,opt,name=price,proto3" json:"price,omitempty"`
+}
+
+func (x *Price) Reset() {
+	*x = Price{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_proto_v2_prices_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Price) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Price) ProtoMessage() {}
+
+func (x *Price) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_v2_prices_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Price.ProtoReflect.Descriptor instead.
+func (*Price) Descriptor() ([]byte, []int) {
+	return file_api_proto_v2_prices_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Price) GetTicker() string {
+	if x != nil {
+		return x.Ticker
+	}
+	return ""
+}
+
+func (x *Price) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+type GetPricesRes struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Prices []*Price `protobuf:"bytes,1,rep,name=prices,proto3" json:"prices,omitempty"`
+}
+
+func (x *GetPricesRes) Reset() {
+	*x = GetPricesRes{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_proto_v2_prices_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetPricesRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPricesRes) ProtoMessage() {}
+
+func (x *GetPricesRes) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_v2_prices_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPricesRes.ProtoReflect.Descriptor instead.
+func (*GetPricesRes) Descriptor() ([]byte, []int) {
+	return file_api_proto_v2_prices_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetPricesRes) GetPrices() []*Price {
+	if x != nil {
+		return x.Prices
+	}
+	return nil
+}
+
+var File_api_proto_v2_prices_proto protoreflect.FileDescriptor
+
+var file_api_proto_v2_prices_proto_rawDesc = []byte{
+	0x0a, 0x19, 0x61, 0x70, 0x69, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x76, 0x32, 0x2f, 0x70,
+	0x72, 0x69, 0x63, 0x65, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0e, 0x63, 0x72, 0x79,
+	0x70, 0x74, 0x6f, 0x77, 0x61, 0x74, 0x63, 0x68, 0x2e, 0x76, 0x32, 0x1a, 0x1a, 0x61, 0x70, 0x69,
+	0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x76, 0x31, 0x2f, 0x6f, 0x70, 0x74, 0x69, 0x6f, 0x6e,
+	0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x28, 0x0a, 0x0c, 0x47, 0x65, 0x74, 0x50, 0x72,
+	0x69, 0x63, 0x65, 0x73, 0x52, 0x65, 0x71, 0x12, 0x18, 0x0a, 0x07, 0x74, 0x69, 0x63, 0x6b, 0x65,
+	0x72, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x74, 0x69, 0x63, 0x6b, 0x65, 0x72,
+	0x73, 0x22, 0x35, 0x0a, 0x05, 0x50, 0x72, 0x69, 0x63, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x74, 0x69,
+	0x63, 0x6b, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x74, 0x69, 0x63, 0x6b,
+	0x65, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x70, 0x72, 0x69, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x01, 0x52, 0x05, 0x70, 0x72, 0x69, 0x63, 0x65, 0x22, 0x3d, 0x0a, 0x0c, 0x47, 0x65, 0x74, 0x50,
+	0x72, 0x69, 0x63, 0x65, 0x73, 0x52, 0x65, 0x73, 0x12, 0x2d, 0x0a, 0x06, 0x70, 0x72, 0x69, 0x63,
+	0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x63, 0x72, 0x79, 0x70, 0x74,
+	0x6f, 0x77, 0x61, 0x74, 0x63, 0x68, 0x2e, 0x76, 0x32, 0x2e, 0x50, 0x72, 0x69, 0x63, 0x65, 0x52,
+	0x06, 0x70, 0x72, 0x69, 0x63, 0x65, 0x73, 0x32, 0x84, 0x01, 0x0a, 0x06, 0x50, 0x72, 0x69, 0x63,
+	0x65, 0x73, 0x12, 0x7a, 0x0a, 0x09, 0x47, 0x65, 0x74, 0x50, 0x72, 0x69, 0x63, 0x65, 0x73, 0x12,
+	0x1c, 0x2e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x6f, 0x77, 0x61, 0x74, 0x63, 0x68, 0x2e, 0x76, 0x32,
+	0x2e, 0x47, 0x65, 0x74, 0x50, 0x72, 0x69, 0x63, 0x65, 0x73, 0x52, 0x65, 0x71, 0x1a, 0x1c, 0x2e,
+	0x63, 0x72, 0x79, 0x70, 0x74, 0x6f, 0x77, 0x61, 0x74, 0x63, 0x68, 0x2e, 0x76, 0x32, 0x2e, 0x47,
+	0x65, 0x74, 0x50, 0x72, 0x69, 0x63, 0x65, 0x73, 0x52, 0x65, 0x73, 0x22, 0x31, 0xc2, 0xf3, 0x18,
+	0x2d, 0x08, 0x02, 0x1a, 0x0f, 0x70, 0x6f, 0x72, 0x74, 0x66, 0x6f, 0x6c, 0x69, 0x6f, 0x73, 0x3a,
+	0x72, 0x65, 0x61, 0x64, 0x1a, 0x10, 0x70, 0x6f, 0x72, 0x74, 0x66, 0x6f, 0x6c, 0x69, 0x6f, 0x73,
+	0x3a, 0x77, 0x72, 0x69, 0x74, 0x65, 0x1a, 0x06, 0x61, 0x6c, 0x65, 0x72, 0x74, 0x73, 0x42, 0x17,
+	0x5a, 0x15, 0x70, 0x6b, 0x67, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x63, 0x72, 0x79, 0x70, 0x74, 0x6f,
+	0x77, 0x61, 0x74, 0x63, 0x68, 0x76, 0x32, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_api_proto_v2_prices_proto_rawDescOnce sync.Once
+	file_api_proto_v2_prices_proto_rawDescData = file_api_proto_v2_prices_proto_rawDesc
+)
+
+func file_api_proto_v2_prices_proto_rawDescGZIP() []byte {
+	file_api_proto_v2_prices_proto_rawDescOnce.Do(func() {
+		file_api_proto_v2_prices_proto_rawDescData = protoimpl.X.CompressGZIP(file_api_proto_v2_prices_proto_rawDescData)
+	})
+	return file_api_proto_v2_prices_proto_rawDescData
+}
+
+var file_api_proto_v2_prices_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_api_proto_v2_prices_proto_goTypes = []interface{}{
+	(*GetPricesReq)(nil), // 0: cryptowatch.v2.GetPricesReq
+	(*Price)(nil),        // 1: cryptowatch.v2.Price
+	(*GetPricesRes)(nil), // 2: cryptowatch.v2.GetPricesRes
+}
+var file_api_proto_v2_prices_proto_depIdxs = []int32{
+	1, // 0: cryptowatch.v2.GetPricesRes.prices:type_name -> cryptowatch.v2.Price
+	0, // 1: cryptowatch.v2.Prices.GetPrices:input_type -> cryptowatch.v2.GetPricesReq
+	2, // 2: cryptowatch.v2.Prices.GetPrices:output_type -> cryptowatch.v2.GetPricesRes
+	2, // [2:3] is the sub-list for method output_type
+	1, // [1:2] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_api_proto_v2_prices_proto_init() }
+func file_api_proto_v2_prices_proto_init() {
+	if File_api_proto_v2_prices_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_api_proto_v2_prices_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetPricesReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_proto_v2_prices_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Price); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_proto_v2_prices_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetPricesRes); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_api_proto_v2_prices_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_proto_v2_prices_proto_goTypes,
+		DependencyIndexes: file_api_proto_v2_prices_proto_depIdxs,
+		MessageInfos:      file_api_proto_v2_prices_proto_msgTypes,
+	}.Build()
+	File_api_proto_v2_prices_proto = out.File
+	file_api_proto_v2_prices_proto_rawDesc = nil
+	file_api_proto_v2_prices_proto_goTypes = nil
+	file_api_proto_v2_prices_proto_depIdxs = nil
+}
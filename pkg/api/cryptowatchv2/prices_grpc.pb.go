@@ -0,0 +1,109 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.17.3
+// source: api/proto/v2/prices.proto
+
+package cryptowatchv2
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// PricesClient is the client API for Prices service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PricesClient interface {
+	// GetPrices returns the latest prices of the tokens. Unknown tokens are tracked
+	// from then on, their price is 0 until the price feed reports one.
+	GetPrices(ctx context.Context, in *GetPricesReq, opts ...grpc.CallOption) (*GetPricesRes, error)
+}
+
+type pricesClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPricesClient(cc grpc.ClientConnInterface) PricesClient {
+	return &pricesClient{cc}
+}
+
+func (c *pricesClient) GetPrices(ctx context.Context, in *GetPricesReq, opts ...grpc.CallOption) (*GetPricesRes, error) {
+	out := new(GetPricesRes)
+	err := c.cc.Invoke(ctx, "/cryptowatch.v2.Prices/GetPrices", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PricesServer is the server API for Prices service.
+// All implementations must embed UnimplementedPricesServer
+// for forward compatibility
+type PricesServer interface {
+	// GetPrices returns the latest prices of the tokens. Unknown tokens are tracked
+	// from then on, their price is 0 until the price feed reports one.
+	GetPrices(context.Context, *GetPricesReq) (*GetPricesRes, error)
+	mustEmbedUnimplementedPricesServer()
+}
+
+// UnimplementedPricesServer must be embedded to have forward compatible implementations.
+type UnimplementedPricesServer struct {
+}
+
+func (UnimplementedPricesServer) GetPrices(context.Context, *GetPricesReq) (*GetPricesRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPrices not implemented")
+}
+func (UnimplementedPricesServer) mustEmbedUnimplementedPricesServer() {}
+
+// UnsafePricesServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PricesServer will
+// result in compilation errors.
+type UnsafePricesServer interface {
+	mustEmbedUnimplementedPricesServer()
+}
+
+func RegisterPricesServer(s grpc.ServiceRegistrar, srv PricesServer) {
+	s.RegisterService(&Prices_ServiceDesc, srv)
+}
+
+func _Prices_GetPrices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPricesReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PricesServer).GetPrices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cryptowatch.v2.Prices/GetPrices",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PricesServer).GetPrices(ctx, req.(*GetPricesReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Prices_ServiceDesc is the grpc.ServiceDesc for Prices service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Prices_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "cryptowatch.v2.Prices",
+	HandlerType: (*PricesServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetPrices",
+			Handler:    _Prices_GetPrices_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "api/proto/v2/prices.proto",
+}
@@ -0,0 +1,491 @@
+// Package botapitest runs an in-process fake of the Telegram Bot API for tests.
+// Tests play users sending messages and pressing buttons and check what the bot replies.
+package botapitest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cryptowatch/pkg/botapi"
+)
+
+var commandRe = regexp.MustCompile("^[a-z0-9_]{1,32}$")
+
+// BotUser is the bot, the sender of messages it sends.
+var BotUser = botapi.User{ID: 1, IsBot: true, FirstName: "Cryptowatch", Username: "cryptowatch_bot"}
+
+// Answer is an answer of the bot to a callback query.
+type Answer struct {
+	CallbackQueryID string
+	Text            string
+}
+
+// Server is a fake Bot API serving one bot. Updates are delivered by getUpdates only.
+type Server struct {
+	*httptest.Server
+
+	token string
+
+	mu            sync.Mutex
+	changed       chan struct{}
+	updates       []botapi.Update
+	nextUpdateID  int
+	nextMessageID int
+	nextQueryID   int
+	chats         map[int64]botapi.Chat
+	blocked       map[int64]bool
+	// messages are the messages of each chat in order, from users and the bot.
+	messages  map[int64][]botapi.Message
+	queries   map[string]bool
+	answers   []Answer
+	webhook   string
+	commands  []botapi.BotCommand
+	callCount map[string]int
+}
+
+// NewServer starts a fake Bot API for the bot with the token. Close it when done.
+func NewServer(token string) *Server {
+	s := &Server{
+		token:         token,
+		changed:       make(chan struct{}),
+		nextUpdateID:  1,
+		nextMessageID: 1,
+		chats:         make(map[int64]botapi.Chat),
+		blocked:       make(map[int64]bool),
+		messages:      make(map[int64][]botapi.Message),
+		queries:       make(map[string]bool),
+		callCount:     make(map[string]int),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+
+	return s
+}
+
+// BotClient returns a client of the bot calling the server.
+func (s *Server) BotClient() *botapi.Client {
+	return botapi.NewClient(s.token, botapi.WithBaseURL(s.URL))
+}
+
+// SendText queues a message of the user of a private chat to the bot and returns it.
+func (s *Server) SendText(chatID int64, text string) botapi.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chat := s.chat(chatID)
+	msg := botapi.Message{
+		MessageID: s.newMessageID(),
+		From:      &botapi.User{ID: chat.ID, FirstName: chat.FirstName, Username: chat.Username},
+		Chat:      &chat,
+		Date:      int(time.Now().Unix()),
+		Text:      text,
+	}
+	s.messages[chatID] = append(s.messages[chatID], msg)
+	s.addUpdate(botapi.Update{Message: &msg})
+
+	return msg
+}
+
+// PressButton queues a callback query of the user pressing the button with data
+// on a message of the bot and returns the query id.
+func (s *Server) PressButton(chatID int64, messageID int, data string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg, ok := s.message(chatID, messageID)
+	if !ok {
+		return "", fmt.Errorf("no message %d in chat %d", messageID, chatID)
+	}
+	if !hasButton(msg.ReplyMarkup, data) {
+		return "", fmt.Errorf("message %d in chat %d has no button with data %q", messageID, chatID, data)
+	}
+
+	// The update keeps the message as it is now, later edits don't change it.
+	pressed := *msg
+	chat := s.chat(chatID)
+	s.nextQueryID++
+	id := strconv.Itoa(s.nextQueryID)
+	s.queries[id] = true
+	s.addUpdate(botapi.Update{CallbackQuery: &botapi.CallbackQuery{
+		ID:      id,
+		From:    &botapi.User{ID: chat.ID, FirstName: chat.FirstName, Username: chat.Username},
+		Message: &pressed,
+		Data:    data,
+	}})
+
+	return id, nil
+}
+
+// Block has the user of the chat block the bot, messages to the chat fail with 403 Forbidden.
+func (s *Server) Block(chatID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.blocked[chatID] = true
+}
+
+// Messages returns the messages the bot sent to the chat, edits applied.
+func (s *Server) Messages(chatID int64) []botapi.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.botMessages(chatID)
+}
+
+// WaitMessages waits until the bot sent at least n messages to the chat and returns them.
+func (s *Server) WaitMessages(chatID int64, n int, timeout time.Duration) ([]botapi.Message, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		s.mu.Lock()
+		msgs := s.botMessages(chatID)
+		changed := s.changed
+		s.mu.Unlock()
+
+		if len(msgs) >= n {
+			return msgs, nil
+		}
+
+		select {
+		case <-changed:
+		case <-deadline.C:
+			return msgs, fmt.Errorf("bot sent %d messages to chat %d, want %d", len(msgs), chatID, n)
+		}
+	}
+}
+
+// Answers returns the answers to callback queries in order.
+func (s *Server) Answers() []Answer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Answer(nil), s.answers...)
+}
+
+// Webhook returns the URL of the webhook set by the bot, empty if none.
+func (s *Server) Webhook() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.webhook
+}
+
+// Commands returns the command menu set by the bot.
+func (s *Server) Commands() []botapi.BotCommand {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]botapi.BotCommand(nil), s.commands...)
+}
+
+// Calls returns how often the method was called.
+func (s *Server) Calls(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.callCount[method]
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/bot")
+	token, method, ok := strings.Cut(path, "/")
+	if !ok || token != s.token {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	s.mu.Lock()
+	s.callCount[method]++
+	s.mu.Unlock()
+
+	var err error
+	var result interface{}
+	switch method {
+	case "getUpdates":
+		var params botapi.GetUpdatesParams
+		if err = decode(r, &params); err == nil {
+			result, err = s.getUpdates(r.Context(), params)
+		}
+	case "sendMessage":
+		var params botapi.SendMessageParams
+		if err = decode(r, &params); err == nil {
+			result, err = s.sendMessage(params)
+		}
+	case "editMessageText":
+		var params botapi.EditMessageTextParams
+		if err = decode(r, &params); err == nil {
+			result, err = s.editMessageText(params)
+		}
+	case "answerCallbackQuery":
+		var params botapi.AnswerCallbackQueryParams
+		if err = decode(r, &params); err == nil {
+			result, err = s.answerCallbackQuery(params)
+		}
+	case "setWebhook":
+		var params botapi.SetWebhookParams
+		if err = decode(r, &params); err == nil {
+			result, err = s.setWebhook(params.URL)
+		}
+	case "deleteWebhook":
+		result, err = s.setWebhook("")
+	case "setMyCommands":
+		var params botapi.SetMyCommandsParams
+		if err = decode(r, &params); err == nil {
+			result, err = s.setMyCommands(params)
+		}
+	default:
+		writeError(w, http.StatusNotFound, "Not Found: method not found")
+		return
+	}
+
+	if err != nil {
+		var apiErr *botapi.Error
+		if e, ok := err.(*botapi.Error); ok {
+			apiErr = e
+		} else {
+			apiErr = &botapi.Error{Code: http.StatusBadRequest, Description: "Bad Request: " + err.Error()}
+		}
+		writeError(w, apiErr.Code, apiErr.Description)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true, "result": result})
+}
+
+// getUpdates confirms updates before the offset and waits up to the timeout for later ones.
+func (s *Server) getUpdates(ctx context.Context, params botapi.GetUpdatesParams) ([]botapi.Update, error) {
+	timeout := time.NewTimer(time.Duration(params.Timeout) * time.Second)
+	defer timeout.Stop()
+
+	for {
+		s.mu.Lock()
+		if s.webhook != "" {
+			s.mu.Unlock()
+			return nil, &botapi.Error{Code: http.StatusConflict, Description: "Conflict: can't use getUpdates method while webhook is active"}
+		}
+
+		pending := s.updates[:0]
+		for _, u := range s.updates {
+			if u.UpdateID >= params.Offset {
+				pending = append(pending, u)
+			}
+		}
+		s.updates = pending
+		changed := s.changed
+		updates := append([]botapi.Update{}, pending...)
+		s.mu.Unlock()
+
+		if len(updates) > 0 {
+			return updates, nil
+		}
+
+		select {
+		case <-changed:
+		case <-timeout.C:
+			return updates, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (s *Server) sendMessage(params botapi.SendMessageParams) (*botapi.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if params.Text == "" {
+		return nil, &botapi.Error{Code: http.StatusBadRequest, Description: "Bad Request: message text is empty"}
+	}
+	if err := validateKeyboard(params.ReplyMarkup); err != nil {
+		return nil, err
+	}
+	if s.blocked[params.ChatID] {
+		return nil, &botapi.Error{Code: http.StatusForbidden, Description: "Forbidden: bot was blocked by the user"}
+	}
+
+	chat := s.chat(params.ChatID)
+	bot := BotUser
+	msg := botapi.Message{
+		MessageID:   s.newMessageID(),
+		From:        &bot,
+		Chat:        &chat,
+		Date:        int(time.Now().Unix()),
+		Text:        params.Text,
+		ReplyMarkup: params.ReplyMarkup,
+	}
+	s.messages[params.ChatID] = append(s.messages[params.ChatID], msg)
+	s.notify()
+
+	return &msg, nil
+}
+
+func (s *Server) editMessageText(params botapi.EditMessageTextParams) (*botapi.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg, ok := s.message(params.ChatID, params.MessageID)
+	if !ok || msg.From == nil || !msg.From.IsBot {
+		return nil, &botapi.Error{Code: http.StatusBadRequest, Description: "Bad Request: message to edit not found"}
+	}
+	if err := validateKeyboard(params.ReplyMarkup); err != nil {
+		return nil, err
+	}
+	if msg.Text == params.Text && reflect.DeepEqual(msg.ReplyMarkup, params.ReplyMarkup) {
+		return nil, &botapi.Error{Code: http.StatusBadRequest, Description: "Bad Request: message is not modified"}
+	}
+
+	msg.Text = params.Text
+	msg.ReplyMarkup = params.ReplyMarkup
+	s.notify()
+
+	edited := *msg
+	return &edited, nil
+}
+
+func (s *Server) answerCallbackQuery(params botapi.AnswerCallbackQueryParams) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.queries[params.CallbackQueryID] {
+		return false, &botapi.Error{Code: http.StatusBadRequest, Description: "Bad Request: query is too old and response timeout expired or query ID is invalid"}
+	}
+	delete(s.queries, params.CallbackQueryID)
+
+	s.answers = append(s.answers, Answer{CallbackQueryID: params.CallbackQueryID, Text: params.Text})
+	s.notify()
+
+	return true, nil
+}
+
+func (s *Server) setWebhook(url string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.webhook = url
+	s.notify()
+
+	return true, nil
+}
+
+func (s *Server) setMyCommands(params botapi.SetMyCommandsParams) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, cmd := range params.Commands {
+		if !commandRe.MatchString(cmd.Command) || cmd.Description == "" {
+			return false, &botapi.Error{Code: http.StatusBadRequest, Description: "Bad Request: BOT_COMMAND_INVALID"}
+		}
+	}
+	s.commands = params.Commands
+
+	return true, nil
+}
+
+// chat returns the private chat with the id, the user of the chat has the same id.
+func (s *Server) chat(id int64) botapi.Chat {
+	chat, ok := s.chats[id]
+	if !ok {
+		chat = botapi.Chat{ID: id, FirstName: fmt.Sprintf("User%d", id), Username: fmt.Sprintf("user%d", id), Type: "private"}
+		s.chats[id] = chat
+	}
+
+	return chat
+}
+
+func (s *Server) message(chatID int64, messageID int) (*botapi.Message, bool) {
+	msgs := s.messages[chatID]
+	for i := range msgs {
+		if msgs[i].MessageID == messageID {
+			return &msgs[i], true
+		}
+	}
+
+	return nil, false
+}
+
+func (s *Server) botMessages(chatID int64) []botapi.Message {
+	var msgs []botapi.Message
+	for _, msg := range s.messages[chatID] {
+		if msg.From != nil && msg.From.IsBot {
+			msgs = append(msgs, msg)
+		}
+	}
+
+	return msgs
+}
+
+func validateKeyboard(keyboard *botapi.InlineKeyboardMarkup) error {
+	if keyboard == nil {
+		return nil
+	}
+	for _, row := range keyboard.InlineKeyboard {
+		for _, button := range row {
+			if button.Text == "" || button.CallbackData == "" || len(button.CallbackData) > 64 {
+				return &botapi.Error{Code: http.StatusBadRequest, Description: "Bad Request: BUTTON_DATA_INVALID"}
+			}
+		}
+	}
+
+	return nil
+}
+
+func hasButton(keyboard *botapi.InlineKeyboardMarkup, data string) bool {
+	if keyboard == nil {
+		return false
+	}
+	for _, row := range keyboard.InlineKeyboard {
+		for _, button := range row {
+			if button.CallbackData == data {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (s *Server) newMessageID() int {
+	id := s.nextMessageID
+	s.nextMessageID++
+	return id
+}
+
+func (s *Server) addUpdate(u botapi.Update) {
+	u.UpdateID = s.nextUpdateID
+	s.nextUpdateID++
+	s.updates = append(s.updates, u)
+	s.notify()
+}
+
+// notify wakes up everyone waiting for a change.
+func (s *Server) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func decode(r *http.Request, v interface{}) error {
+	if r.ContentLength == 0 {
+		return nil
+	}
+
+	return json.NewDecoder(r.Body).Decode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, description string) {
+	writeJSON(w, code, map[string]interface{}{"ok": false, "error_code": code, "description": description})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,193 @@
+// Package botapi is a client of the Telegram Bot API methods the bot uses.
+package botapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultURL is the base URL of the Telegram Bot API.
+	DefaultURL = "https://api.telegram.org"
+
+	// requestTimeout limits requests, long polls wait this long more than their timeout.
+	requestTimeout = 10 * time.Second
+	// maxResponseSize limits responses read from the API.
+	maxResponseSize = 10 << 20
+)
+
+// Error is a request the API rejected.
+type Error struct {
+	Code        int
+	Description string
+	// RetryAfter is set when requests are sent too fast.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("bot api: %d %s", e.Code, e.Description)
+}
+
+// response is the envelope of every method response.
+type response struct {
+	Ok          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+	Parameters  *struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// Option configures the client.
+type Option func(c *Client)
+
+// WithBaseURL sends requests to another server than DefaultURL, like a local Bot API server or a fake one.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithHTTPClient sends requests with the client, timeouts are set per request anyway.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.http = httpClient
+	}
+}
+
+func NewClient(token string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: DefaultURL,
+		token:   token,
+		http:    &http.Client{},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// GetUpdates waits for updates from params.Offset on, up to params.Timeout seconds.
+func (c *Client) GetUpdates(ctx context.Context, params GetUpdatesParams) ([]Update, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(params.Timeout)*time.Second+requestTimeout)
+	defer cancel()
+
+	var updates []Update
+	err := c.call(ctx, "getUpdates", params, &updates)
+	if err != nil {
+		return nil, err
+	}
+
+	return updates, nil
+}
+
+func (c *Client) SendMessage(ctx context.Context, params SendMessageParams) (*Message, error) {
+	var msg Message
+	err := c.callTimeout(ctx, "sendMessage", params, &msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &msg, nil
+}
+
+func (c *Client) EditMessageText(ctx context.Context, params EditMessageTextParams) (*Message, error) {
+	var msg Message
+	err := c.callTimeout(ctx, "editMessageText", params, &msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &msg, nil
+}
+
+// AnswerCallbackQuery stops the progress indicator of the pressed button.
+func (c *Client) AnswerCallbackQuery(ctx context.Context, params AnswerCallbackQueryParams) error {
+	return c.callTimeout(ctx, "answerCallbackQuery", params, nil)
+}
+
+// SetWebhook has Telegram post updates to the URL, GetUpdates fails while it is set.
+func (c *Client) SetWebhook(ctx context.Context, params SetWebhookParams) error {
+	return c.callTimeout(ctx, "setWebhook", params, nil)
+}
+
+func (c *Client) DeleteWebhook(ctx context.Context) error {
+	return c.callTimeout(ctx, "deleteWebhook", struct{}{}, nil)
+}
+
+// SetMyCommands replaces the command menu of the bot.
+func (c *Client) SetMyCommands(ctx context.Context, params SetMyCommandsParams) error {
+	return c.callTimeout(ctx, "setMyCommands", params, nil)
+}
+
+func (c *Client) callTimeout(ctx context.Context, method string, params interface{}, result interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	return c.call(ctx, method, params, result)
+}
+
+// call posts params as JSON to the method and decodes its result into result unless nil.
+func (c *Client) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/bot%s/%s", c.baseURL, c.token, method), bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		// The URL contains the token, which must not end up in logs.
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			uerr.URL = strings.Replace(uerr.URL, c.token, "<token>", 1)
+		}
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	defer res.Body.Close()
+
+	var data response
+	err = json.NewDecoder(io.LimitReader(res.Body, maxResponseSize)).Decode(&data)
+	if err != nil {
+		return fmt.Errorf("%s: %s: %w", method, res.Status, err)
+	}
+	if !data.Ok {
+		apiErr := &Error{Code: data.ErrorCode, Description: data.Description}
+		if data.Parameters != nil {
+			apiErr.RetryAfter = time.Duration(data.Parameters.RetryAfter) * time.Second
+		}
+		return fmt.Errorf("%s: %w", method, apiErr)
+	}
+
+	if result == nil {
+		return nil
+	}
+	err = json.Unmarshal(data.Result, result)
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	return nil
+}
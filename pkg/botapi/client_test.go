@@ -0,0 +1,115 @@
+package botapi_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cryptowatch/pkg/botapi"
+	"cryptowatch/pkg/botapi/botapitest"
+)
+
+const token = "123:secret"
+
+func TestClient_Conversation(t *testing.T) {
+	srv := botapitest.NewServer(token)
+	defer srv.Close()
+	client := srv.BotClient()
+	ctx := context.Background()
+
+	srv.SendText(42, "/start")
+	updates, err := client.GetUpdates(ctx, botapi.GetUpdatesParams{Timeout: 1})
+	require.NoError(t, err)
+	require.Len(t, updates, 1)
+	require.NotNil(t, updates[0].Message)
+	assert.Equal(t, "/start", updates[0].Message.Text)
+	assert.Equal(t, int64(42), updates[0].Message.Chat.ID)
+
+	keyboard := &botapi.InlineKeyboardMarkup{InlineKeyboard: [][]botapi.InlineKeyboardButton{{{Text: "Refresh", CallbackData: "refresh"}}}}
+	msg, err := client.SendMessage(ctx, botapi.SendMessageParams{ChatID: 42, Text: "hello", ReplyMarkup: keyboard})
+	require.NoError(t, err)
+	assert.True(t, msg.From.IsBot)
+	assert.Equal(t, keyboard, msg.ReplyMarkup)
+
+	_, err = client.EditMessageText(ctx, botapi.EditMessageTextParams{ChatID: 42, MessageID: msg.MessageID, Text: "hi", ReplyMarkup: keyboard})
+	require.NoError(t, err)
+	_, err = client.EditMessageText(ctx, botapi.EditMessageTextParams{ChatID: 42, MessageID: msg.MessageID, Text: "hi", ReplyMarkup: keyboard})
+	var apiErr *botapi.Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusBadRequest, apiErr.Code)
+
+	_, err = srv.PressButton(42, msg.MessageID, "other")
+	assert.Error(t, err)
+	queryID, err := srv.PressButton(42, msg.MessageID, "refresh")
+	require.NoError(t, err)
+	// The offset confirms the first update.
+	updates, err = client.GetUpdates(ctx, botapi.GetUpdatesParams{Offset: updates[0].UpdateID + 1, Timeout: 1})
+	require.NoError(t, err)
+	require.Len(t, updates, 1)
+	require.NotNil(t, updates[0].CallbackQuery)
+	assert.Equal(t, "refresh", updates[0].CallbackQuery.Data)
+	assert.Equal(t, "hi", updates[0].CallbackQuery.Message.Text)
+
+	require.NoError(t, client.AnswerCallbackQuery(ctx, botapi.AnswerCallbackQueryParams{CallbackQueryID: queryID}))
+	assert.Equal(t, []botapitest.Answer{{CallbackQueryID: queryID}}, srv.Answers())
+
+	msgs, err := srv.WaitMessages(42, 1, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "hi", msgs[0].Text)
+
+	// No updates are left, the poll times out empty.
+	updates, err = client.GetUpdates(ctx, botapi.GetUpdatesParams{Offset: updates[0].UpdateID + 1, Timeout: 1})
+	require.NoError(t, err)
+	assert.Empty(t, updates)
+}
+
+func TestClient_Webhook(t *testing.T) {
+	srv := botapitest.NewServer(token)
+	defer srv.Close()
+	client := srv.BotClient()
+	ctx := context.Background()
+
+	require.NoError(t, client.SetWebhook(ctx, botapi.SetWebhookParams{URL: "https://example.com/telegram/webhook"}))
+	assert.Equal(t, "https://example.com/telegram/webhook", srv.Webhook())
+
+	_, err := client.GetUpdates(ctx, botapi.GetUpdatesParams{})
+	var apiErr *botapi.Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusConflict, apiErr.Code)
+
+	require.NoError(t, client.DeleteWebhook(ctx))
+	assert.Empty(t, srv.Webhook())
+}
+
+func TestClient_Errors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"ok": false, "error_code": 429, "description": "Too Many Requests: retry after 3", "parameters": {"retry_after": 3}}`))
+	}))
+	defer srv.Close()
+
+	_, err := botapi.NewClient(token, botapi.WithBaseURL(srv.URL+"/")).SendMessage(context.Background(), botapi.SendMessageParams{ChatID: 1, Text: "hi"})
+	var apiErr *botapi.Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, &botapi.Error{Code: 429, Description: "Too Many Requests: retry after 3", RetryAfter: 3 * time.Second}, apiErr)
+
+	// A wrong token is rejected by the fake like by Telegram.
+	fake := botapitest.NewServer(token)
+	defer fake.Close()
+	_, err = botapi.NewClient("123:wrong", botapi.WithBaseURL(fake.URL)).SendMessage(context.Background(), botapi.SendMessageParams{ChatID: 1, Text: "hi"})
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusUnauthorized, apiErr.Code)
+
+	// Transport errors don't leak the token.
+	srv.Close()
+	_, err = botapi.NewClient(token, botapi.WithBaseURL(srv.URL)).SendMessage(context.Background(), botapi.SendMessageParams{ChatID: 1, Text: "hi"})
+	require.Error(t, err)
+	assert.False(t, strings.Contains(err.Error(), token), err.Error())
+}
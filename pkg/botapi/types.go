@@ -0,0 +1,99 @@
+package botapi
+
+// Update is an incoming update, one of the optional fields is set.
+type Update struct {
+	UpdateID      int            `json:"update_id"`
+	Message       *Message       `json:"message,omitempty"`
+	CallbackQuery *CallbackQuery `json:"callback_query,omitempty"`
+}
+
+type Message struct {
+	MessageID   int                   `json:"message_id"`
+	From        *User                 `json:"from,omitempty"`
+	Chat        *Chat                 `json:"chat"`
+	Date        int                   `json:"date"`
+	Text        string                `json:"text"`
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+}
+
+type Chat struct {
+	ID        int64  `json:"id"`
+	FirstName string `json:"first_name,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Type      string `json:"type"`
+}
+
+type User struct {
+	ID        int64  `json:"id"`
+	IsBot     bool   `json:"is_bot"`
+	FirstName string `json:"first_name"`
+	Username  string `json:"username,omitempty"`
+}
+
+// InlineKeyboardMarkup is a keyboard shown below a message, in rows of buttons.
+type InlineKeyboardMarkup struct {
+	InlineKeyboard [][]InlineKeyboardButton `json:"inline_keyboard"`
+}
+
+// InlineKeyboardButton sends a callback query with its data when pressed.
+type InlineKeyboardButton struct {
+	Text string `json:"text"`
+	// CallbackData is 1 to 64 bytes.
+	CallbackData string `json:"callback_data,omitempty"`
+}
+
+// CallbackQuery is sent when a user presses a button of an inline keyboard.
+type CallbackQuery struct {
+	ID   string `json:"id"`
+	From *User  `json:"from"`
+	// Message is the message with the button.
+	Message *Message `json:"message,omitempty"`
+	Data    string   `json:"data,omitempty"`
+}
+
+type GetUpdatesParams struct {
+	// Offset confirms updates before it, they aren't returned again.
+	Offset int `json:"offset,omitempty"`
+	// Timeout is how many seconds the request waits for an update if there is none.
+	Timeout        int      `json:"timeout,omitempty"`
+	AllowedUpdates []string `json:"allowed_updates,omitempty"`
+}
+
+type SendMessageParams struct {
+	ChatID      int64                 `json:"chat_id"`
+	Text        string                `json:"text"`
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+}
+
+type EditMessageTextParams struct {
+	ChatID    int64  `json:"chat_id"`
+	MessageID int    `json:"message_id"`
+	Text      string `json:"text"`
+	// ReplyMarkup replaces the keyboard of the message, which is removed if nil.
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+}
+
+type AnswerCallbackQueryParams struct {
+	CallbackQueryID string `json:"callback_query_id"`
+	// Text is shown to the user as a notification, nothing is if empty.
+	Text string `json:"text,omitempty"`
+}
+
+type SetWebhookParams struct {
+	URL string `json:"url"`
+	// SecretToken is sent in the X-Telegram-Bot-Api-Secret-Token header of every update.
+	SecretToken    string   `json:"secret_token,omitempty"`
+	MaxConnections int      `json:"max_connections,omitempty"`
+	AllowedUpdates []string `json:"allowed_updates,omitempty"`
+}
+
+// BotCommand is a command shown in the command menu of chats with the bot.
+type BotCommand struct {
+	// Command is 1 to 32 lowercase letters, digits and underscores, without the slash.
+	Command     string `json:"command"`
+	Description string `json:"description"`
+}
+
+type SetMyCommandsParams struct {
+	Commands []BotCommand `json:"commands"`
+}
@@ -1,35 +1,188 @@
+// Package config loads the app configuration.
+//
+// Every key is read, in increasing order of precedence, from the default in the
+// `default` struct tag, the optional config file, the environment variable of
+// the same name and the file named by the <KEY>_FILE variable, which is meant
+// for secrets mounted into a container. Empty values are treated as unset.
+//
+// Keys tagged `reload:"hot"` may be changed while running, see Watcher.
 package config
 
-import "github.com/spf13/viper"
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
 
 // Config represents whole app configuration.
 type Config struct {
-	SymmetricKey       string `mapstructure:"SYMMETRIC_KEY" validate:"required"`
-	BindAddr           string `mapstructure:"BIND_ADDR" validate:"required"`
-	DBHost             string `mapstructure:"DB_HOST" validate:"required"`
-	DBPort             string `mapstructure:"DB_PORT" validate:"required"`
-	DBUser             string `mapstructure:"DB_USER" validate:"required"`
-	DBPassword         string `mapstructure:"DB_PASSWORD" validate:"required"`
-	DBName             string `mapstructure:"DB_NAME" validate:"required"`
-	DBSSLMode          string `mapstructure:"DB_SSLMODE" validate:"required"`
-	TelegramToken      string `mapstructure:"TELEGRAM_TOKEN" validate:"required"`
-	CryptoCompareToken string `mapstructure:"CRYPTOCOMPARE_TOKEN" validate:"required"`
-}
+	// TokenFormat is local for access tokens encrypted with SymmetricKey or public
+	// for tokens signed with rotated Ed25519 keys, which other services can verify.
+	TokenFormat string `mapstructure:"TOKEN_FORMAT" default:"local" validate:"oneof=local|public"`
+	// SymmetricKey is the key used to encrypt auth tokens of the local format, PASETO v2.local
+	// keys are 32 bytes.
+	SymmetricKey string `mapstructure:"SYMMETRIC_KEY" validate:"len=32"`
+	// TokenKeyRotationInterval is how long a key signs tokens of the public format before the next one.
+	TokenKeyRotationInterval time.Duration `mapstructure:"TOKEN_KEY_ROTATION_INTERVAL" default:"168h"`
+	// TokenKeyRefreshInterval is how often signing keys are loaded from the database,
+	// new keys are published this long before they sign.
+	TokenKeyRefreshInterval time.Duration `mapstructure:"TOKEN_KEY_REFRESH_INTERVAL" default:"1m" validate:"min=1s"`
+	// TokenIssuer and TokenAudience are claims of issued access tokens. Only tokens with
+	// both are accepted, so tokens of other issuers or meant for other services are not.
+	TokenIssuer   string `mapstructure:"TOKEN_ISSUER" default:"cryptowatch"`
+	TokenAudience string `mapstructure:"TOKEN_AUDIENCE" default:"cryptowatch-api"`
+	// AccessTokenDuration is the lifetime of access tokens, they are renewed with a refresh token.
+	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION" default:"15m" validate:"min=1m"`
+	// RefreshTokenDuration is how long a session can stay unused before it expires.
+	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION" default:"720h" validate:"min=1h"`
+	// SessionDenylistInterval is how often revoked sessions are loaded from the database.
+	SessionDenylistInterval time.Duration `mapstructure:"SESSION_DENYLIST_INTERVAL" default:"10s" validate:"min=1s"`
+	// StreamRevalidateInterval is how often tokens of open streams are checked for expiry and revocation.
+	StreamRevalidateInterval time.Duration `mapstructure:"STREAM_REVALIDATE_INTERVAL" default:"30s" validate:"min=1s"`
+
+	// OTPTTL is how long a one-time password request and its code are valid.
+	OTPTTL time.Duration `mapstructure:"OTP_TTL" default:"5m"`
+	// OTPMaxAttempts is the number of wrong codes after which a code is locked.
+	OTPMaxAttempts int `mapstructure:"OTP_MAX_ATTEMPTS" default:"5" validate:"min=1"`
+	// OTPGenerateInterval is the minimum time between two one-time password requests of a user.
+	OTPGenerateInterval time.Duration `mapstructure:"OTP_GENERATE_INTERVAL" default:"30s"`
+
+	// PasswordMinLength is the minimum number of characters of a password.
+	PasswordMinLength int `mapstructure:"PASSWORD_MIN_LENGTH" default:"8" validate:"min=1"`
+	// PasswordMinCharClasses is how many of lowercase letters, uppercase letters,
+	// digits and symbols a password must contain.
+	PasswordMinCharClasses int `mapstructure:"PASSWORD_MIN_CHAR_CLASSES" default:"2" validate:"min=1,max=4"`
+	// PasswordAllowUsername allows passwords containing the username.
+	PasswordAllowUsername bool `mapstructure:"PASSWORD_ALLOW_USERNAME" default:"false"`
+
+	// RateLimitStore keeps rate limit buckets in memory of each instance or shared in postgres.
+	RateLimitStore string `mapstructure:"RATE_LIMIT_STORE" default:"memory" validate:"oneof=memory|postgres"`
+	// RateLimitIPBurst calls of authentication methods are allowed from a client address at once
+	// and one more every RateLimitIPInterval.
+	RateLimitIPBurst    int           `mapstructure:"RATE_LIMIT_IP_BURST" default:"20" validate:"min=1"`
+	RateLimitIPInterval time.Duration `mapstructure:"RATE_LIMIT_IP_INTERVAL" default:"3s"`
+	// RateLimitKeyBurst calls of authentication methods are allowed for a username at once
+	// and one more every RateLimitKeyInterval.
+	RateLimitKeyBurst    int           `mapstructure:"RATE_LIMIT_KEY_BURST" default:"5" validate:"min=1"`
+	RateLimitKeyInterval time.Duration `mapstructure:"RATE_LIMIT_KEY_INTERVAL" default:"1m"`
+	// RateLimitTrustedProxies are comma separated networks of proxies, like the gateway,
+	// whose x-forwarded-for header is the client address, for rate limits and sessions.
+	// Other clients could claim any address with the header, so only the networks of
+	// the real proxies may be listed. The default trusts the gateway of the same host.
+	RateLimitTrustedProxies string `mapstructure:"RATE_LIMIT_TRUSTED_PROXIES" default:"127.0.0.0/8,::1/128"`
+
+	// LoginDelayAfter is the number of failed logins of a user after which
+	// the user has to wait before trying again, twice as long after every next failure.
+	LoginDelayAfter int `mapstructure:"LOGIN_DELAY_AFTER" default:"3" validate:"min=1"`
+	// LoginLockoutAfter is the number of failed logins that lock the user out for LoginLockoutDuration.
+	LoginLockoutAfter    int           `mapstructure:"LOGIN_LOCKOUT_AFTER" default:"10" validate:"min=1"`
+	LoginLockoutDuration time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION" default:"15m" validate:"min=1s"`
+	// LoginFailureWindow is how long failed logins are remembered after the last one.
+	LoginFailureWindow time.Duration `mapstructure:"LOGIN_FAILURE_WINDOW" default:"24h"`
 
-func LoadConfig(path string, name string) (*Config, error) {
-	viper.AddConfigPath(path)
-	viper.SetConfigName(name)
-	viper.SetConfigType("env")
+	// TOTPIssuer is the name authenticator apps show for two-factor authentication accounts.
+	TOTPIssuer string `mapstructure:"TOTP_ISSUER" default:"cryptowatch"`
 
-	viper.AutomaticEnv()
+	// OIDCIssuerURL enables logins with the OpenID provider of the issuer, like https://accounts.google.com.
+	OIDCIssuerURL *url.URL `mapstructure:"OIDC_ISSUER_URL"`
+	// OIDCClientID and OIDCClientSecret are the credentials of cryptowatch registered at the provider.
+	OIDCClientID     string `mapstructure:"OIDC_CLIENT_ID"`
+	OIDCClientSecret string `mapstructure:"OIDC_CLIENT_SECRET"`
+	// OIDCRedirectURL is the gateway callback registered at the provider, ending in /v2/oidc/callback.
+	OIDCRedirectURL *url.URL `mapstructure:"OIDC_REDIRECT_URL"`
+	// OIDCScopes are space separated scopes requested besides openid.
+	OIDCScopes string `mapstructure:"OIDC_SCOPES" default:"profile email"`
+	// OIDCProvision creates an account on the first login of an identity linked to none.
+	OIDCProvision bool `mapstructure:"OIDC_PROVISION" default:"true"`
 
-	err := viper.ReadInConfig()
+	// BindAddr is the address the gRPC server listens on.
+	BindAddr string `mapstructure:"BIND_ADDR" default:":50051" validate:"hostport"`
+	// GatewayAddr is the address the HTTP gateway listens on.
+	GatewayAddr string `mapstructure:"GATEWAY_ADDR" default:":8081" validate:"hostport"`
+	// GRPCEndpoint is the address the gateway and the bot use to reach the gRPC server.
+	GRPCEndpoint string `mapstructure:"GRPC_ENDPOINT" default:"localhost:50051" validate:"hostport"`
+	// ShutdownTimeout limits how long servers wait for in-flight requests on shutdown.
+	ShutdownTimeout time.Duration `mapstructure:"SHUTDOWN_TIMEOUT" default:"10s"`
+
+	// TLSCertFile and TLSKeyFile enable TLS on the gRPC server.
+	TLSCertFile string `mapstructure:"TLS_CERT_FILE" validate:"file"`
+	TLSKeyFile  string `mapstructure:"TLS_KEY_FILE" validate:"file"`
+	// TLSClientCAFile enables mTLS on the gRPC server: client certificates must be signed by this CA.
+	TLSClientCAFile string `mapstructure:"TLS_CLIENT_CA_FILE" validate:"file"`
+	// TLSClientAuth is whether clients without a certificate are accepted when TLSClientCAFile is set.
+	TLSClientAuth string `mapstructure:"TLS_CLIENT_AUTH" default:"verify-if-given" validate:"oneof=verify-if-given|require"`
+	// GatewayTLSCertFile and GatewayTLSKeyFile enable HTTPS on the gateway.
+	GatewayTLSCertFile string `mapstructure:"GATEWAY_TLS_CERT_FILE" validate:"file"`
+	GatewayTLSKeyFile  string `mapstructure:"GATEWAY_TLS_KEY_FILE" validate:"file"`
+	// GRPCTLS enables TLS for the gateway and the bot connecting to GRPCEndpoint.
+	GRPCTLS bool `mapstructure:"GRPC_TLS" default:"false"`
+	// GRPCTLSCAFile is the CA verifying the gRPC server, the system roots are used when empty.
+	GRPCTLSCAFile string `mapstructure:"GRPC_TLS_CA_FILE" validate:"file"`
+	// GRPCTLSCertFile and GRPCTLSKeyFile are the client certificate presented to the gRPC server for mTLS.
+	GRPCTLSCertFile string `mapstructure:"GRPC_TLS_CERT_FILE" validate:"file"`
+	GRPCTLSKeyFile  string `mapstructure:"GRPC_TLS_KEY_FILE" validate:"file"`
+	// GRPCTLSServerName overrides the server name verified in the gRPC server certificate.
+	GRPCTLSServerName string `mapstructure:"GRPC_TLS_SERVER_NAME"`
+
+	DBHost     string `mapstructure:"DB_HOST" validate:"required"`
+	DBPort     int    `mapstructure:"DB_PORT" default:"5432" validate:"port"`
+	DBUser     string `mapstructure:"DB_USER" validate:"required"`
+	DBPassword string `mapstructure:"DB_PASSWORD" validate:"required"`
+	DBName     string `mapstructure:"DB_NAME" validate:"required"`
+	DBSSLMode  string `mapstructure:"DB_SSLMODE" default:"prefer" validate:"oneof=disable|allow|prefer|require|verify-ca|verify-full"`
+
+	TelegramToken string `mapstructure:"TELEGRAM_TOKEN" validate:"required"`
+	// TelegramAPIURL is the base URL of the Bot API, e.g. of a local Bot API server.
+	TelegramAPIURL *url.URL `mapstructure:"TELEGRAM_API_URL" default:"https://api.telegram.org"`
+	// TelegramMode is how the bot receives updates: run-bot long-polls them with polling,
+	// Telegram posts them to the gateway at TelegramWebhookURL with webhook.
+	TelegramMode string `mapstructure:"TELEGRAM_MODE" default:"polling" validate:"oneof=polling|webhook"`
+	// TelegramWebhookURL is the public HTTPS URL of the gateway receiving updates, its path is served by the gateway.
+	TelegramWebhookURL *url.URL `mapstructure:"TELEGRAM_WEBHOOK_URL" validate:"required"`
+	// TelegramWebhookSecret is sent by Telegram with every update, so nobody else can post updates.
+	TelegramWebhookSecret string `mapstructure:"TELEGRAM_WEBHOOK_SECRET" validate:"required"`
+	// TelegramPollInterval is the minimum time between two requests for bot updates.
+	TelegramPollInterval time.Duration `mapstructure:"TELEGRAM_POLL_INTERVAL" default:"1s" validate:"min=100ms" reload:"hot"`
+	// TelegramConversationTimeout is how long multi-step bot commands wait for the next message.
+	TelegramConversationTimeout time.Duration `mapstructure:"TELEGRAM_CONVERSATION_TIMEOUT" default:"5m" validate:"min=1s" reload:"hot"`
+	// AlertInterval is the minimum time between two price alerts sent to a chat.
+	AlertInterval time.Duration `mapstructure:"ALERT_INTERVAL" default:"5s" validate:"min=1s" reload:"hot"`
+
+	CryptoCompareToken     string        `mapstructure:"CRYPTOCOMPARE_TOKEN" validate:"required"`
+	CryptoCompareAPIURL    *url.URL      `mapstructure:"CRYPTOCOMPARE_API_URL" default:"https://min-api.cryptocompare.com/data/pricemulti"`
+	CryptoCompareStreamURL *url.URL      `mapstructure:"CRYPTOCOMPARE_STREAM_URL" default:"wss://streamer.cryptocompare.com/v2"`
+	CryptoCompareTimeout   time.Duration `mapstructure:"CRYPTOCOMPARE_TIMEOUT" default:"10s"`
+}
+
+// Keys needed by every process that connects to the database.
+var DBKeys = []string{"DB_HOST", "DB_PORT", "DB_USER", "DB_PASSWORD", "DB_NAME", "DB_SSLMODE"}
+
+// Load reads the configuration from file and the environment.
+// File may be empty to read the environment only.
+//
+// Every value that is set is checked against its type and `validate` tag.
+// Of the `validate:"required"` keys only those listed in keys must be set,
+// so each process requires only the keys of the components it runs.
+// The returned *ValidationError lists every missing and invalid key at once.
+func Load(file string, keys ...string) (*Config, error) {
+	src, err := newSource(file)
+	if err != nil {
+		return nil, err
+	}
 
 	var cfg Config
-	err = viper.Unmarshal(&cfg)
+	err = decode(src, &cfg, keys)
 	if err != nil {
 		return nil, err
 	}
 
 	return &cfg, nil
 }
+
+// DBSource returns the Postgres connection string.
+func (c *Config) DBSource() string {
+	return fmt.Sprintf(
+		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		c.DBHost, c.DBPort, c.DBUser, c.DBPassword, c.DBName, c.DBSSLMode,
+	)
+}
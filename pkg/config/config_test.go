@@ -0,0 +1,164 @@
+package config_test
+
+import (
+	"cryptowatch/pkg/config"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clearEnv unsets the config keys for the test, so values set on the machine don't change the result.
+func clearEnv(t *testing.T) {
+	t.Helper()
+
+	typ := reflect.TypeOf(config.Config{})
+	for i := 0; i < typ.NumField(); i++ {
+		key := typ.Field(i).Tag.Get("mapstructure")
+		// Empty values count as unset.
+		t.Setenv(key, "")
+		t.Setenv(key+"_FILE", "")
+	}
+}
+
+func writeFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(path, []byte(content), 0o600)
+	require.NoError(t, err)
+
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	clearEnv(t)
+	cfg, err := config.Load("")
+	require.NoError(t, err)
+
+	assert.Equal(t, ":50051", cfg.BindAddr)
+	assert.Equal(t, 5432, cfg.DBPort)
+	assert.Equal(t, "prefer", cfg.DBSSLMode)
+	assert.Equal(t, 10*time.Second, cfg.ShutdownTimeout)
+	assert.Equal(t, "https://min-api.cryptocompare.com/data/pricemulti", cfg.CryptoCompareAPIURL.String())
+	// Only the gateway of the same host may forward client addresses.
+	assert.Equal(t, "127.0.0.0/8,::1/128", cfg.RateLimitTrustedProxies)
+}
+
+func TestLoad_Sources(t *testing.T) {
+	clearEnv(t)
+	file := writeFile(t, "test.env", "DB_HOST=filehost\nDB_USER=fileuser\nDB_NAME=\nDB_PORT=5433\n")
+	secret := writeFile(t, "password", "secret\n")
+
+	t.Setenv("DB_USER", "envuser")
+	t.Setenv("DB_NAME", "envdb")
+	t.Setenv("DB_PASSWORD_FILE", secret)
+
+	cfg, err := config.Load(file, config.DBKeys...)
+	require.NoError(t, err)
+
+	assert.Equal(t, "filehost", cfg.DBHost)
+	assert.Equal(t, 5433, cfg.DBPort)
+	assert.Equal(t, "envuser", cfg.DBUser)
+	assert.Equal(t, "envdb", cfg.DBName)
+	assert.Equal(t, "secret", cfg.DBPassword)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("DB_PORT", "99999")
+	t.Setenv("BIND_ADDR", "50051")
+	t.Setenv("SHUTDOWN_TIMEOUT", "10")
+	t.Setenv("DB_SSLMODE", "on")
+	t.Setenv("SYMMETRIC_KEY", "short")
+	t.Setenv("OTP_MAX_ATTEMPTS", "0")
+	t.Setenv("PASSWORD_MIN_CHAR_CLASSES", "5")
+	t.Setenv("CRYPTOCOMPARE_API_URL", "/data")
+	t.Setenv("DB_USER", "user")
+	t.Setenv("DB_USER_FILE", "/run/secrets/db_user")
+
+	_, err := config.Load("", "DB_HOST", "DB_USER", "TELEGRAM_TOKEN")
+
+	var verr *config.ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, []string{
+		"BIND_ADDR",
+		"CRYPTOCOMPARE_API_URL",
+		"DB_HOST",
+		"DB_PORT",
+		"DB_SSLMODE",
+		"DB_USER",
+		"OTP_MAX_ATTEMPTS",
+		"PASSWORD_MIN_CHAR_CLASSES",
+		"SHUTDOWN_TIMEOUT",
+		"SYMMETRIC_KEY",
+		"TELEGRAM_TOKEN",
+	}, verr.Keys())
+}
+
+func TestLoad_DurationMin(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("TELEGRAM_CONVERSATION_TIMEOUT", "0s")
+
+	_, err := config.Load("")
+
+	var verr *config.ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, []string{"TELEGRAM_CONVERSATION_TIMEOUT"}, verr.Keys())
+	assert.EqualError(t, err, "invalid config: TELEGRAM_CONVERSATION_TIMEOUT: must be at least 1s")
+
+	t.Setenv("TELEGRAM_CONVERSATION_TIMEOUT", "1s")
+	cfg, err := config.Load("")
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, cfg.TelegramConversationTimeout)
+}
+
+// TestLoad_IntervalsMin checks intervals of tickers and token lifetimes can't be zero.
+func TestLoad_IntervalsMin(t *testing.T) {
+	keys := []string{
+		"ACCESS_TOKEN_DURATION",
+		"ALERT_INTERVAL",
+		"LOGIN_LOCKOUT_DURATION",
+		"REFRESH_TOKEN_DURATION",
+		"SESSION_DENYLIST_INTERVAL",
+		"STREAM_REVALIDATE_INTERVAL",
+		"TELEGRAM_POLL_INTERVAL",
+		"TOKEN_KEY_REFRESH_INTERVAL",
+	}
+
+	for _, key := range keys {
+		key := key
+		t.Run(key, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv(key, "0s")
+
+			_, err := config.Load("")
+
+			var verr *config.ValidationError
+			require.ErrorAs(t, err, &verr)
+			assert.Equal(t, []string{key}, verr.Keys())
+		})
+	}
+}
+
+func TestLoad_SymmetricKeyLen(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("SYMMETRIC_KEY", strings.Repeat("k", 33))
+
+	_, err := config.Load("")
+	assert.EqualError(t, err, "invalid config: SYMMETRIC_KEY: must be exactly 32 characters")
+
+	t.Setenv("SYMMETRIC_KEY", strings.Repeat("k", 32))
+	cfg, err := config.Load("")
+	require.NoError(t, err)
+	assert.Len(t, cfg.SymmetricKey, 32)
+}
+
+func TestLoad_MissingFile(t *testing.T) {
+	clearEnv(t)
+	_, err := config.Load(filepath.Join(t.TempDir(), "missing.env"))
+	require.Error(t, err)
+}
@@ -0,0 +1,289 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errRequired = errors.New("required")
+	errNotInt   = errors.New("must be an integer")
+)
+
+// KeyError describes a problem with a single config key.
+type KeyError struct {
+	Key string
+	Err error
+}
+
+func (e *KeyError) Error() string {
+	return e.Key + ": " + e.Err.Error()
+}
+
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError lists every config key that is missing or invalid.
+type ValidationError struct {
+	Errors []*KeyError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+
+	return "invalid config: " + strings.Join(msgs, "; ")
+}
+
+// Keys returns the keys that failed validation.
+func (e *ValidationError) Keys() []string {
+	keys := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		keys = append(keys, err.Key)
+	}
+
+	return keys
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	urlType      = reflect.TypeOf(&url.URL{})
+)
+
+func decode(src *source, cfg *Config, required []string) error {
+	isRequired := make(map[string]bool, len(required))
+	for _, key := range required {
+		isRequired[key] = true
+	}
+
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+
+	var verr ValidationError
+	known := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := field.Tag.Get("mapstructure")
+		known[key] = true
+
+		rules := parseRules(field.Tag.Get("validate"))
+
+		raw, ok, err := src.lookup(key)
+		if err != nil {
+			verr.Errors = append(verr.Errors, &KeyError{Key: key, Err: err})
+			continue
+		}
+		if !ok {
+			raw, ok = field.Tag.Lookup("default")
+		}
+		if !ok {
+			if hasRule(rules, "required") && isRequired[key] {
+				verr.Errors = append(verr.Errors, &KeyError{Key: key, Err: errRequired})
+			}
+			continue
+		}
+
+		err = setValue(v.Field(i), raw)
+		if err == nil {
+			err = checkRules(v.Field(i), rules)
+		}
+		if err != nil {
+			verr.Errors = append(verr.Errors, &KeyError{Key: key, Err: err})
+		}
+	}
+
+	for _, key := range required {
+		if !known[key] {
+			verr.Errors = append(verr.Errors, &KeyError{Key: key, Err: errors.New("unknown key")})
+		}
+	}
+
+	if len(verr.Errors) > 0 {
+		sort.SliceStable(verr.Errors, func(i, j int) bool {
+			return verr.Errors[i].Key < verr.Errors[j].Key
+		})
+		return &verr
+	}
+
+	return nil
+}
+
+func setValue(field reflect.Value, raw string) error {
+	switch {
+	case field.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return errors.New("must be a duration like 10s or 1m30s")
+		}
+		field.SetInt(int64(d))
+	case field.Type() == urlType:
+		u, err := url.Parse(raw)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return errors.New("must be an absolute URL")
+		}
+		field.Set(reflect.ValueOf(u))
+	case field.Kind() == reflect.String:
+		field.SetString(raw)
+	case field.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return errNotInt
+		}
+		field.SetInt(int64(n))
+	case field.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return errors.New("must be a boolean")
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+
+	return nil
+}
+
+type rule struct {
+	name string
+	arg  string
+}
+
+// parseRules parses a validate tag like "required,len=32,oneof=a|b" into rule names and arguments.
+func parseRules(tag string) []rule {
+	if tag == "" {
+		return nil
+	}
+
+	var rules []rule
+	for _, r := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(r, "=")
+		rules = append(rules, rule{name: name, arg: arg})
+	}
+
+	return rules
+}
+
+func hasRule(rules []rule, name string) bool {
+	for _, r := range rules {
+		if r.name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func checkRules(field reflect.Value, rules []rule) error {
+	for _, r := range rules {
+		var err error
+		switch r.name {
+		case "required":
+			// Checked before decoding: a set value always satisfies it.
+		case "min":
+			min, argErr := ruleArg(field, r)
+			switch {
+			case argErr != nil:
+				err = argErr
+			case field.Type() == durationType:
+				if field.Int() < min {
+					err = fmt.Errorf("must be at least %s", time.Duration(min))
+				}
+			case field.Kind() == reflect.Int || field.Kind() == reflect.Int64:
+				if field.Int() < min {
+					err = fmt.Errorf("must be at least %d", min)
+				}
+			default:
+				if int64(len(field.String())) < min {
+					err = fmt.Errorf("must be at least %d characters", min)
+				}
+			}
+		case "max":
+			max, argErr := ruleArg(field, r)
+			switch {
+			case argErr != nil:
+				err = argErr
+			case field.Type() == durationType:
+				if field.Int() > max {
+					err = fmt.Errorf("must be at most %s", time.Duration(max))
+				}
+			default:
+				if field.Int() > max {
+					err = fmt.Errorf("must be at most %d", max)
+				}
+			}
+		case "len":
+			n, argErr := ruleArg(field, r)
+			if argErr != nil {
+				err = argErr
+			} else if int64(len(field.String())) != n {
+				err = fmt.Errorf("must be exactly %d characters", n)
+			}
+		case "port":
+			if port := field.Int(); port < 1 || port > 65535 {
+				err = errors.New("must be a port number between 1 and 65535")
+			}
+		case "hostport":
+			_, port, splitErr := net.SplitHostPort(field.String())
+			if splitErr != nil || port == "" {
+				err = errors.New("must be an address like host:port or :port")
+			}
+		case "file":
+			fi, statErr := os.Stat(field.String())
+			if statErr != nil || fi.IsDir() {
+				err = errors.New("must be an existing file")
+			}
+		case "oneof":
+			options := strings.Split(r.arg, "|")
+			if !contains(options, field.String()) {
+				err = fmt.Errorf("must be one of %s", strings.Join(options, ", "))
+			}
+		default:
+			err = fmt.Errorf("unknown validation rule %q", r.name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ruleArg parses the argument of a rule comparing with the field, a duration like 1s
+// for durations and an integer otherwise.
+func ruleArg(field reflect.Value, r rule) (int64, error) {
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(r.arg)
+		if err != nil {
+			return 0, fmt.Errorf("invalid argument %q of rule %s, must be a duration", r.arg, r.name)
+		}
+		return int64(d), nil
+	}
+
+	n, err := strconv.ParseInt(r.arg, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid argument %q of rule %s, must be an integer", r.arg, r.name)
+	}
+
+	return n, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	"os"
+	"strings"
+)
+
+// fileSuffix marks a key whose value is the path of a file holding the actual value.
+const fileSuffix = "_FILE"
+
+type source struct {
+	file *viper.Viper
+}
+
+func newSource(file string) (*source, error) {
+	src := &source{}
+	if file == "" {
+		return src, nil
+	}
+
+	src.file = viper.New()
+	src.file.SetConfigFile(file)
+	err := src.file.ReadInConfig()
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	return src, nil
+}
+
+// lookup returns the value of the key and whether it is set.
+func (s *source) lookup(key string) (string, bool, error) {
+	if path, ok := s.raw(key + fileSuffix); ok {
+		if _, ok := s.raw(key); ok {
+			return "", false, fmt.Errorf("both %s and %s%s are set", key, key, fileSuffix)
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", false, fmt.Errorf("read %s%s: %w", key, fileSuffix, err)
+		}
+
+		value := strings.TrimRight(string(b), "\r\n")
+		return value, value != "", nil
+	}
+
+	value, ok := s.raw(key)
+	return value, ok, nil
+}
+
+// raw returns the value from the environment or the config file, ignoring empty values.
+func (s *source) raw(key string) (string, bool) {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value, true
+	}
+
+	if s.file != nil {
+		// viper keys are case-insensitive and stored lowercased.
+		if value := s.file.GetString(key); value != "" {
+			return value, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"context"
+	"github.com/fsnotify/fsnotify"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"syscall"
+)
+
+// Subscriber is notified about configuration changes applied without a restart.
+type Subscriber interface {
+	ConfigChanged(cfg *Config)
+}
+
+// SubscriberFunc is an adapter to use ordinary functions as subscribers.
+type SubscriberFunc func(cfg *Config)
+
+func (f SubscriberFunc) ConfigChanged(cfg *Config) {
+	f(cfg)
+}
+
+// Watcher reloads the configuration when the config file changes or the process gets SIGHUP.
+//
+// Only keys tagged `reload:"hot"` are updated; changes to other keys are
+// logged and ignored until restart. A reloaded configuration that fails
+// validation is rejected as a whole.
+type Watcher struct {
+	file string
+	keys []string
+
+	mu          sync.RWMutex
+	current     *Config
+	subscribers []Subscriber
+}
+
+// NewWatcher creates a watcher for the configuration loaded by Load(file, keys...).
+func NewWatcher(cfg *Config, file string, keys ...string) *Watcher {
+	return &Watcher{
+		file:    file,
+		keys:    keys,
+		current: cfg,
+	}
+}
+
+// Subscribe registers s to be notified after hot keys change.
+func (w *Watcher) Subscribe(s Subscriber) {
+	w.mu.Lock()
+	w.subscribers = append(w.subscribers, s)
+	w.mu.Unlock()
+}
+
+// Current returns the configuration with all hot changes applied.
+func (w *Watcher) Current() *Config {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return w.current
+}
+
+// Reload loads the configuration again and applies changed hot keys.
+func (w *Watcher) Reload() error {
+	cfg, err := Load(w.file, w.keys...)
+	if err != nil {
+		log.Printf("config: reload rejected: %v", err)
+		return err
+	}
+
+	w.mu.Lock()
+	next := *w.current
+	changed := applyHot(&next, cfg)
+	if changed {
+		w.current = &next
+	}
+	subscribers := append([]Subscriber(nil), w.subscribers...)
+	w.mu.Unlock()
+
+	if changed {
+		for _, s := range subscribers {
+			s.ConfigChanged(&next)
+		}
+	}
+
+	return nil
+}
+
+// applyHot copies hot keys changed in src into dst and reports whether any was copied.
+func applyHot(dst *Config, src *Config) bool {
+	dv := reflect.ValueOf(dst).Elem()
+	sv := reflect.ValueOf(src).Elem()
+	t := dv.Type()
+
+	changed := false
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if reflect.DeepEqual(dv.Field(i).Interface(), sv.Field(i).Interface()) {
+			continue
+		}
+
+		key := field.Tag.Get("mapstructure")
+		if field.Tag.Get("reload") != "hot" {
+			log.Printf("config: %s changed but requires a restart, keeping the current value", key)
+			continue
+		}
+
+		dv.Field(i).Set(sv.Field(i))
+		log.Printf("config: %s reloaded", key)
+		changed = true
+	}
+
+	return changed
+}
+
+// Run reloads the configuration on SIGHUP and on changes of the config file until ctx is done.
+func (w *Watcher) Run(ctx context.Context) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var events chan fsnotify.Event
+	var errs chan error
+	if w.file != "" {
+		fw, err := fsnotify.NewWatcher()
+		if err != nil {
+			return err
+		}
+		defer fw.Close()
+
+		// The directory is watched because editors and Kubernetes ConfigMap
+		// updates replace the file instead of writing to it.
+		err = fw.Add(filepath.Dir(w.file))
+		if err != nil {
+			return err
+		}
+		events, errs = fw.Events, fw.Errors
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+			log.Printf("config: SIGHUP received, reloading")
+			w.Reload()
+		case ev := <-events:
+			if w.affects(ev) {
+				w.Reload()
+			}
+		case err := <-errs:
+			log.Printf("config: watch %s: %v", w.file, err)
+		}
+	}
+}
+
+// affects reports whether ev may have changed the contents of the config file.
+func (w *Watcher) affects(ev fsnotify.Event) bool {
+	if ev.Op == fsnotify.Chmod {
+		return false
+	}
+
+	// Kubernetes swaps the ..data symlink when a mounted ConfigMap is updated.
+	return filepath.Clean(ev.Name) == filepath.Clean(w.file) || filepath.Base(ev.Name) == "..data"
+}
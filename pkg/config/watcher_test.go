@@ -0,0 +1,59 @@
+package config_test
+
+import (
+	"cryptowatch/pkg/config"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcher_Reload(t *testing.T) {
+	clearEnv(t)
+	file := writeFile(t, "test.env", "BIND_ADDR=:50051\nALERT_INTERVAL=5s\n")
+
+	cfg, err := config.Load(file)
+	require.NoError(t, err)
+
+	w := config.NewWatcher(cfg, file)
+
+	var notified []*config.Config
+	w.Subscribe(config.SubscriberFunc(func(cfg *config.Config) {
+		notified = append(notified, cfg)
+	}))
+
+	// Restart-only keys are kept, hot keys are applied.
+	err = os.WriteFile(file, []byte("BIND_ADDR=:50052\nALERT_INTERVAL=1m\n"), 0o600)
+	require.NoError(t, err)
+	require.NoError(t, w.Reload())
+
+	require.Len(t, notified, 1)
+	assert.Equal(t, time.Minute, notified[0].AlertInterval)
+	assert.Equal(t, ":50051", notified[0].BindAddr)
+	assert.Equal(t, notified[0], w.Current())
+
+	// Invalid configuration is rejected as a whole.
+	err = os.WriteFile(file, []byte("ALERT_INTERVAL=2m\nDB_PORT=none\n"), 0o600)
+	require.NoError(t, err)
+	require.Error(t, w.Reload())
+
+	assert.Len(t, notified, 1)
+	assert.Equal(t, time.Minute, w.Current().AlertInterval)
+
+	// Hot keys are validated too, a zero interval would make the bot spin.
+	err = os.WriteFile(file, []byte("ALERT_INTERVAL=0s\n"), 0o600)
+	require.NoError(t, err)
+	require.Error(t, w.Reload())
+
+	assert.Len(t, notified, 1)
+	assert.Equal(t, time.Minute, w.Current().AlertInterval)
+
+	// Nothing changed, nobody is notified.
+	err = os.WriteFile(file, []byte("ALERT_INTERVAL=1m\n"), 0o600)
+	require.NoError(t, err)
+	require.NoError(t, w.Reload())
+
+	assert.Len(t, notified, 1)
+}
@@ -0,0 +1,276 @@
+// Package migrate applies the SQL schema migrations to a Postgres database.
+//
+// Applied state is kept in the schema_migrations table using the same layout
+// as golang-migrate, so databases migrated with the migrate/migrate image keep working.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"log"
+)
+
+const (
+	schemaMigrationsTable = "schema_migrations"
+
+	// lockID is the key of the advisory lock held while migrating,
+	// so replicas started at the same time don't apply migrations concurrently.
+	lockID int64 = 7_265_631_844
+)
+
+var (
+	ErrInvalidMigration = errors.New("invalid migration")
+	ErrDirty            = errors.New("database is dirty, fix it manually and use force")
+	ErrUnknownVersion   = errors.New("unknown migration version")
+)
+
+// Status describes which migrations are applied to the database.
+type Status struct {
+	// Version is the last applied migration version, 0 when none are applied.
+	Version    uint64            `json:"version"`
+	Dirty      bool              `json:"dirty"`
+	Migrations []MigrationStatus `json:"migrations"`
+}
+
+type MigrationStatus struct {
+	Version uint64 `json:"version"`
+	Name    string `json:"name"`
+	Applied bool   `json:"applied"`
+}
+
+// Migrator applies migrations to the database.
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []Migration
+}
+
+func New(db *pgxpool.Pool, migrations []Migration) *Migrator {
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}
+}
+
+// Up applies all migrations newer than the current version.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		version, dirty, err := getVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("%w: version %d", ErrDirty, version)
+		}
+
+		applied := 0
+		for _, mg := range m.migrations {
+			if mg.Version <= version {
+				continue
+			}
+
+			err = execMigration(ctx, conn, mg.Up, mg.Version)
+			if err != nil {
+				return fmt.Errorf("apply migration %d_%s: %w", mg.Version, mg.Name, err)
+			}
+			log.Printf("migrate: applied %d_%s", mg.Version, mg.Name)
+			applied++
+		}
+
+		if applied == 0 {
+			log.Printf("migrate: no change, version %d", version)
+		}
+
+		return nil
+	})
+}
+
+// Down reverts the n most recently applied migrations.
+func (m *Migrator) Down(ctx context.Context, n int) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		version, dirty, err := getVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("%w: version %d", ErrDirty, version)
+		}
+		if version == 0 {
+			log.Printf("migrate: no change, nothing is applied")
+			return nil
+		}
+
+		idx := m.indexOf(version)
+		if idx < 0 {
+			return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+		}
+
+		for i := idx; i >= 0 && i > idx-n; i-- {
+			mg := m.migrations[i]
+
+			var prev uint64
+			if i > 0 {
+				prev = m.migrations[i-1].Version
+			}
+
+			err = execMigration(ctx, conn, mg.Down, prev)
+			if err != nil {
+				return fmt.Errorf("revert migration %d_%s: %w", mg.Version, mg.Name, err)
+			}
+			log.Printf("migrate: reverted %d_%s", mg.Version, mg.Name)
+		}
+
+		return nil
+	})
+}
+
+// Force sets the current version without running any migration and clears the dirty flag.
+// Version 0 marks the database as having no migrations applied.
+func (m *Migrator) Force(ctx context.Context, version uint64) error {
+	if version != 0 && m.indexOf(version) < 0 {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		return conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+			return setVersion(ctx, tx, version)
+		})
+	})
+}
+
+// Status returns the current version and the list of known migrations.
+func (m *Migrator) Status(ctx context.Context) (*Status, error) {
+	var st Status
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		var err error
+		st.Version, st.Dirty, err = getVersion(ctx, conn)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, mg := range m.migrations {
+		st.Migrations = append(st.Migrations, MigrationStatus{
+			Version: mg.Version,
+			Name:    mg.Name,
+			Applied: mg.Version <= st.Version,
+		})
+	}
+
+	return &st, nil
+}
+
+func (m *Migrator) indexOf(version uint64) int {
+	for i, mg := range m.migrations {
+		if mg.Version == version {
+			return i
+		}
+	}
+
+	return -1
+}
+
+var createSchemaMigrationsQuery = fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s
+(
+    version bigint  NOT NULL PRIMARY KEY,
+    dirty   boolean NOT NULL
+)
+`, schemaMigrationsTable)
+
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, "SELECT pg_advisory_lock($1)", lockID)
+	if err != nil {
+		return fmt.Errorf("acquire advisory lock: %w", err)
+	}
+	defer func() {
+		_, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", lockID)
+		if err != nil {
+			log.Printf("migrate: release advisory lock: %v", err)
+		}
+	}()
+
+	_, err = conn.Exec(ctx, createSchemaMigrationsQuery)
+	if err != nil {
+		return fmt.Errorf("create %s table: %w", schemaMigrationsTable, err)
+	}
+
+	return fn(conn)
+}
+
+var getVersionQuery = fmt.Sprintf(`
+SELECT version, dirty FROM %s
+LIMIT 1
+`, schemaMigrationsTable)
+
+func getVersion(ctx context.Context, conn *pgxpool.Conn) (uint64, bool, error) {
+	var version int64
+	var dirty bool
+	err := conn.QueryRow(ctx, getVersionQuery).Scan(&version, &dirty)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("get version: %w", err)
+	}
+
+	// golang-migrate stores -1 when every migration was reverted.
+	if version < 0 {
+		return 0, dirty, nil
+	}
+
+	return uint64(version), dirty, nil
+}
+
+var (
+	deleteVersionQuery = fmt.Sprintf(`DELETE FROM %s`, schemaMigrationsTable)
+	insertVersionQuery = fmt.Sprintf(`
+INSERT INTO %s
+(version, dirty)
+VALUES ($1, false)
+`, schemaMigrationsTable)
+)
+
+func setVersion(ctx context.Context, tx pgx.Tx, version uint64) error {
+	_, err := tx.Exec(ctx, deleteVersionQuery)
+	if err != nil {
+		return fmt.Errorf("set version: %w", err)
+	}
+
+	if version == 0 {
+		return nil
+	}
+
+	_, err = tx.Exec(ctx, insertVersionQuery, int64(version))
+	if err != nil {
+		return fmt.Errorf("set version: %w", err)
+	}
+
+	return nil
+}
+
+// execMigration runs the SQL and records the resulting version in one transaction,
+// so a failed migration leaves neither partial schema changes nor a dirty version behind.
+func execMigration(ctx context.Context, conn *pgxpool.Conn, sql string, version uint64) error {
+	return conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if sql != "" {
+			// Exec without arguments uses the simple protocol, which allows multiple statements.
+			_, err := tx.Exec(ctx, sql)
+			if err != nil {
+				return err
+			}
+		}
+
+		return setVersion(ctx, tx, version)
+	})
+}
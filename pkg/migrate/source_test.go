@@ -0,0 +1,74 @@
+package migrate_test
+
+import (
+	dbschema "cryptowatch/db"
+	"cryptowatch/pkg/migrate"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/000002_second.up.sql":   {Data: []byte("CREATE TABLE b ();")},
+		"migrations/000002_second.down.sql": {Data: []byte("DROP TABLE b;")},
+		"migrations/000001_first.up.sql":    {Data: []byte("CREATE TABLE a ();")},
+		"migrations/000001_first.down.sql":  {Data: []byte("DROP TABLE a;")},
+		"migrations/README.md":              {Data: []byte("ignored")},
+	}
+
+	migrations, err := migrate.Load(fsys, "migrations")
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+
+	assert.Equal(t, migrate.Migration{
+		Version: 1,
+		Name:    "first",
+		Up:      "CREATE TABLE a ();",
+		Down:    "DROP TABLE a;",
+	}, migrations[0])
+	assert.Equal(t, uint64(2), migrations[1].Version)
+	assert.Equal(t, "second", migrations[1].Name)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{
+			name: "Missing up migration",
+			fsys: fstest.MapFS{
+				"migrations/000001_first.down.sql": {Data: []byte("DROP TABLE a;")},
+			},
+		},
+		{
+			name: "Name mismatch",
+			fsys: fstest.MapFS{
+				"migrations/000001_first.up.sql":   {Data: []byte("CREATE TABLE a ();")},
+				"migrations/000001_other.down.sql": {Data: []byte("DROP TABLE a;")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := migrate.Load(tt.fsys, "migrations")
+			assert.ErrorIs(t, err, migrate.ErrInvalidMigration)
+		})
+	}
+}
+
+func TestLoad_Embedded(t *testing.T) {
+	migrations, err := migrate.Load(dbschema.Migrations, dbschema.MigrationsDir)
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, m := range migrations {
+		assert.Equal(t, uint64(i+1), m.Version)
+		assert.NotEmpty(t, m.Down)
+	}
+}
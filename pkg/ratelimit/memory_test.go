@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_Take(t *testing.T) {
+	now := time.Now()
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+
+	limit := Limit{Burst: 2, Interval: 10 * time.Second}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		res, err := s.Take(ctx, "key", limit)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+	}
+
+	res, err := s.Take(ctx, "key", limit)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 10*time.Second, res.RetryAfter)
+
+	// Other keys have their own buckets.
+	res, err = s.Take(ctx, "other", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	now = now.Add(4 * time.Second)
+	res, err = s.Take(ctx, "key", limit)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 6*time.Second, res.RetryAfter)
+
+	now = now.Add(6 * time.Second)
+	res, err = s.Take(ctx, "key", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+}
+
+func TestMemoryStore_Drop(t *testing.T) {
+	now := time.Now()
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+
+	_, err := s.Take(context.Background(), "key", Limit{Burst: 2, Interval: time.Second})
+	require.NoError(t, err)
+
+	now = now.Add(time.Second)
+	s.drop()
+	assert.Len(t, s.buckets, 1)
+
+	now = now.Add(time.Second)
+	s.drop()
+	assert.Empty(t, s.buckets)
+}
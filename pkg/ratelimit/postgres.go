@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"log"
+	"time"
+)
+
+const bucketsTable = "rate_limit_buckets"
+
+// PostgresStore keeps buckets shared by all instances.
+type PostgresStore struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgresStore(db *pgxpool.Pool) *PostgresStore {
+	return &PostgresStore{
+		db: db,
+	}
+}
+
+// takeQuery refills and takes a token in one statement, so concurrent calls can't overdraw a bucket.
+// $2 is the burst, $3 the interval in seconds and $4 the drop time.
+var takeQuery = fmt.Sprintf(`
+INSERT INTO %[1]s AS b
+(key, tokens, allowed, update_time, drop_time)
+VALUES ($1, $2::double precision - 1, true, current_timestamp, $4)
+ON CONFLICT (key)
+DO UPDATE SET
+	tokens = CASE
+		WHEN %[2]s >= 1 THEN %[2]s - 1
+		ELSE %[2]s
+	END,
+	allowed = %[2]s >= 1,
+	update_time = current_timestamp,
+	drop_time = EXCLUDED.drop_time
+RETURNING tokens, allowed
+`, bucketsTable, refilledTokens)
+
+// refilledTokens are the tokens of an existing bucket now.
+const refilledTokens = `least($2::double precision, b.tokens + extract(epoch FROM current_timestamp - b.update_time) / $3::double precision)`
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	var tokens float64
+	var allowed bool
+	err := s.db.QueryRow(
+		ctx,
+		takeQuery,
+		key,
+		float64(limit.Burst),
+		limit.Interval.Seconds(),
+		time.Now().Add(idleFor(limit)),
+	).Scan(&tokens, &allowed)
+	if err != nil {
+		return Result{}, fmt.Errorf("take token: %w", err)
+	}
+
+	if allowed {
+		return Result{Allowed: true}, nil
+	}
+
+	return Result{RetryAfter: retryAfter(limit, tokens)}, nil
+}
+
+var dropQuery = fmt.Sprintf(`
+DELETE FROM %s
+WHERE drop_time <= current_timestamp
+`, bucketsTable)
+
+// Run drops full buckets every interval until ctx is done.
+func (s *PostgresStore) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := s.db.Exec(ctx, dropQuery)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("drop rate limit buckets: %v", err)
+			}
+		}
+	}
+}
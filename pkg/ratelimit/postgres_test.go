@@ -0,0 +1,52 @@
+//go:build integration
+// +build integration
+
+package ratelimit_test
+
+import (
+	"context"
+	"cryptowatch/pkg/config"
+	"cryptowatch/pkg/ratelimit"
+	"cryptowatch/pkg/util"
+	"path"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresStore_Take(t *testing.T) {
+	_, filename, _, _ := runtime.Caller(0)
+	rootDir := path.Join(path.Dir(filename), "../..")
+
+	cfg, err := config.Load(path.Join(rootDir, "configs", "test.env"), config.DBKeys...)
+	require.NoError(t, err)
+
+	db, err := util.OpenDB(cfg.DBSource())
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	_, err = db.Exec(ctx, "TRUNCATE TABLE rate_limit_buckets")
+	require.NoError(t, err)
+
+	s := ratelimit.NewPostgresStore(db)
+	limit := ratelimit.Limit{Burst: 2, Interval: time.Minute}
+
+	for i := 0; i < 2; i++ {
+		res, err := s.Take(ctx, "key", limit)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+	}
+
+	res, err := s.Take(ctx, "key", limit)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.InDelta(t, float64(time.Minute), float64(res.RetryAfter), float64(time.Second))
+
+	res, err = s.Take(ctx, "other", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+}
@@ -0,0 +1,51 @@
+// Package ratelimit implements token bucket rate limits with buckets kept in memory or in Postgres.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit allows Burst calls at once and one more every Interval.
+type Limit struct {
+	Burst    int
+	Interval time.Duration
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed bool
+	// RetryAfter is how long until a token is available when the call isn't allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps token buckets by key.
+type Store interface {
+	// Take takes a token from the bucket of key, creating a full bucket of limit if there is none.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// refill returns the tokens of a bucket that had tokens at last and takes one if possible.
+func refill(limit Limit, tokens float64, last time.Time, now time.Time) (float64, Result) {
+	if elapsed := now.Sub(last); elapsed > 0 {
+		tokens += float64(elapsed) / float64(limit.Interval)
+	}
+	tokens = math.Min(tokens, float64(limit.Burst))
+
+	if tokens >= 1 {
+		return tokens - 1, Result{Allowed: true}
+	}
+
+	return tokens, Result{RetryAfter: retryAfter(limit, tokens)}
+}
+
+func retryAfter(limit Limit, tokens float64) time.Duration {
+	return time.Duration(math.Ceil((1 - tokens) * float64(limit.Interval)))
+}
+
+// idleFor returns how long a bucket of limit takes to become full from empty,
+// after that it can be dropped.
+func idleFor(limit Limit) time.Duration {
+	return time.Duration(limit.Burst) * limit.Interval
+}
@@ -0,0 +1,26 @@
+package authtoken
+
+import "context"
+
+type payloadCtxKey struct{}
+
+// NewContext returns a copy of ctx carrying the verified token payload.
+func NewContext(ctx context.Context, payload *Payload) context.Context {
+	return context.WithValue(ctx, payloadCtxKey{}, payload)
+}
+
+// FromContext returns the token payload stored in ctx by NewContext.
+func FromContext(ctx context.Context) (*Payload, bool) {
+	payload, ok := ctx.Value(payloadCtxKey{}).(*Payload)
+	return payload, ok
+}
+
+// UserIDFromContext returns the id of the user authenticated by the token in ctx.
+func UserIDFromContext(ctx context.Context) (uint64, bool) {
+	payload, ok := FromContext(ctx)
+	if !ok {
+		return 0, false
+	}
+
+	return payload.UserID, true
+}
@@ -0,0 +1,229 @@
+package authtoken
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SigningKey is an Ed25519 key pair signing v2.public tokens.
+type SigningKey struct {
+	ID         string
+	PrivateKey ed25519.PrivateKey
+	CreateTime time.Time
+	// ActivateTime is when the key starts signing tokens. Keys are published
+	// before, so every instance can verify the tokens by then.
+	ActivateTime time.Time
+	// ExpireTime is when every token signed by the key has expired.
+	ExpireTime time.Time
+}
+
+// Public returns the verification key of the key pair.
+func (k *SigningKey) Public() PublicKey {
+	return PublicKey{
+		ID:         k.ID,
+		Key:        k.PrivateKey.Public().(ed25519.PublicKey),
+		ExpireTime: k.ExpireTime,
+	}
+}
+
+// PublicKey verifies tokens whose footer carries its ID.
+type PublicKey struct {
+	ID         string
+	Key        ed25519.PublicKey
+	ExpireTime time.Time
+}
+
+// KeyStore keeps signing keys shared by all instances.
+type KeyStore interface {
+	// ListKeys returns unexpired keys ordered by activate time.
+	ListKeys(ctx context.Context) ([]*SigningKey, error)
+	CreateKey(ctx context.Context, key *SigningKey) error
+	DeleteExpiredKeys(ctx context.Context) error
+}
+
+type KeyRingConfig struct {
+	// RotationInterval is how long a key signs tokens before the next one takes over.
+	RotationInterval time.Duration
+	// TokenDuration is the longest lifetime of signed tokens, keys verify tokens this long after they stop signing.
+	TokenDuration time.Duration
+	// RefreshInterval is how often keys are loaded from the store.
+	// New keys are published this long before they start signing.
+	RefreshInterval time.Duration
+}
+
+// KeyRing holds one key signing tokens and every key whose tokens may still be valid.
+// Keys are rotated on schedule by whichever instance notices first.
+type KeyRing struct {
+	store KeyStore
+	cfg   KeyRingConfig
+
+	mu      sync.RWMutex
+	signing *SigningKey
+	public  map[string]PublicKey
+}
+
+func NewKeyRing(store KeyStore, cfg KeyRingConfig) *KeyRing {
+	return &KeyRing{
+		store:  store,
+		cfg:    cfg,
+		public: make(map[string]PublicKey),
+	}
+}
+
+// SigningKey returns the key signing new tokens, false before the first refresh.
+func (r *KeyRing) SigningKey() (*SigningKey, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.signing, r.signing != nil
+}
+
+// PublicKey returns the unexpired key with the id.
+func (r *KeyRing) PublicKey(id string) (PublicKey, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.public[id]
+	if !ok || !time.Now().Before(key.ExpireTime) {
+		return PublicKey{}, false
+	}
+
+	return key, true
+}
+
+// PublicKeys returns every unexpired key ordered by id, including keys about to start signing.
+func (r *KeyRing) PublicKeys() []PublicKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	keys := make([]PublicKey, 0, len(r.public))
+	for _, key := range r.public {
+		if now.Before(key.ExpireTime) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+
+	return keys
+}
+
+// Refresh loads the keys from the store, creating the next key when the signing one is due for rotation.
+func (r *KeyRing) Refresh(ctx context.Context) error {
+	err := r.store.DeleteExpiredKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys, err := r.store.ListKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	signing, pending := r.selectKeys(keys, now)
+
+	var activateTime time.Time
+	switch {
+	case signing == nil:
+		// There is no usable key, e.g. on the first start, so one signs right away.
+		activateTime = now
+	case pending == nil && !now.Before(signing.ActivateTime.Add(r.cfg.RotationInterval-r.cfg.RefreshInterval)):
+		activateTime = now.Add(r.cfg.RefreshInterval)
+	}
+
+	if !activateTime.IsZero() {
+		key, err := r.newKey(activateTime)
+		if err != nil {
+			return err
+		}
+		err = r.store.CreateKey(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		// Instances rotating at once all sign with the latest of their keys.
+		keys, err = r.store.ListKeys(ctx)
+		if err != nil {
+			return err
+		}
+		signing, _ = r.selectKeys(keys, now)
+	}
+
+	public := make(map[string]PublicKey, len(keys))
+	for _, key := range keys {
+		public[key.ID] = key.Public()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.signing = signing
+	r.public = public
+
+	return nil
+}
+
+// Run refreshes the keys every refresh interval until ctx is done.
+func (r *KeyRing) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := r.Refresh(ctx)
+			if err != nil {
+				log.Printf("failed to refresh token signing keys: %v", err)
+			}
+		}
+	}
+}
+
+// selectKeys returns the latest active key that outlives tokens signed now and a key not active yet, if any.
+func (r *KeyRing) selectKeys(keys []*SigningKey, now time.Time) (signing, pending *SigningKey) {
+	for _, key := range keys {
+		if now.Before(key.ActivateTime) {
+			pending = key
+			continue
+		}
+		if now.Add(r.cfg.TokenDuration).After(key.ExpireTime) {
+			continue
+		}
+		if signing == nil || !key.ActivateTime.Before(signing.ActivateTime) {
+			signing = key
+		}
+	}
+
+	return signing, pending
+}
+
+func (r *KeyRing) newKey(activateTime time.Time) (*SigningKey, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	// The id is derived from the public key, so it is the same wherever the key is listed.
+	sum := sha256.Sum256(pub)
+
+	return &SigningKey{
+		ID:           base64.RawURLEncoding.EncodeToString(sum[:12]),
+		PrivateKey:   priv,
+		CreateTime:   time.Now(),
+		ActivateTime: activateTime,
+		// The key signs until the next one is active, which may be a refresh late.
+		ExpireTime: activateTime.Add(r.cfg.RotationInterval + r.cfg.RefreshInterval + r.cfg.TokenDuration),
+	}, nil
+}
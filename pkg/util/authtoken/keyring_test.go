@@ -0,0 +1,148 @@
+package authtoken
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memKeyStore keeps keys in memory of the test.
+type memKeyStore struct {
+	mu   sync.Mutex
+	keys []*SigningKey
+}
+
+func (s *memKeyStore) ListKeys(_ context.Context) ([]*SigningKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]*SigningKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		if time.Now().Before(key.ExpireTime) {
+			k := *key
+			keys = append(keys, &k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ActivateTime.Before(keys[j].ActivateTime)
+	})
+
+	return keys, nil
+}
+
+func (s *memKeyStore) CreateKey(_ context.Context, key *SigningKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := *key
+	s.keys = append(s.keys, &k)
+	return nil
+}
+
+func (s *memKeyStore) DeleteExpiredKeys(_ context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := s.keys[:0]
+	for _, key := range s.keys {
+		if time.Now().Before(key.ExpireTime) {
+			keys = append(keys, key)
+		}
+	}
+	s.keys = keys
+	return nil
+}
+
+// shift moves the times of the stored key by d.
+func (s *memKeyStore) shift(id string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range s.keys {
+		if key.ID == id {
+			key.ActivateTime = key.ActivateTime.Add(d)
+			key.ExpireTime = key.ExpireTime.Add(d)
+		}
+	}
+}
+
+var testKeyRingConfig = KeyRingConfig{
+	RotationInterval: time.Hour,
+	TokenDuration:    15 * time.Minute,
+	RefreshInterval:  time.Minute,
+}
+
+func newTestKeyRing(t *testing.T) (*KeyRing, *memKeyStore) {
+	store := &memKeyStore{}
+	ring := NewKeyRing(store, testKeyRingConfig)
+	require.NoError(t, ring.Refresh(context.Background()))
+
+	return ring, store
+}
+
+func TestKeyRing_Rotation(t *testing.T) {
+	ctx := context.Background()
+	ring, store := newTestKeyRing(t)
+
+	first, ok := ring.SigningKey()
+	require.True(t, ok)
+	assert.Len(t, ring.PublicKeys(), 1)
+
+	// Nothing is due yet.
+	require.NoError(t, ring.Refresh(ctx))
+	key, _ := ring.SigningKey()
+	assert.Equal(t, first.ID, key.ID)
+	assert.Len(t, store.keys, 1)
+
+	// The next key is published before it signs.
+	store.shift(first.ID, -testKeyRingConfig.RotationInterval+testKeyRingConfig.RefreshInterval)
+	require.NoError(t, ring.Refresh(ctx))
+	key, _ = ring.SigningKey()
+	assert.Equal(t, first.ID, key.ID)
+	require.Len(t, ring.PublicKeys(), 2)
+
+	var next *SigningKey
+	for _, k := range store.keys {
+		if k.ID != first.ID {
+			next = k
+		}
+	}
+	require.NotNil(t, next)
+	_, ok = ring.PublicKey(next.ID)
+	assert.True(t, ok)
+
+	// Once active the next key signs and the previous one still verifies.
+	store.shift(next.ID, -2*testKeyRingConfig.RefreshInterval)
+	require.NoError(t, ring.Refresh(ctx))
+	key, _ = ring.SigningKey()
+	assert.Equal(t, next.ID, key.ID)
+	_, ok = ring.PublicKey(first.ID)
+	assert.True(t, ok)
+	assert.Len(t, store.keys, 2)
+
+	// Expired keys are dropped.
+	store.shift(first.ID, -testKeyRingConfig.TokenDuration-3*testKeyRingConfig.RefreshInterval)
+	require.NoError(t, ring.Refresh(ctx))
+	_, ok = ring.PublicKey(first.ID)
+	assert.False(t, ok)
+	assert.Len(t, store.keys, 1)
+}
+
+func TestKeyRing_Expired(t *testing.T) {
+	ring, store := newTestKeyRing(t)
+	first, _ := ring.SigningKey()
+
+	// Tokens signed now would outlive the key, so a new key signs right away.
+	store.shift(first.ID, -testKeyRingConfig.RotationInterval-2*testKeyRingConfig.RefreshInterval)
+	require.NoError(t, ring.Refresh(context.Background()))
+
+	key, ok := ring.SigningKey()
+	require.True(t, ok)
+	assert.NotEqual(t, first.ID, key.ID)
+	assert.False(t, time.Now().Add(testKeyRingConfig.TokenDuration).After(key.ExpireTime))
+}
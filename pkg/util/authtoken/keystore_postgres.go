@@ -0,0 +1,93 @@
+package authtoken
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const signingKeysTable = "token_signing_keys"
+
+// PostgresKeyStore keeps signing keys shared by all instances.
+type PostgresKeyStore struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgresKeyStore(db *pgxpool.Pool) *PostgresKeyStore {
+	return &PostgresKeyStore{
+		db: db,
+	}
+}
+
+var listKeysQuery = fmt.Sprintf(`
+SELECT id, private_key, create_time, activate_time, expire_time
+FROM %s
+WHERE expire_time > current_timestamp
+ORDER BY activate_time, id
+`, signingKeysTable)
+
+func (s *PostgresKeyStore) ListKeys(ctx context.Context) ([]*SigningKey, error) {
+	rows, err := s.db.Query(ctx, listKeysQuery)
+	if err != nil {
+		return nil, fmt.Errorf("list signing keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]*SigningKey, 0)
+	for rows.Next() {
+		var key SigningKey
+		var privateKey []byte
+		err := rows.Scan(&key.ID, &privateKey, &key.CreateTime, &key.ActivateTime, &key.ExpireTime)
+		if err != nil {
+			return nil, fmt.Errorf("scan signing key: %w", err)
+		}
+		if len(privateKey) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("signing key %s: invalid private key size", key.ID)
+		}
+		key.PrivateKey = privateKey
+		keys = append(keys, &key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list signing keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+var createKeyQuery = fmt.Sprintf(`
+INSERT INTO %s
+(id, private_key, create_time, activate_time, expire_time)
+VALUES ($1, $2, $3, $4, $5)
+`, signingKeysTable)
+
+func (s *PostgresKeyStore) CreateKey(ctx context.Context, key *SigningKey) error {
+	_, err := s.db.Exec(
+		ctx,
+		createKeyQuery,
+		key.ID,
+		[]byte(key.PrivateKey),
+		key.CreateTime,
+		key.ActivateTime,
+		key.ExpireTime,
+	)
+	if err != nil {
+		return fmt.Errorf("create signing key: %w", err)
+	}
+
+	return nil
+}
+
+var deleteExpiredKeysQuery = fmt.Sprintf(`
+DELETE FROM %s
+WHERE expire_time <= current_timestamp
+`, signingKeysTable)
+
+func (s *PostgresKeyStore) DeleteExpiredKeys(ctx context.Context) error {
+	_, err := s.db.Exec(ctx, deleteExpiredKeysQuery)
+	if err != nil {
+		return fmt.Errorf("delete expired signing keys: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+//go:build integration
+// +build integration
+
+package authtoken_test
+
+import (
+	"context"
+	"cryptowatch/pkg/config"
+	"cryptowatch/pkg/util"
+	"cryptowatch/pkg/util/authtoken"
+	"path"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresKeyStore(t *testing.T) {
+	_, filename, _, _ := runtime.Caller(0)
+	rootDir := path.Join(path.Dir(filename), "../../..")
+
+	cfg, err := config.Load(path.Join(rootDir, "configs", "test.env"), config.DBKeys...)
+	require.NoError(t, err)
+
+	db, err := util.OpenDB(cfg.DBSource())
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	_, err = db.Exec(ctx, "TRUNCATE TABLE token_signing_keys")
+	require.NoError(t, err)
+
+	store := authtoken.NewPostgresKeyStore(db)
+	ring := authtoken.NewKeyRing(store, authtoken.KeyRingConfig{
+		RotationInterval: time.Hour,
+		TokenDuration:    time.Minute,
+		RefreshInterval:  time.Minute,
+	})
+	require.NoError(t, ring.Refresh(ctx))
+
+	keys, err := store.ListKeys(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+
+	key, ok := ring.SigningKey()
+	require.True(t, ok)
+	assert.Equal(t, key.ID, keys[0].ID)
+	assert.Equal(t, key.PrivateKey, keys[0].PrivateKey)
+
+	// Another instance verifies tokens signed by the first one.
+	other := authtoken.NewKeyRing(store, authtoken.KeyRingConfig{
+		RotationInterval: time.Hour,
+		TokenDuration:    time.Minute,
+		RefreshInterval:  time.Minute,
+	})
+	require.NoError(t, other.Refresh(ctx))
+
+	token, err := authtoken.NewPasetoPublicMaker(ring).CreateToken(1, time.Minute)
+	require.NoError(t, err)
+	payload, err := authtoken.NewPasetoPublicMaker(other).VerifyToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), payload.UserID)
+
+	expired := &authtoken.SigningKey{
+		ID:           "expired",
+		PrivateKey:   key.PrivateKey,
+		CreateTime:   time.Now().Add(-time.Hour),
+		ActivateTime: time.Now().Add(-time.Hour),
+		ExpireTime:   time.Now().Add(-time.Minute),
+	}
+	require.NoError(t, store.CreateKey(ctx, expired))
+	keys, err = store.ListKeys(ctx)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	require.NoError(t, store.DeleteExpiredKeys(ctx))
+	var count int
+	require.NoError(t, db.QueryRow(ctx, "SELECT count(*) FROM token_signing_keys").Scan(&count))
+	assert.Equal(t, 1, count)
+}
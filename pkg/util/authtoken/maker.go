@@ -5,8 +5,14 @@ import "time"
 // Maker is an interface for managing tokens.
 type Maker interface {
 	// CreateToken creates a new token for a specific user id and duration.
-	CreateToken(userID uint64, duration time.Duration) (string, error)
+	CreateToken(userID uint64, duration time.Duration, opts ...Option) (string, error)
 
-	// VerifyToken checks if the provided token is valid or not.
-	VerifyToken(token string) (*Payload, error)
+	// VerifyToken checks if the provided token is valid and has the expected claims.
+	VerifyToken(token string, expect ...Expectation) (*Payload, error)
+}
+
+// PublicKeyLister is implemented by makers whose tokens are verified with public keys,
+// which may be shared with other services.
+type PublicKeyLister interface {
+	PublicKeys() []PublicKey
 }
@@ -26,8 +26,8 @@ func NewPasetoMaker(symmetricKey string) (Maker, error) {
 }
 
 // CreateToken creates a new token for a specific user id and duration.
-func (m *PasetoMaker) CreateToken(userID uint64, duration time.Duration) (string, error) {
-	payload, err := NewPayload(userID, duration)
+func (m *PasetoMaker) CreateToken(userID uint64, duration time.Duration, opts ...Option) (string, error) {
+	payload, err := NewPayload(userID, duration, opts...)
 	if err != nil {
 		return "", err
 	}
@@ -35,8 +35,8 @@ func (m *PasetoMaker) CreateToken(userID uint64, duration time.Duration) (string
 	return m.paseto.Encrypt(m.symmetricKey, payload, nil)
 }
 
-// VerifyToken checks if the provided token is valid or not.
-func (m *PasetoMaker) VerifyToken(token string) (*Payload, error) {
+// VerifyToken checks if the provided token is valid and has the expected claims.
+func (m *PasetoMaker) VerifyToken(token string, expect ...Expectation) (*Payload, error) {
 	payload := &Payload{}
 
 	err := m.paseto.Decrypt(token, m.symmetricKey, payload, nil)
@@ -44,7 +44,7 @@ func (m *PasetoMaker) VerifyToken(token string) (*Payload, error) {
 		return nil, ErrInvalidToken
 	}
 
-	err = payload.Valid()
+	err = payload.Valid(expect...)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,78 @@
+package authtoken
+
+import (
+	"errors"
+	"time"
+
+	"github.com/o1egl/paseto"
+)
+
+// ErrNoSigningKey is returned when tokens are created before the key ring was loaded.
+var ErrNoSigningKey = errors.New("no signing key")
+
+// PasetoPublicMaker is a PASETO v2.public token maker. Tokens are signed
+// with the current key of a key ring and verified with the key named in the footer,
+// so verifying them needs public keys only.
+type PasetoPublicMaker struct {
+	paseto *paseto.V2
+	keys   *KeyRing
+}
+
+// keyFooter is the token footer naming the signing key.
+type keyFooter struct {
+	KeyID string `json:"kid"`
+}
+
+func NewPasetoPublicMaker(keys *KeyRing) *PasetoPublicMaker {
+	return &PasetoPublicMaker{
+		paseto: paseto.NewV2(),
+		keys:   keys,
+	}
+}
+
+// CreateToken creates a new token for a specific user id and duration.
+func (m *PasetoPublicMaker) CreateToken(userID uint64, duration time.Duration, opts ...Option) (string, error) {
+	key, ok := m.keys.SigningKey()
+	if !ok {
+		return "", ErrNoSigningKey
+	}
+
+	payload, err := NewPayload(userID, duration, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	return m.paseto.Sign(key.PrivateKey, payload, keyFooter{KeyID: key.ID})
+}
+
+// VerifyToken checks if the provided token is valid and has the expected claims.
+func (m *PasetoPublicMaker) VerifyToken(token string, expect ...Expectation) (*Payload, error) {
+	var footer keyFooter
+	err := paseto.ParseFooter(token, &footer)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	key, ok := m.keys.PublicKey(footer.KeyID)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	payload := &Payload{}
+	err = m.paseto.Verify(token, key.Key, payload, nil)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	err = payload.Valid(expect...)
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// PublicKeys returns the keys verifying tokens of the maker.
+func (m *PasetoPublicMaker) PublicKeys() []PublicKey {
+	return m.keys.PublicKeys()
+}
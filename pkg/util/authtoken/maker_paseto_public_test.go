@@ -0,0 +1,85 @@
+package authtoken
+
+import (
+	"context"
+	"cryptowatch/pkg/util"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/o1egl/paseto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasetoPublicMaker(t *testing.T) {
+	ring, _ := newTestKeyRing(t)
+	maker := NewPasetoPublicMaker(ring)
+
+	userID := uint64(util.RandomInt(1, 1000))
+	duration := time.Minute
+
+	issuedAt := time.Now()
+	expiresAt := issuedAt.Add(duration)
+
+	token, err := maker.CreateToken(userID, duration, WithRole("admin"))
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(token, "v2.public."))
+
+	var footer keyFooter
+	require.NoError(t, paseto.ParseFooter(token, &footer))
+	key, _ := ring.SigningKey()
+	require.Equal(t, key.ID, footer.KeyID)
+
+	payload, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, userID, payload.UserID)
+	require.Equal(t, "admin", payload.Role)
+	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+	require.WithinDuration(t, expiresAt, payload.ExpiresAt, time.Second)
+}
+
+func TestPasetoPublicMaker_Invalid(t *testing.T) {
+	ring, _ := newTestKeyRing(t)
+	maker := NewPasetoPublicMaker(ring)
+
+	otherRing, _ := newTestKeyRing(t)
+	other := NewPasetoPublicMaker(otherRing)
+
+	token, err := other.CreateToken(1, time.Minute)
+	require.NoError(t, err)
+	_, err = maker.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+
+	// A token signed by another key with the footer of a known key.
+	key, _ := ring.SigningKey()
+	otherKey, _ := otherRing.SigningKey()
+	payload, err := NewPayload(1, time.Minute)
+	require.NoError(t, err)
+	forged, err := paseto.NewV2().Sign(otherKey.PrivateKey, payload, keyFooter{KeyID: key.ID})
+	require.NoError(t, err)
+	_, err = maker.VerifyToken(forged)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+
+	local, err := NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+	token, err = local.CreateToken(1, time.Minute)
+	require.NoError(t, err)
+	_, err = maker.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+
+	token, err = maker.CreateToken(1, -time.Minute)
+	require.NoError(t, err)
+	_, err = maker.VerifyToken(token)
+	require.EqualError(t, err, ErrExpiredToken.Error())
+}
+
+func TestPasetoPublicMaker_NoKey(t *testing.T) {
+	maker := NewPasetoPublicMaker(NewKeyRing(&memKeyStore{}, testKeyRingConfig))
+
+	_, err := maker.CreateToken(1, time.Minute)
+	require.ErrorIs(t, err, ErrNoSigningKey)
+	require.Empty(t, maker.PublicKeys())
+
+	require.NoError(t, maker.keys.Refresh(context.Background()))
+	require.Len(t, maker.PublicKeys(), 1)
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 )
 
@@ -12,13 +13,13 @@ func TestPasetoMaker(t *testing.T) {
 	maker, err := NewPasetoMaker(util.RandomString(32))
 	require.NoError(t, err)
 
-	username := util.RandomString(10)
+	userID := uint64(util.RandomInt(1, 1000))
 	duration := time.Minute
 
 	issuedAt := time.Now()
 	expiresAt := issuedAt.Add(duration)
 
-	token, err := maker.CreateToken(username, duration)
+	token, err := maker.CreateToken(userID, duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
 
@@ -27,7 +28,7 @@ func TestPasetoMaker(t *testing.T) {
 	require.NotEmpty(t, payload)
 
 	require.NotZero(t, payload.ID)
-	require.Equal(t, username, payload.Username)
+	require.Equal(t, userID, payload.UserID)
 	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
 	require.WithinDuration(t, expiresAt, payload.ExpiresAt, time.Second)
 }
@@ -36,7 +37,7 @@ func TestExpiredPasetoToken(t *testing.T) {
 	maker, err := NewPasetoMaker(util.RandomString(32))
 	require.NoError(t, err)
 
-	token, err := maker.CreateToken(util.RandomString(10), -time.Minute)
+	token, err := maker.CreateToken(uint64(util.RandomInt(1, 1000)), -time.Minute)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
 
@@ -44,3 +45,52 @@ func TestExpiredPasetoToken(t *testing.T) {
 	require.EqualError(t, err, ErrExpiredToken.Error())
 	require.Nil(t, payload)
 }
+
+func TestPasetoMakerWithID(t *testing.T) {
+	maker, err := NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	id := uuid.New()
+	token, err := maker.CreateToken(uint64(util.RandomInt(1, 1000)), time.Minute, WithID(id))
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, id, payload.ID)
+}
+
+func TestPasetoMakerClaims(t *testing.T) {
+	maker, err := NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	token, err := maker.CreateToken(1, time.Minute, WithIssuer("issuer"), WithAudience("audience"), WithScopes("alerts"))
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token, ExpectIssuer("issuer"), ExpectAudience("audience"))
+	require.NoError(t, err)
+	require.Equal(t, "issuer", payload.Issuer)
+	require.Equal(t, "audience", payload.Audience)
+	require.Equal(t, []string{"alerts"}, payload.Scopes)
+
+	_, err = maker.VerifyToken(token, ExpectIssuer("other"))
+	require.ErrorIs(t, err, ErrInvalidIssuer)
+	_, err = maker.VerifyToken(token, ExpectAudience("other"))
+	require.ErrorIs(t, err, ErrInvalidAudience)
+
+	// Tokens without claims don't meet expectations.
+	token, err = maker.CreateToken(1, time.Minute)
+	require.NoError(t, err)
+	_, err = maker.VerifyToken(token, ExpectAudience("audience"))
+	require.ErrorIs(t, err, ErrInvalidAudience)
+}
+
+func TestPasetoMakerNotBefore(t *testing.T) {
+	maker, err := NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	token, err := maker.CreateToken(1, time.Hour, WithNotBefore(time.Now().Add(time.Minute)))
+	require.NoError(t, err)
+
+	_, err = maker.VerifyToken(token)
+	require.ErrorIs(t, err, ErrTokenNotYetValid)
+}
@@ -7,8 +7,11 @@ import (
 )
 
 var (
-	ErrInvalidToken = errors.New("invalid token")
-	ErrExpiredToken = errors.New("token has expired")
+	ErrInvalidToken     = errors.New("invalid token")
+	ErrExpiredToken     = errors.New("token has expired")
+	ErrTokenNotYetValid = errors.New("token is not valid yet")
+	ErrInvalidIssuer    = errors.New("token has another issuer")
+	ErrInvalidAudience  = errors.New("token is meant for another audience")
 )
 
 // Payload contains the payload data of the token.
@@ -17,10 +20,88 @@ type Payload struct {
 	UserID    uint64    `json:"user_id"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiresAt time.Time `json:"expires_at"`
+	// NotBefore is when the token becomes valid, it is valid from issue if zero.
+	NotBefore time.Time `json:"not_before"`
+	// Issuer names the service that issued the token.
+	Issuer string `json:"issuer,omitempty"`
+	// Audience names the service the token is meant for.
+	Audience string `json:"audience,omitempty"`
+	// Scopes limit what the bearer may do, nothing is limited if empty.
+	Scopes []string `json:"scopes,omitempty"`
+	// Role is the role of the user when the token was issued.
+	Role string `json:"role,omitempty"`
+}
+
+// Option sets optional payload fields.
+type Option func(p *Payload)
+
+// WithID sets the payload id instead of a random one,
+// e.g. to bind the token to a session.
+func WithID(id uuid.UUID) Option {
+	return func(p *Payload) {
+		p.ID = id
+	}
+}
+
+// WithRole sets the role of the user.
+func WithRole(role string) Option {
+	return func(p *Payload) {
+		p.Role = role
+	}
+}
+
+// WithIssuer sets the issuer of the token.
+func WithIssuer(issuer string) Option {
+	return func(p *Payload) {
+		p.Issuer = issuer
+	}
+}
+
+// WithAudience sets the service the token is meant for.
+func WithAudience(audience string) Option {
+	return func(p *Payload) {
+		p.Audience = audience
+	}
+}
+
+// WithNotBefore sets when the token becomes valid.
+func WithNotBefore(t time.Time) Option {
+	return func(p *Payload) {
+		p.NotBefore = t
+	}
+}
+
+// WithScopes limits what the bearer may do.
+func WithScopes(scopes ...string) Option {
+	return func(p *Payload) {
+		p.Scopes = scopes
+	}
+}
+
+// Expectation is a claim the caller requires of a token.
+type Expectation func(e *expectations)
+
+type expectations struct {
+	issuer   string
+	audience string
+}
+
+// ExpectIssuer accepts only tokens issued by issuer.
+func ExpectIssuer(issuer string) Expectation {
+	return func(e *expectations) {
+		e.issuer = issuer
+	}
+}
+
+// ExpectAudience accepts only tokens meant for audience.
+func ExpectAudience(audience string) Expectation {
+	return func(e *expectations) {
+		e.audience = audience
+	}
 }
 
 // NewPayload creates a new token payload with a specific username and duration.
-func NewPayload(userID uint64, duration time.Duration) (*Payload, error) {
+func NewPayload(userID uint64, duration time.Duration, opts ...Option) (*Payload, error) {
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
@@ -33,14 +114,33 @@ func NewPayload(userID uint64, duration time.Duration) (*Payload, error) {
 		ExpiresAt: time.Now().Add(duration),
 	}
 
+	for _, opt := range opts {
+		opt(payload)
+	}
+
 	return payload, nil
 }
 
-// Valid checks if the token payload is valid or not.
-func (p *Payload) Valid() error {
-	if time.Now().After(p.ExpiresAt) {
+// Valid checks if the token payload is valid at the moment and has the expected claims.
+func (p *Payload) Valid(expect ...Expectation) error {
+	now := time.Now()
+	if now.After(p.ExpiresAt) {
 		return ErrExpiredToken
 	}
+	if now.Before(p.NotBefore) {
+		return ErrTokenNotYetValid
+	}
+
+	var e expectations
+	for _, opt := range expect {
+		opt(&e)
+	}
+	if e.issuer != "" && p.Issuer != e.issuer {
+		return ErrInvalidIssuer
+	}
+	if e.audience != "" && p.Audience != e.audience {
+		return ErrInvalidAudience
+	}
 
 	return nil
 }
@@ -0,0 +1,184 @@
+// Package oidc implements what a relying party needs of OpenID Connect:
+// provider discovery, the authorization code flow with PKCE and ID token verification.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxResponseSize limits responses read from the provider.
+const maxResponseSize = 1 << 20
+
+var ErrInvalidIDToken = errors.New("invalid ID token")
+
+// Provider is the discovery document of an OpenID provider.
+type Provider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Discover loads the discovery document of the issuer.
+func Discover(ctx context.Context, client *http.Client, issuer string) (*Provider, error) {
+	wellKnown := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+
+	var p Provider
+	err := getJSON(ctx, client, wellKnown, &p)
+	if err != nil {
+		return nil, fmt.Errorf("discover %s: %w", issuer, err)
+	}
+	if strings.TrimSuffix(p.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+		return nil, fmt.Errorf("discover %s: document is of issuer %s", issuer, p.Issuer)
+	}
+	if p.AuthorizationEndpoint == "" || p.TokenEndpoint == "" || p.JWKSURI == "" {
+		return nil, fmt.Errorf("discover %s: missing endpoints", issuer)
+	}
+
+	return &p, nil
+}
+
+type Config struct {
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is where the provider sends the user back with the code.
+	RedirectURL string
+	// Scopes are requested in addition to openid.
+	Scopes []string
+}
+
+// Client runs the authorization code flow with a provider.
+type Client struct {
+	provider *Provider
+	cfg      Config
+	http     *http.Client
+}
+
+func NewClient(provider *Provider, cfg Config, httpClient *http.Client) *Client {
+	return &Client{
+		provider: provider,
+		cfg:      cfg,
+		http:     httpClient,
+	}
+}
+
+// AuthCodeURL returns the URL sending the user to log in at the provider. state is returned
+// with the code, nonce is put into the ID token and codeVerifier is needed to redeem the code.
+func (c *Client) AuthCodeURL(state string, nonce string, codeVerifier string) string {
+	scopes := []string{"openid"}
+	for _, scope := range c.cfg.Scopes {
+		if scope != "openid" {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.cfg.ClientID},
+		"redirect_uri":          {c.cfg.RedirectURL},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {CodeChallenge(codeVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	sep := "?"
+	if strings.Contains(c.provider.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+
+	return c.provider.AuthorizationEndpoint + sep + q.Encode()
+}
+
+// tokenResponse is the token endpoint response, only the ID token is used.
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange redeems the code for tokens and returns the raw ID token, which still has to be verified.
+func (c *Client) Exchange(ctx context.Context, code string, codeVerifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.cfg.RedirectURL},
+		"code_verifier": {codeVerifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(c.cfg.ClientSecret))
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("exchange code: %w", err)
+	}
+	defer res.Body.Close()
+
+	var tr tokenResponse
+	err = json.NewDecoder(io.LimitReader(res.Body, maxResponseSize)).Decode(&tr)
+	if err != nil {
+		return "", fmt.Errorf("exchange code: %s: %w", res.Status, err)
+	}
+	if tr.Error != "" {
+		return "", fmt.Errorf("exchange code: %s: %s", tr.Error, tr.ErrorDescription)
+	}
+	if res.StatusCode != http.StatusOK || tr.IDToken == "" {
+		return "", fmt.Errorf("exchange code: %s: no ID token", res.Status)
+	}
+
+	return tr.IDToken, nil
+}
+
+// RandomString returns a random URL safe string, like a state, a nonce or a code verifier.
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CodeChallenge returns the S256 PKCE challenge of the code verifier.
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	return json.NewDecoder(io.LimitReader(res.Body, maxResponseSize)).Decode(v)
+}
@@ -0,0 +1,136 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"cryptowatch/pkg/util/oidc"
+	"cryptowatch/pkg/util/oidc/oidctest"
+)
+
+const redirectURL = "http://localhost:8080/v2/oidc/callback"
+
+func newTestProvider(t *testing.T) (*oidctest.Server, *oidc.Provider) {
+	t.Helper()
+
+	srv, err := oidctest.NewServer("cryptowatch", "secret")
+	require.NoError(t, err)
+	t.Cleanup(srv.Close)
+
+	provider, err := oidc.Discover(context.Background(), srv.Client(), srv.Issuer())
+	require.NoError(t, err)
+	require.Equal(t, srv.Issuer(), provider.Issuer)
+
+	return srv, provider
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	srv, provider := newTestProvider(t)
+	ctx := context.Background()
+
+	client := oidc.NewClient(provider, oidc.Config{
+		ClientID:     "cryptowatch",
+		ClientSecret: "secret",
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"profile", "email"},
+	}, srv.Client())
+	verifier := oidc.NewIDTokenVerifier(provider, "cryptowatch", srv.Client())
+
+	state, err := oidc.RandomString()
+	require.NoError(t, err)
+	nonce, err := oidc.RandomString()
+	require.NoError(t, err)
+	codeVerifier, err := oidc.RandomString()
+	require.NoError(t, err)
+
+	authURL := client.AuthCodeURL(state, nonce, codeVerifier)
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	require.Equal(t, "openid profile email", u.Query().Get("scope"))
+	require.Equal(t, oidc.CodeChallenge(codeVerifier), u.Query().Get("code_challenge"))
+
+	// The mock provider approves right away, the redirect back carries the code.
+	noRedirect := *srv.Client()
+	noRedirect.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	res, err := noRedirect.Get(authURL)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusFound, res.StatusCode)
+
+	callback, err := res.Location()
+	require.NoError(t, err)
+	require.Equal(t, state, callback.Query().Get("state"))
+	code := callback.Query().Get("code")
+
+	// Codes can't be redeemed without the verifier.
+	_, err = client.Exchange(ctx, code, "wrong")
+	require.Error(t, err)
+
+	res, err = noRedirect.Get(client.AuthCodeURL(state, nonce, codeVerifier))
+	require.NoError(t, err)
+	res.Body.Close()
+	callback, err = res.Location()
+	require.NoError(t, err)
+
+	raw, err := client.Exchange(ctx, callback.Query().Get("code"), codeVerifier)
+	require.NoError(t, err)
+
+	token, err := verifier.Verify(ctx, raw, nonce)
+	require.NoError(t, err)
+	require.Equal(t, srv.Issuer(), token.Issuer)
+	require.Equal(t, "1234567890", token.Subject)
+	require.Equal(t, "alice@example.com", token.Email)
+	require.True(t, token.EmailVerified)
+	require.Equal(t, "alice", token.PreferredUsername)
+
+	_, err = verifier.Verify(ctx, raw, "other nonce")
+	require.True(t, errors.Is(err, oidc.ErrInvalidIDToken))
+}
+
+func TestIDTokenVerifier(t *testing.T) {
+	srv, provider := newTestProvider(t)
+	ctx := context.Background()
+	verifier := oidc.NewIDTokenVerifier(provider, "cryptowatch", srv.Client())
+
+	tests := []struct {
+		name  string
+		extra map[string]interface{}
+		valid bool
+	}{
+		{"valid", nil, true},
+		{"audience array", map[string]interface{}{"aud": []string{"other", "cryptowatch"}}, true},
+		{"other audience", map[string]interface{}{"aud": "other"}, false},
+		{"other issuer", map[string]interface{}{"iss": "https://evil.example.com"}, false},
+		{"expired", map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()}, false},
+		{"issued in the future", map[string]interface{}{"iat": time.Now().Add(time.Hour).Unix()}, false},
+		{"no subject", map[string]interface{}{"sub": ""}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := verifier.Verify(ctx, srv.IDToken("nonce", tt.extra), "nonce")
+			if tt.valid {
+				require.NoError(t, err)
+			} else {
+				require.True(t, errors.Is(err, oidc.ErrInvalidIDToken), err)
+			}
+		})
+	}
+
+	t.Run("tampered", func(t *testing.T) {
+		raw := srv.IDToken("nonce", nil)
+		other := srv.IDToken("nonce", map[string]interface{}{"sub": "admin"})
+		tampered := raw[:len(raw)-10] + other[len(other)-10:]
+
+		_, err := verifier.Verify(ctx, tampered, "nonce")
+		require.True(t, errors.Is(err, oidc.ErrInvalidIDToken))
+	})
+}
@@ -0,0 +1,300 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clockSkew is tolerated between the provider and us when checking token times.
+const clockSkew = time.Minute
+
+// IDToken holds the claims of a verified ID token.
+type IDToken struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          audience `json:"aud"`
+	Expiry            int64    `json:"exp"`
+	IssuedAt          int64    `json:"iat"`
+	Nonce             string   `json:"nonce"`
+	Email             string   `json:"email"`
+	EmailVerified     bool     `json:"email_verified"`
+	Name              string   `json:"name"`
+	GivenName         string   `json:"given_name"`
+	FamilyName        string   `json:"family_name"`
+	PreferredUsername string   `json:"preferred_username"`
+}
+
+// audience is a string or an array of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if json.Unmarshal(b, &s) == nil {
+		*a = audience{s}
+		return nil
+	}
+
+	var ss []string
+	err := json.Unmarshal(b, &ss)
+	if err != nil {
+		return err
+	}
+	*a = ss
+
+	return nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// IDTokenVerifier verifies ID tokens issued by a provider to a client.
+// Signing keys are cached and loaded again when a token names an unknown key.
+type IDTokenVerifier struct {
+	provider *Provider
+	clientID string
+	http     *http.Client
+
+	mu   sync.Mutex
+	keys map[string]crypto.PublicKey
+}
+
+func NewIDTokenVerifier(provider *Provider, clientID string, httpClient *http.Client) *IDTokenVerifier {
+	return &IDTokenVerifier{
+		provider: provider,
+		clientID: clientID,
+		http:     httpClient,
+		keys:     make(map[string]crypto.PublicKey),
+	}
+}
+
+// Issuer returns the issuer of tokens the verifier accepts.
+func (v *IDTokenVerifier) Issuer() string {
+	return v.provider.Issuer
+}
+
+// Verify checks the signature and the claims of the raw ID token, including that it carries the nonce.
+func (v *IDTokenVerifier) Verify(ctx context.Context, raw string, nonce string) (*IDToken, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed", ErrInvalidIDToken)
+	}
+
+	var header jwtHeader
+	err := decodeSegment(parts[0], &header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidIDToken, err)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrInvalidIDToken, err)
+	}
+
+	key, err := v.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	err = verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), sig)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	var token IDToken
+	err = decodeSegment(parts[1], &token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrInvalidIDToken, err)
+	}
+
+	err = v.validate(&token, nonce, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	return &token, nil
+}
+
+func (v *IDTokenVerifier) validate(token *IDToken, nonce string, now time.Time) error {
+	if token.Issuer != v.provider.Issuer {
+		return fmt.Errorf("issued by %s", token.Issuer)
+	}
+	if token.Subject == "" {
+		return fmt.Errorf("no subject")
+	}
+
+	found := false
+	for _, aud := range token.Audience {
+		if aud == v.clientID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("not issued to %s", v.clientID)
+	}
+
+	if !now.Add(-clockSkew).Before(time.Unix(token.Expiry, 0)) {
+		return fmt.Errorf("expired")
+	}
+	if token.IssuedAt != 0 && now.Add(clockSkew).Before(time.Unix(token.IssuedAt, 0)) {
+		return fmt.Errorf("issued in the future")
+	}
+	if token.Nonce != nonce {
+		return fmt.Errorf("nonce mismatch")
+	}
+
+	return nil
+}
+
+// key returns the signing key with the id, loading the key set again if it is unknown.
+func (v *IDTokenVerifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	key, ok := v.keys[kid]
+	if ok {
+		return key, nil
+	}
+
+	var set jsonWebKeySet
+	err := getJSON(ctx, v.http, v.provider.JWKSURI, &set)
+	if err != nil {
+		return nil, fmt.Errorf("load signing keys: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		pub, err := jwk.publicKey()
+		if err != nil {
+			// Keys of other types are skipped, they can't verify the tokens anyway.
+			continue
+		}
+		keys[jwk.Kid] = pub
+	}
+	v.keys = keys
+
+	key, ok = v.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidIDToken, kid)
+	}
+
+	return key, nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+	}
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed []byte, sig []byte) error {
+	digest := sha256.Sum256(signed)
+
+	switch alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key does not match %s", alg)
+		}
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig)
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key does not match %s", alg)
+		}
+		if len(sig) != 64 {
+			return fmt.Errorf("invalid signature size")
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return fmt.Errorf("invalid signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("empty integer")
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
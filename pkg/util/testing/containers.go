@@ -2,6 +2,9 @@ package testing
 
 import (
 	"context"
+	dbschema "cryptowatch/db"
+	"cryptowatch/pkg/migrate"
+	"cryptowatch/pkg/util"
 	"fmt"
 	"github.com/docker/go-connections/nat"
 	"github.com/testcontainers/testcontainers-go"
@@ -20,6 +23,7 @@ type PostgresConfig struct {
 	DBName   string
 }
 
+// SetupPostgres starts a Postgres container and applies all schema migrations to it.
 func SetupPostgres(ctx context.Context, cfg PostgresConfig) (*Container, error) {
 	port := "5432/tcp"
 
@@ -64,8 +68,29 @@ func SetupPostgres(ctx context.Context, cfg PostgresConfig) (*Container, error)
 
 	uri := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", cfg.User, cfg.Password, ip, mappedPort.Port(), cfg.DBName)
 
+	err = migratePostgres(ctx, uri)
+	if err != nil {
+		container.Terminate(ctx)
+		return nil, err
+	}
+
 	return &Container{
 		Container: container,
 		URI:       uri,
 	}, nil
 }
+
+func migratePostgres(ctx context.Context, uri string) error {
+	db, err := util.OpenDB(uri)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrations, err := migrate.Load(dbschema.Migrations, dbschema.MigrationsDir)
+	if err != nil {
+		return err
+	}
+
+	return migrate.New(db, migrations).Up(ctx)
+}
@@ -0,0 +1,233 @@
+// Package tlsutil builds TLS configurations from certificate files.
+// Certificates and client CAs are reloaded when their files change, so they can
+// be rotated without a restart.
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+var ErrNoCertificates = errors.New("no certificates found")
+
+// ClientAuth selects how the server treats client certificates when a client CA is set.
+type ClientAuth string
+
+const (
+	// VerifyIfGiven verifies client certificates but also accepts clients without one.
+	VerifyIfGiven ClientAuth = "verify-if-given"
+	// Require rejects clients without a valid certificate.
+	Require ClientAuth = "require"
+)
+
+// NewServerConfig returns a server TLS configuration serving the certificate in certFile and keyFile.
+// When clientCAFile is not empty client certificates signed by it are verified according to clientAuth.
+func NewServerConfig(certFile string, keyFile string, clientCAFile string, clientAuth ClientAuth) (*tls.Config, error) {
+	r, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return r.get()
+		},
+	}
+
+	if clientCAFile != "" {
+		ca, err := newCAReloader(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+		if clientAuth == Require {
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+
+		base := cfg.Clone()
+		// ClientCAs is read from the config itself, so every handshake gets a copy
+		// carrying the current pool.
+		cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			pool, err := ca.get()
+			if err != nil {
+				return nil, err
+			}
+			c := base.Clone()
+			c.ClientCAs = pool
+			return c, nil
+		}
+	}
+
+	return cfg, nil
+}
+
+// NewClientConfig returns a client TLS configuration verifying the server against caFile,
+// or the system roots when caFile is empty. When certFile and keyFile are not empty
+// the client presents that certificate for mTLS.
+func NewClientConfig(caFile string, certFile string, keyFile string, serverName string) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+	}
+
+	if caFile != "" {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+
+	if certFile != "" || keyFile != "" {
+		r, err := newCertReloader(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return r.get()
+		}
+	}
+
+	return cfg, nil
+}
+
+func loadCertPool(file string) (*x509.CertPool, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read CA file: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("%w in %s", ErrNoCertificates, file)
+	}
+
+	return pool, nil
+}
+
+// certReloader loads a key pair again when either file is modified.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func newCertReloader(certFile string, keyFile string) (*certReloader, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("both certificate and key files must be set")
+	}
+
+	r := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+
+	_, err := r.get()
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// get returns the current certificate. If the files changed but can't be loaded,
+// for example while only one of them is replaced, the previous certificate is kept.
+func (r *certReloader) get() (*tls.Certificate, error) {
+	modTime, err := r.latestModTime()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err != nil || !modTime.After(r.modTime) {
+		if r.cert == nil {
+			return nil, fmt.Errorf("load key pair: %w", err)
+		}
+		return r.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		if r.cert == nil {
+			return nil, fmt.Errorf("load key pair: %w", err)
+		}
+		return r.cert, nil
+	}
+
+	r.cert = &cert
+	r.modTime = modTime
+
+	return r.cert, nil
+}
+
+func (r *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, file := range []string{r.certFile, r.keyFile} {
+		fi, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+
+	return latest, nil
+}
+
+// caReloader loads a CA pool again when its file is modified.
+type caReloader struct {
+	file string
+
+	mu      sync.Mutex
+	pool    *x509.CertPool
+	modTime time.Time
+}
+
+func newCAReloader(file string) (*caReloader, error) {
+	r := &caReloader{file: file}
+
+	_, err := r.get()
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// get returns the current pool. If the file changed but can't be loaded, the previous pool is kept.
+func (r *caReloader) get() (*x509.CertPool, error) {
+	fi, err := os.Stat(r.file)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err != nil || !fi.ModTime().After(r.modTime) {
+		if r.pool == nil {
+			return nil, fmt.Errorf("read CA file: %w", err)
+		}
+		return r.pool, nil
+	}
+
+	pool, err := loadCertPool(r.file)
+	if err != nil {
+		if r.pool == nil {
+			return nil, err
+		}
+		return r.pool, nil
+	}
+
+	r.pool = pool
+	r.modTime = fi.ModTime()
+
+	return r.pool, nil
+}
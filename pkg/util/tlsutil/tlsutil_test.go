@@ -0,0 +1,192 @@
+package tlsutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	file string
+}
+
+func newTestCA(t *testing.T, dir string) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	file := filepath.Join(dir, "ca.crt")
+	writePEM(t, file, "CERTIFICATE", der)
+
+	return &testCA{cert: cert, key: key, file: file}
+}
+
+// issue writes a certificate for name signed by the CA and returns the cert and key paths.
+func (ca *testCA) issue(t *testing.T, dir string, name string, serial int64) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, file string, typ string, der []byte) {
+	b := pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+	require.NoError(t, os.WriteFile(file, b, 0o600))
+}
+
+// handshake connects client to server and returns the serial number of the server certificate.
+func handshake(t *testing.T, server *tls.Config, client *tls.Config) (*big.Int, error) {
+	lis, err := tls.Listen("tcp", "127.0.0.1:0", server)
+	require.NoError(t, err)
+	defer lis.Close()
+
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// The handshake completes on the first write, a failed one closes the connection.
+		conn.Write([]byte{1})
+	}()
+
+	conn, err := tls.Dial("tcp", lis.Addr().String(), client)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// With TLS 1.3 the server verifies the client certificate after the client
+	// considers the handshake done, so a rejection shows up on read.
+	_, err = conn.Read(make([]byte, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	return conn.ConnectionState().PeerCertificates[0].SerialNumber, nil
+}
+
+func TestServerConfig_Reload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir)
+	certFile, keyFile := ca.issue(t, dir, "server", 10)
+
+	server, err := NewServerConfig(certFile, keyFile, "", "")
+	require.NoError(t, err)
+	client, err := NewClientConfig(ca.file, "", "", "server")
+	require.NoError(t, err)
+
+	serial, err := handshake(t, server, client)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), serial.Int64())
+
+	ca.issue(t, dir, "server", 11)
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+	require.NoError(t, os.Chtimes(keyFile, later, later))
+
+	serial, err = handshake(t, server, client)
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), serial.Int64())
+}
+
+func TestServerConfig_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir)
+	certFile, keyFile := ca.issue(t, dir, "server", 10)
+	clientCertFile, clientKeyFile := ca.issue(t, dir, "gateway", 20)
+
+	server, err := NewServerConfig(certFile, keyFile, ca.file, Require)
+	require.NoError(t, err)
+
+	withCert, err := NewClientConfig(ca.file, clientCertFile, clientKeyFile, "server")
+	require.NoError(t, err)
+	_, err = handshake(t, server, withCert)
+	require.NoError(t, err)
+
+	withoutCert, err := NewClientConfig(ca.file, "", "", "server")
+	require.NoError(t, err)
+	_, err = handshake(t, server, withoutCert)
+	require.Error(t, err)
+}
+
+func TestServerConfig_ReloadClientCA(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir)
+	certFile, keyFile := ca.issue(t, dir, "server", 10)
+	oldCertFile, oldKeyFile := ca.issue(t, dir, "old-gateway", 20)
+
+	server, err := NewServerConfig(certFile, keyFile, ca.file, Require)
+	require.NoError(t, err)
+
+	oldClient, err := NewClientConfig(ca.file, oldCertFile, oldKeyFile, "server")
+	require.NoError(t, err)
+	_, err = handshake(t, server, oldClient)
+	require.NoError(t, err)
+
+	// The new CA replaces the old one in the same file, the server certificate is unchanged.
+	newDir := t.TempDir()
+	newCA := newTestCA(t, newDir)
+	newCertFile, newKeyFile := newCA.issue(t, newDir, "new-gateway", 30)
+	b, err := os.ReadFile(newCA.file)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(ca.file, b, 0o600))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(ca.file, later, later))
+
+	newClient, err := NewClientConfig(ca.file, newCertFile, newKeyFile, "server")
+	require.NoError(t, err)
+	newClient.RootCAs = oldClient.RootCAs
+	_, err = handshake(t, server, newClient)
+	require.NoError(t, err)
+
+	_, err = handshake(t, server, oldClient)
+	require.Error(t, err)
+}
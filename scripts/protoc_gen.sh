@@ -1,14 +1,32 @@
 protoc \
   --go_out=. --go_opt=paths=import \
   --go-grpc_out=. --go-grpc_opt=paths=import \
+  api/proto/v1/options.proto \
   api/proto/v1/users.proto \
   api/proto/v1/portfolios.proto \
   api/proto/v1/triggers.proto
 
-protoc -I api/proto/v1 --grpc-gateway_out pkg/api/cryptowatchv1 \
+protoc -I api/proto/v1 -I . --grpc-gateway_out pkg/api/cryptowatchv1 \
           --grpc-gateway_opt logtostderr=true \
           --grpc-gateway_opt paths=source_relative \
           --grpc-gateway_opt generate_unbound_methods=true \
             api/proto/v1/users.proto \
             api/proto/v1/portfolios.proto \
             api/proto/v1/triggers.proto
+
+protoc \
+  --go_out=. --go_opt=paths=import \
+  --go_opt=Mapi/proto/v1/options.proto=cryptowatch/pkg/api/cryptowatchv1 \
+  --go-grpc_out=. --go-grpc_opt=paths=import \
+  --go-grpc_opt=Mapi/proto/v1/options.proto=cryptowatch/pkg/api/cryptowatchv1 \
+  api/proto/v2/users.proto \
+  api/proto/v2/portfolios.proto \
+  api/proto/v2/triggers.proto
+
+protoc -I api/proto/v2 -I . --grpc-gateway_out pkg/api/cryptowatchv2 \
+          --grpc-gateway_opt logtostderr=true \
+          --grpc-gateway_opt paths=source_relative \
+          --grpc-gateway_opt generate_unbound_methods=true \
+            api/proto/v2/users.proto \
+            api/proto/v2/portfolios.proto \
+            api/proto/v2/triggers.proto